@@ -0,0 +1,164 @@
+package dyndb
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/oktasecuritylabs/sgt/logger"
+	osq_types "github.com/oktasecuritylabs/sgt/osquery_types"
+)
+
+const (
+	campaignsTable     = "osquery_campaigns"
+	campaignNodesTable = "osquery_campaign_nodes"
+)
+
+// NewCampaign saves a campaign and the state of each of its nodes
+func (dyn DynDB) NewCampaign(c osq_types.Campaign, nodes []osq_types.CampaignNode) error {
+	mm, err := dynamodbattribute.MarshalMap(c)
+	if err != nil {
+		logger.Error(err)
+		return err
+	}
+	_, err = dyn.DB.PutItem(&dynamodb.PutItemInput{
+		TableName: aws.String(campaignsTable),
+		Item:      mm,
+	})
+	if err != nil {
+		logger.Error(err)
+		return err
+	}
+	for _, cn := range nodes {
+		if err := dyn.UpsertCampaignNode(cn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetCampaign returns the campaign, or an empty campaign if there is none with the ID
+func (dyn DynDB) GetCampaign(campaignID string) (osq_types.Campaign, error) {
+	c := osq_types.Campaign{}
+	key, err := dynamodbattribute.MarshalMap(struct {
+		CampaignID string `json:"campaign_id"`
+	}{campaignID})
+	if err != nil {
+		logger.Error(err)
+		return c, err
+	}
+	resp, err := dyn.DB.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String(campaignsTable),
+		Key:       key,
+	})
+	if err != nil {
+		logger.Error(err)
+		return c, err
+	}
+	if len(resp.Item) > 0 {
+		err = dynamodbattribute.UnmarshalMap(resp.Item, &c)
+		if err != nil {
+			logger.Error(err)
+		}
+	}
+	return c, err
+}
+
+// GetCampaigns returns every campaign
+func (dyn DynDB) GetCampaigns() ([]osq_types.Campaign, error) {
+	campaigns := []osq_types.Campaign{}
+	var unmarshalErr error
+	err := dyn.DB.ScanPages(&dynamodb.ScanInput{TableName: aws.String(campaignsTable)},
+		func(page *dynamodb.ScanOutput, lastPage bool) bool {
+			for _, item := range page.Items {
+				c := osq_types.Campaign{}
+				if unmarshalErr = dynamodbattribute.UnmarshalMap(item, &c); unmarshalErr != nil {
+					return false
+				}
+				campaigns = append(campaigns, c)
+			}
+			return true
+		})
+	if err == nil {
+		err = unmarshalErr
+	}
+	if err != nil {
+		logger.Error(err)
+	}
+	return campaigns, err
+}
+
+// GetCampaignNode returns the node's state in the campaign, or an empty CampaignNode if the campaign
+// doesn't target the node
+func (dyn DynDB) GetCampaignNode(campaignID, nodeKey string) (osq_types.CampaignNode, error) {
+	cn := osq_types.CampaignNode{}
+	key, err := dynamodbattribute.MarshalMap(struct {
+		CampaignID string `json:"campaign_id"`
+		NodeKey    string `json:"node_key"`
+	}{campaignID, nodeKey})
+	if err != nil {
+		logger.Error(err)
+		return cn, err
+	}
+	resp, err := dyn.DB.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String(campaignNodesTable),
+		Key:       key,
+	})
+	if err != nil {
+		logger.Error(err)
+		return cn, err
+	}
+	if len(resp.Item) > 0 {
+		err = dynamodbattribute.UnmarshalMap(resp.Item, &cn)
+		if err != nil {
+			logger.Error(err)
+		}
+	}
+	return cn, err
+}
+
+// GetCampaignNodes returns the state and results of every node the campaign targets
+func (dyn DynDB) GetCampaignNodes(campaignID string) ([]osq_types.CampaignNode, error) {
+	nodes := []osq_types.CampaignNode{}
+	var unmarshalErr error
+	err := dyn.DB.QueryPages(&dynamodb.QueryInput{
+		TableName:              aws.String(campaignNodesTable),
+		KeyConditionExpression: aws.String("campaign_id = :campaign_id"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":campaign_id": {S: aws.String(campaignID)},
+		},
+	}, func(page *dynamodb.QueryOutput, lastPage bool) bool {
+		for _, item := range page.Items {
+			cn := osq_types.CampaignNode{}
+			if unmarshalErr = dynamodbattribute.UnmarshalMap(item, &cn); unmarshalErr != nil {
+				return false
+			}
+			nodes = append(nodes, cn)
+		}
+		return true
+	})
+	if err == nil {
+		err = unmarshalErr
+	}
+	if err != nil {
+		logger.Error(err)
+	}
+	return nodes, err
+}
+
+// UpsertCampaignNode saves a node's state in a campaign
+func (dyn DynDB) UpsertCampaignNode(cn osq_types.CampaignNode) error {
+	mm, err := dynamodbattribute.MarshalMap(cn)
+	if err != nil {
+		logger.Error(err)
+		return err
+	}
+	_, err = dyn.DB.PutItem(&dynamodb.PutItemInput{
+		TableName: aws.String(campaignNodesTable),
+		Item:      mm,
+	})
+	if err != nil {
+		logger.Error(err)
+		return err
+	}
+	return nil
+}
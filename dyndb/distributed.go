@@ -1,6 +1,9 @@
 package dyndb
 
 import (
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/aws/aws-sdk-go/aws"
@@ -52,59 +55,143 @@ func (dyn DynDB) DeleteDistributedQuery(dq osq_types.DistributedQuery) (error) {
 }
 
 
-func (dyn DynDB) AppendDistributedQuery(dq osq_types.DistributedQuery) (error) {
-	//
-	//NOTE:  This could be optimized to take in teh results of the already made call to check if the key exists
-	// This is probably worth doing at some point when its beyond POC
-	//should only be called if a check has been run to verify that the node_key for the
-	//distributed query already exists (EG call distributed.SearchNodeKey())
-	existingDQ, err := dyn.SearchDistributedNodeKey(dq.NodeKey)
-	//create copy of queries in existing distributed query
-	existingQueries := map[string]bool{}
-	for _, j := range existingDQ.Queries {
-		existingQueries[j] = true
-	}
-	//delete existing distributed query
-	err = dyn.DeleteDistributedQuery(existingDQ)
-	if err != nil {
-		logger.Error(err)
+// distributedQuerySaveAttempts is how many times an update of a node's distributed queries is retried
+// when they are saved by another request between being read and saved
+const distributedQuerySaveAttempts = 5
+
+// UpdateDistributedQuery reads the node's distributed queries, applies update to them and saves them
+// if update returns true.  Nodes reading queries and writing results, the sweeper and queries being
+// added all change the same item, so the put is conditional on the revision that was read, and the
+// update is applied again to the newly saved queries when it loses
+func (dyn DynDB) UpdateDistributedQuery(nodeKey string, update func(dq *osq_types.DistributedQuery) bool) error {
+	for attempt := 1; ; attempt++ {
+		dq, err := dyn.SearchDistributedNodeKey(nodeKey)
+		if err != nil {
+			return err
+		}
+		dq.NodeKey = nodeKey
+		revision := dq.Revision
+		if !update(&dq) {
+			return nil
+		}
+		dq.Revision = revision + 1
+
+		mm, err := dynamodbattribute.MarshalMap(dq)
+		if err != nil {
+			logger.Error(err)
+			return err
+		}
+		_, err = dyn.DB.PutItem(&dynamodb.PutItemInput{
+			TableName:           aws.String("osquery_distributed_queries"),
+			Item:                mm,
+			ConditionExpression: aws.String("attribute_not_exists(revision) OR revision = :revision"),
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+				":revision": {N: aws.String(strconv.Itoa(revision))},
+			},
+		})
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException && attempt < distributedQuerySaveAttempts {
+			continue
+		}
+		if err != nil {
+			logger.Error(err)
+		}
 		return err
 	}
-	//append new queries to existing queries
-	for _, j := range dq.Queries {
-		if !existingQueries[j] {
-			existingDQ.Queries = append(existingDQ.Queries, j)
+}
+
+// AppendDistributedQuery adds the queries to those the node already has, skipping queries it already
+// has waiting to be sent
+func (dyn DynDB) AppendDistributedQuery(dq osq_types.DistributedQuery) error {
+	return dyn.UpdateDistributedQuery(dq.NodeKey, func(existing *osq_types.DistributedQuery) bool {
+		existingQueries := map[string]bool{}
+		for _, j := range existing.Queries {
+			existingQueries[j] = true
 		}
-	}
+		for _, j := range dq.Queries {
+			if !existingQueries[j] {
+				existing.Queries = append(existing.Queries, j)
+				existingQueries[j] = true
+			}
+		}
+		existing.AddPending(dq.Pending...)
+		return true
+	})
+}
+
+// UpsertDistributedQuery adds the queries to the node's distributed queries, creating them if the node
+// has none
+func (dyn DynDB) UpsertDistributedQuery(dq osq_types.DistributedQuery) error {
+	return dyn.AppendDistributedQuery(dq)
+}
+
+// NewDistributedStatus saves the status a node reported for a distributed query
+func (dyn DynDB) NewDistributedStatus(ds osq_types.DistributedStatus) error {
+	mm, err := dynamodbattribute.MarshalMap(ds)
 	if err != nil {
 		logger.Error(err)
 		return err
 	}
-	//recreate distributed query with new + old queries
-	err = dyn.NewDistributedQuery(existingDQ)
+	_, err = dyn.DB.PutItem(&dynamodb.PutItemInput{
+		TableName: aws.String("osquery_distributed_statuses"),
+		Item:      mm,
+	})
 	if err != nil {
 		logger.Error(err)
 		return err
 	}
 	return nil
-
 }
 
-
-func (dyn DynDB) UpsertDistributedQuery(dq osq_types.DistributedQuery) (error) {
-	//queries for node_key in dynamodb.  if found, appends queries to existing list
-	//if not found, creates item and adds queries
-	//Search for key
-	//dynamoDB := dyndb.DbInstance()
-	existing, err := dyn.SearchDistributedNodeKey(dq.NodeKey)
+// GetDistributedStatuses returns the statuses a node has reported for distributed queries, newest first
+func (dyn DynDB) GetDistributedStatuses(nodeKey string) ([]osq_types.DistributedStatus, error) {
+	statuses := []osq_types.DistributedStatus{}
+	var unmarshalErr error
+	err := dyn.DB.QueryPages(&dynamodb.QueryInput{
+		TableName:              aws.String("osquery_distributed_statuses"),
+		KeyConditionExpression: aws.String("node_key = :node_key"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":node_key": {S: aws.String(nodeKey)},
+		},
+		ScanIndexForward: aws.Bool(false),
+	}, func(page *dynamodb.QueryOutput, lastPage bool) bool {
+		for _, item := range page.Items {
+			ds := osq_types.DistributedStatus{}
+			if unmarshalErr = dynamodbattribute.UnmarshalMap(item, &ds); unmarshalErr != nil {
+				return false
+			}
+			statuses = append(statuses, ds)
+		}
+		return true
+	})
+	if err == nil {
+		err = unmarshalErr
+	}
 	if err != nil {
-		return err
+		logger.Error(err)
 	}
+	return statuses, err
+}
 
-	if existing.NodeKey != "" {
-		return dyn.AppendDistributedQuery(dq)
+// GetDistributedQueries returns the distributed queries of every node
+func (dyn DynDB) GetDistributedQueries() ([]osq_types.DistributedQuery, error) {
+	queries := []osq_types.DistributedQuery{}
+	var unmarshalErr error
+	err := dyn.DB.ScanPages(&dynamodb.ScanInput{TableName: aws.String("osquery_distributed_queries")},
+		func(page *dynamodb.ScanOutput, lastPage bool) bool {
+			for _, item := range page.Items {
+				dq := osq_types.DistributedQuery{}
+				if unmarshalErr = dynamodbattribute.UnmarshalMap(item, &dq); unmarshalErr != nil {
+					return false
+				}
+				queries = append(queries, dq)
+			}
+			return true
+		})
+	if err == nil {
+		err = unmarshalErr
 	}
-
-	return dyn.NewDistributedQuery(dq)
-
+	if err != nil {
+		logger.Error(err)
+	}
+	return queries, err
 }
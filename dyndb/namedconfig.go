@@ -7,63 +7,126 @@ import (
 	"github.com/aws/aws-sdk-go/aws"
 	osq_types "github.com/oktasecuritylabs/sgt/osquery_types"
 	"github.com/oktasecuritylabs/sgt/logger"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"errors"
+	"strconv"
+	"time"
 )
 
-func (db DynDB) BuildNamedConfig(configName string) (osq_types.OsqueryNamedConfig, error) {
+// BuildNamedConfig returns the named config with its packs filled in for the target node at now.  Packs
+// outside their window or limited to a tag the node doesn't have, and packs and queries that don't apply
+// to the node's platform or osquery version, are left out and listed in Filtered
+func (db DynDB) BuildNamedConfig(configName string, target osq_types.RenderTarget, now time.Time) (osq_types.OsqueryNamedConfig, error) {
 	storedNC := osq_types.OsqueryNamedConfig{}
 	oc := osq_types.OsqueryConfig{}
 	storedNC, err := db.GetNamedConfig(configName)
 	if err != nil {
 		return storedNC, err
 	}
-	storedNC.OsqueryConfig.Packs = make(map[string]map[string]map[string]map[string]string)
-	//oc = storedNC.OsqueryConfig
-	for _, packName := range storedNC.PackList {
+	storedNC.OsqueryConfig.Packs = make(map[string]map[string]interface{})
+	//a pack can be listed more than once, eg for different tags, and is sent if any of its entries apply
+	sent := map[string]bool{}
+	excluded := map[string]string{}
+	for _, entry := range storedNC.PackList {
+		packName := entry.PackName
+		if sent[packName] {
+			continue
+		}
+		if reason := entry.ForTarget(target, now); reason != "" {
+			if _, ok := excluded[packName]; !ok {
+				excluded[packName] = reason
+			}
+			continue
+		}
+		sent[packName] = true
 		fmt.Printf("adding %s to config", packName)
 		fmt.Printf("config now: %+v", oc)
 		p, err := db.GetPackByName(packName)
 		if err != nil {
 			return storedNC, err
 		}
+		p, ok, filtered := p.ForTarget(target)
+		storedNC.Filtered = append(storedNC.Filtered, filtered...)
+		if !ok {
+			continue
+		}
 		storedNC.OsqueryConfig.Packs[packName] = p.AsMap()
 	}
+	for _, packName := range storedNC.PackList.Names() {
+		if reason, ok := excluded[packName]; ok && !sent[packName] {
+			storedNC.Filtered = append(storedNC.Filtered, osq_types.FilteredEntry{Pack: packName, Reason: reason})
+			delete(excluded, packName)
+		}
+	}
 
 	return storedNC, nil
 }
 
 
-// UpsertNamedConfig upserts named config to dynamo db.  Returns true if successful, else false
+// namedConfigSaveAttempts is how many times a save is retried when another save of the same config
+// takes the revision it was going to write
+const namedConfigSaveAttempts = 5
+
+// UpsertNamedConfig upserts named config to dynamo db.  Every save is a new revision, so nodes still on
+// an older one can be found.  The put is conditional on the revision it replaces so that concurrent
+// saves each get their own revision
 func (db DynDB) UpsertNamedConfig(onc *osq_types.OsqueryNamedConfig) error {
+	for attempt := 1; ; attempt++ {
+		existing, err := db.GetNamedConfig(onc.ConfigName)
+		if err != nil {
+			return err
+		}
+		onc.Revision = existing.Revision + 1
 
-	av, err := dynamodbattribute.MarshalMap(onc)
-	if err != nil {
-		logger.Error("Marshal Failed")
+		av, err := dynamodbattribute.MarshalMap(onc)
+		if err != nil {
+			logger.Error("Marshal Failed")
+			return err
+		}
+
+		_, err = db.DB.PutItem(&dynamodb.PutItemInput{
+			TableName:           aws.String("osquery_configurations"),
+			Item:                av,
+			ConditionExpression: aws.String("attribute_not_exists(revision) OR revision = :revision"),
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+				":revision": {N: aws.String(strconv.Itoa(existing.Revision))},
+			},
+		})
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException && attempt < namedConfigSaveAttempts {
+			continue
+		}
+		if err != nil {
+			logger.Error(err)
+		}
 		return err
 	}
-
-	_, err = db.DB.PutItem(&dynamodb.PutItemInput{
-		TableName: aws.String("osquery_configurations"),
-		Item:      av,
-	})
-
-	return err
 }
 
-func UpsertNamedConfig(dynamoDB *dynamodb.DynamoDB, onc *osq_types.OsqueryNamedConfig) error {
-
-	av, err := dynamodbattribute.MarshalMap(onc)
+// DeleteNamedConfig removes the named config from dynamo db
+func (db DynDB) DeleteNamedConfig(configName string) error {
+	type qs struct {
+		ConfigName string `json:"config_name"`
+	}
+	av, err := dynamodbattribute.MarshalMap(qs{configName})
 	if err != nil {
-		logger.Error("Marshal Failed")
+		logger.Error(err)
 		return err
 	}
 
-	_, err = dynamoDB.PutItem(&dynamodb.PutItemInput{
+	_, err = db.DB.DeleteItem(&dynamodb.DeleteItemInput{
 		TableName: aws.String("osquery_configurations"),
-		Item:      av,
+		Key:       av,
 	})
+	if err != nil {
+		logger.Error(err)
+		return err
+	}
+	return nil
+}
 
-	return err
+// UpsertNamedConfig upserts named config to dynamo db, see DynDB.UpsertNamedConfig
+func UpsertNamedConfig(dynamoDB *dynamodb.DynamoDB, onc *osq_types.OsqueryNamedConfig) error {
+	return DynDB{DB: dynamoDB}.UpsertNamedConfig(onc)
 }
 
 // GetNamedConfigs returns all named configs
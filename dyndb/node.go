@@ -30,6 +30,8 @@ func (db DynDB) ApprovePendingNode(nodeKey string) (error) {
 		newClient.HostDetails = osqNode.HostDetails
 		newClient.ConfigurationGroup = osqNode.ConfigurationGroup
 		newClient.Tags = osqNode.Tags
+		newClient.ConfigOverride = osqNode.ConfigOverride
+		newClient.ConfigDelivery = osqNode.ConfigDelivery
 		err := db.UpsertClient(newClient)
 		if err != nil {
 			logger.Error(err)
@@ -89,3 +89,26 @@ func (dyn DynDB) UpsertPackQuery(pq osq_types.PackQuery) (error) {
 	return err
 
 }
+
+// DeletePackQuery removes the pack query from dynamo db
+func (dyn DynDB) DeletePackQuery(queryName string) (error) {
+	type qs struct {
+		QueryName string `json:"query_name"`
+	}
+	av, err := dynamodbattribute.MarshalMap(qs{queryName})
+	if err != nil {
+		logger.Error(err)
+		return err
+	}
+
+	_, err = dyn.DB.DeleteItem(&dynamodb.DeleteItemInput{
+		TableName: aws.String("osquery_packqueries"),
+		Key:       av,
+	})
+	if err != nil {
+		logger.Error(err)
+		return err
+	}
+	return nil
+
+}
@@ -37,11 +37,7 @@ func (dyn DynDB) GetPackByName(packName string) (osq_types.Pack, error) {
 		return pack, err
 	}
 	//create empty pack to marshal data into
-	type QueryPack struct {
-		PackName string   `json:"packName"`
-		Queries  []string `json:"queries"`
-	}
-	querypack := QueryPack{}
+	querypack := osq_types.QueryPack{}
 	if len(resp.Item) > 0 {
 		err = dynamodbattribute.UnmarshalMap(resp.Item, &querypack)
 		if err != nil {
@@ -50,6 +46,7 @@ func (dyn DynDB) GetPackByName(packName string) (osq_types.Pack, error) {
 		}
 		//here we actually build our osquery.Pack
 		pack.PackName = querypack.PackName
+		pack.PackSettings = querypack.PackSettings
 		//pack.Queries = qp.Queries
 		//itterate over list of queries and retrieve actual queries
 		for _, query := range querypack.Queries {
@@ -57,7 +54,7 @@ func (dyn DynDB) GetPackByName(packName string) (osq_types.Pack, error) {
 			if err != nil {
 				logger.Error(err)
 			}
-			pack.Queries = append(pack.Queries, packquery)
+			pack.Queries = append(pack.Queries, querypack.ResolveQuery(packquery))
 		}
 		return pack, nil
 	}
@@ -65,6 +62,35 @@ func (dyn DynDB) GetPackByName(packName string) (osq_types.Pack, error) {
 
 }
 
+// GetQueryPack returns the stored pack with the names of its queries, an empty pack if it doesn't exist
+func (dyn DynDB) GetQueryPack(packName string) (osq_types.QueryPack, error) {
+	querypack := osq_types.QueryPack{}
+	type QS struct {
+		PackName string `json:"pack_name"`
+	}
+	js, err := dynamodbattribute.MarshalMap(QS{packName})
+	if err != nil {
+		logger.Error(err)
+		return querypack, err
+	}
+	resp, err := dyn.DB.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String("osquery_querypacks"),
+		Key:       js,
+	})
+	if err != nil {
+		logger.Error(err)
+		return querypack, err
+	}
+	if len(resp.Item) > 0 {
+		err = dynamodbattribute.UnmarshalMap(resp.Item, &querypack)
+		if err != nil {
+			logger.Error(err)
+			return querypack, err
+		}
+	}
+	return querypack, nil
+}
+
 func (dyn DynDB) SearchQueryPacks(searchString string) ([]osq_types.QueryPack, error) {
 	results := []osq_types.QueryPack{}
 	scanItems, err := dyn.DB.Scan(&dynamodb.ScanInput{
@@ -135,7 +161,7 @@ func (dyn DynDB) DeleteQueryPack(queryPackName string) (error) {
 
 func (dyn DynDB) UpsertPack(qp osq_types.QueryPack) (error) {
 	//Additive upsert.
-	existing, err := dyn.GetPackByName(qp.PackName)
+	existing, err := dyn.GetQueryPack(qp.PackName)
 	if err != nil {
 		return err
 	}
@@ -145,8 +171,8 @@ func (dyn DynDB) UpsertPack(qp osq_types.QueryPack) (error) {
 	}
 
 	existingQueries := map[string]bool{}
-	for _, packQuery := range existing.Queries {
-		existingQueries[packQuery.QueryName] = true
+	for _, query := range existing.Queries {
+		existingQueries[query] = true
 	}
 
 	//note:  qp.Queries is a list of strings, not pack_queries
@@ -161,9 +187,24 @@ func (dyn DynDB) UpsertPack(qp osq_types.QueryPack) (error) {
 	logger.Debug(existingQueries)
 	newQueryPack := osq_types.QueryPack{}
 	newQueryPack.PackName = existing.PackName
+	newQueryPack.Deployed = existing.Deployed
+	newQueryPack.PackSettings = existing.PackSettings
+	if !qp.PackSettings.IsZero() {
+		newQueryPack.PackSettings = qp.PackSettings
+	}
 	for query := range existingQueries {
 		newQueryPack.Queries = append(newQueryPack.Queries, query)
 	}
+	//overrides posted for a query replace any existing override for it
+	if len(existing.Overrides) > 0 || len(qp.Overrides) > 0 {
+		newQueryPack.Overrides = map[string]osq_types.PackQueryOverride{}
+		for query, override := range existing.Overrides {
+			newQueryPack.Overrides[query] = override
+		}
+		for query, override := range qp.Overrides {
+			newQueryPack.Overrides[query] = override
+		}
+	}
 
 	err = dyn.DeleteQueryPack(qp.PackName)
 	if err != nil {
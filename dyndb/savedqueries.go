@@ -0,0 +1,101 @@
+package dyndb
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/oktasecuritylabs/sgt/logger"
+	osq_types "github.com/oktasecuritylabs/sgt/osquery_types"
+)
+
+const savedQueriesTable = "osquery_saved_queries"
+
+// UpsertSavedQuery saves a query to the library
+func (dyn DynDB) UpsertSavedQuery(sq osq_types.SavedQuery) error {
+	mm, err := dynamodbattribute.MarshalMap(sq)
+	if err != nil {
+		logger.Error(err)
+		return err
+	}
+	_, err = dyn.DB.PutItem(&dynamodb.PutItemInput{
+		TableName: aws.String(savedQueriesTable),
+		Item:      mm,
+	})
+	if err != nil {
+		logger.Error(err)
+		return err
+	}
+	return nil
+}
+
+func savedQueryKey(name string) (map[string]*dynamodb.AttributeValue, error) {
+	return dynamodbattribute.MarshalMap(struct {
+		Name string `json:"name"`
+	}{name})
+}
+
+// GetSavedQuery returns the saved query, or an empty SavedQuery if there is none with the name
+func (dyn DynDB) GetSavedQuery(name string) (osq_types.SavedQuery, error) {
+	sq := osq_types.SavedQuery{}
+	key, err := savedQueryKey(name)
+	if err != nil {
+		logger.Error(err)
+		return sq, err
+	}
+	resp, err := dyn.DB.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String(savedQueriesTable),
+		Key:       key,
+	})
+	if err != nil {
+		logger.Error(err)
+		return sq, err
+	}
+	if len(resp.Item) > 0 {
+		err = dynamodbattribute.UnmarshalMap(resp.Item, &sq)
+		if err != nil {
+			logger.Error(err)
+		}
+	}
+	return sq, err
+}
+
+// GetSavedQueries returns every saved query
+func (dyn DynDB) GetSavedQueries() ([]osq_types.SavedQuery, error) {
+	queries := []osq_types.SavedQuery{}
+	var unmarshalErr error
+	err := dyn.DB.ScanPages(&dynamodb.ScanInput{TableName: aws.String(savedQueriesTable)},
+		func(page *dynamodb.ScanOutput, lastPage bool) bool {
+			for _, item := range page.Items {
+				sq := osq_types.SavedQuery{}
+				if unmarshalErr = dynamodbattribute.UnmarshalMap(item, &sq); unmarshalErr != nil {
+					return false
+				}
+				queries = append(queries, sq)
+			}
+			return true
+		})
+	if err == nil {
+		err = unmarshalErr
+	}
+	if err != nil {
+		logger.Error(err)
+	}
+	return queries, err
+}
+
+// DeleteSavedQuery removes a query from the library
+func (dyn DynDB) DeleteSavedQuery(name string) error {
+	key, err := savedQueryKey(name)
+	if err != nil {
+		logger.Error(err)
+		return err
+	}
+	_, err = dyn.DB.DeleteItem(&dynamodb.DeleteItemInput{
+		TableName: aws.String(savedQueriesTable),
+		Key:       key,
+	})
+	if err != nil {
+		logger.Error(err)
+	}
+	return err
+}
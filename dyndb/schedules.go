@@ -0,0 +1,191 @@
+package dyndb
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/oktasecuritylabs/sgt/logger"
+	osq_types "github.com/oktasecuritylabs/sgt/osquery_types"
+)
+
+const (
+	distributedSchedulesTable    = "osquery_distributed_schedules"
+	distributedScheduleRunsTable = "osquery_distributed_schedule_runs"
+	leasesTable                  = "osquery_leases"
+)
+
+// UpsertDistributedSchedule saves a schedule
+func (dyn DynDB) UpsertDistributedSchedule(ds osq_types.DistributedSchedule) error {
+	mm, err := dynamodbattribute.MarshalMap(ds)
+	if err != nil {
+		logger.Error(err)
+		return err
+	}
+	_, err = dyn.DB.PutItem(&dynamodb.PutItemInput{
+		TableName: aws.String(distributedSchedulesTable),
+		Item:      mm,
+	})
+	if err != nil {
+		logger.Error(err)
+		return err
+	}
+	return nil
+}
+
+func scheduleKey(scheduleID string) (map[string]*dynamodb.AttributeValue, error) {
+	return dynamodbattribute.MarshalMap(struct {
+		ScheduleID string `json:"schedule_id"`
+	}{scheduleID})
+}
+
+// GetDistributedSchedule returns the schedule, or an empty schedule if there is none with the ID
+func (dyn DynDB) GetDistributedSchedule(scheduleID string) (osq_types.DistributedSchedule, error) {
+	ds := osq_types.DistributedSchedule{}
+	key, err := scheduleKey(scheduleID)
+	if err != nil {
+		logger.Error(err)
+		return ds, err
+	}
+	resp, err := dyn.DB.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String(distributedSchedulesTable),
+		Key:       key,
+	})
+	if err != nil {
+		logger.Error(err)
+		return ds, err
+	}
+	if len(resp.Item) > 0 {
+		err = dynamodbattribute.UnmarshalMap(resp.Item, &ds)
+		if err != nil {
+			logger.Error(err)
+		}
+	}
+	return ds, err
+}
+
+// GetDistributedSchedules returns every schedule
+func (dyn DynDB) GetDistributedSchedules() ([]osq_types.DistributedSchedule, error) {
+	schedules := []osq_types.DistributedSchedule{}
+	var unmarshalErr error
+	err := dyn.DB.ScanPages(&dynamodb.ScanInput{TableName: aws.String(distributedSchedulesTable)},
+		func(page *dynamodb.ScanOutput, lastPage bool) bool {
+			for _, item := range page.Items {
+				ds := osq_types.DistributedSchedule{}
+				if unmarshalErr = dynamodbattribute.UnmarshalMap(item, &ds); unmarshalErr != nil {
+					return false
+				}
+				schedules = append(schedules, ds)
+			}
+			return true
+		})
+	if err == nil {
+		err = unmarshalErr
+	}
+	if err != nil {
+		logger.Error(err)
+	}
+	return schedules, err
+}
+
+// DeleteDistributedSchedule deletes a schedule.  Its run history is kept
+func (dyn DynDB) DeleteDistributedSchedule(scheduleID string) error {
+	key, err := scheduleKey(scheduleID)
+	if err != nil {
+		logger.Error(err)
+		return err
+	}
+	_, err = dyn.DB.DeleteItem(&dynamodb.DeleteItemInput{
+		TableName: aws.String(distributedSchedulesTable),
+		Key:       key,
+	})
+	if err != nil {
+		logger.Error(err)
+	}
+	return err
+}
+
+// NewDistributedScheduleRun saves a run of a schedule
+func (dyn DynDB) NewDistributedScheduleRun(run osq_types.DistributedScheduleRun) error {
+	mm, err := dynamodbattribute.MarshalMap(run)
+	if err != nil {
+		logger.Error(err)
+		return err
+	}
+	_, err = dyn.DB.PutItem(&dynamodb.PutItemInput{
+		TableName: aws.String(distributedScheduleRunsTable),
+		Item:      mm,
+	})
+	if err != nil {
+		logger.Error(err)
+		return err
+	}
+	return nil
+}
+
+// GetDistributedScheduleRuns returns the runs of a schedule, newest first
+func (dyn DynDB) GetDistributedScheduleRuns(scheduleID string) ([]osq_types.DistributedScheduleRun, error) {
+	runs := []osq_types.DistributedScheduleRun{}
+	var unmarshalErr error
+	err := dyn.DB.QueryPages(&dynamodb.QueryInput{
+		TableName:              aws.String(distributedScheduleRunsTable),
+		KeyConditionExpression: aws.String("schedule_id = :schedule_id"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":schedule_id": {S: aws.String(scheduleID)},
+		},
+		ScanIndexForward: aws.Bool(false),
+	}, func(page *dynamodb.QueryOutput, lastPage bool) bool {
+		for _, item := range page.Items {
+			run := osq_types.DistributedScheduleRun{}
+			if unmarshalErr = dynamodbattribute.UnmarshalMap(item, &run); unmarshalErr != nil {
+				return false
+			}
+			runs = append(runs, run)
+		}
+		return true
+	})
+	if err == nil {
+		err = unmarshalErr
+	}
+	if err != nil {
+		logger.Error(err)
+	}
+	return runs, err
+}
+
+// AcquireLease takes or renews the named lease for owner until ttl after now, and returns true if
+// owner holds it.  The lease can only be taken from another owner once it has expired
+func (dyn DynDB) AcquireLease(name, owner string, ttl time.Duration, now time.Time) (bool, error) {
+	mm, err := dynamodbattribute.MarshalMap(osq_types.Lease{
+		LeaseName: name,
+		Owner:     owner,
+		ExpiresAt: now.Add(ttl).Unix(),
+	})
+	if err != nil {
+		logger.Error(err)
+		return false, err
+	}
+	_, err = dyn.DB.PutItem(&dynamodb.PutItemInput{
+		TableName:           aws.String(leasesTable),
+		Item:                mm,
+		ConditionExpression: aws.String("attribute_not_exists(lease_name) OR expires_at < :now OR #owner = :owner"),
+		ExpressionAttributeNames: map[string]*string{
+			"#owner": aws.String("owner"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":now":   {N: aws.String(strconv.FormatInt(now.Unix(), 10))},
+			":owner": {S: aws.String(owner)},
+		},
+	})
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return false, nil
+	}
+	if err != nil {
+		logger.Error(err)
+		return false, err
+	}
+	return true, nil
+}
@@ -6,9 +6,12 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/oktasecuritylabs/sgt/handlers/response"
+	"github.com/oktasecuritylabs/sgt/internal/pkg/sqllint"
 	"github.com/oktasecuritylabs/sgt/logger"
 	"github.com/oktasecuritylabs/sgt/osquery_types"
 	log "github.com/sirupsen/logrus"
@@ -17,7 +20,9 @@ import (
 type ApiDB interface {
 	GetNamedConfigs() ([]osquery_types.OsqueryNamedConfig, error)
 	GetNamedConfig(configName string) (osquery_types.OsqueryNamedConfig, error)
+	BuildNamedConfig(configName string, target osquery_types.RenderTarget, now time.Time) (osquery_types.OsqueryNamedConfig, error)
 	UpsertNamedConfig(onc *osquery_types.OsqueryNamedConfig) error
+	DeleteNamedConfig(configName string) error
 	UpsertClient(oc osquery_types.OsqueryClient) error
 	SearchByHostIdentifier(hid string) ([]osquery_types.OsqueryClient, error)
 	ApprovePendingNode(nodeKey string) error
@@ -27,7 +32,9 @@ type ApiDB interface {
 	APISearchPackQueries(searchString string) ([]osquery_types.PackQuery, error)
 	GetPackQuery(queryName string) (osquery_types.PackQuery, error)
 	UpsertPackQuery(pq osquery_types.PackQuery) error
+	DeletePackQuery(queryName string) error
 	GetPackByName(packName string) (osquery_types.Pack, error)
+	GetQueryPack(packName string) (osquery_types.QueryPack, error)
 	SearchQueryPacks(searchString string) ([]osquery_types.QueryPack, error)
 	NewQueryPack(qp osquery_types.QueryPack) error
 	DeleteQueryPack(queryPackName string) error
@@ -37,6 +44,7 @@ type ApiDB interface {
 	DeleteDistributedQuery(dq osquery_types.DistributedQuery) error
 	AppendDistributedQuery(dq osquery_types.DistributedQuery) error
 	UpsertDistributedQuery(dq osquery_types.DistributedQuery) error
+	GetDistributedStatuses(nodeKey string) ([]osquery_types.DistributedStatus, error)
 	NewUser(u osquery_types.User) error
 	GetUser(username string) (osquery_types.User, error)
 	DeleteNodeByNodekey(nodeKey string) error
@@ -75,7 +83,11 @@ func GetNamedConfigsHandler(db ApiDB) http.Handler {
 	})
 }
 
-// ConfigurationRequestHandler accepts a json post body of a NamedConfig
+// ConfigurationRequestHandler gets, updates or deletes the NamedConfig specified by {config_name}.  POST merges
+// the body over the stored config with options reset to their defaults, PUT replaces the config with
+// the body and PATCH applies a JSON Merge Patch or JSON Patch to it.  With dry_run=true the updated
+// config is validated and diffed against the stored one, but not saved.  DELETE is refused while nodes
+// are assigned to the config unless cascade=true
 func ConfigurationRequestHandler(db ApiDB) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		handleRequest := func() (interface{}, error) {
@@ -95,9 +107,32 @@ func ConfigurationRequestHandler(db ApiDB) http.Handler {
 
 			switch r.Method {
 			case http.MethodGet:
-
 				return existingNamedConfig, nil
 
+			case http.MethodDelete:
+				if existingNamedConfig.ConfigName == "" {
+					return nil, fmt.Errorf("no config found with name [%s]", configName)
+				}
+				refs, err := deleteNamedConfig(db, configName, cascadeRequested(r))
+				if err != nil {
+					return nil, err
+				}
+				return deleteResult{Deleted: configName, Cascaded: refs}, nil
+			}
+
+			// keep a copy of the stored config to diff a dry run against, the maps in it are
+			// shared with existingNamedConfig and get merged into below
+			storedNamedConfig, err := json.Marshal(existingNamedConfig)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal named config: %s", err)
+			}
+			// nodes only need restarting for options changed on a config they may already be using
+			var storedOptions osquery_types.OsqueryOptions
+			if existingNamedConfig.ConfigName != "" {
+				storedOptions = existingNamedConfig.OsqueryConfig.Options.Copy()
+			}
+
+			switch r.Method {
 			case http.MethodPost:
 
 				//now merge what's already in teh database with our defaults
@@ -116,20 +151,60 @@ func ConfigurationRequestHandler(db ApiDB) http.Handler {
 					return nil, fmt.Errorf("merging of named configs failed: %s", err)
 				}
 
-				if configName != existingNamedConfig.ConfigName {
-					return nil, errors.New("named config endpoint does not match posted data config_name")
+			case http.MethodPut:
+
+				body, err := ioutil.ReadAll(r.Body)
+				defer r.Body.Close()
+				if err != nil {
+					return nil, fmt.Errorf("failed to read request body: %s", err)
 				}
 
-				//err = dyndb.UpsertNamedConfig(dynDBInstance, &existingNamedConfig)
-				err = db.UpsertNamedConfig(&existingNamedConfig)
+				existingNamedConfig = osquery_types.OsqueryNamedConfig{}
+				err = json.Unmarshal(body, &existingNamedConfig)
 				if err != nil {
-					return nil, fmt.Errorf("dynamo named config upsert failed: %s", err)
+					return nil, fmt.Errorf("failed to unmarshal named config: %s", err)
 				}
 
-				return existingNamedConfig, nil
+			case http.MethodPatch:
+
+				if existingNamedConfig.ConfigName == "" {
+					return nil, fmt.Errorf("no config found with name [%s]", configName)
+				}
+
+				patched := osquery_types.OsqueryNamedConfig{}
+				err = patchFromRequest(r, existingNamedConfig, &patched)
+				if err != nil {
+					return nil, err
+				}
+				existingNamedConfig = patched
+
+			default:
+				return nil, fmt.Errorf("method not supported: %s", r.Method)
 			}
 
-			return nil, fmt.Errorf("method not supported: %s", r.Method)
+			if configName != existingNamedConfig.ConfigName {
+				return nil, errors.New("named config endpoint does not match posted data config_name")
+			}
+
+			if r.URL.Query().Get("dry_run") == "true" {
+				return dryRunNamedConfig(db, json.RawMessage(storedNamedConfig), storedOptions, existingNamedConfig)
+			}
+
+			err = validateNamedConfig(db, existingNamedConfig)
+			if err != nil {
+				return nil, err
+			}
+
+			//err = dyndb.UpsertNamedConfig(dynDBInstance, &existingNamedConfig)
+			err = db.UpsertNamedConfig(&existingNamedConfig)
+			if err != nil {
+				return nil, fmt.Errorf("dynamo named config upsert failed: %s", err)
+			}
+			if restart := existingNamedConfig.OsqueryConfig.Options.RestartRequired(storedOptions); storedOptions != nil && len(restart) > 0 {
+				logger.Warn(fmt.Sprintf("config %s: nodes apply changes to %s after osquery restarts", configName, strings.Join(restart, ", ")))
+			}
+
+			return existingNamedConfig, nil
 		}
 
 		result, err := handleRequest()
@@ -253,10 +328,73 @@ func ConfigureNodeHandler(db ApiDB) http.Handler {
 				client.NodeInvalid = client.NodeInvalid || existingClient.NodeInvalid
 				client.PendingRegistrationApproval = client.PendingRegistrationApproval && existingClient.PendingRegistrationApproval
 				client.HostDetails = existingClient.HostDetails
+				//the config delivery is recorded by the server when the node fetches its config
+				client.ConfigDelivery = existingClient.ConfigDelivery
 
 				if len(client.Tags) == 0 {
 					client.Tags = existingClient.Tags
 				}
+				if client.ConfigOverride == nil {
+					client.ConfigOverride = existingClient.ConfigOverride
+				} else if err = client.ConfigOverride.Validate(); err != nil {
+					return nil, fmt.Errorf("invalid config override: %s", err)
+				}
+				if err = checkConfigExists(db, client.ConfigName); err != nil {
+					return nil, err
+				}
+
+				err = db.UpsertClient(client)
+				if err != nil {
+					return nil, fmt.Errorf("client update in dynamo failed: %s", err)
+				}
+
+				return client, nil
+
+			case http.MethodPut, http.MethodPatch:
+
+				if existingClient.NodeKey == "" {
+					return nil, errors.New("existing client node_key is empty")
+				}
+
+				client := osquery_types.OsqueryClient{}
+				if r.Method == http.MethodPut {
+					body, err := ioutil.ReadAll(r.Body)
+					defer r.Body.Close()
+					if err != nil {
+						return nil, fmt.Errorf("failed to read request body: %s", err)
+					}
+					err = json.Unmarshal(body, &client)
+					if err != nil {
+						return nil, fmt.Errorf("failed to unmarshal request body [%s]: %s", string(body), err)
+					}
+				} else {
+					err = patchFromRequest(r, existingClient, &client)
+					if err != nil {
+						return nil, err
+					}
+				}
+
+				//the identity and host details of a node are reported by the node itself, and the
+				//config delivery is recorded by the server when the node fetches its config
+				client.NodeKey = nodeKey
+				client.HostIdentifier = existingClient.HostIdentifier
+				client.HostName = existingClient.HostName
+				client.HostDetails = existingClient.HostDetails
+				client.LastUpdated = existingClient.LastUpdated
+				client.ConfigDelivery = existingClient.ConfigDelivery
+				//a node is only approved through /approve, an edit can invalidate a node or hold it for
+				//approval but not undo either
+				client.NodeInvalid = client.NodeInvalid || existingClient.NodeInvalid
+				client.PendingRegistrationApproval = client.PendingRegistrationApproval || existingClient.PendingRegistrationApproval
+
+				if client.ConfigOverride != nil {
+					if err = client.ConfigOverride.Validate(); err != nil {
+						return nil, fmt.Errorf("invalid config override: %s", err)
+					}
+				}
+				if err = checkConfigExists(db, client.ConfigName); err != nil {
+					return nil, err
+				}
 
 				err = db.UpsertClient(client)
 				if err != nil {
@@ -683,46 +821,117 @@ func SearchQueryPacks(respWriter http.ResponseWriter, request *http.Request) {
 }
 */
 
-// ConfigurePack configures named pack
-func ConfigurePack(db ApiDB) http.Handler {
+// ConfigurePack gets, configures or deletes named pack.  POST adds the posted queries to those already
+// in the pack, PUT replaces the pack and PATCH applies a JSON Merge Patch or JSON Patch to it.  The
+// queries must exist, and each is linted with the pack's platform and version according to lintPolicy.
+// DELETE is refused while configs or node overrides use the pack unless cascade=true
+func ConfigurePack(db ApiDB, lintPolicy sqllint.Policy) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != http.MethodPost {
-			return
-		}
-
-		handleRequest := func() error {
+		handleRequest := func() (interface{}, error) {
 
 			vars := mux.Vars(r)
 			packName, ok := vars["pack_name"]
 			if !ok || packName == "" {
-				return errors.New("no pack specified")
+				return nil, errors.New("no pack specified")
 			}
 
-			body, err := ioutil.ReadAll(r.Body)
-			defer r.Body.Close()
+			querypack := osquery_types.QueryPack{}
+			switch r.Method {
+			case http.MethodGet:
+
+				existing, err := db.GetQueryPack(packName)
+				if err != nil {
+					return nil, fmt.Errorf("failed to get pack [%s]: %s", packName, err)
+				}
+				if existing.PackName == "" {
+					return nil, fmt.Errorf("no pack found with name [%s]", packName)
+				}
+				return existing, nil
+
+			case http.MethodDelete:
+
+				existing, err := db.GetQueryPack(packName)
+				if err != nil {
+					return nil, fmt.Errorf("failed to get pack [%s]: %s", packName, err)
+				}
+				if existing.PackName == "" {
+					return nil, fmt.Errorf("no pack found with name [%s]", packName)
+				}
+				refs, err := deletePack(db, packName, cascadeRequested(r))
+				if err != nil {
+					return nil, err
+				}
+				return deleteResult{Deleted: packName, Cascaded: refs}, nil
+
+			case http.MethodPost, http.MethodPut:
+
+				body, err := ioutil.ReadAll(r.Body)
+				defer r.Body.Close()
+				if err != nil {
+					return nil, fmt.Errorf("failed to read request body: %s", err)
+				}
+
+				err = json.Unmarshal(body, &querypack)
+				if err != nil {
+					return nil, fmt.Errorf("failed to unmarshal request body [%s]: %s", string(body), err)
+				}
+
+			case http.MethodPatch:
+
+				existing, err := db.GetQueryPack(packName)
+				if err != nil {
+					return nil, fmt.Errorf("failed to get pack [%s]: %s", packName, err)
+				}
+				if existing.PackName == "" {
+					return nil, fmt.Errorf("no pack found with name [%s]", packName)
+				}
+
+				err = patchFromRequest(r, existing, &querypack)
+				if err != nil {
+					return nil, err
+				}
+
+			default:
+				return nil, fmt.Errorf("method not supported: %s", r.Method)
+			}
+
+			if r.Method != http.MethodPost && querypack.PackName != packName {
+				return nil, errors.New("pack endpoint does not match posted data pack_name")
+			}
+
+			err := checkPackQueriesExist(db, querypack.Queries)
 			if err != nil {
-				return fmt.Errorf("failed to read request body: %s", err)
+				return nil, err
+			}
+			err = querypack.ValidateOverrides()
+			if err != nil {
+				return nil, err
 			}
 
-			querypack := osquery_types.QueryPack{}
-			err = json.Unmarshal(body, &querypack)
+			result, err := lintQueryPack(db, lintPolicy, querypack)
 			if err != nil {
-				return fmt.Errorf("failed to unmarshal request body [%s]: %s", string(body), err)
+				return nil, err
 			}
 
-			err = db.UpsertPack(querypack)
+			if r.Method == http.MethodPost {
+				err = db.UpsertPack(querypack)
+			} else {
+				err = db.NewQueryPack(querypack)
+			}
 			if err != nil {
-				return fmt.Errorf("dynamo pack upsert failed: %s", err)
+				return nil, fmt.Errorf("dynamo pack upsert failed: %s", err)
 			}
 
-			return nil
+			return result, nil
 		}
 
-		err := handleRequest()
+		result, err := handleRequest()
 		if err != nil {
 			logger.Error(err)
 			errString := fmt.Sprintf("[ConfigurePack] %s", err)
 			response.WriteError(w, errString)
+		} else {
+			response.WriteCustomJSON(w, result)
 		}
 
 	})
@@ -774,20 +983,48 @@ func ConfigurePack(respWriter http.ResponseWriter, request *http.Request) {
 }
 */
 
-// ConfigurePackQuery accepts post body with packquery config
-func ConfigurePackQuery(db ApiDB) http.Handler {
+// ConfigurePackQuery gets, saves or deletes the packquery {query_name}, or {pack_name}/{query_name}
+// when routed under a pack.  POST and PUT save the body as the query, PATCH applies a JSON Merge Patch or JSON Patch to the stored query.  Saved queries are
+// linted according to lintPolicy.  DELETE is refused while packs include the query unless cascade=true
+func ConfigurePackQuery(db ApiDB, lintPolicy sqllint.Policy) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		savePackQuery := func(pq osquery_types.PackQuery) error {
+			if err := validatePackQueryName(pq.QueryName); err != nil {
+				return err
+			}
+
+			findings, err := lintPolicy.Check(pq.Query, sqllint.PackQueryTarget(pq, osquery_types.PackSettings{}))
+			if err != nil {
+				return err
+			}
+
+			err = db.UpsertPackQuery(pq)
+			if err != nil {
+				return fmt.Errorf("dynamo pack query upsert failed: %s", err)
+			}
+
+			//a query saved under a pack belongs to it
+			if packName := mux.Vars(r)["pack_name"]; packName != "" {
+				err = db.UpsertPack(osquery_types.QueryPack{PackName: packName, Queries: []string{pq.QueryName}})
+				if err != nil {
+					return fmt.Errorf("dynamo pack upsert failed: %s", err)
+				}
+			}
+
+			response.WriteCustomJSON(w, lintedPackQuery{PackQuery: pq, Lint: findings})
+			return nil
+		}
+
 		handleRequest := func() error {
 
+			qName := packQueryNameFromRequest(r)
+			if qName == "" {
+				return errors.New("no pack query specified")
+			}
+
 			switch r.Method {
 			case http.MethodGet:
 
-				vars := mux.Vars(r)
-				qName, ok := vars["query_name"]
-				if !ok || qName == "" {
-					return errors.New("no pack specified")
-				}
-
 				packQuery, err := db.GetPackQuery(qName)
 				if err != nil {
 					return fmt.Errorf("failed to get pack query: %s", err)
@@ -795,7 +1032,7 @@ func ConfigurePackQuery(db ApiDB) http.Handler {
 
 				response.WriteCustomJSON(w, packQuery)
 
-			case http.MethodPost:
+			case http.MethodPost, http.MethodPut:
 				body, err := ioutil.ReadAll(r.Body)
 				defer r.Body.Close()
 				if err != nil {
@@ -808,10 +1045,53 @@ func ConfigurePackQuery(db ApiDB) http.Handler {
 					return fmt.Errorf("failed to unmarshal request body [%s]: %s", string(body), err)
 				}
 
-				err = db.UpsertPackQuery(postData)
+				//under a pack the body may use the query's name without the pack's namespace
+				if postData.QueryName == "" || postData.QueryName == mux.Vars(r)["query_name"] {
+					postData.QueryName = qName
+				}
+				if r.Method == http.MethodPut && postData.QueryName != qName {
+					return errors.New("pack query endpoint does not match posted data query_name")
+				}
+
+				return savePackQuery(postData)
+
+			case http.MethodDelete:
+				existing, err := db.GetPackQuery(qName)
+				if err != nil {
+					return fmt.Errorf("failed to get pack query: %s", err)
+				}
+				if existing.QueryName == "" {
+					return fmt.Errorf("no pack query found with name [%s]", qName)
+				}
+
+				refs, err := deletePackQuery(db, qName, cascadeRequested(r))
 				if err != nil {
-					return fmt.Errorf("dynamo pack query upsert failed: %s", err)
+					return err
 				}
+				response.WriteCustomJSON(w, deleteResult{Deleted: qName, Cascaded: refs})
+
+			case http.MethodPatch:
+				existing, err := db.GetPackQuery(qName)
+				if err != nil {
+					return fmt.Errorf("failed to get pack query: %s", err)
+				}
+				if existing.QueryName == "" {
+					return fmt.Errorf("no pack query found with name [%s]", qName)
+				}
+
+				var postData osquery_types.PackQuery
+				err = patchFromRequest(r, existing, &postData)
+				if err != nil {
+					return err
+				}
+				if postData.QueryName != qName {
+					return errors.New("pack query endpoint does not match patched query_name")
+				}
+
+				return savePackQuery(postData)
+
+			default:
+				return fmt.Errorf("method not supported: %s", r.Method)
 			}
 			return nil
 		}
@@ -826,6 +1106,30 @@ func ConfigurePackQuery(db ApiDB) http.Handler {
 	})
 }
 
+// packQueryNameFromRequest returns the name of the pack query a request is for.  Queries routed under
+// a pack are namespaced by the pack
+func packQueryNameFromRequest(r *http.Request) string {
+	vars := mux.Vars(r)
+	if vars["query_name"] == "" || vars["pack_name"] == "" {
+		return vars["query_name"]
+	}
+	return osquery_types.NamespacedQueryName(vars["pack_name"], vars["query_name"])
+}
+
+// validatePackQueryName checks a query name has at most one namespace, so it can be addressed by the api
+func validatePackQueryName(queryName string) error {
+	parts := strings.Split(queryName, osquery_types.QueryNamespaceSeparator)
+	if len(parts) > 2 {
+		return fmt.Errorf("invalid pack query name [%s], only one %q is allowed", queryName, osquery_types.QueryNamespaceSeparator)
+	}
+	for _, part := range parts {
+		if part == "" {
+			return fmt.Errorf("invalid pack query name [%s]", queryName)
+		}
+	}
+	return nil
+}
+
 /*
 func ConfigurePackQuery(respWriter http.ResponseWriter, request *http.Request) {
 
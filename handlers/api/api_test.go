@@ -1,9 +1,16 @@
 package api
 
 import (
+	"encoding/json"
+	"github.com/gorilla/mux"
 	"github.com/oktasecuritylabs/sgt/handlers/helpers"
+	"github.com/oktasecuritylabs/sgt/internal/pkg/jsonpatch"
+	"github.com/oktasecuritylabs/sgt/internal/pkg/sqllint"
+	"github.com/oktasecuritylabs/sgt/osquery_types"
 	"net/http"
 	"net/url"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -49,3 +56,244 @@ func TestGetNodesHandler(t *testing.T) {
 	}
 
 }
+
+func TestPatchFromRequest(t *testing.T) {
+	current := osquery_types.OsqueryClient{
+		NodeKey:    "abc",
+		ConfigName: "default",
+		Tags:       []string{"a", "b"},
+	}
+
+	tests := []struct {
+		contentType string
+		body        string
+		expected    osquery_types.OsqueryClient
+	}{
+		{"application/merge-patch+json", `{"config_name": "mac", "tags": null}`,
+			osquery_types.OsqueryClient{NodeKey: "abc", ConfigName: "mac"}},
+		{"", `{"node_invalid": true}`,
+			osquery_types.OsqueryClient{NodeKey: "abc", ConfigName: "default", Tags: []string{"a", "b"}, NodeInvalid: true}},
+		{"application/json-patch+json", `[{"op": "add", "path": "/tags/-", "value": "c"}, {"op": "remove", "path": "/tags/0"}]`,
+			osquery_types.OsqueryClient{NodeKey: "abc", ConfigName: "default", Tags: []string{"b", "c"}}},
+	}
+
+	for _, test := range tests {
+		r, err := http.NewRequest(http.MethodPatch, "/", strings.NewReader(test.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Header.Set("Content-Type", test.contentType)
+
+		patched := osquery_types.OsqueryClient{}
+		err = patchFromRequest(r, current, &patched)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", test.body, err)
+			continue
+		}
+		if !reflect.DeepEqual(patched, test.expected) {
+			t.Errorf("%s: Got: \n\t%+v, expected: \n\t%+v", test.body, patched, test.expected)
+		}
+	}
+
+	r, _ := http.NewRequest(http.MethodPatch, "/", strings.NewReader(`[{"op": "test", "path": "/config_name", "value": "mac"}]`))
+	r.Header.Set("Content-Type", "application/json-patch+json")
+	if err := patchFromRequest(r, current, &osquery_types.OsqueryClient{}); err == nil {
+		t.Error("expected a failed test operation to fail the patch")
+	}
+}
+
+// pendingNodeDB holds a node waiting for registration approval, and records the nodes saved
+type pendingNodeDB struct {
+	*helpers.MockDB
+	saved []osquery_types.OsqueryClient
+}
+
+func (db *pendingNodeDB) SearchByNodeKey(nk string) (osquery_types.OsqueryClient, error) {
+	return osquery_types.OsqueryClient{NodeKey: nk, HostIdentifier: "host1", ConfigName: "default",
+		PendingRegistrationApproval: true}, nil
+}
+
+func (db *pendingNodeDB) UpsertClient(oc osquery_types.OsqueryClient) error {
+	db.saved = append(db.saved, oc)
+	return nil
+}
+
+func TestConfigureNodeHandler_StaysPending(t *testing.T) {
+	db := &pendingNodeDB{MockDB: helpers.NewMockDB()}
+	handler := mux.NewRouter()
+	handler.Handle("/nodes/{node_key}", ConfigureNodeHandler(db))
+	test := helpers.GenerateHandleTester(t, handler)
+
+	for _, method := range []string{http.MethodPut, http.MethodPatch} {
+		db.saved = nil
+		test(method, "/nodes/abc", url.Values{}, strings.NewReader(`{"config_name": "default", "tags": ["a"]}`))
+		if len(db.saved) != 1 {
+			t.Fatalf("%s: expected the node saved, got %+v", method, db.saved)
+		}
+		if !db.saved[0].PendingRegistrationApproval {
+			t.Errorf("%s: expected the node to still be pending approval", method)
+		}
+	}
+}
+
+func TestDeleteReferenced(t *testing.T) {
+	mockdb := helpers.NewMockDB()
+
+	refs, err := configReferences(mockdb, "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(refs.Nodes) != 1 || refs.Nodes[0].HostIdentifier != "host1" {
+		t.Errorf("expected host1 to reference the default config, got %+v", refs)
+	}
+	if _, err := deleteNamedConfig(mockdb, "default", true); err == nil {
+		t.Error("expected deleting the default config to fail")
+	}
+	if _, err := deleteNamedConfig(mockdb, "test-config", false); err != nil {
+		t.Errorf("unexpected error deleting an unreferenced config: %s", err)
+	}
+
+	queryName := "select * from users"
+	if _, err := deletePackQuery(mockdb, queryName, false); err == nil {
+		t.Error("expected deleting a pack query used by a pack to fail without cascade")
+	}
+	refs, err = deletePackQuery(mockdb, queryName, true)
+	if err != nil {
+		t.Errorf("unexpected error deleting a pack query with cascade: %s", err)
+	}
+	if !reflect.DeepEqual(refs.Packs, []string{"test-pack"}) {
+		t.Errorf("expected test-pack to be cascaded, got %+v", refs)
+	}
+}
+
+func TestDryRunNamedConfig_Redacted(t *testing.T) {
+	mockdb := helpers.NewMockDB()
+	stored := osquery_types.OsqueryNamedConfig{
+		ConfigName:    "secrets",
+		OsqueryConfig: osquery_types.OsqueryConfig{Options: osquery_types.OsqueryOptions{"aws_secret_access_key": "old-secret"}},
+	}
+	storedJSON, err := json.Marshal(stored)
+	if err != nil {
+		t.Fatal(err)
+	}
+	proposed := stored
+	proposed.OsqueryConfig.Options = osquery_types.OsqueryOptions{"aws_secret_access_key": "new-secret", "verbose": true}
+
+	result, err := dryRunNamedConfig(mockdb, storedJSON, stored.OsqueryConfig.Options, proposed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	js, err := json.Marshal(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(js), "old-secret") || strings.Contains(string(js), "new-secret") {
+		t.Errorf("expected secrets to be redacted, got %s", js)
+	}
+	expected := []jsonpatch.Operation{
+		{Op: "replace", Path: "/osquery_config/options/aws_secret_access_key", Value: osquery_types.RedactedValue},
+		{Op: "add", Path: "/osquery_config/options/verbose", Value: true},
+	}
+	if !reflect.DeepEqual(result.Diff, expected) {
+		t.Errorf("expected the changed secret replaced with a redacted value, got %+v", result.Diff)
+	}
+}
+
+func TestImportPackHandler(t *testing.T) {
+	mockdb := helpers.NewMockDB()
+	handler := ImportPackHandler(mockdb, sqllint.PolicyWarn)
+	test := helpers.GenerateHandleTester(t, handler)
+
+	pack := `{
+  "platform": "darwin",
+  // comments and continued lines are accepted
+  "queries": {
+    "launchd": {"query": "select * from launchd \
+      where name = 'evil';", "interval": "3600"}
+  }
+}`
+
+	v := url.Values{}
+	v.Add("pack_name", "test-pack")
+	w := test("POST", "", v, strings.NewReader(pack))
+	if !strings.Contains(w.Body.String(), "already exists") {
+		t.Errorf("expected importing over an existing pack to fail, got %s", w.Body.String())
+	}
+
+	v.Add("replace", "true")
+	w = test("POST", "", v, strings.NewReader(pack))
+	result := osquery_types.QueryPack{}
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.Platform != "darwin" || !reflect.DeepEqual(result.Queries, []string{"test-pack/launchd"}) {
+		t.Errorf("unexpected imported pack: %s", w.Body.String())
+	}
+
+	v.Set("pack_name", "test/pack")
+	w = test("POST", "", v, strings.NewReader(pack))
+	if !strings.Contains(w.Body.String(), "invalid pack_name") {
+		t.Errorf("expected a pack name with a namespace to be rejected, got %s", w.Body.String())
+	}
+	v.Set("pack_name", "test-pack")
+	w = test("POST", "", v, strings.NewReader(`{"queries": {"a/b": {"query": "select * from users;"}}}`))
+	if !strings.Contains(w.Body.String(), "invalid pack query name") {
+		t.Errorf("expected a query name with a namespace to be rejected, got %s", w.Body.String())
+	}
+}
+
+func TestKeptOverrides(t *testing.T) {
+	interval := osquery_types.PackQueryOverride{Interval: 60}
+	existing := osquery_types.QueryPack{PackName: "p", Overrides: map[string]osquery_types.PackQueryOverride{
+		"p/kept": interval, "legacy": interval, "p/dropped": interval,
+	}}
+	replacement := osquery_types.QueryPack{PackName: "p", Queries: []string{"p/kept", "p/legacy"}}
+	expected := map[string]osquery_types.PackQueryOverride{"p/kept": interval, "p/legacy": interval}
+	if overrides := keptOverrides(existing, replacement); !reflect.DeepEqual(overrides, expected) {
+		t.Errorf("expected %v, got %v", expected, overrides)
+	}
+}
+
+func TestResolveNodesHandler(t *testing.T) {
+	mockdb := helpers.NewMockDB()
+	handler := ResolveNodesHandler(mockdb)
+	test := helpers.GenerateHandleTester(t, handler)
+
+	v := url.Values{}
+	v.Add("selector", "tag:a AND NOT group:other")
+	w := test("GET", "", v, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("got %d", w.Code)
+	}
+	resolved := resolvedNodes{}
+	if err := json.Unmarshal(w.Body.Bytes(), &resolved); err != nil {
+		t.Fatal(err)
+	}
+	if resolved.Count != 1 || resolved.Nodes[0].HostIdentifier != "host1" {
+		t.Errorf("got %+v", resolved)
+	}
+
+	v.Set("selector", "tag:")
+	w = test("GET", "", v, nil)
+	if !strings.Contains(w.Body.String(), "invalid selector") {
+		t.Errorf("expected an invalid selector error, got %s", w.Body.String())
+	}
+}
+
+func TestDistributedStatusesHandler(t *testing.T) {
+	mockdb := helpers.NewMockDB()
+	handler := mux.NewRouter()
+	handler.Handle("/nodes/{node_key}/distributed/statuses", DistributedStatusesHandler(mockdb))
+	test := helpers.GenerateHandleTester(t, handler)
+
+	v := url.Values{}
+	v.Add("failed", "true")
+	w := test("GET", "/nodes/3lkjsdf0jdfoiasdjf/distributed/statuses", v, nil)
+	statuses := []osquery_types.DistributedStatus{}
+	if err := json.Unmarshal(w.Body.Bytes(), &statuses); err != nil {
+		t.Fatalf("%s: %s", err, w.Body.String())
+	}
+	if len(statuses) != 1 || statuses[0].Message != "no such table: userz" {
+		t.Errorf("expected only the failed status, got %+v", statuses)
+	}
+}
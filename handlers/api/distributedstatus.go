@@ -0,0 +1,47 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/oktasecuritylabs/sgt/handlers/response"
+	"github.com/oktasecuritylabs/sgt/logger"
+	"github.com/oktasecuritylabs/sgt/osquery_types"
+)
+
+// DistributedStatusesHandler returns the statuses the node specified by {node_key} has reported for
+// distributed queries, newest first.  With failed=true only the queries that failed are returned
+func DistributedStatusesHandler(db ApiDB) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handleRequest := func() (interface{}, error) {
+			nodeKey := mux.Vars(r)["node_key"]
+			if nodeKey == "" {
+				return nil, errors.New("request did not contain node_key")
+			}
+			statuses, err := db.GetDistributedStatuses(nodeKey)
+			if err != nil {
+				return nil, fmt.Errorf("could not get distributed query statuses for node [%s]: %s", nodeKey, err)
+			}
+			if r.URL.Query().Get("failed") != "true" {
+				return statuses, nil
+			}
+			failed := []osquery_types.DistributedStatus{}
+			for _, ds := range statuses {
+				if ds.Failed() {
+					failed = append(failed, ds)
+				}
+			}
+			return failed, nil
+		}
+
+		result, err := handleRequest()
+		if err != nil {
+			logger.Error(err)
+			response.WriteError(w, fmt.Sprintf("[DistributedStatuses] %s", err))
+		} else {
+			response.WriteCustomJSON(w, result)
+		}
+	})
+}
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"fmt"
+
+	"github.com/oktasecuritylabs/sgt/internal/pkg/sqllint"
+	"github.com/oktasecuritylabs/sgt/osquery_types"
+)
+
+// lintedPackQuery is returned when a pack query is saved, along with any sql lint findings for it
+type lintedPackQuery struct {
+	osquery_types.PackQuery
+	Lint []sqllint.Finding `json:"lint,omitempty"`
+}
+
+// lintedQueryPack is returned when a pack is saved, with sql lint findings keyed by query name
+type lintedQueryPack struct {
+	osquery_types.QueryPack
+	Lint map[string][]sqllint.Finding `json:"lint,omitempty"`
+}
+
+// lintQueryPack lints each query in the pack, with the pack's override for it applied, using the pack's
+// platform and version according to lintPolicy
+func lintQueryPack(db ApiDB, lintPolicy sqllint.Policy, querypack osquery_types.QueryPack) (lintedQueryPack, error) {
+	result := lintedQueryPack{QueryPack: querypack}
+	if lintPolicy == sqllint.PolicyOff {
+		return result, nil
+	}
+
+	result.Lint = map[string][]sqllint.Finding{}
+	for _, queryName := range querypack.Queries {
+		pq, err := db.GetPackQuery(queryName)
+		if err != nil {
+			return result, fmt.Errorf("failed to get pack query [%s]: %s", queryName, err)
+		}
+		pq = querypack.ResolveQuery(pq)
+		findings, err := lintPolicy.Check(pq.Query, sqllint.PackQueryTarget(pq, querypack.PackSettings))
+		if err != nil {
+			return result, fmt.Errorf("pack query [%s]: %s", queryName, err)
+		}
+		if len(findings) > 0 {
+			result.Lint[queryName] = findings
+		}
+	}
+	return result, nil
+}
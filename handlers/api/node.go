@@ -3,8 +3,11 @@ package api
 import (
 	"net/http"
 	"fmt"
+	"encoding/json"
+	"io/ioutil"
 	"github.com/oktasecuritylabs/sgt/handlers/response"
 	"github.com/oktasecuritylabs/sgt/logger"
+	"github.com/oktasecuritylabs/sgt/osquery_types"
 	"github.com/gorilla/mux"
 	"github.com/pkg/errors"
 )
@@ -38,3 +41,93 @@ func DeleteNodeHandler(db ApiDB) http.Handler {
 
 	})
 }
+
+// ConfigureNodeOverrideHandler gets, sets or clears the config override of the node specified by {node_key}
+func ConfigureNodeOverrideHandler(db ApiDB) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handleRequest := func() (interface{}, error) {
+
+			vars := mux.Vars(r)
+			nodeKey, ok := vars["node_key"]
+			if !ok || nodeKey == "" {
+				return nil, errors.New("request did not contain node_key")
+			}
+
+			client, err := db.SearchByNodeKey(nodeKey)
+			if err != nil {
+				return nil, errors.Errorf("failed to find node by key [%s]: %s", nodeKey, err)
+			}
+			if client.NodeKey == "" {
+				return nil, errors.Errorf("no node found with key [%s]", nodeKey)
+			}
+
+			switch r.Method {
+			case http.MethodGet:
+
+				if client.ConfigOverride == nil {
+					return osquery_types.NodeConfigOverride{}, nil
+				}
+				return client.ConfigOverride, nil
+
+			case http.MethodPost:
+
+				body, err := ioutil.ReadAll(r.Body)
+				defer r.Body.Close()
+				if err != nil {
+					return nil, errors.Errorf("failed to read request body: %s", err)
+				}
+
+				override := osquery_types.NodeConfigOverride{}
+				err = json.Unmarshal(body, &override)
+				if err != nil {
+					return nil, errors.Errorf("failed to unmarshal request body [%s]: %s", string(body), err)
+				}
+
+				err = override.Validate()
+				if err != nil {
+					return nil, err
+				}
+
+				for _, packName := range override.Packs {
+					pack, err := db.GetPackByName(packName)
+					if err != nil {
+						return nil, errors.Errorf("failed to get pack [%s]: %s", packName, err)
+					}
+					if len(pack.Queries) == 0 {
+						return nil, errors.Errorf("pack [%s] does not exist or has no queries", packName)
+					}
+				}
+
+				client.ConfigOverride = &override
+				err = db.UpsertClient(client)
+				if err != nil {
+					return nil, errors.Errorf("client update in dynamo failed: %s", err)
+				}
+
+				return override, nil
+
+			case http.MethodDelete:
+
+				client.ConfigOverride = nil
+				err = db.UpsertClient(client)
+				if err != nil {
+					return nil, errors.Errorf("client update in dynamo failed: %s", err)
+				}
+
+				return osquery_types.NodeConfigOverride{}, nil
+			}
+
+			return nil, errors.Errorf("method not supported: %s", r.Method)
+		}
+
+		result, err := handleRequest()
+		if err != nil {
+			logger.Error(err)
+			errString := fmt.Sprintf("[ConfigureNodeOverride] failed to handle node override in %s request: %s", r.Method, err)
+			response.WriteError(w, errString)
+		} else {
+			response.WriteCustomJSON(w, result)
+		}
+
+	})
+}
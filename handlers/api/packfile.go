@@ -0,0 +1,177 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/oktasecuritylabs/sgt/handlers/response"
+	"github.com/oktasecuritylabs/sgt/internal/pkg/osquerypack"
+	"github.com/oktasecuritylabs/sgt/internal/pkg/sqllint"
+	"github.com/oktasecuritylabs/sgt/logger"
+	"github.com/oktasecuritylabs/sgt/osquery_types"
+)
+
+// ImportPackHandler creates the pack named by the pack_name parameter, and its queries, from an
+// osquery pack file posted as the body.  The queries are namespaced by the pack and linted according
+// to lintPolicy.  An existing pack is only replaced if replace=true, keeping the overrides of the
+// queries that are still in it
+func ImportPackHandler(db ApiDB, lintPolicy sqllint.Policy) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handleRequest := func() (interface{}, error) {
+
+			packName := r.URL.Query().Get("pack_name")
+			if packName == "" {
+				return nil, errors.New("no pack_name parameter specified")
+			}
+			if strings.Contains(packName, osquery_types.QueryNamespaceSeparator) {
+				return nil, fmt.Errorf("invalid pack_name [%s], %q is not allowed", packName, osquery_types.QueryNamespaceSeparator)
+			}
+
+			body, err := ioutil.ReadAll(r.Body)
+			defer r.Body.Close()
+			if err != nil {
+				return nil, fmt.Errorf("failed to read request body: %s", err)
+			}
+
+			parsed, err := osquerypack.Parse(body)
+			if err != nil {
+				return nil, err
+			}
+
+			existing, err := db.GetQueryPack(packName)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get pack [%s]: %s", packName, err)
+			}
+			if existing.PackName != "" && r.URL.Query().Get("replace") != "true" {
+				return nil, fmt.Errorf("pack [%s] already exists, import with replace=true to replace it", packName)
+			}
+
+			querypack := osquery_types.QueryPack{PackName: packName, PackSettings: parsed.PackSettings}
+			result := lintedQueryPack{Lint: map[string][]sqllint.Finding{}}
+			packQueries := []osquery_types.PackQuery{}
+			for _, name := range parsed.QueryNames() {
+				pq := parsed.Queries[name]
+				pq.QueryName = osquery_types.NamespacedQueryName(packName, name)
+				if err := validatePackQueryName(pq.QueryName); err != nil {
+					return nil, err
+				}
+
+				findings, err := lintPolicy.Check(pq.Query, sqllint.PackQueryTarget(pq, querypack.PackSettings))
+				if err != nil {
+					return nil, fmt.Errorf("pack query [%s]: %s", name, err)
+				}
+				if len(findings) > 0 {
+					result.Lint[pq.QueryName] = findings
+				}
+
+				packQueries = append(packQueries, pq)
+				querypack.Queries = append(querypack.Queries, pq.QueryName)
+			}
+			querypack.Overrides = keptOverrides(existing, querypack)
+
+			//nothing is saved until every query has passed linting
+			for _, pq := range packQueries {
+				err = db.UpsertPackQuery(pq)
+				if err != nil {
+					return nil, fmt.Errorf("dynamo pack query upsert failed: %s", err)
+				}
+			}
+			err = db.NewQueryPack(querypack)
+			if err != nil {
+				return nil, fmt.Errorf("dynamo pack upsert failed: %s", err)
+			}
+
+			//queries in the replaced pack's namespace that aren't in the new file belong to no other pack
+			for _, queryName := range existing.Queries {
+				if containsString(querypack.Queries, queryName) ||
+					!strings.HasPrefix(queryName, packName+osquery_types.QueryNamespaceSeparator) {
+					continue
+				}
+				err = db.DeletePackQuery(queryName)
+				if err != nil {
+					return nil, fmt.Errorf("dynamo pack query delete failed: %s", err)
+				}
+			}
+
+			result.QueryPack = querypack
+			return result, nil
+		}
+
+		result, err := handleRequest()
+		if err != nil {
+			logger.Error(err)
+			errString := fmt.Sprintf("[ImportPack] %s", err)
+			response.WriteError(w, errString)
+		} else {
+			response.WriteCustomJSON(w, result)
+		}
+
+	})
+}
+
+// keptOverrides returns the overrides of the existing pack for queries that are in the replacement.
+// Overrides keyed by a query's name without the pack's namespace are kept under the namespaced name
+func keptOverrides(existing, replacement osquery_types.QueryPack) map[string]osquery_types.PackQueryOverride {
+	var overrides map[string]osquery_types.PackQueryOverride
+	for queryName, override := range existing.Overrides {
+		if !containsString(replacement.Queries, queryName) {
+			queryName = osquery_types.NamespacedQueryName(replacement.PackName, queryName)
+		}
+		if !containsString(replacement.Queries, queryName) {
+			continue
+		}
+		if overrides == nil {
+			overrides = map[string]osquery_types.PackQueryOverride{}
+		}
+		overrides[queryName] = override
+	}
+	return overrides
+}
+
+// ExportPackHandler returns the pack {pack_name} as an osquery pack file, with its queries as they
+// run in the pack
+func ExportPackHandler(db ApiDB) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handleRequest := func() ([]byte, error) {
+
+			packName := mux.Vars(r)["pack_name"]
+			if packName == "" {
+				return nil, errors.New("no pack specified")
+			}
+
+			pack, err := db.GetPackByName(packName)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get pack [%s]: %s", packName, err)
+			}
+			if pack.PackName == "" {
+				return nil, fmt.Errorf("no pack found with name [%s]", packName)
+			}
+
+			queries := []osquery_types.PackQuery{}
+			for _, pq := range pack.Queries {
+				if pq.QueryName == "" {
+					logger.Warn(fmt.Sprintf("pack [%s] refers to a pack query that does not exist", packName))
+					continue
+				}
+				queries = append(queries, pq)
+			}
+			pack.Queries = queries
+
+			return osquerypack.Marshal(pack)
+		}
+
+		result, err := handleRequest()
+		if err != nil {
+			logger.Error(err)
+			errString := fmt.Sprintf("[ExportPack] %s", err)
+			response.WriteError(w, errString)
+		} else {
+			response.WriteRawJSON(w, result)
+		}
+
+	})
+}
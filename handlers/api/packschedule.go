@@ -0,0 +1,50 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/oktasecuritylabs/sgt/handlers/response"
+	"github.com/oktasecuritylabs/sgt/logger"
+	"github.com/oktasecuritylabs/sgt/osquery_types"
+)
+
+// PackScheduleHandler lists the active, scheduled and expired packs of every named config.  The
+// optional at query parameter, an RFC 3339 timestamp, lists them as they will be at that time
+func PackScheduleHandler(db ApiDB) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handleRequest := func() (interface{}, error) {
+
+			now := time.Now().UTC()
+			if s := r.URL.Query().Get("at"); s != "" {
+				at, err := time.Parse(time.RFC3339, s)
+				if err != nil {
+					return nil, fmt.Errorf("invalid at %q, expected an RFC 3339 timestamp", s)
+				}
+				now = at
+			}
+
+			configs, err := db.GetNamedConfigs()
+			if err != nil {
+				return nil, fmt.Errorf("could not get named configs: %s", err)
+			}
+
+			schedules := []osquery_types.PackSchedule{}
+			for _, nc := range configs {
+				schedules = append(schedules, nc.PackSchedule(now))
+			}
+			return schedules, nil
+		}
+
+		result, err := handleRequest()
+		if err != nil {
+			logger.Error(err)
+			errString := fmt.Sprintf("[PackSchedule] failed to get pack schedule: %s", err)
+			response.WriteError(w, errString)
+		} else {
+			response.WriteCustomJSON(w, result)
+		}
+
+	})
+}
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+
+	"github.com/oktasecuritylabs/sgt/internal/pkg/jsonpatch"
+)
+
+// jsonPatchContentType marks a PATCH body as a JSON Patch rather than a JSON Merge Patch
+const jsonPatchContentType = "application/json-patch+json"
+
+// patchFromRequest applies the body of a PATCH request to current and unmarshals the result into
+// patched.  The body is treated as a JSON Patch when sent as application/json-patch+json, and as a
+// JSON Merge Patch (application/merge-patch+json) otherwise
+func patchFromRequest(r *http.Request, current, patched interface{}) error {
+	body, err := ioutil.ReadAll(r.Body)
+	defer r.Body.Close()
+	if err != nil {
+		return fmt.Errorf("failed to read request body: %s", err)
+	}
+
+	doc, err := json.Marshal(current)
+	if err != nil {
+		return fmt.Errorf("failed to marshal existing value: %s", err)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case jsonPatchContentType:
+		ops, err := jsonpatch.DecodePatch(body)
+		if err != nil {
+			return err
+		}
+		doc, err = jsonpatch.Apply(doc, ops)
+		if err != nil {
+			return fmt.Errorf("failed to apply json patch: %s", err)
+		}
+	default:
+		doc, err = jsonpatch.MergePatch(doc, body)
+		if err != nil {
+			return fmt.Errorf("failed to apply merge patch: %s", err)
+		}
+	}
+
+	err = json.Unmarshal(doc, patched)
+	if err != nil {
+		return fmt.Errorf("patched document is invalid: %s", err)
+	}
+	return nil
+}
@@ -0,0 +1,354 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/oktasecuritylabs/sgt/handlers/response"
+	"github.com/oktasecuritylabs/sgt/logger"
+	"github.com/oktasecuritylabs/sgt/osquery_types"
+)
+
+// nodeReference identifies a node that refers to a config or pack
+type nodeReference struct {
+	NodeKey        string `json:"node_key"`
+	HostIdentifier string `json:"host_identifier"`
+}
+
+// references lists what refers to a named config, pack or pack query
+type references struct {
+	// Configs whose pack_list includes the pack
+	Configs []string `json:"configs"`
+	// Packs whose queries include the pack query
+	Packs []string `json:"packs"`
+	// Nodes assigned to the config, or with the pack in their config override
+	Nodes []nodeReference `json:"nodes"`
+}
+
+func newReferences() references {
+	return references{Configs: []string{}, Packs: []string{}, Nodes: []nodeReference{}}
+}
+
+func (refs references) empty() bool {
+	return len(refs.Configs) == 0 && len(refs.Packs) == 0 && len(refs.Nodes) == 0
+}
+
+func (refs references) String() string {
+	parts := []string{}
+	if len(refs.Configs) > 0 {
+		parts = append(parts, fmt.Sprintf("configs [%s]", strings.Join(refs.Configs, ", ")))
+	}
+	if len(refs.Packs) > 0 {
+		parts = append(parts, fmt.Sprintf("packs [%s]", strings.Join(refs.Packs, ", ")))
+	}
+	if len(refs.Nodes) > 0 {
+		parts = append(parts, fmt.Sprintf("%d nodes", len(refs.Nodes)))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// nodeConfigName returns the config a node is sent, nodes without one get the default config
+func nodeConfigName(oc osquery_types.OsqueryClient) string {
+	if oc.ConfigName == "" {
+		return "default"
+	}
+	return oc.ConfigName
+}
+
+// configReferences returns the nodes assigned to the named config
+func configReferences(db ApiDB, configName string) (references, error) {
+	refs := newReferences()
+	nodes, err := db.SearchByHostIdentifier("")
+	if err != nil {
+		return refs, fmt.Errorf("failed to get all nodes: %s", err)
+	}
+	for _, n := range nodes {
+		if nodeConfigName(n) == configName {
+			refs.Nodes = append(refs.Nodes, nodeReference{n.NodeKey, n.HostIdentifier})
+		}
+	}
+	return refs, nil
+}
+
+// packReferences returns the configs with the pack in their pack list and the nodes with the pack in
+// their config override
+func packReferences(db ApiDB, packName string) (references, error) {
+	refs := newReferences()
+	configs, err := db.GetNamedConfigs()
+	if err != nil {
+		return refs, fmt.Errorf("could not get named configs: %s", err)
+	}
+	for _, nc := range configs {
+		if nc.PackList.Contains(packName) {
+			refs.Configs = append(refs.Configs, nc.ConfigName)
+		}
+	}
+	sort.Strings(refs.Configs)
+
+	nodes, err := db.SearchByHostIdentifier("")
+	if err != nil {
+		return refs, fmt.Errorf("failed to get all nodes: %s", err)
+	}
+	for _, n := range nodes {
+		if n.ConfigOverride != nil && containsString(n.ConfigOverride.Packs, packName) {
+			refs.Nodes = append(refs.Nodes, nodeReference{n.NodeKey, n.HostIdentifier})
+		}
+	}
+	return refs, nil
+}
+
+// packQueryReferences returns the packs that include the pack query
+func packQueryReferences(db ApiDB, queryName string) (references, error) {
+	refs := newReferences()
+	packs, err := db.SearchQueryPacks("")
+	if err != nil {
+		return refs, fmt.Errorf("failed to get all query packs: %s", err)
+	}
+	for _, qp := range packs {
+		if containsString(qp.Queries, queryName) {
+			refs.Packs = append(refs.Packs, qp.PackName)
+		}
+	}
+	sort.Strings(refs.Packs)
+	return refs, nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func removeString(list []string, s string) []string {
+	result := []string{}
+	for _, item := range list {
+		if item != s {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// deleteNamedConfig deletes the named config.  Nodes assigned to it block the delete unless cascade
+// is set, in which case they are unassigned and fall back to the default config
+func deleteNamedConfig(db ApiDB, configName string, cascade bool) (references, error) {
+	if configName == "default" {
+		return references{}, errors.New("the default config can't be deleted, nodes without a config are sent it")
+	}
+
+	refs, err := configReferences(db, configName)
+	if err != nil {
+		return refs, err
+	}
+	if !refs.empty() && !cascade {
+		return refs, fmt.Errorf("config [%s] is referenced by %s, delete with cascade=true to unassign them", configName, refs)
+	}
+
+	for _, ref := range refs.Nodes {
+		client, err := db.SearchByNodeKey(ref.NodeKey)
+		if err != nil {
+			return refs, fmt.Errorf("failed to find node by key [%s]: %s", ref.NodeKey, err)
+		}
+		client.ConfigName = ""
+		err = db.UpsertClient(client)
+		if err != nil {
+			return refs, fmt.Errorf("client update in dynamo failed: %s", err)
+		}
+	}
+
+	err = db.DeleteNamedConfig(configName)
+	if err != nil {
+		return refs, fmt.Errorf("dynamo named config delete failed: %s", err)
+	}
+	return refs, nil
+}
+
+// deletePack deletes the pack.  Configs and node overrides that use it block the delete unless cascade
+// is set, in which case it is removed from them
+func deletePack(db ApiDB, packName string, cascade bool) (references, error) {
+	refs, err := packReferences(db, packName)
+	if err != nil {
+		return refs, err
+	}
+	if !refs.empty() && !cascade {
+		return refs, fmt.Errorf("pack [%s] is referenced by %s, delete with cascade=true to remove it from them", packName, refs)
+	}
+
+	for _, configName := range refs.Configs {
+		nc, err := db.GetNamedConfig(configName)
+		if err != nil {
+			return refs, fmt.Errorf("failed to get config with name [%s]: %s", configName, err)
+		}
+		nc.PackList = nc.PackList.Remove(packName)
+		err = db.UpsertNamedConfig(&nc)
+		if err != nil {
+			return refs, fmt.Errorf("dynamo named config upsert failed: %s", err)
+		}
+	}
+	for _, ref := range refs.Nodes {
+		client, err := db.SearchByNodeKey(ref.NodeKey)
+		if err != nil {
+			return refs, fmt.Errorf("failed to find node by key [%s]: %s", ref.NodeKey, err)
+		}
+		if client.ConfigOverride != nil {
+			client.ConfigOverride.Packs = removeString(client.ConfigOverride.Packs, packName)
+		}
+		err = db.UpsertClient(client)
+		if err != nil {
+			return refs, fmt.Errorf("client update in dynamo failed: %s", err)
+		}
+	}
+
+	err = db.DeleteQueryPack(packName)
+	if err != nil {
+		return refs, fmt.Errorf("dynamo pack delete failed: %s", err)
+	}
+	return refs, nil
+}
+
+// deletePackQuery deletes the pack query.  Packs that include it block the delete unless cascade is
+// set, in which case it is removed from them
+func deletePackQuery(db ApiDB, queryName string, cascade bool) (references, error) {
+	refs, err := packQueryReferences(db, queryName)
+	if err != nil {
+		return refs, err
+	}
+	if !refs.empty() && !cascade {
+		return refs, fmt.Errorf("pack query [%s] is referenced by %s, delete with cascade=true to remove it from them", queryName, refs)
+	}
+
+	for _, packName := range refs.Packs {
+		qp, err := db.GetQueryPack(packName)
+		if err != nil {
+			return refs, fmt.Errorf("failed to get pack [%s]: %s", packName, err)
+		}
+		qp.Queries = removeString(qp.Queries, queryName)
+		delete(qp.Overrides, queryName)
+		//UpsertPack only ever adds queries, so replace the pack instead
+		err = db.NewQueryPack(qp)
+		if err != nil {
+			return refs, fmt.Errorf("dynamo pack upsert failed: %s", err)
+		}
+	}
+
+	err = db.DeletePackQuery(queryName)
+	if err != nil {
+		return refs, fmt.Errorf("dynamo pack query delete failed: %s", err)
+	}
+	return refs, nil
+}
+
+// checkPacksExist returns an error naming any of the packs that don't exist
+func checkPacksExist(db ApiDB, packNames []string) error {
+	missing := []string{}
+	for _, packName := range packNames {
+		qp, err := db.GetQueryPack(packName)
+		if err != nil {
+			return fmt.Errorf("failed to get pack [%s]: %s", packName, err)
+		}
+		if qp.PackName == "" {
+			missing = append(missing, packName)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("packs [%s] do not exist", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// checkPackQueriesExist returns an error naming any of the pack queries that don't exist
+func checkPackQueriesExist(db ApiDB, queryNames []string) error {
+	missing := []string{}
+	for _, queryName := range queryNames {
+		pq, err := db.GetPackQuery(queryName)
+		if err != nil {
+			return fmt.Errorf("failed to get pack query [%s]: %s", queryName, err)
+		}
+		if pq.QueryName == "" {
+			missing = append(missing, queryName)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("pack queries [%s] do not exist", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// ReferencesHandler lists what refers to the config {config_name}, pack {pack_name} or pack query
+// {query_name}, whichever the route provides.  A query routed under a pack is namespaced by the pack
+func ReferencesHandler(db ApiDB) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handleRequest := func() (interface{}, error) {
+
+			vars := mux.Vars(r)
+			if name := packQueryNameFromRequest(r); name != "" {
+				return packQueryReferences(db, name)
+			}
+			if name := vars["pack_name"]; name != "" {
+				return packReferences(db, name)
+			}
+			if name := vars["config_name"]; name != "" {
+				return configReferences(db, name)
+			}
+			return nil, errors.New("no config, pack or pack query specified")
+		}
+
+		result, err := handleRequest()
+		if err != nil {
+			logger.Error(err)
+			errString := fmt.Sprintf("[References] failed to get references: %s", err)
+			response.WriteError(w, errString)
+		} else {
+			response.WriteCustomJSON(w, result)
+		}
+
+	})
+}
+
+// deleteResult is returned when a config, pack or pack query is deleted, listing what was changed to
+// no longer refer to it
+type deleteResult struct {
+	Deleted  string     `json:"deleted"`
+	Cascaded references `json:"cascaded"`
+}
+
+// cascadeRequested returns true if the delete request asked for references to be removed too
+func cascadeRequested(r *http.Request) bool {
+	return r.URL.Query().Get("cascade") == "true"
+}
+
+// checkConfigExists returns an error if a node is assigned to a config that doesn't exist.  An empty
+// config name is fine, the node is sent the default config
+func checkConfigExists(db ApiDB, configName string) error {
+	if configName == "" {
+		return nil
+	}
+	nc, err := db.GetNamedConfig(configName)
+	if err != nil {
+		return fmt.Errorf("failed to get config with name [%s]: %s", configName, err)
+	}
+	if nc.ConfigName == "" {
+		return fmt.Errorf("config [%s] does not exist", configName)
+	}
+	return nil
+}
+
+// validateNamedConfig checks a named config before it is saved, including that its packs exist
+func validateNamedConfig(db ApiDB, nc osquery_types.OsqueryNamedConfig) error {
+	err := nc.OsqueryConfig.Validate()
+	if err != nil {
+		return err
+	}
+	err = nc.PackList.Validate()
+	if err != nil {
+		return err
+	}
+	return checkPacksExist(db, nc.PackList.Names())
+}
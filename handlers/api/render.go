@@ -0,0 +1,145 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/oktasecuritylabs/sgt/handlers/node"
+	"github.com/oktasecuritylabs/sgt/handlers/response"
+	"github.com/oktasecuritylabs/sgt/internal/pkg/jsonpatch"
+	"github.com/oktasecuritylabs/sgt/logger"
+	"github.com/oktasecuritylabs/sgt/osquery_types"
+)
+
+// RenderNamedConfigHandler returns the config a node assigned to {config_name} would receive, with
+// secrets redacted.  The optional platform, osquery_version and tags (comma separated) query parameters
+// select the node to render for, packs and queries that wouldn't be sent to that node are left out.
+// The optional at parameter, an RFC 3339 timestamp, renders the config as it will be at that time
+func RenderNamedConfigHandler(db ApiDB, config *osquery_types.ServerConfig) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handleRequest := func() (interface{}, error) {
+
+			namedConfig, err := namedConfigFromRequest(db, r)
+			if err != nil {
+				return nil, err
+			}
+
+			target := osquery_types.RenderTarget{
+				Platform:       osquery_types.NormalizePlatform(r.URL.Query().Get("platform")),
+				OsqueryVersion: r.URL.Query().Get("osquery_version"),
+			}
+			if tags := r.URL.Query().Get("tags"); tags != "" {
+				target.Tags = strings.Split(tags, ",")
+			}
+			at := time.Now().UTC()
+			if s := r.URL.Query().Get("at"); s != "" {
+				at, err = time.Parse(time.RFC3339, s)
+				if err != nil {
+					return nil, fmt.Errorf("invalid at %q, expected an RFC 3339 timestamp", s)
+				}
+			}
+			rendered, err := node.RenderNamedConfig(db, namedConfig.ConfigName, target, config, at)
+			if err != nil {
+				return nil, err
+			}
+
+			return rendered.OsqueryConfig.Redacted(), nil
+		}
+
+		result, err := handleRequest()
+		if err != nil {
+			logger.Error(err)
+			errString := fmt.Sprintf("[RenderNamedConfig] failed to render config: %s", err)
+			response.WriteError(w, errString)
+		} else {
+			response.WriteCustomJSON(w, result)
+		}
+
+	})
+}
+
+// RenderedNodeConfigHandler returns the config the node specified by {node_key} receives the next
+// time it asks for one, with secrets redacted
+func RenderedNodeConfigHandler(db ApiDB, config *osquery_types.ServerConfig) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handleRequest := func() (interface{}, error) {
+
+			vars := mux.Vars(r)
+			nodeKey, ok := vars["node_key"]
+			if !ok || nodeKey == "" {
+				return nil, errors.New("request did not contain node_key")
+			}
+
+			client, err := db.SearchByNodeKey(nodeKey)
+			if err != nil {
+				return nil, fmt.Errorf("failed to find node by key [%s]: %s", nodeKey, err)
+			}
+			if client.NodeKey == "" {
+				return nil, fmt.Errorf("no node found with key [%s]", nodeKey)
+			}
+
+			rendered, err := node.RenderNodeConfig(db, client, config, time.Now().UTC())
+			if err != nil {
+				return nil, err
+			}
+
+			return rendered.OsqueryConfig.Redacted(), nil
+		}
+
+		result, err := handleRequest()
+		if err != nil {
+			logger.Error(err)
+			errString := fmt.Sprintf("[RenderedNodeConfig] failed to render node config: %s", err)
+			response.WriteError(w, errString)
+		} else {
+			response.WriteCustomJSON(w, result)
+		}
+
+	})
+}
+
+// dryRunResult is returned instead of the saved config when a config change is posted with dry_run=true
+type dryRunResult struct {
+	Valid  bool                             `json:"valid"`
+	Error  string                           `json:"error,omitempty"`
+	Diff   []jsonpatch.Operation            `json:"diff"`
+	Config osquery_types.OsqueryNamedConfig `json:"config"`
+	// RestartRequired lists the changed options that nodes only apply when osquery restarts
+	RestartRequired []string `json:"restart_required,omitempty"`
+}
+
+// dryRunNamedConfig validates a proposed config and diffs it against the stored one without saving it.
+// Sensitive options are redacted in both the config and the diff, after diffing so that a changed
+// secret still shows up as replaced
+func dryRunNamedConfig(db ApiDB, stored json.RawMessage, storedOptions osquery_types.OsqueryOptions, proposed osquery_types.OsqueryNamedConfig) (dryRunResult, error) {
+	result := dryRunResult{Valid: true}
+	if storedOptions != nil {
+		result.RestartRequired = proposed.OsqueryConfig.Options.RestartRequired(storedOptions)
+	}
+	if err := validateNamedConfig(db, proposed); err != nil {
+		result.Valid = false
+		result.Error = err.Error()
+	}
+
+	storedConfig := osquery_types.OsqueryNamedConfig{}
+	if err := json.Unmarshal(stored, &storedConfig); err != nil {
+		return result, fmt.Errorf("failed to read stored config: %s", err)
+	}
+	diff, err := jsonpatch.Diff(storedConfig, proposed)
+	if err != nil {
+		return result, fmt.Errorf("failed to diff config: %s", err)
+	}
+
+	proposed.OsqueryConfig = proposed.OsqueryConfig.Redacted()
+	result.Config = proposed
+	result.Diff, err = jsonpatch.ValuesFrom(diff, proposed)
+	if err != nil {
+		return result, fmt.Errorf("failed to redact config diff: %s", err)
+	}
+	return result, nil
+}
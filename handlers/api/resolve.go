@@ -0,0 +1,44 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/oktasecuritylabs/sgt/handlers/response"
+	"github.com/oktasecuritylabs/sgt/internal/pkg/selector"
+	"github.com/oktasecuritylabs/sgt/logger"
+	"github.com/oktasecuritylabs/sgt/osquery_types"
+)
+
+// resolvedNodes are the nodes a selector picks out
+type resolvedNodes struct {
+	Selector string                        `json:"selector"`
+	Count    int                           `json:"count"`
+	Nodes    []osquery_types.OsqueryClient `json:"nodes"`
+}
+
+// ResolveNodesHandler returns the nodes picked out by the selector= param, see internal/pkg/selector
+func ResolveNodesHandler(db ApiDB) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handleRequest := func() (interface{}, error) {
+			sel, err := selector.Parse(r.URL.Query().Get("selector"))
+			if err != nil {
+				return nil, err
+			}
+			clients, err := db.SearchByHostIdentifier("")
+			if err != nil {
+				return nil, fmt.Errorf("failed to get all nodes: %s", err)
+			}
+			nodes := sel.Filter(clients)
+			return resolvedNodes{Selector: sel.String(), Count: len(nodes), Nodes: nodes}, nil
+		}
+
+		result, err := handleRequest()
+		if err != nil {
+			logger.Error(err)
+			response.WriteError(w, fmt.Sprintf("[ResolveNodes] %s", err))
+		} else {
+			response.WriteCustomJSON(w, result)
+		}
+	})
+}
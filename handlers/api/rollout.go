@@ -0,0 +1,43 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/oktasecuritylabs/sgt/handlers/node"
+	"github.com/oktasecuritylabs/sgt/handlers/response"
+	"github.com/oktasecuritylabs/sgt/logger"
+	"github.com/oktasecuritylabs/sgt/osquery_types"
+)
+
+// ConfigRolloutHandler reports how many of the nodes assigned to {config_name} have fetched the
+// config they would be sent now, and lists the ones that haven't
+func ConfigRolloutHandler(db ApiDB, config *osquery_types.ServerConfig) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handleRequest := func() (interface{}, error) {
+
+			namedConfig, err := namedConfigFromRequest(db, r)
+			if err != nil {
+				return nil, err
+			}
+
+			nodes, err := db.SearchByHostIdentifier("")
+			if err != nil {
+				return nil, fmt.Errorf("failed to get all nodes: %s", err)
+			}
+
+			return node.Rollout(db, namedConfig, nodes, config, time.Now().UTC())
+		}
+
+		result, err := handleRequest()
+		if err != nil {
+			logger.Error(err)
+			errString := fmt.Sprintf("[ConfigRollout] failed to get config rollout: %s", err)
+			response.WriteError(w, errString)
+		} else {
+			response.WriteCustomJSON(w, result)
+		}
+
+	})
+}
@@ -0,0 +1,177 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/oktasecuritylabs/sgt/handlers/response"
+	"github.com/oktasecuritylabs/sgt/logger"
+	"github.com/oktasecuritylabs/sgt/osquery_types"
+)
+
+// namedConfigFromRequest returns the named config specified by {config_name}, failing if it doesn't exist
+func namedConfigFromRequest(db ApiDB, r *http.Request) (osquery_types.OsqueryNamedConfig, error) {
+	vars := mux.Vars(r)
+	configName, ok := vars["config_name"]
+	if !ok || configName == "" {
+		return osquery_types.OsqueryNamedConfig{}, errors.New("no config name specified")
+	}
+
+	namedConfig, err := db.GetNamedConfig(configName)
+	if err != nil {
+		return namedConfig, fmt.Errorf("failed to get config with name [%s]: %s", configName, err)
+	}
+	if namedConfig.ConfigName == "" {
+		return namedConfig, fmt.Errorf("no config found with name [%s]", configName)
+	}
+	return namedConfig, nil
+}
+
+// ConfigScheduleHandler gets or replaces the whole schedule of the config specified by {config_name}
+func ConfigScheduleHandler(db ApiDB) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handleRequest := func() (interface{}, error) {
+
+			namedConfig, err := namedConfigFromRequest(db, r)
+			if err != nil {
+				return nil, err
+			}
+
+			switch r.Method {
+			case http.MethodGet:
+
+				if namedConfig.OsqueryConfig.Schedule == nil {
+					return osquery_types.OsquerySchedule{}, nil
+				}
+				return namedConfig.OsqueryConfig.Schedule, nil
+
+			case http.MethodPost:
+
+				body, err := ioutil.ReadAll(r.Body)
+				defer r.Body.Close()
+				if err != nil {
+					return nil, fmt.Errorf("failed to read request body: %s", err)
+				}
+
+				schedule := osquery_types.OsquerySchedule{}
+				err = json.Unmarshal(body, &schedule)
+				if err != nil {
+					return nil, fmt.Errorf("failed to unmarshal schedule: %s", err)
+				}
+
+				err = schedule.Validate()
+				if err != nil {
+					return nil, err
+				}
+
+				namedConfig.OsqueryConfig.Schedule = schedule
+				err = db.UpsertNamedConfig(&namedConfig)
+				if err != nil {
+					return nil, fmt.Errorf("dynamo named config upsert failed: %s", err)
+				}
+
+				return schedule, nil
+			}
+
+			return nil, fmt.Errorf("method not supported: %s", r.Method)
+		}
+
+		result, err := handleRequest()
+		if err != nil {
+			logger.Error(err)
+			errString := fmt.Sprintf("[ConfigSchedule] failed to handle schedule in %s request: %s", r.Method, err)
+			response.WriteError(w, errString)
+		} else {
+			response.WriteCustomJSON(w, result)
+		}
+
+	})
+}
+
+// ConfigScheduledQueryHandler gets, sets or removes the query {query_name} in the schedule of the config
+// specified by {config_name}
+func ConfigScheduledQueryHandler(db ApiDB) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handleRequest := func() (interface{}, error) {
+
+			namedConfig, err := namedConfigFromRequest(db, r)
+			if err != nil {
+				return nil, err
+			}
+
+			queryName := mux.Vars(r)["query_name"]
+			if queryName == "" {
+				return nil, errors.New("no query name specified")
+			}
+
+			switch r.Method {
+			case http.MethodGet:
+
+				sq, ok := namedConfig.OsqueryConfig.Schedule[queryName]
+				if !ok {
+					return nil, fmt.Errorf("no scheduled query [%s] in config [%s]", queryName, namedConfig.ConfigName)
+				}
+				return sq, nil
+
+			case http.MethodPost:
+
+				body, err := ioutil.ReadAll(r.Body)
+				defer r.Body.Close()
+				if err != nil {
+					return nil, fmt.Errorf("failed to read request body: %s", err)
+				}
+
+				sq := osquery_types.ScheduledQuery{}
+				err = json.Unmarshal(body, &sq)
+				if err != nil {
+					return nil, fmt.Errorf("failed to unmarshal scheduled query: %s", err)
+				}
+
+				err = sq.Validate()
+				if err != nil {
+					return nil, fmt.Errorf("scheduled query [%s]: %s", queryName, err)
+				}
+
+				if namedConfig.OsqueryConfig.Schedule == nil {
+					namedConfig.OsqueryConfig.Schedule = osquery_types.OsquerySchedule{}
+				}
+				namedConfig.OsqueryConfig.Schedule[queryName] = sq
+				err = db.UpsertNamedConfig(&namedConfig)
+				if err != nil {
+					return nil, fmt.Errorf("dynamo named config upsert failed: %s", err)
+				}
+
+				return sq, nil
+
+			case http.MethodDelete:
+
+				if _, ok := namedConfig.OsqueryConfig.Schedule[queryName]; !ok {
+					return nil, fmt.Errorf("no scheduled query [%s] in config [%s]", queryName, namedConfig.ConfigName)
+				}
+				delete(namedConfig.OsqueryConfig.Schedule, queryName)
+				err = db.UpsertNamedConfig(&namedConfig)
+				if err != nil {
+					return nil, fmt.Errorf("dynamo named config upsert failed: %s", err)
+				}
+
+				return namedConfig.OsqueryConfig.Schedule, nil
+			}
+
+			return nil, fmt.Errorf("method not supported: %s", r.Method)
+		}
+
+		result, err := handleRequest()
+		if err != nil {
+			logger.Error(err)
+			errString := fmt.Sprintf("[ConfigScheduledQuery] failed to handle scheduled query in %s request: %s", r.Method, err)
+			response.WriteError(w, errString)
+		} else {
+			response.WriteCustomJSON(w, result)
+		}
+
+	})
+}
@@ -16,7 +16,8 @@ import (
 	"github.com/briandowns/spinner"
 	"github.com/oktasecuritylabs/sgt/dyndb"
 	"github.com/oktasecuritylabs/sgt/handlers/auth"
-	"github.com/oktasecuritylabs/sgt/handlers/helpers"
+	"github.com/oktasecuritylabs/sgt/internal/pkg/osquerypack"
+	"github.com/oktasecuritylabs/sgt/internal/pkg/sqllint"
 	"github.com/oktasecuritylabs/sgt/logger"
 	osq_types "github.com/oktasecuritylabs/sgt/osquery_types"
 )
@@ -98,6 +99,8 @@ type DeploymentConfig struct {
 	MailDomain                  string   `json:"mail_domain"`
 	TerraformBackendBucketName  string   `json:"terraform_backend_bucket_name"`
 	AutoApproveNodes            string   `json:"auto_approve_nodes"`
+	// SQLLintPolicy is written to the server config and applied to packs as they are deployed
+	SQLLintPolicy               string   `json:"sql_lint_policy"`
 }
 
 // copyFile copies file from src to dst
@@ -229,6 +232,11 @@ func UserAwsCredFile() (string, error) {
 func osqueryDefaultPacks(config DeploymentConfig, environ string) error {
 	var files []string
 
+	lintPolicy, err := sqllint.ParsePolicy(config.SQLLintPolicy)
+	if err != nil {
+		return err
+	}
+
 	//if environ specific dir exists in packs, deploy those.  Otherwise use defaults
 	if _, err := os.Stat(filepath.Join("packs", environ)); os.IsNotExist(err) {
 		logger.Infof("No environment specific packs found for: %s\n", environ)
@@ -246,58 +254,56 @@ func osqueryDefaultPacks(config DeploymentConfig, environ string) error {
 		}
 	}
 
+	// every pack is parsed and linted before any are deployed, so a query the lint policy rejects
+	// doesn't leave the environment with only some of its packs updated
+	packFiles := []string{}
+	packs := map[string]osquerypack.Pack{}
 	for _, fn := range files {
-
 		_, filename := filepath.Split(fn)
-		if strings.HasSuffix(filename, "json") {
-			logger.Infof("Deploying %s", fn)
-			pack := osq_types.QueryPack{}
-			helperPack := helpers.OsqueryPack{}
-			s, err := helpers.CleanPack(fn)
-			if err != nil {
-				return err
-			}
-			file := strings.NewReader(s)
-			if err != nil {
-				return err
-			}
-			decoder := json.NewDecoder(file)
-			err = decoder.Decode(&helperPack)
-			if err != nil {
-				return err
-			}
-			credfile, err := UserAwsCredFile()
-			if err != nil {
-				return err
-			}
-			dynDBInstance := auth.CrendentialedDbInstance(credfile, config.AWSProfile)
-			dyn := dyndb.DynDB{
-				DB: dynDBInstance,
-			}
-			//logger.Infof("%+v", pack)
-			//logger.Infof("%+v", helperPack)
-			for k, v := range helperPack.Queries {
-				pq := osq_types.PackQuery{}
-				pq.QueryName = k
-				pq.Query = v.Query
-				pq.Value = v.Value
-				pq.Description = v.Description
-				pq.Interval = v.Interval
-				pq.Version = v.Version
-				pq.Snapshot = v.Snapshot
-				dyn.UpsertPackQuery(pq)
+		if !strings.HasSuffix(filename, "json") {
+			continue
+		}
+		data, err := ioutil.ReadFile(fn)
+		if err != nil {
+			return err
+		}
+		helperPack, err := osquerypack.Parse(data)
+		if err != nil {
+			return fmt.Errorf("%s: %s", fn, err)
+		}
+		for _, k := range helperPack.QueryNames() {
+			pq := helperPack.Queries[k]
+			findings, err := lintPolicy.Check(pq.Query, sqllint.PackQueryTarget(pq, helperPack.PackSettings))
+			for _, f := range findings {
+				logger.Warn(fmt.Sprintf("%s query %s: %s", filename, k, f))
 			}
-			//logger.Info("queries done\n")
-			pack.Queries = helperPack.ListQueries()
-			pack.PackName = strings.Split(filename, ".")[0]
-			err = dyn.UpsertPack(pack)
 			if err != nil {
-				return err
+				return fmt.Errorf("%s query %s: %s", fn, k, err)
 			}
 		}
+		packFiles = append(packFiles, fn)
+		packs[fn] = helperPack
 	}
 
-	return nil
+	credfile, err := UserAwsCredFile()
+	if err != nil {
+		return err
+	}
+	dyn := dyndb.DynDB{
+		DB: auth.CrendentialedDbInstance(credfile, config.AWSProfile),
+	}
+	removed := []string{}
+	for _, fn := range packFiles {
+		_, filename := filepath.Split(fn)
+		logger.Infof("Deploying %s", fn)
+		packRemoved, err := deployPack(dyn, strings.Split(filename, ".")[0], packs[fn])
+		if err != nil {
+			return fmt.Errorf("%s: %s", fn, err)
+		}
+		removed = append(removed, packRemoved...)
+	}
+
+	return deleteUnusedPackQueries(dyn, removed)
 }
 
 // osqueryDefaultConfigs deploys default configs for env
@@ -369,10 +375,7 @@ func osqueryDefaultConfigs(config DeploymentConfig, environ string) error {
 		default:
 			namedConfig.OsType = "all"
 		}
-		var pl []string
-		for _, i := range config.Packs {
-			pl = append(pl, i)
-		}
+		pl := osq_types.NewPackList(config.Packs...)
 		//err = json.Unmarshal(*config.Packs, &pl)
 		//if err != nil {
 		//return err
@@ -383,6 +386,19 @@ func osqueryDefaultConfigs(config DeploymentConfig, environ string) error {
 		oc.Options = config.Options
 		oc.Decorators = config.Decorators
 		oc.Schedule = config.Schedule
+		oc.FilePaths = config.FilePaths
+		oc.FileAccesses = config.FileAccesses
+		oc.ExcludePaths = config.ExcludePaths
+		oc.Yara = config.Yara
+		oc.Events = config.Events
+		oc.Views = config.Views
+		oc.AutoTableConstruction = config.AutoTableConstruction
+		oc.PrometheusTargets = config.PrometheusTargets
+		err = oc.Validate()
+		if err != nil {
+			logger.Infof("%s: invalid config: %s\n", namedConfig.ConfigName, err)
+			return err
+		}
 		//blank out config packs since the options config doesn't have a packs kv
 		config.Packs = nil
 		namedConfig.OsqueryConfig = oc
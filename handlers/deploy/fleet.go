@@ -0,0 +1,170 @@
+package deploy
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/oktasecuritylabs/sgt/internal/pkg/fleetspec"
+	"github.com/oktasecuritylabs/sgt/internal/pkg/sqllint"
+	"github.com/oktasecuritylabs/sgt/logger"
+	osq_types "github.com/oktasecuritylabs/sgt/osquery_types"
+)
+
+// FleetDB is the storage used to import and export fleetctl specs
+type FleetDB interface {
+	GetNamedConfig(configName string) (osq_types.OsqueryNamedConfig, error)
+	UpsertNamedConfig(onc *osq_types.OsqueryNamedConfig) error
+	GetPackQuery(queryName string) (osq_types.PackQuery, error)
+	UpsertPackQuery(pq osq_types.PackQuery) error
+	DeletePackQuery(queryName string) error
+	GetQueryPack(packName string) (osq_types.QueryPack, error)
+	NewQueryPack(qp osq_types.QueryPack) error
+}
+
+// ImportFleet imports fleetctl specs into SGT.  Packs and queries are saved, options and other config
+// sections are set on the named config and packs Fleet runs on all hosts are added to its pack list.
+// Existing packs and queries are only replaced if replace is true, and nothing is saved if dryRun is
+// true.  The report lists everything that couldn't be imported
+func ImportFleet(db FleetDB, data []byte, configName string, replace, dryRun bool) (fleetspec.Report, error) {
+	specs, err := fleetspec.Parse(data)
+	if err != nil {
+		return fleetspec.Report{}, err
+	}
+	content, report, err := fleetspec.ToSGT(specs)
+	if err != nil {
+		return report, err
+	}
+
+	settings := map[string]osq_types.PackSettings{}
+	existing := map[string]osq_types.QueryPack{}
+	for _, qp := range content.Packs {
+		stored, err := db.GetQueryPack(qp.PackName)
+		if err != nil {
+			return report, err
+		}
+		if stored.PackName != "" {
+			if !replace {
+				return report, fmt.Errorf("pack [%s] already exists, use -replace to replace it", qp.PackName)
+			}
+			existing[qp.PackName] = stored
+		}
+		for _, queryName := range qp.Queries {
+			settings[queryName] = qp.PackSettings
+		}
+	}
+	for _, pq := range content.Queries {
+		stored, err := db.GetPackQuery(pq.QueryName)
+		if err != nil {
+			return report, err
+		}
+		if stored.QueryName != "" && !replace {
+			return report, fmt.Errorf("query [%s] already exists, use -replace to replace it", pq.QueryName)
+		}
+		for _, f := range sqllint.Lint(pq.Query, sqllint.PackQueryTarget(pq, settings[pq.QueryName])) {
+			logger.Warn(fmt.Sprintf("query %s: %s", pq.QueryName, f))
+		}
+	}
+	if dryRun {
+		return report, nil
+	}
+
+	for _, pq := range content.Queries {
+		if err := db.UpsertPackQuery(pq); err != nil {
+			return report, err
+		}
+	}
+	for _, qp := range content.Packs {
+		if err := db.NewQueryPack(qp); err != nil {
+			return report, err
+		}
+		// the replaced pack's own queries that aren't in the new pack would be left unused
+		for _, queryName := range existing[qp.PackName].Queries {
+			if strings.HasPrefix(queryName, qp.PackName+osq_types.QueryNamespaceSeparator) &&
+				!containsQuery(qp.Queries, queryName) {
+				if err := db.DeletePackQuery(queryName); err != nil {
+					return report, err
+				}
+			}
+		}
+	}
+
+	if content.Config == nil && len(content.ConfigPacks) == 0 {
+		return report, nil
+	}
+	nc, err := db.GetNamedConfig(configName)
+	if err != nil {
+		return report, err
+	}
+	nc.ConfigName = configName
+	content.ApplyTo(&nc)
+	return report, db.UpsertNamedConfig(&nc)
+}
+
+// ExportFleet returns the named config, its packs and their queries as fleetctl specs, with a report
+// of everything that couldn't be exported
+func ExportFleet(db FleetDB, configName string) ([]byte, fleetspec.Report, error) {
+	report := fleetspec.Report{}
+	nc, err := db.GetNamedConfig(configName)
+	if err != nil {
+		return nil, report, err
+	}
+	if nc.ConfigName == "" {
+		return nil, report, fmt.Errorf("config [%s] does not exist", configName)
+	}
+
+	packs := []osq_types.QueryPack{}
+	queries := map[string]osq_types.PackQuery{}
+	for _, entry := range nc.PackList {
+		packName := entry.PackName
+		if containsPack(packs, packName) {
+			continue
+		}
+		if entry.Start != "" || entry.End != "" || entry.Tag != "" {
+			report.Add("pack [%s] was exported to run on all hosts at all times: Fleet packs have no windows or tags", packName)
+		}
+		qp, err := db.GetQueryPack(packName)
+		if err != nil {
+			return nil, report, err
+		}
+		if qp.PackName == "" {
+			report.Add("pack [%s] was not exported: the pack does not exist", packName)
+			continue
+		}
+		packs = append(packs, qp)
+		for _, queryName := range qp.Queries {
+			pq, err := db.GetPackQuery(queryName)
+			if err != nil {
+				return nil, report, err
+			}
+			if pq.QueryName != "" {
+				queries[queryName] = pq
+			}
+		}
+	}
+
+	specs, converted, err := fleetspec.FromSGT(nc, packs, queries)
+	report.Unmapped = append(report.Unmapped, converted.Unmapped...)
+	if err != nil {
+		return nil, report, err
+	}
+	out, err := fleetspec.Marshal(specs)
+	return out, report, err
+}
+
+func containsPack(packs []osq_types.QueryPack, packName string) bool {
+	for _, qp := range packs {
+		if qp.PackName == packName {
+			return true
+		}
+	}
+	return false
+}
+
+func containsQuery(queries []string, queryName string) bool {
+	for _, q := range queries {
+		if q == queryName {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,108 @@
+package deploy
+
+import (
+	"sort"
+
+	"github.com/oktasecuritylabs/sgt/internal/pkg/osquerypack"
+	osq_types "github.com/oktasecuritylabs/sgt/osquery_types"
+)
+
+// PackDB is the storage deploy uploads packs to
+type PackDB interface {
+	UpsertPackQuery(pq osq_types.PackQuery) error
+	DeletePackQuery(queryName string) error
+	GetQueryPack(packName string) (osq_types.QueryPack, error)
+	NewQueryPack(qp osq_types.QueryPack) error
+	SearchQueryPacks(searchString string) ([]osq_types.QueryPack, error)
+}
+
+// deployPack replaces the queries the last deploy of the pack created with the parsed ones, keeping
+// queries added to the pack through the api and the stored pack's overrides.  Queries are namespaced
+// by their pack so packs with the same query names don't clobber each other.  The queries the last
+// deploy created that are no longer in the pack are returned, to be deleted once every pack has been
+// deployed
+func deployPack(db PackDB, packName string, parsed osquerypack.Pack) ([]string, error) {
+	existing, err := db.GetQueryPack(packName)
+	if err != nil {
+		return nil, err
+	}
+
+	pack := osq_types.QueryPack{PackName: packName, PackSettings: parsed.PackSettings}
+	inFile, inPack := map[string]bool{}, map[string]bool{}
+	for _, name := range parsed.QueryNames() {
+		pq := parsed.Queries[name]
+		pq.QueryName = osq_types.NamespacedQueryName(packName, name)
+		if err := db.UpsertPackQuery(pq); err != nil {
+			return nil, err
+		}
+		pack.Queries = append(pack.Queries, pq.QueryName)
+		inFile[pq.QueryName] = true
+		inPack[pq.QueryName] = true
+	}
+	pack.Deployed = append([]string{}, pack.Queries...)
+
+	// packs deployed before queries were namespaced hold the file's queries under their bare names,
+	// and have no record of what was deployed
+	deployed := map[string]bool{}
+	for _, queryName := range existing.Deployed {
+		deployed[queryName] = true
+	}
+	removed := []string{}
+	for _, queryName := range existing.Queries {
+		switch {
+		case inPack[queryName]:
+		case deployed[queryName] || inFile[osq_types.NamespacedQueryName(packName, queryName)]:
+			removed = append(removed, queryName)
+		default:
+			pack.Queries = append(pack.Queries, queryName)
+			inPack[queryName] = true
+		}
+	}
+
+	// overrides set on packs deployed before queries were namespaced are keyed by the bare name
+	for queryName, override := range existing.Overrides {
+		if !inPack[queryName] {
+			queryName = osq_types.NamespacedQueryName(packName, queryName)
+		}
+		if inPack[queryName] {
+			if pack.Overrides == nil {
+				pack.Overrides = map[string]osq_types.PackQueryOverride{}
+			}
+			pack.Overrides[queryName] = override
+		}
+	}
+
+	if err := db.NewQueryPack(pack); err != nil {
+		return nil, err
+	}
+	return removed, nil
+}
+
+// deleteUnusedPackQueries deletes the queries removed from deployed packs that no pack uses any more.
+// Queries deployed before they were namespaced were stored under their bare name, which packs that
+// weren't deployed may still share
+func deleteUnusedPackQueries(db PackDB, removed []string) error {
+	if len(removed) == 0 {
+		return nil
+	}
+	packs, err := db.SearchQueryPacks("")
+	if err != nil {
+		return err
+	}
+	used := map[string]bool{}
+	for _, qp := range packs {
+		for _, queryName := range qp.Queries {
+			used[queryName] = true
+		}
+	}
+	sort.Strings(removed)
+	for i, queryName := range removed {
+		if used[queryName] || i > 0 && removed[i-1] == queryName {
+			continue
+		}
+		if err := db.DeletePackQuery(queryName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package deploy
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/oktasecuritylabs/sgt/internal/pkg/osquerypack"
+	osq_types "github.com/oktasecuritylabs/sgt/osquery_types"
+)
+
+// packStore keeps packs and queries in memory
+type packStore struct {
+	packs   map[string]osq_types.QueryPack
+	queries map[string]osq_types.PackQuery
+}
+
+func (s packStore) UpsertPackQuery(pq osq_types.PackQuery) error {
+	s.queries[pq.QueryName] = pq
+	return nil
+}
+
+func (s packStore) DeletePackQuery(queryName string) error {
+	delete(s.queries, queryName)
+	return nil
+}
+
+func (s packStore) GetQueryPack(packName string) (osq_types.QueryPack, error) {
+	return s.packs[packName], nil
+}
+
+func (s packStore) NewQueryPack(qp osq_types.QueryPack) error {
+	s.packs[qp.PackName] = qp
+	return nil
+}
+
+func (s packStore) SearchQueryPacks(searchString string) ([]osq_types.QueryPack, error) {
+	results := []osq_types.QueryPack{}
+	for _, qp := range s.packs {
+		results = append(results, qp)
+	}
+	return results, nil
+}
+
+func TestDeployPack_ReplacesLegacyQueries(t *testing.T) {
+	interval := osq_types.PackQueryOverride{Interval: 60}
+	// both packs were deployed before queries were namespaced and share osquery_info
+	store := packStore{
+		packs: map[string]osq_types.QueryPack{
+			"monitoring": {PackName: "monitoring", Queries: []string{"osquery_info", "schedule"},
+				Overrides: map[string]osq_types.PackQueryOverride{"schedule": interval}},
+			"other": {PackName: "other", Queries: []string{"osquery_info"}},
+		},
+		queries: map[string]osq_types.PackQuery{
+			"osquery_info": {QueryName: "osquery_info"},
+			"schedule":     {QueryName: "schedule"},
+		},
+	}
+
+	parsed, err := osquerypack.Parse([]byte(`{"queries": {
+		"osquery_info": {"query": "select * from osquery_info;", "interval": 3600},
+		"schedule": {"query": "select * from osquery_schedule;", "interval": 3600}
+	}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	removed, err := deployPack(store, "monitoring", parsed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = deleteUnusedPackQueries(store, removed); err != nil {
+		t.Fatal(err)
+	}
+
+	pack := store.packs["monitoring"]
+	if !reflect.DeepEqual(pack.Queries, []string{"monitoring/osquery_info", "monitoring/schedule"}) {
+		t.Errorf("expected only the namespaced queries, got %v", pack.Queries)
+	}
+	if !reflect.DeepEqual(pack.Overrides, map[string]osq_types.PackQueryOverride{"monitoring/schedule": interval}) {
+		t.Errorf("expected the override to follow its query, got %v", pack.Overrides)
+	}
+	names := []string{}
+	for name := range store.queries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	expected := []string{"monitoring/osquery_info", "monitoring/schedule", "osquery_info"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected %v, got %v", expected, names)
+	}
+}
+
+func TestDeployPack_KeepsQueriesAddedThroughAPI(t *testing.T) {
+	store := packStore{
+		packs: map[string]osq_types.QueryPack{
+			"monitoring": {PackName: "monitoring",
+				Queries:  []string{"monitoring/osquery_info", "monitoring/schedule", "monitoring/added"},
+				Deployed: []string{"monitoring/osquery_info", "monitoring/schedule"}},
+		},
+		queries: map[string]osq_types.PackQuery{
+			"monitoring/osquery_info": {QueryName: "monitoring/osquery_info"},
+			"monitoring/schedule":     {QueryName: "monitoring/schedule"},
+			"monitoring/added":        {QueryName: "monitoring/added"},
+		},
+	}
+
+	parsed, err := osquerypack.Parse([]byte(`{"queries": {
+		"osquery_info": {"query": "select * from osquery_info;", "interval": 3600}
+	}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	removed, err := deployPack(store, "monitoring", parsed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = deleteUnusedPackQueries(store, removed); err != nil {
+		t.Fatal(err)
+	}
+
+	pack := store.packs["monitoring"]
+	if !reflect.DeepEqual(pack.Queries, []string{"monitoring/osquery_info", "monitoring/added"}) {
+		t.Errorf("expected the query added through the api kept, got %v", pack.Queries)
+	}
+	if !reflect.DeepEqual(pack.Deployed, []string{"monitoring/osquery_info"}) {
+		t.Errorf("expected only the deployed query recorded, got %v", pack.Deployed)
+	}
+	if _, ok := store.queries["monitoring/added"]; !ok {
+		t.Error("expected the query added through the api to survive the deploy")
+	}
+	if _, ok := store.queries["monitoring/schedule"]; ok {
+		t.Error("expected the query dropped from the pack file deleted")
+	}
+}
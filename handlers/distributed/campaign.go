@@ -0,0 +1,357 @@
+package distributed
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/oktasecuritylabs/sgt/handlers/response"
+	"github.com/oktasecuritylabs/sgt/internal/pkg/selector"
+	"github.com/oktasecuritylabs/sgt/internal/pkg/sqllint"
+	"github.com/oktasecuritylabs/sgt/logger"
+	"github.com/oktasecuritylabs/sgt/osquery_types"
+)
+
+// CampaignDB is the storage used to start campaigns and read their results
+type CampaignDB interface {
+	SearchByHostIdentifier(hid string) ([]osquery_types.OsqueryClient, error)
+	UpsertDistributedQuery(dq osquery_types.DistributedQuery) error
+	NewCampaign(c osquery_types.Campaign, nodes []osquery_types.CampaignNode) error
+	GetCampaign(campaignID string) (osquery_types.Campaign, error)
+	GetCampaigns() ([]osquery_types.Campaign, error)
+	GetCampaignNodes(campaignID string) ([]osquery_types.CampaignNode, error)
+	UpsertCampaignNode(cn osquery_types.CampaignNode) error
+}
+
+// campaignRequest is the body of a request to start a campaign.  Timeout is in seconds and defaults to
+// osquery_types.DefaultCampaignTimeout.  scheduleID is set for the runs of a schedule, and savedQuery
+// and platforms for a launched saved query, whose targets are narrowed to the platforms it runs on
+type campaignRequest struct {
+	Query      string                        `json:"query"`
+	Targets    osquery_types.CampaignTargets `json:"targets"`
+	Timeout    int                           `json:"timeout"`
+	scheduleID string
+	savedQuery string
+	platforms  []string
+}
+
+// campaignStarted is the response to starting a campaign
+type campaignStarted struct {
+	osquery_types.CampaignStatus
+	// UnknownNodeKeys are targeted node keys that aren't a node
+	UnknownNodeKeys []string `json:"unknown_node_keys,omitempty"`
+}
+
+// campaignResults are the results of a campaign's nodes
+type campaignResults struct {
+	CampaignID string                       `json:"campaign_id"`
+	Query      string                       `json:"query"`
+	Results    []osquery_types.CampaignNode `json:"results"`
+}
+
+// CampaignsHandler lists campaigns on GET, and on POST starts a campaign: the targets are resolved to
+// nodes, the query is linted for each of their platforms and osquery versions and is queued for each
+// node under the campaign's ID
+func CampaignsHandler(dyn CampaignDB, lintPolicy sqllint.Policy) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handleRequest := func() (interface{}, error) {
+			if r.Method == http.MethodGet {
+				campaigns, err := dyn.GetCampaigns()
+				if err != nil {
+					return nil, fmt.Errorf("could not get campaigns: %s", err)
+				}
+				sort.SliceStable(campaigns, func(i, j int) bool {
+					return campaigns[i].CreatedAt > campaigns[j].CreatedAt
+				})
+				return campaigns, nil
+			}
+
+			body, err := ioutil.ReadAll(r.Body)
+			defer r.Body.Close()
+			if err != nil {
+				return nil, fmt.Errorf("failed to read request body: %s", err)
+			}
+			req := campaignRequest{}
+			err = json.Unmarshal(body, &req)
+			if err != nil {
+				return nil, fmt.Errorf("unmarshal failed: %s", err)
+			}
+			return startCampaign(dyn, lintPolicy, req, time.Now())
+		}
+
+		result, err := handleRequest()
+		if err != nil {
+			logger.Error(err)
+			response.WriteError(w, fmt.Sprintf("[CampaignsHandler] %s", err))
+		} else {
+			response.WriteCustomJSON(w, result)
+		}
+	})
+}
+
+func startCampaign(dyn CampaignDB, lintPolicy sqllint.Policy, req campaignRequest, now time.Time) (campaignStarted, error) {
+	id, err := osquery_types.NewCampaignID()
+	if err != nil {
+		return campaignStarted{}, fmt.Errorf("could not create a campaign ID: %s", err)
+	}
+	c := osquery_types.Campaign{
+		CampaignID: id,
+		Query:      req.Query,
+		Targets:    req.Targets,
+		CreatedAt:  now.UTC().Format(time.RFC3339),
+		Timeout:    req.Timeout,
+		ScheduleID: req.scheduleID,
+		SavedQuery: req.savedQuery,
+	}
+	if c.Timeout == 0 {
+		c.Timeout = osquery_types.DefaultCampaignTimeout
+	}
+	if err := c.Validate(); err != nil {
+		return campaignStarted{}, err
+	}
+
+	clients, err := dyn.SearchByHostIdentifier("")
+	if err != nil {
+		return campaignStarted{}, fmt.Errorf("could not get nodes: %s", err)
+	}
+	var selected func(osquery_types.OsqueryClient) bool
+	if c.Targets.Selector != "" {
+		sel, err := selector.Parse(c.Targets.Selector)
+		if err != nil {
+			return campaignStarted{}, err
+		}
+		selected = sel.Matches
+	}
+	targeted, unknown := c.Targets.Resolve(clients, selected)
+	if len(targeted) > 0 && len(req.platforms) > 0 {
+		targeted = onPlatforms(targeted, strings.Join(req.platforms, ","))
+		if len(targeted) == 0 {
+			return campaignStarted{}, fmt.Errorf("no targeted nodes run on %s", strings.Join(req.platforms, ", "))
+		}
+	}
+	if len(targeted) == 0 {
+		return campaignStarted{}, errors.New("no nodes match the campaign's targets")
+	}
+	if err := lintCampaignQuery(lintPolicy, c.Query, targeted); err != nil {
+		return campaignStarted{}, err
+	}
+
+	nodes := []osquery_types.CampaignNode{}
+	for _, oc := range targeted {
+		cn := osquery_types.CampaignNode{
+			CampaignID:     c.CampaignID,
+			NodeKey:        oc.NodeKey,
+			HostIdentifier: oc.HostIdentifier,
+		}
+		cn.SetState(osquery_types.CampaignQueued, now)
+		nodes = append(nodes, cn)
+	}
+	// the nodes are saved before the query is queued, so a node that fetches it straight away is found
+	if err := dyn.NewCampaign(c, nodes); err != nil {
+		return campaignStarted{}, fmt.Errorf("could not save campaign: %s", err)
+	}
+	for i, cn := range nodes {
+		err := dyn.UpsertDistributedQuery(osquery_types.DistributedQuery{
+			NodeKey: cn.NodeKey,
+			Pending: []osquery_types.PendingQuery{{
+				Name:       c.CampaignID,
+				Query:      c.Query,
+				CampaignID: c.CampaignID,
+				QueuedAt:   c.CreatedAt,
+				ExpiresAt:  c.Deadline().Format(time.RFC3339),
+			}},
+		})
+		if err == nil {
+			continue
+		}
+		logger.Error(err)
+		nodes[i].SetState(osquery_types.CampaignErrored, now)
+		nodes[i].Error = fmt.Sprintf("could not queue query: %s", err)
+		if err := dyn.UpsertCampaignNode(nodes[i]); err != nil {
+			logger.Error(err)
+		}
+	}
+	return campaignStarted{
+		CampaignStatus:  osquery_types.NewCampaignStatus(c, nodes, now),
+		UnknownNodeKeys: unknown,
+	}, nil
+}
+
+// onPlatforms returns the nodes whose platform matches the platform constraint.  Nodes that haven't
+// reported their platform are kept
+func onPlatforms(nodes []osquery_types.OsqueryClient, constraint string) []osquery_types.OsqueryClient {
+	matched := []osquery_types.OsqueryClient{}
+	for _, oc := range nodes {
+		if osquery_types.PlatformMatches(constraint, oc.RenderTarget().Platform) {
+			matched = append(matched, oc)
+		}
+	}
+	return matched
+}
+
+// lintCampaignQuery lints the query once for each platform and osquery version among the nodes
+func lintCampaignQuery(lintPolicy sqllint.Policy, query string, nodes []osquery_types.OsqueryClient) error {
+	if lintPolicy == sqllint.PolicyOff {
+		return nil
+	}
+	linted := map[sqllint.Target]bool{}
+	for _, oc := range nodes {
+		target := sqllint.NodeTarget(oc.RenderTarget())
+		if linted[target] {
+			continue
+		}
+		linted[target] = true
+		findings, err := lintPolicy.Check(query, target)
+		for _, f := range findings {
+			logger.Warn(fmt.Sprintf("sql lint for campaign query on [%s %s]: %s", target.Platform, target.Version, f))
+		}
+		if err != nil {
+			return fmt.Errorf("campaign query: %s", err)
+		}
+	}
+	return nil
+}
+
+// CampaignHandler returns the campaign specified by {campaign_id} with the state of each of its nodes
+func CampaignHandler(dyn CampaignDB) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handleRequest := func() (interface{}, error) {
+			c, nodes, err := getCampaign(dyn, mux.Vars(r)["campaign_id"])
+			if err != nil {
+				return nil, err
+			}
+			return osquery_types.NewCampaignStatus(c, nodes, time.Now()), nil
+		}
+
+		result, err := handleRequest()
+		if err != nil {
+			logger.Error(err)
+			response.WriteError(w, fmt.Sprintf("[CampaignHandler] %s", err))
+		} else {
+			response.WriteCustomJSON(w, result)
+		}
+	})
+}
+
+// CampaignResultsHandler returns the rows and statuses of the nodes that have answered the campaign
+// specified by {campaign_id}, or with state=, of the nodes in that state
+func CampaignResultsHandler(dyn CampaignDB) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handleRequest := func() (interface{}, error) {
+			c, nodes, err := getCampaign(dyn, mux.Vars(r)["campaign_id"])
+			if err != nil {
+				return nil, err
+			}
+			return filterCampaignResults(c, nodes, r.URL.Query().Get("state"), time.Now()), nil
+		}
+
+		result, err := handleRequest()
+		if err != nil {
+			logger.Error(err)
+			response.WriteError(w, fmt.Sprintf("[CampaignResultsHandler] %s", err))
+		} else {
+			response.WriteCustomJSON(w, result)
+		}
+	})
+}
+
+func getCampaign(dyn CampaignDB, campaignID string) (osquery_types.Campaign, []osquery_types.CampaignNode, error) {
+	if campaignID == "" {
+		return osquery_types.Campaign{}, nil, errors.New("no campaign ID specified")
+	}
+	c, err := dyn.GetCampaign(campaignID)
+	if err != nil {
+		return c, nil, fmt.Errorf("could not get campaign [%s]: %s", campaignID, err)
+	}
+	if c.CampaignID == "" {
+		return c, nil, fmt.Errorf("no campaign found with ID [%s]", campaignID)
+	}
+	nodes, err := dyn.GetCampaignNodes(campaignID)
+	if err != nil {
+		return c, nil, fmt.Errorf("could not get nodes for campaign [%s]: %s", campaignID, err)
+	}
+	return c, nodes, nil
+}
+
+// filterCampaignResults returns the nodes in the given state at now, or the answered and errored nodes
+// if no state is given
+func filterCampaignResults(c osquery_types.Campaign, nodes []osquery_types.CampaignNode, state string, now time.Time) campaignResults {
+	results := campaignResults{
+		CampaignID: c.CampaignID,
+		Query:      c.Query,
+		Results:    []osquery_types.CampaignNode{},
+	}
+	for _, cn := range nodes {
+		cn.State = cn.StateAt(c, now)
+		if state == "" && (cn.State == osquery_types.CampaignAnswered || cn.State == osquery_types.CampaignErrored) ||
+			string(cn.State) == state {
+			results.Results = append(results.Results, cn.Public())
+		}
+	}
+	sort.SliceStable(results.Results, func(i, j int) bool {
+		return results.Results[i].HostIdentifier < results.Results[j].HostIdentifier
+	})
+	return results
+}
+
+// markCampaignsDelivered marks the node as delivered in each campaign whose query it has just been
+// sent.  The queries have already been sent, so errors are only logged
+func markCampaignsDelivered(dyn DistributedDB, nodeKey string, sent []osquery_types.PendingQuery, now time.Time) {
+	for _, pq := range sent {
+		if pq.CampaignID == "" {
+			continue
+		}
+		cn, err := dyn.GetCampaignNode(pq.CampaignID, nodeKey)
+		if err != nil {
+			logger.Error(err)
+			continue
+		}
+		if cn.NodeKey == "" || cn.State != osquery_types.CampaignQueued {
+			continue
+		}
+		cn.SetState(osquery_types.CampaignDelivered, now)
+		if err := dyn.UpsertCampaignNode(cn); err != nil {
+			logger.Error(err)
+		}
+	}
+}
+
+// recordCampaignResults stores the rows, status and error message the node wrote for each campaign
+// query, and returns the node's answer to each campaign by campaign ID.  Queries that aren't a campaign
+// the node is targeted by are ignored, so a node can't add results to another node's campaign
+func recordCampaignResults(dyn DistributedDB, d distributedWrite, now time.Time) map[string]osquery_types.CampaignNode {
+	campaigns := map[string]osquery_types.CampaignNode{}
+	for _, name := range d.names() {
+		cn, err := dyn.GetCampaignNode(name, d.NodeKey)
+		if err != nil {
+			logger.Error(err)
+			continue
+		}
+		if cn.NodeKey == "" {
+			continue
+		}
+		rows, err := d.rows(name)
+		if err != nil {
+			logger.Warn(fmt.Sprintf("campaign [%s] node [%s]: %s", name, cn.HostIdentifier, err))
+		}
+		cn.SetRows(rows, osquery_types.MaxCampaignResultBytes)
+		cn.Status = int(d.Statuses[name])
+		cn.Message = d.Messages[name]
+		if cn.Status != 0 {
+			cn.SetState(osquery_types.CampaignErrored, now)
+		} else {
+			cn.SetState(osquery_types.CampaignAnswered, now)
+		}
+		if err := dyn.UpsertCampaignNode(cn); err != nil {
+			logger.Error(err)
+		}
+		campaigns[name] = cn
+	}
+	return campaigns
+}
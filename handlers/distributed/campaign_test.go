@@ -0,0 +1,207 @@
+package distributed
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/oktasecuritylabs/sgt/handlers/helpers"
+	"github.com/oktasecuritylabs/sgt/internal/pkg/sqllint"
+	"github.com/oktasecuritylabs/sgt/osquery_types"
+)
+
+// recordingDB records the campaign nodes and distributed queries saved to the mock db
+type recordingDB struct {
+	*helpers.MockDB
+	nodes   []osquery_types.CampaignNode
+	queries []osquery_types.DistributedQuery
+}
+
+func (db *recordingDB) UpsertCampaignNode(cn osquery_types.CampaignNode) error {
+	db.nodes = append(db.nodes, cn)
+	return nil
+}
+
+func (db *recordingDB) NewCampaign(c osquery_types.Campaign, nodes []osquery_types.CampaignNode) error {
+	db.nodes = append(db.nodes, nodes...)
+	return nil
+}
+
+func (db *recordingDB) UpsertDistributedQuery(dq osquery_types.DistributedQuery) error {
+	db.queries = append(db.queries, dq)
+	return nil
+}
+
+func TestStartCampaign(t *testing.T) {
+	db := &recordingDB{MockDB: helpers.NewMockDB()}
+	req := campaignRequest{
+		Query:   "SELECT * FROM users",
+		Targets: osquery_types.CampaignTargets{Tags: []string{"a"}, NodeKeys: []string{"missing"}},
+	}
+	started, err := startCampaign(db, sqllint.PolicyWarn, req, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if started.Timeout != osquery_types.DefaultCampaignTimeout || len(started.UnknownNodeKeys) != 1 {
+		t.Errorf("got %+v", started)
+	}
+	if len(db.nodes) != 1 || db.nodes[0].State != osquery_types.CampaignQueued {
+		t.Fatalf("expected one queued node, got %+v", db.nodes)
+	}
+	if len(db.queries) != 1 || len(db.queries[0].Pending) != 1 || db.queries[0].Pending[0].Name != started.CampaignID ||
+		db.queries[0].Pending[0].Query != req.Query {
+		t.Errorf("expected the query queued under the campaign ID, got %+v", db.queries)
+	}
+	if started.Counts[osquery_types.CampaignQueued] != 1 {
+		t.Errorf("counts: got %v", started.Counts)
+	}
+	if len(started.Nodes) != 1 || started.Nodes[0].NodeKey != "" || db.nodes[0].NodeKey == "" {
+		t.Errorf("expected the node key stored but not returned, got %+v", started.Nodes)
+	}
+
+	req.Targets = osquery_types.CampaignTargets{Tags: []string{"nobody"}}
+	if _, err := startCampaign(db, sqllint.PolicyWarn, req, time.Now()); err == nil {
+		t.Error("expected an error for targets that match no nodes")
+	}
+}
+
+func TestDistributedQueryRead_CampaignQueries(t *testing.T) {
+	handler := DistributedQueryRead(helpers.NewMockDB(), &osquery_types.ServerConfig{})
+	test := helpers.GenerateHandleTester(t, handler)
+	w := test("POST", "", url.Values{}, bytes.NewBufferString(`{"node_key": "3lkjsdf0jdfoiasdjf"}`))
+	if w.Code != http.StatusOK {
+		t.Fatalf("got %d", w.Code)
+	}
+	read := struct {
+		Queries map[string]string `json:"queries"`
+	}{}
+	if err := json.Unmarshal(w.Body.Bytes(), &read); err != nil {
+		t.Fatal(err)
+	}
+	if read.Queries["id1"] == "" || read.Queries["5f0c6e1a9d2b4c7e8a1f3b6d"] == "" {
+		t.Errorf("expected numbered and campaign queries, got %v", read.Queries)
+	}
+}
+
+func TestDistributedQueryRead_NothingDue(t *testing.T) {
+	now := time.Now().UTC()
+	sent := osquery_types.PendingQuery{Name: "id1", Query: "select * from users;", Deliveries: 1,
+		QueuedAt: now.Format(time.RFC3339), DeliveredAt: now.Format(time.RFC3339)}
+	db := &queueDB{MockDB: helpers.NewMockDB(),
+		stored: osquery_types.DistributedQuery{NodeKey: "3lkjsdf0jdfoiasdjf", Pending: []osquery_types.PendingQuery{sent}, Revision: 1}}
+	handler := DistributedQueryRead(db, &osquery_types.ServerConfig{})
+	test := helpers.GenerateHandleTester(t, handler)
+	test("POST", "", url.Values{}, bytes.NewBufferString(`{"node_key": "3lkjsdf0jdfoiasdjf"}`))
+	if db.stored.Revision != 1 {
+		t.Errorf("expected nothing saved for a query that was just sent, got %+v", db.stored)
+	}
+}
+
+func TestRecordCampaignResults(t *testing.T) {
+	db := &recordingDB{MockDB: helpers.NewMockDB()}
+	d := distributedWrite{}
+	err := json.Unmarshal([]byte(`{
+		"node_key": "3lkjsdf0jdfoiasdjf",
+		"queries": {"5f0c6e1a9d2b4c7e8a1f3b6d": [{"username": "root"}, {"username": "admin"}], "id1": ""},
+		"statuses": {"5f0c6e1a9d2b4c7e8a1f3b6d": 0, "id1": "1"}
+	}`), &d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recordCampaignResults(db, d, time.Now())
+	if len(db.nodes) != 1 {
+		t.Fatalf("expected only the campaign query to be recorded, got %+v", db.nodes)
+	}
+	if cn := db.nodes[0]; cn.State != osquery_types.CampaignAnswered || cn.RowCount != 2 || len(cn.Rows) != 2 {
+		t.Errorf("got %+v", cn)
+	}
+
+	// a node can't write results to a campaign that doesn't target it
+	db.nodes = nil
+	d.NodeKey = "someone-else"
+	d.Statuses["5f0c6e1a9d2b4c7e8a1f3b6d"] = 1
+	recordCampaignResults(db, d, time.Now())
+	if len(db.nodes) != 0 {
+		t.Errorf("expected nothing recorded, got %+v", db.nodes)
+	}
+
+	client := osquery_types.OsqueryClient{NodeKey: d.NodeKey, HostIdentifier: "host1", HostName: "laptop-1", ConfigName: "default"}
+	statuses := []osquery_types.DistributedStatus{{Name: "5f0c6e1a9d2b4c7e8a1f3b6d", CampaignID: "5f0c6e1a9d2b4c7e8a1f3b6d"}}
+	now := time.Date(2019, 1, 1, 15, 4, 5, 0, time.UTC)
+	results, err := d.results(client, statuses, now)
+	if err != nil || len(results) != 2 {
+		t.Fatalf("expected a result per row, got %v %v", results, err)
+	}
+	if r := results[0]; r.HostIdentifier != "host1" || r.HostName != "laptop-1" || r.ConfigName != "default" ||
+		r.CampaignID != "5f0c6e1a9d2b4c7e8a1f3b6d" || r.CalendarTime != "2019-01-01T15:04:05Z" {
+		t.Errorf("got %+v", r)
+	}
+	js, err := json.Marshal(results)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(js, []byte(d.NodeKey)) {
+		t.Errorf("expected no node key in the results, got %s", js)
+	}
+}
+
+func TestDistributedWrite_Statuses(t *testing.T) {
+	d := distributedWrite{}
+	err := json.Unmarshal([]byte(`{
+		"node_key": "3lkjsdf0jdfoiasdjf",
+		"queries": {"id1": "", "id2": [], "5f0c6e1a9d2b4c7e8a1f3b6d": [{"username": "root"}]},
+		"statuses": {"id1": 1, "id2": 0, "5f0c6e1a9d2b4c7e8a1f3b6d": 0},
+		"messages": {"id1": "no such table: userz"}
+	}`), &d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := &recordingDB{MockDB: helpers.NewMockDB()}
+	campaigns := recordCampaignResults(db, d, time.Now())
+	if campaigns["5f0c6e1a9d2b4c7e8a1f3b6d"].State != osquery_types.CampaignAnswered || len(campaigns) != 1 {
+		t.Errorf("campaigns: got %v", campaigns)
+	}
+
+	client := osquery_types.OsqueryClient{NodeKey: d.NodeKey, HostIdentifier: "host1"}
+	statuses := d.statuses(client, campaigns, time.Now())
+	if len(statuses) != 3 {
+		t.Fatalf("expected a status per query, got %+v", statuses)
+	}
+	// names are sorted, so the campaign's status is first
+	if statuses[0].CampaignID == "" || statuses[0].RowCount != 1 || statuses[0].Failed() {
+		t.Errorf("got %+v", statuses[0])
+	}
+	if !statuses[1].Failed() || statuses[1].Message != "no such table: userz" || statuses[1].HostIdentifier != "host1" {
+		t.Errorf("expected id1 to have failed, got %+v", statuses[1])
+	}
+	if statuses[2].Failed() || statuses[2].RowCount != 0 {
+		t.Errorf("expected id2 to have succeeded with no rows, got %+v", statuses[2])
+	}
+
+	events := statusEvents(client, statuses, time.Now())
+	js, err := json.Marshal(events[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `"log_type":"status","host_identifier":"host1","status":1,"message":"no such table: userz"`
+	if !bytes.Contains(js, []byte(expected)) || bytes.Contains(js, []byte("columns")) || bytes.Contains(js, []byte(d.NodeKey)) {
+		t.Errorf("got %s", js)
+	}
+}
+
+func TestRecordExpiredQueries(t *testing.T) {
+	db := &recordingDB{MockDB: helpers.NewMockDB()}
+	expired := []osquery_types.ExpiredQuery{{
+		PendingQuery: osquery_types.PendingQuery{Name: "5f0c6e1a9d2b4c7e8a1f3b6d", CampaignID: "5f0c6e1a9d2b4c7e8a1f3b6d"},
+		Outcome:      osquery_types.DistributedExpired,
+		Reason:       "not answered",
+	}}
+	recordExpiredQueries(db, "3lkjsdf0jdfoiasdjf", expired, time.Now())
+	// the mock campaign node has already answered, so it keeps its results
+	if len(db.nodes) != 0 {
+		t.Errorf("expected the answered node left alone, got %+v", db.nodes)
+	}
+}
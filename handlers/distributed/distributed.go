@@ -7,6 +7,8 @@ import (
 	"io"
 	"io/ioutil"
 	"net/http"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -16,6 +18,8 @@ import (
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/firehose"
 	"github.com/oktasecuritylabs/sgt/handlers/response"
+	"github.com/oktasecuritylabs/sgt/internal/pkg/selector"
+	"github.com/oktasecuritylabs/sgt/internal/pkg/sqllint"
 	"github.com/oktasecuritylabs/sgt/logger"
 	"github.com/oktasecuritylabs/sgt/osquery_types"
 )
@@ -36,9 +40,20 @@ type DistributedDB interface {
 	DeleteDistributedQuery(dq osquery_types.DistributedQuery) error
 	ValidNode(nodeKey string) error
 	UpsertDistributedQuery(dq osquery_types.DistributedQuery) error
+	SearchByNodeKey(nk string) (osquery_types.OsqueryClient, error)
+	SearchByHostIdentifier(hid string) ([]osquery_types.OsqueryClient, error)
+	GetCampaignNode(campaignID, nodeKey string) (osquery_types.CampaignNode, error)
+	UpsertCampaignNode(cn osquery_types.CampaignNode) error
+	NewDistributedStatus(ds osquery_types.DistributedStatus) error
+	UpdateDistributedQuery(nodeKey string, update func(dq *osquery_types.DistributedQuery) bool) error
+	GetDistributedQueries() ([]osquery_types.DistributedQuery, error)
 }
 
-func DistributedQueryRead(dyn DistributedDB) http.Handler {
+// DistributedQueryRead sends a node the distributed queries that are due.  Queries stay pending until
+// the node writes their results, and are sent again or given up on according to the server config's
+// delivery policy
+func DistributedQueryRead(dyn DistributedDB, config *osquery_types.ServerConfig) http.Handler {
+	policy := config.DistributedDeliveryPolicy()
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		handleRequest := func() error {
 
@@ -59,21 +74,29 @@ func DistributedQueryRead(dyn DistributedDB) http.Handler {
 				return fmt.Errorf("unmarshal failed: %s", err)
 			}
 
-			distributedQuery, err := dyn.SearchDistributedNodeKey(n.NodeKey)
+			// the queries are saved as sent before they are sent, so one lost along with the response
+			// or the node is sent again.  Nothing is saved when nothing is sent or given up on
+			now := time.Now()
+			toSend := osquery_types.DistributedQuery{}
+			var expired []osquery_types.ExpiredQuery
+			err = dyn.UpdateDistributedQuery(n.NodeKey, func(dq *osquery_types.DistributedQuery) bool {
+				toSend = osquery_types.DistributedQuery{NodeInvalid: dq.NodeInvalid}
+				if len(dq.Queries) == 0 && len(dq.Pending) == 0 {
+					return false
+				}
+				toSend.Pending, expired = dq.Deliver(now, policy)
+				return len(toSend.Pending) > 0 || len(expired) > 0
+			})
 			if err != nil {
-				return fmt.Errorf("could not find node with key '%s': %s", n.NodeKey, err)
+				return fmt.Errorf("could not deliver queries to node with key '%s': %s", n.NodeKey, err)
 			}
-
-			if len(distributedQuery.Queries) == 0 {
+			if toSend.Pending == nil {
 				return errors.New("no queries in list: %s")
 			}
 
-			io.WriteString(w, distributedQuery.ToJSON())
-			err = dyn.DeleteDistributedQuery(distributedQuery)
-			if err != nil {
-				return fmt.Errorf("could not delete query: %s", err)
-			}
-
+			io.WriteString(w, toSend.ToJSON())
+			recordExpiredQueries(dyn, n.NodeKey, expired, now)
+			markCampaignsDelivered(dyn, n.NodeKey, toSend.Pending, now)
 			return nil
 		}
 
@@ -92,58 +115,196 @@ func DistributedQueryRead(respWriter http.ResponseWriter, request *http.Request)
 }
 */
 
-func ParseDistributedResults(request *http.Request) ([]osquery_types.DistributedQueryResult, error) {
-	results := []osquery_types.DistributedQueryResult{}
+// distributedWrite is the body of a node's /distributed/write request.  osquery sends an empty
+// string rather than a list of rows for a query that failed, so rows are decoded by rows().  Messages
+// are the error messages of failed queries, which osquery only sends from version 3.3
+type distributedWrite struct {
+	NodeKey  string                           `json:"node_key"`
+	Queries  map[string]json.RawMessage       `json:"queries"`
+	Statuses map[string]osquery_types.FlexInt `json:"statuses"`
+	Messages map[string]string                `json:"messages"`
+}
+
+func readDistributedWrite(request *http.Request) (distributedWrite, error) {
+	d := distributedWrite{}
 	body, err := ioutil.ReadAll(request.Body)
 	defer request.Body.Close()
 	if err != nil {
-		logger.Error(err)
-		return results, err
-	}
-	//file, err := os.Open(fn)
-	type dr struct {
-		NodeKey  string                         `json:"node_key"`
-		Queries  map[string][]map[string]string `json:"queries"`
-		Statuses map[string]string              `json:"statuses"`
+		return d, err
 	}
-	//decoder := json.NewDecoder(file)
-	d := dr{}
-	//err = decoder.Decode(&d)
 	err = json.Unmarshal(body, &d)
-	if err != nil {
-		logger.Error(err)
-		return results, err
+	return d, err
+}
+
+// rows returns the rows the node wrote for the named query
+func (d distributedWrite) rows(name string) ([]map[string]string, error) {
+	rows := []map[string]string{}
+	raw := strings.TrimSpace(string(d.Queries[name]))
+	if raw == "" || raw == "null" || raw == `""` {
+		return rows, nil
 	}
-	for k, v := range d.Queries {
-		for _, v1 := range v {
-			qr := osquery_types.DistributedQueryResult{
-				Name:           k,
-				CalendarTime:   time.Now().UTC().Format("2006-01-02 03:04:05"),
-				Action:         "added",
-				LogType:        "result",
-				Columns:        v1,
-				HostIdentifier: d.NodeKey,
-			}
+	if err := json.Unmarshal(d.Queries[name], &rows); err != nil {
+		return rows, fmt.Errorf("invalid rows for query [%s]: %s", name, err)
+	}
+	return rows, nil
+}
+
+// results returns a result for each row the node wrote, identifying the node oc.  statuses are the
+// statuses of the queries, which give the campaign and SQL of each query when they are known
+func (d distributedWrite) results(oc osquery_types.OsqueryClient, statuses []osquery_types.DistributedStatus, now time.Time) ([]osquery_types.DistributedQueryResult, error) {
+	byName := map[string]osquery_types.DistributedStatus{}
+	for _, ds := range statuses {
+		byName[ds.Name] = ds
+	}
+	results := []osquery_types.DistributedQueryResult{}
+	for _, name := range d.names() {
+		rows, err := d.rows(name)
+		if err != nil {
+			return results, err
+		}
+		for _, row := range rows {
+			qr := osquery_types.NewDistributedQueryResult(oc, name, "result", now)
+			qr.Action = "added"
+			qr.Columns = row
+			qr.CampaignID = byName[name].CampaignID
+			qr.Query = byName[name].Query
 			results = append(results, qr)
 		}
 	}
 	return results, nil
 }
 
-func DistributedQueryWrite(dyn DistributedDB) http.Handler {
+// names returns the name of every query the node wrote rows, a status or a message for
+func (d distributedWrite) names() []string {
+	seen := map[string]bool{}
+	names := []string{}
+	for name := range d.Queries {
+		seen[name] = true
+	}
+	for name := range d.Statuses {
+		seen[name] = true
+	}
+	for name := range d.Messages {
+		seen[name] = true
+	}
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// statuses returns the status of every query the node wrote.  campaigns are the node's answers to the
+// campaign queries among them
+func (d distributedWrite) statuses(oc osquery_types.OsqueryClient, campaigns map[string]osquery_types.CampaignNode, now time.Time) []osquery_types.DistributedStatus {
+	if oc.NodeKey == "" {
+		oc.NodeKey = d.NodeKey
+	}
+	statuses := []osquery_types.DistributedStatus{}
+	for _, name := range d.names() {
+		rows, _ := d.rows(name)
+		ds := osquery_types.NewDistributedStatus(oc, name, int(d.Statuses[name]), d.Messages[name], len(rows), now)
+		if _, ok := campaigns[name]; ok {
+			ds.CampaignID = name
+		}
+		statuses = append(statuses, ds)
+	}
+	return statuses
+}
+
+// statusEvents returns a status event for each status of the node oc, to be sent with the results
+func statusEvents(oc osquery_types.OsqueryClient, statuses []osquery_types.DistributedStatus, now time.Time) []osquery_types.DistributedQueryResult {
+	events := []osquery_types.DistributedQueryResult{}
+	for _, ds := range statuses {
+		status := ds.Status
+		event := osquery_types.NewDistributedQueryResult(oc, ds.Name, "status", now)
+		event.CampaignID = ds.CampaignID
+		event.Query = ds.Query
+		event.Status = &status
+		event.Message = ds.Message
+		events = append(events, event)
+	}
+	return events
+}
+
+// acknowledgeQueries removes the queries the node has written results or a status for from its
+// pending queries, and returns them by name
+func acknowledgeQueries(dyn DistributedDB, d distributedWrite) map[string]osquery_types.PendingQuery {
+	answered := map[string]osquery_types.PendingQuery{}
+	err := dyn.UpdateDistributedQuery(d.NodeKey, func(dq *osquery_types.DistributedQuery) bool {
+		answered = map[string]osquery_types.PendingQuery{}
+		for _, pq := range dq.Acknowledge(d.names()) {
+			answered[pq.Name] = pq
+		}
+		return len(answered) > 0
+	})
+	if err != nil {
+		logger.Error(err)
+	}
+	return answered
+}
+
+// recordDistributedStatuses saves each status against its node.  The results have been received
+// whether or not the statuses are saved, so errors are only logged
+func recordDistributedStatuses(dyn DistributedDB, statuses []osquery_types.DistributedStatus) {
+	for _, ds := range statuses {
+		if ds.Failed() {
+			logger.Warn(fmt.Sprintf("distributed query [%s] failed on node [%s] with status %d: %s",
+				ds.Name, ds.HostIdentifier, ds.Status, ds.Message))
+		}
+		if err := dyn.NewDistributedStatus(ds); err != nil {
+			logger.Error(err)
+		}
+	}
+}
+
+// ParseDistributedResults returns a result for each row in a /distributed/write request from the node oc
+func ParseDistributedResults(request *http.Request, oc osquery_types.OsqueryClient) ([]osquery_types.DistributedQueryResult, error) {
+	d, err := readDistributedWrite(request)
+	if err != nil {
+		logger.Error(err)
+		return []osquery_types.DistributedQueryResult{}, err
+	}
+	return d.results(oc, nil, time.Now())
+}
+
+// DistributedQueryWrite records the results and statuses a node writes, passes campaign answers to the
+// broker for clients streaming the campaigns, and sends the results to the writer
+func DistributedQueryWrite(dyn DistributedDB, writer ResultWriter, broker *CampaignBroker) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		handleRequest := func() error {
 
-			fhSvc := FirehoseService()
-			config, err := osquery_types.GetServerConfig("config.json")
+			d, err := readDistributedWrite(r)
 			if err != nil {
-				return fmt.Errorf("could not get server config: %s", err)
+				return fmt.Errorf("could not parsed results: %s", err)
+			}
+			// campaign results and statuses are stored before anything else can fail, so analysts can
+			// see them
+			now := time.Now()
+			answered := acknowledgeQueries(dyn, d)
+			campaigns := recordCampaignResults(dyn, d, now)
+			for _, cn := range campaigns {
+				broker.Publish(cn)
 			}
-			results, err := ParseDistributedResults(r)
+			client, err := dyn.SearchByNodeKey(d.NodeKey)
+			if err != nil {
+				logger.Error(err)
+			}
+			if client.HostIdentifier == "" {
+				logger.Warn(fmt.Sprintf("distributed results written by a node that isn't enrolled, %d queries", len(d.names())))
+			}
+			statuses := d.statuses(client, campaigns, now)
+			for i := range statuses {
+				statuses[i].Query = answered[statuses[i].Name].Query
+			}
+			recordDistributedStatuses(dyn, statuses)
+
+			results, err := d.results(client, statuses, now)
 			if err != nil {
 				return fmt.Errorf("could not parsed results: %s", err)
 			}
-			return PutFirehoseBatch(results, config.DistributedQueryLoggerFirehoseStreamName, fhSvc)
+			results = append(results, statusEvents(client, statuses, now)...)
+			return writer.Write(results)
 		}
 
 		err := handleRequest()
@@ -282,12 +443,18 @@ if len(records) == 450 || a == len(s) -1 {
 	}
 	records = records[:0]
 }*/
-func DistributedQueryAdd(dyn DistributedDB) http.Handler {
+// DistributedQueryAdd schedules distributed queries for nodes.  Every query is linted against the
+// platform and osquery version of the node it's for before anything is scheduled, findings are logged
+// and under the reject policy a query with errors fails the whole request.  Besides the listed nodes,
+// queries may be scheduled for every node picked out by a selector, see internal/pkg/selector
+func DistributedQueryAdd(dyn DistributedDB, lintPolicy sqllint.Policy) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		handleRequest := func() (interface{}, error) {
 
 			type distributedQueryAdd struct {
-				Nodes []osquery_types.DistributedQuery `json:"nodes"`
+				Nodes    []osquery_types.DistributedQuery `json:"nodes"`
+				Selector string                           `json:"selector"`
+				Queries  []string                         `json:"queries"`
 			}
 
 			body, err := ioutil.ReadAll(r.Body)
@@ -302,6 +469,19 @@ func DistributedQueryAdd(dyn DistributedDB) http.Handler {
 				return nil, fmt.Errorf("unmarshal failed: %s", err)
 			}
 
+			if nodes.Selector != "" {
+				selected, err := selectNodes(dyn, nodes.Selector, nodes.Queries)
+				if err != nil {
+					return nil, err
+				}
+				nodes.Nodes = append(nodes.Nodes, selected...)
+			}
+
+			err = lintDistributedQueries(dyn, lintPolicy, nodes.Nodes)
+			if err != nil {
+				return nil, err
+			}
+
 			success := map[string]bool{}
 			for _, j := range nodes.Nodes {
 				err = dyn.ValidNode(j.NodeKey)
@@ -334,6 +514,57 @@ func DistributedQueryAdd(dyn DistributedDB) http.Handler {
 	})
 }
 
+// selectNodes returns the queries for each node the selector picks out.  Nodes that are invalid or
+// pending registration approval don't fetch queries, so they are left out
+func selectNodes(dyn DistributedDB, s string, queries []string) ([]osquery_types.DistributedQuery, error) {
+	if len(queries) == 0 {
+		return nil, errors.New("no queries given for the selector")
+	}
+	sel, err := selector.Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	clients, err := dyn.SearchByHostIdentifier("")
+	if err != nil {
+		return nil, fmt.Errorf("could not get nodes: %s", err)
+	}
+	selected := []osquery_types.DistributedQuery{}
+	for _, oc := range sel.Filter(clients) {
+		if oc.NodeInvalid || oc.PendingRegistrationApproval {
+			continue
+		}
+		selected = append(selected, osquery_types.DistributedQuery{NodeKey: oc.NodeKey, Queries: queries})
+	}
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("selector [%s] matches no nodes", s)
+	}
+	return selected, nil
+}
+
+// lintDistributedQueries lints each node's queries for that node's platform and osquery version
+func lintDistributedQueries(dyn DistributedDB, lintPolicy sqllint.Policy, queries []osquery_types.DistributedQuery) error {
+	if lintPolicy == sqllint.PolicyOff {
+		return nil
+	}
+	for _, dq := range queries {
+		target := sqllint.Target{}
+		client, err := dyn.SearchByNodeKey(dq.NodeKey)
+		if err == nil {
+			target = sqllint.NodeTarget(client.RenderTarget())
+		}
+		for _, q := range dq.Queries {
+			findings, err := lintPolicy.Check(q, target)
+			for _, f := range findings {
+				logger.Warn(fmt.Sprintf("sql lint for node [%s] query [%s]: %s", dq.NodeKey, q, f))
+			}
+			if err != nil {
+				return fmt.Errorf("query for node [%s]: %s", dq.NodeKey, err)
+			}
+		}
+	}
+	return nil
+}
+
 /*
 func DistributedQueryAdd(respWriter http.ResponseWriter, request *http.Request) {
 
@@ -0,0 +1,222 @@
+package distributed
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/oktasecuritylabs/sgt/handlers/response"
+	"github.com/oktasecuritylabs/sgt/internal/pkg/sqllint"
+	"github.com/oktasecuritylabs/sgt/logger"
+	"github.com/oktasecuritylabs/sgt/osquery_types"
+)
+
+// SavedQueryDB is the storage used for the saved query library and to launch its queries
+type SavedQueryDB interface {
+	CampaignDB
+	UpsertSavedQuery(sq osquery_types.SavedQuery) error
+	GetSavedQuery(name string) (osquery_types.SavedQuery, error)
+	GetSavedQueries() ([]osquery_types.SavedQuery, error)
+	DeleteSavedQuery(name string) error
+}
+
+// launchRequest is the body of a request to launch a saved query.  Params are JSON strings, numbers or
+// booleans, and are checked against the types of the query's parameters when the query is rendered
+type launchRequest struct {
+	Params  map[string]interface{}        `json:"params"`
+	Targets osquery_types.CampaignTargets `json:"targets"`
+	Timeout int                           `json:"timeout"`
+}
+
+// values returns the params as text to render a saved query with.  The request must be decoded with
+// UseNumber, so large integers keep their digits
+func (lr launchRequest) values() (map[string]string, error) {
+	values := map[string]string{}
+	for name, v := range lr.Params {
+		switch v := v.(type) {
+		case string:
+			values[name] = v
+		case json.Number:
+			values[name] = v.String()
+		case bool:
+			values[name] = strconv.FormatBool(v)
+		default:
+			return nil, fmt.Errorf("parameter %q must be a string, number or boolean", name)
+		}
+	}
+	return values, nil
+}
+
+func getSavedQuery(dyn SavedQueryDB, name string) (osquery_types.SavedQuery, error) {
+	if name == "" {
+		return osquery_types.SavedQuery{}, errors.New("no saved query name specified")
+	}
+	sq, err := dyn.GetSavedQuery(name)
+	if err != nil {
+		return sq, fmt.Errorf("could not get saved query [%s]: %s", name, err)
+	}
+	if sq.Name == "" {
+		return sq, fmt.Errorf("no saved query found named [%s]", name)
+	}
+	return sq, nil
+}
+
+func hasTag(sq osquery_types.SavedQuery, tag string) bool {
+	for _, t := range sq.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// SavedQueriesHandler lists the saved query library on GET, only the queries with the tag if ?tag= is
+// given.  On POST it saves a query, replacing any query of the same name
+func SavedQueriesHandler(dyn SavedQueryDB) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handleRequest := func() (interface{}, error) {
+			if r.Method == http.MethodGet {
+				queries, err := dyn.GetSavedQueries()
+				if err != nil {
+					return nil, fmt.Errorf("could not get saved queries: %s", err)
+				}
+				if tag := r.URL.Query().Get("tag"); tag != "" {
+					tagged := []osquery_types.SavedQuery{}
+					for _, sq := range queries {
+						if hasTag(sq, tag) {
+							tagged = append(tagged, sq)
+						}
+					}
+					queries = tagged
+				}
+				sort.SliceStable(queries, func(i, j int) bool {
+					return queries[i].Name < queries[j].Name
+				})
+				return queries, nil
+			}
+
+			body, err := ioutil.ReadAll(r.Body)
+			defer r.Body.Close()
+			if err != nil {
+				return nil, fmt.Errorf("failed to read request body: %s", err)
+			}
+			sq := osquery_types.SavedQuery{}
+			err = json.Unmarshal(body, &sq)
+			if err != nil {
+				return nil, fmt.Errorf("unmarshal failed: %s", err)
+			}
+			if err := sq.Validate(); err != nil {
+				return nil, err
+			}
+			existing, err := dyn.GetSavedQuery(sq.Name)
+			if err != nil {
+				return nil, fmt.Errorf("could not get saved query [%s]: %s", sq.Name, err)
+			}
+			sq.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+			sq.CreatedAt = existing.CreatedAt
+			if sq.CreatedAt == "" {
+				sq.CreatedAt = sq.UpdatedAt
+			}
+			if err := dyn.UpsertSavedQuery(sq); err != nil {
+				return nil, fmt.Errorf("could not save saved query [%s]: %s", sq.Name, err)
+			}
+			return sq, nil
+		}
+
+		result, err := handleRequest()
+		if err != nil {
+			logger.Error(err)
+			response.WriteError(w, fmt.Sprintf("[SavedQueriesHandler] %s", err))
+		} else {
+			response.WriteCustomJSON(w, result)
+		}
+	})
+}
+
+// SavedQueryHandler returns the saved query named {query_name} on GET, and deletes it on DELETE
+func SavedQueryHandler(dyn SavedQueryDB) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handleRequest := func() (interface{}, error) {
+			sq, err := getSavedQuery(dyn, mux.Vars(r)["query_name"])
+			if err != nil {
+				return nil, err
+			}
+			if r.Method == http.MethodDelete {
+				if err := dyn.DeleteSavedQuery(sq.Name); err != nil {
+					return nil, fmt.Errorf("could not delete saved query [%s]: %s", sq.Name, err)
+				}
+				return map[string]string{"result": "success"}, nil
+			}
+			return sq, nil
+		}
+
+		result, err := handleRequest()
+		if err != nil {
+			logger.Error(err)
+			response.WriteError(w, fmt.Sprintf("[SavedQueryHandler] %s", err))
+		} else {
+			response.WriteCustomJSON(w, result)
+		}
+	})
+}
+
+// launchSavedQuery renders the saved query with the request's params and starts a campaign for it on
+// the targeted nodes that run on the query's platforms
+func launchSavedQuery(dyn SavedQueryDB, lintPolicy sqllint.Policy, sq osquery_types.SavedQuery, req launchRequest, now time.Time) (campaignStarted, error) {
+	values, err := req.values()
+	if err != nil {
+		return campaignStarted{}, err
+	}
+	query, err := sq.Render(values)
+	if err != nil {
+		return campaignStarted{}, err
+	}
+	return startCampaign(dyn, lintPolicy, campaignRequest{
+		Query:      query,
+		Targets:    req.Targets,
+		Timeout:    req.Timeout,
+		savedQuery: sq.Name,
+		platforms:  sq.Platforms,
+	}, now)
+}
+
+// LaunchSavedQueryHandler starts a campaign for the saved query named {query_name}, with the values of
+// its parameters in the request body
+func LaunchSavedQueryHandler(dyn SavedQueryDB, lintPolicy sqllint.Policy) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handleRequest := func() (interface{}, error) {
+			sq, err := getSavedQuery(dyn, mux.Vars(r)["query_name"])
+			if err != nil {
+				return nil, err
+			}
+			body, err := ioutil.ReadAll(r.Body)
+			defer r.Body.Close()
+			if err != nil {
+				return nil, fmt.Errorf("failed to read request body: %s", err)
+			}
+			req := launchRequest{}
+			d := json.NewDecoder(bytes.NewReader(body))
+			d.UseNumber()
+			err = d.Decode(&req)
+			if err != nil {
+				return nil, fmt.Errorf("unmarshal failed: %s", err)
+			}
+			return launchSavedQuery(dyn, lintPolicy, sq, req, time.Now())
+		}
+
+		result, err := handleRequest()
+		if err != nil {
+			logger.Error(err)
+			response.WriteError(w, fmt.Sprintf("[LaunchSavedQueryHandler] %s", err))
+		} else {
+			response.WriteCustomJSON(w, result)
+		}
+	})
+}
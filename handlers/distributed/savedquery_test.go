@@ -0,0 +1,51 @@
+package distributed
+
+import (
+	"testing"
+	"time"
+
+	"github.com/oktasecuritylabs/sgt/handlers/helpers"
+	"github.com/oktasecuritylabs/sgt/internal/pkg/sqllint"
+	"github.com/oktasecuritylabs/sgt/osquery_types"
+)
+
+func TestLaunchSavedQuery(t *testing.T) {
+	db := &recordingDB{MockDB: helpers.NewMockDB()}
+	sq, err := db.GetSavedQuery("file_hash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := launchRequest{
+		Params:  map[string]interface{}{"sha256": "E3B0C44298FC1C149AFBF4C8996FB92427AE41E4649B934CA495991B7852B855"},
+		Targets: osquery_types.CampaignTargets{Tags: []string{"a"}},
+	}
+	started, err := launchSavedQuery(db, sqllint.PolicyWarn, sq, req, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "SELECT path FROM hash WHERE directory = '/tmp' AND sha256 = 'e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855';"
+	if started.Query != expected || started.SavedQuery != "file_hash" {
+		t.Errorf("got %+v", started)
+	}
+	if len(db.queries) != 1 || db.queries[0].Pending[0].Query != expected {
+		t.Errorf("expected the rendered query queued, got %+v", db.queries)
+	}
+
+	// the mock node hasn't reported a platform, so it is kept, but a node on another platform isn't
+	sq.Platforms = []string{"windows"}
+	if _, err := launchSavedQuery(db, sqllint.PolicyWarn, sq, req, time.Now()); err != nil {
+		t.Errorf("expected a node without a platform to be targeted, got %s", err)
+	}
+	nodes := onPlatforms([]osquery_types.OsqueryClient{{
+		NodeKey:     "linux1",
+		HostDetails: map[string]map[string]string{"os_version": {"platform": "ubuntu"}},
+	}}, "windows")
+	if len(nodes) != 0 {
+		t.Errorf("expected the linux node left out, got %+v", nodes)
+	}
+
+	req.Params["sha256"] = 7
+	if _, err := launchSavedQuery(db, sqllint.PolicyWarn, sq, req, time.Now()); err == nil {
+		t.Error("expected a non-hash value to be rejected")
+	}
+}
@@ -0,0 +1,267 @@
+package distributed
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/oktasecuritylabs/sgt/handlers/response"
+	"github.com/oktasecuritylabs/sgt/internal/pkg/cron"
+	"github.com/oktasecuritylabs/sgt/internal/pkg/selector"
+	"github.com/oktasecuritylabs/sgt/internal/pkg/sqllint"
+	"github.com/oktasecuritylabs/sgt/logger"
+	"github.com/oktasecuritylabs/sgt/osquery_types"
+)
+
+// ScheduleDB is the storage used to save distributed query schedules and run them
+type ScheduleDB interface {
+	CampaignDB
+	UpsertDistributedSchedule(ds osquery_types.DistributedSchedule) error
+	GetDistributedSchedule(scheduleID string) (osquery_types.DistributedSchedule, error)
+	GetDistributedSchedules() ([]osquery_types.DistributedSchedule, error)
+	DeleteDistributedSchedule(scheduleID string) error
+	NewDistributedScheduleRun(run osquery_types.DistributedScheduleRun) error
+	GetDistributedScheduleRuns(scheduleID string) ([]osquery_types.DistributedScheduleRun, error)
+	AcquireLease(name, owner string, ttl time.Duration, now time.Time) (bool, error)
+}
+
+// SchedulerInterval is how often the scheduler runs the schedules that are due.  A schedule runs at
+// most once per interval, however often its cron schedule says
+const SchedulerInterval = time.Minute
+
+// schedulerLease is the lease held by the server running schedules
+const schedulerLease = "distributed_scheduler"
+
+// newSchedule validates a schedule being created, and sets its ID and first run
+func newSchedule(ds osquery_types.DistributedSchedule, now time.Time) (osquery_types.DistributedSchedule, error) {
+	id, err := osquery_types.NewCampaignID()
+	if err != nil {
+		return ds, fmt.Errorf("could not create a schedule ID: %s", err)
+	}
+	ds.ScheduleID = id
+	ds.CreatedAt = now.UTC().Format(time.RFC3339)
+	ds.LastRunAt = ""
+	if ds.Timeout == 0 {
+		ds.Timeout = osquery_types.DefaultCampaignTimeout
+	}
+	if err := ds.Validate(); err != nil {
+		return ds, err
+	}
+	if ds.Targets.Selector != "" {
+		if _, err := selector.Parse(ds.Targets.Selector); err != nil {
+			return ds, err
+		}
+	}
+	sched, err := cron.Parse(ds.Cron)
+	if err != nil {
+		return ds, err
+	}
+	ds.SetNextRun(sched.Next(now))
+	if ds.NextRunAt == "" {
+		return ds, errors.New("the schedule doesn't run before it ends")
+	}
+	return ds, nil
+}
+
+// DistributedSchedulesHandler lists distributed query schedules on GET, and creates one on POST
+func DistributedSchedulesHandler(dyn ScheduleDB) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handleRequest := func() (interface{}, error) {
+			if r.Method == http.MethodGet {
+				schedules, err := dyn.GetDistributedSchedules()
+				if err != nil {
+					return nil, fmt.Errorf("could not get schedules: %s", err)
+				}
+				sort.SliceStable(schedules, func(i, j int) bool {
+					return schedules[i].CreatedAt > schedules[j].CreatedAt
+				})
+				return schedules, nil
+			}
+
+			body, err := ioutil.ReadAll(r.Body)
+			defer r.Body.Close()
+			if err != nil {
+				return nil, fmt.Errorf("failed to read request body: %s", err)
+			}
+			ds := osquery_types.DistributedSchedule{}
+			err = json.Unmarshal(body, &ds)
+			if err != nil {
+				return nil, fmt.Errorf("unmarshal failed: %s", err)
+			}
+			ds, err = newSchedule(ds, time.Now())
+			if err != nil {
+				return nil, err
+			}
+			if err := dyn.UpsertDistributedSchedule(ds); err != nil {
+				return nil, fmt.Errorf("could not save schedule: %s", err)
+			}
+			return ds, nil
+		}
+
+		result, err := handleRequest()
+		if err != nil {
+			logger.Error(err)
+			response.WriteError(w, fmt.Sprintf("[DistributedSchedulesHandler] %s", err))
+		} else {
+			response.WriteCustomJSON(w, result)
+		}
+	})
+}
+
+func getSchedule(dyn ScheduleDB, scheduleID string) (osquery_types.DistributedSchedule, error) {
+	if scheduleID == "" {
+		return osquery_types.DistributedSchedule{}, errors.New("no schedule ID specified")
+	}
+	ds, err := dyn.GetDistributedSchedule(scheduleID)
+	if err != nil {
+		return ds, fmt.Errorf("could not get schedule [%s]: %s", scheduleID, err)
+	}
+	if ds.ScheduleID == "" {
+		return ds, fmt.Errorf("no schedule found with ID [%s]", scheduleID)
+	}
+	return ds, nil
+}
+
+// DistributedScheduleHandler returns the schedule specified by {schedule_id} on GET, and deletes it on
+// DELETE.  The runs of a deleted schedule are kept
+func DistributedScheduleHandler(dyn ScheduleDB) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handleRequest := func() (interface{}, error) {
+			ds, err := getSchedule(dyn, mux.Vars(r)["schedule_id"])
+			if err != nil {
+				return nil, err
+			}
+			if r.Method == http.MethodDelete {
+				if err := dyn.DeleteDistributedSchedule(ds.ScheduleID); err != nil {
+					return nil, fmt.Errorf("could not delete schedule [%s]: %s", ds.ScheduleID, err)
+				}
+				return map[string]string{"result": "success"}, nil
+			}
+			return ds, nil
+		}
+
+		result, err := handleRequest()
+		if err != nil {
+			logger.Error(err)
+			response.WriteError(w, fmt.Sprintf("[DistributedScheduleHandler] %s", err))
+		} else {
+			response.WriteCustomJSON(w, result)
+		}
+	})
+}
+
+// DistributedScheduleRunsHandler returns the runs of the schedule specified by {schedule_id}, newest
+// first
+func DistributedScheduleRunsHandler(dyn ScheduleDB) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handleRequest := func() (interface{}, error) {
+			scheduleID := mux.Vars(r)["schedule_id"]
+			if scheduleID == "" {
+				return nil, errors.New("no schedule ID specified")
+			}
+			runs, err := dyn.GetDistributedScheduleRuns(scheduleID)
+			if err != nil {
+				return nil, fmt.Errorf("could not get runs of schedule [%s]: %s", scheduleID, err)
+			}
+			return runs, nil
+		}
+
+		result, err := handleRequest()
+		if err != nil {
+			logger.Error(err)
+			response.WriteError(w, fmt.Sprintf("[DistributedScheduleRunsHandler] %s", err))
+		} else {
+			response.WriteCustomJSON(w, result)
+		}
+	})
+}
+
+// runSchedule starts a campaign for the schedule, and records the run and when the schedule next runs
+func runSchedule(dyn ScheduleDB, lintPolicy sqllint.Policy, ds osquery_types.DistributedSchedule, now time.Time) osquery_types.DistributedScheduleRun {
+	run := osquery_types.DistributedScheduleRun{
+		ScheduleID: ds.ScheduleID,
+		RunAt:      now.UTC().Format(time.RFC3339),
+	}
+	started, err := startCampaign(dyn, lintPolicy, campaignRequest{
+		Query:      ds.Query,
+		Targets:    ds.Targets,
+		Timeout:    ds.Timeout,
+		scheduleID: ds.ScheduleID,
+	}, now)
+	if err != nil {
+		run.Error = err.Error()
+		logger.Warn(fmt.Sprintf("schedule [%s] could not run: %s", ds.ScheduleID, err))
+	} else {
+		run.CampaignID = started.CampaignID
+		run.Targeted = len(started.Nodes)
+	}
+	if err := dyn.NewDistributedScheduleRun(run); err != nil {
+		logger.Error(err)
+	}
+
+	ds.LastRunAt = run.RunAt
+	sched, err := cron.Parse(ds.Cron)
+	if err != nil {
+		// the cron schedule was checked when the schedule was created, so this only happens if it was
+		// edited by hand
+		logger.Error(fmt.Errorf("schedule [%s] ended: %s", ds.ScheduleID, err))
+		ds.NextRunAt = ""
+	} else {
+		ds.SetNextRun(sched.Next(now))
+	}
+	if err := dyn.UpsertDistributedSchedule(ds); err != nil {
+		logger.Error(err)
+	}
+	return run
+}
+
+// RunDueSchedules runs every schedule that is due at now, and returns the runs.  Runs missed while no
+// server was running schedules aren't made up, a schedule that is overdue runs once
+func RunDueSchedules(dyn ScheduleDB, lintPolicy sqllint.Policy, now time.Time) ([]osquery_types.DistributedScheduleRun, error) {
+	runs := []osquery_types.DistributedScheduleRun{}
+	schedules, err := dyn.GetDistributedSchedules()
+	if err != nil {
+		return runs, fmt.Errorf("could not get schedules: %s", err)
+	}
+	for _, ds := range schedules {
+		if ds.Due(now) {
+			runs = append(runs, runSchedule(dyn, lintPolicy, ds, now))
+		}
+	}
+	return runs, nil
+}
+
+// StartScheduler runs the schedules that are due each interval, in the background.  Every server runs
+// a scheduler, but only the one holding the scheduler lease runs schedules.  The lease lasts for a few
+// intervals, so another server takes over soon after the one holding it stops
+func StartScheduler(dyn ScheduleDB, lintPolicy sqllint.Policy, interval time.Duration) {
+	owner := leaseOwner()
+	go func() {
+		for now := range time.Tick(interval) {
+			leader, err := dyn.AcquireLease(schedulerLease, owner, 3*interval, now)
+			if err != nil {
+				logger.Error(err)
+				continue
+			}
+			if !leader {
+				continue
+			}
+			if _, err := RunDueSchedules(dyn, lintPolicy, now); err != nil {
+				logger.Error(err)
+			}
+		}
+	}()
+}
+
+// leaseOwner returns a name for this server to hold leases under, unique to the process
+func leaseOwner() string {
+	hostname, _ := os.Hostname()
+	suffix, _ := osquery_types.NewCampaignID()
+	return fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), suffix)
+}
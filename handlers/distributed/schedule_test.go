@@ -0,0 +1,84 @@
+package distributed
+
+import (
+	"testing"
+	"time"
+
+	"github.com/oktasecuritylabs/sgt/handlers/helpers"
+	"github.com/oktasecuritylabs/sgt/internal/pkg/sqllint"
+	"github.com/oktasecuritylabs/sgt/osquery_types"
+)
+
+// scheduleDB records the schedules and runs saved to the mock db
+type scheduleDB struct {
+	recordingDB
+	schedules []osquery_types.DistributedSchedule
+	runs      []osquery_types.DistributedScheduleRun
+}
+
+func (db *scheduleDB) UpsertDistributedSchedule(ds osquery_types.DistributedSchedule) error {
+	db.schedules = append(db.schedules, ds)
+	return nil
+}
+
+func (db *scheduleDB) NewDistributedScheduleRun(run osquery_types.DistributedScheduleRun) error {
+	db.runs = append(db.runs, run)
+	return nil
+}
+
+func TestNewSchedule(t *testing.T) {
+	now := time.Date(2019, 1, 1, 10, 30, 0, 0, time.UTC)
+	ds := osquery_types.DistributedSchedule{
+		Query:   "select * from users;",
+		Targets: osquery_types.CampaignTargets{Tags: []string{"a"}},
+		Cron:    "0 */4 * * *",
+		EndsAt:  "2019-01-02T00:00:00Z",
+	}
+	created, err := newSchedule(ds, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.ScheduleID == "" || created.NextRunAt != "2019-01-01T12:00:00Z" || created.Timeout != osquery_types.DefaultCampaignTimeout {
+		t.Errorf("got %+v", created)
+	}
+
+	invalid := []osquery_types.DistributedSchedule{ds, ds, ds, ds}
+	invalid[0].Cron = "every 4 hours"
+	invalid[1].EndsAt = "2019-01-01T11:00:00Z"
+	invalid[2].Targets = osquery_types.CampaignTargets{Selector: "tag:"}
+	invalid[3].EndsAt = "tomorrow"
+	for _, ds := range invalid {
+		if _, err := newSchedule(ds, now); err == nil {
+			t.Errorf("expected %+v to be invalid", ds)
+		}
+	}
+}
+
+func TestRunDueSchedules(t *testing.T) {
+	db := &scheduleDB{recordingDB: recordingDB{MockDB: helpers.NewMockDB()}}
+
+	runs, err := RunDueSchedules(db, sqllint.PolicyWarn, time.Date(2019, 1, 1, 3, 59, 0, 0, time.UTC))
+	if err != nil || len(runs) != 0 {
+		t.Errorf("expected nothing due yet, got %+v %v", runs, err)
+	}
+
+	// a run that is overdue runs once, and the next run is after now
+	now := time.Date(2019, 1, 1, 9, 30, 0, 0, time.UTC)
+	runs, err = RunDueSchedules(db, sqllint.PolicyWarn, now)
+	if err != nil || len(runs) != 1 {
+		t.Fatalf("expected one run, got %+v %v", runs, err)
+	}
+	if runs[0].CampaignID == "" || runs[0].Targeted != 1 || runs[0].Error != "" || len(db.runs) != 1 {
+		t.Errorf("got %+v", runs[0])
+	}
+	if len(db.queries) != 1 || db.queries[0].Pending[0].CampaignID != runs[0].CampaignID {
+		t.Errorf("expected the query queued for the campaign, got %+v", db.queries)
+	}
+	if len(db.schedules) != 1 || db.schedules[0].NextRunAt != "2019-01-01T12:00:00Z" || db.schedules[0].LastRunAt != "2019-01-01T09:30:00Z" {
+		t.Errorf("got %+v", db.schedules)
+	}
+
+	if runs, _ := RunDueSchedules(db, sqllint.PolicyWarn, time.Date(2019, 2, 1, 4, 0, 0, 0, time.UTC)); len(runs) != 0 {
+		t.Errorf("expected nothing run after the schedule ends, got %+v", runs)
+	}
+}
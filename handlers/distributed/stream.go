@@ -0,0 +1,224 @@
+package distributed
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/oktasecuritylabs/sgt/handlers/response"
+	"github.com/oktasecuritylabs/sgt/logger"
+	"github.com/oktasecuritylabs/sgt/osquery_types"
+)
+
+// CampaignStreamPollInterval is how often a campaign stream reads the campaign's nodes back, to pick
+// up answers written to other servers and nodes timing out.  The progress is sent after each poll, which
+// also keeps proxies from closing idle streams
+const CampaignStreamPollInterval = 5 * time.Second
+
+// CampaignBroker passes the answers nodes write on to the clients streaming their campaigns.  It only
+// sees answers written to this server, streams poll for the rest
+type CampaignBroker struct {
+	mu   sync.Mutex
+	subs map[string]map[chan osquery_types.CampaignNode]bool
+}
+
+// NewCampaignBroker returns a broker with no subscribers
+func NewCampaignBroker() *CampaignBroker {
+	return &CampaignBroker{subs: map[string]map[chan osquery_types.CampaignNode]bool{}}
+}
+
+// Subscribe returns a channel of the campaign's nodes as they answer, and a function that unsubscribes
+func (b *CampaignBroker) Subscribe(campaignID string) (<-chan osquery_types.CampaignNode, func()) {
+	ch := make(chan osquery_types.CampaignNode, 64)
+	b.mu.Lock()
+	if b.subs[campaignID] == nil {
+		b.subs[campaignID] = map[chan osquery_types.CampaignNode]bool{}
+	}
+	b.subs[campaignID][ch] = true
+	b.mu.Unlock()
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subs[campaignID], ch)
+		if len(b.subs[campaignID]) == 0 {
+			delete(b.subs, campaignID)
+		}
+	}
+}
+
+// Publish sends the node to its campaign's subscribers.  It never blocks, a subscriber that has fallen
+// behind misses the node until it next polls
+func (b *CampaignBroker) Publish(cn osquery_types.CampaignNode) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subs[cn.CampaignID] {
+		select {
+		case ch <- cn:
+		default:
+		}
+	}
+}
+
+// campaignProgress counts a campaign's nodes.  Responded nodes have answered or errored
+type campaignProgress struct {
+	CampaignID string                                  `json:"campaign_id"`
+	Targeted   int                                     `json:"targeted"`
+	Responded  int                                     `json:"responded"`
+	Errored    int                                     `json:"errored"`
+	TimedOut   int                                     `json:"timed_out"`
+	Counts     map[osquery_types.CampaignNodeState]int `json:"counts"`
+	// Done is set once no node is left to answer
+	Done bool `json:"done"`
+}
+
+// campaignStream is what a client streaming a campaign has been sent
+type campaignStream struct {
+	campaign osquery_types.Campaign
+	nodes    map[string]osquery_types.CampaignNode
+}
+
+func newCampaignStream(c osquery_types.Campaign, nodes []osquery_types.CampaignNode) *campaignStream {
+	s := &campaignStream{campaign: c, nodes: map[string]osquery_types.CampaignNode{}}
+	for _, cn := range nodes {
+		s.nodes[cn.NodeKey] = cn
+	}
+	return s
+}
+
+// update records the node, and returns true if it has changed since it was last seen
+func (s *campaignStream) update(cn osquery_types.CampaignNode) bool {
+	seen, ok := s.nodes[cn.NodeKey]
+	if ok && seen.State == cn.State && seen.UpdatedAt == cn.UpdatedAt {
+		return false
+	}
+	s.nodes[cn.NodeKey] = cn
+	return true
+}
+
+func (s *campaignStream) progress(now time.Time) campaignProgress {
+	nodes := []osquery_types.CampaignNode{}
+	for _, cn := range s.nodes {
+		nodes = append(nodes, cn)
+	}
+	status := osquery_types.NewCampaignStatus(s.campaign, nodes, now)
+	return campaignProgress{
+		CampaignID: s.campaign.CampaignID,
+		Targeted:   len(nodes),
+		Responded:  status.Counts[osquery_types.CampaignAnswered] + status.Counts[osquery_types.CampaignErrored],
+		Errored:    status.Counts[osquery_types.CampaignErrored],
+		TimedOut:   status.Counts[osquery_types.CampaignTimedOut],
+		Counts:     status.Counts,
+		Done:       status.Counts[osquery_types.CampaignQueued]+status.Counts[osquery_types.CampaignDelivered] == 0,
+	}
+}
+
+// writeEvent writes a server-sent event with the value as its JSON data
+func writeEvent(w http.ResponseWriter, event string, v interface{}) error {
+	js, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, js); err != nil {
+		return err
+	}
+	w.(http.Flusher).Flush()
+	return nil
+}
+
+// CampaignStreamHandler streams the campaign specified by {campaign_id} as server-sent events.  A
+// progress event is sent when the stream starts, whenever a node's state changes and after each poll,
+// and a result event with the node's rows and status as each node answers or errors.  Nodes that
+// answered before the stream started are sent first.  The stream ends with a done event once no node
+// is left to answer
+func CampaignStreamHandler(dyn CampaignDB, broker *CampaignBroker) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		campaignID := mux.Vars(r)["campaign_id"]
+		// subscribe before reading the nodes, so no answer falls between the two
+		updates, unsubscribe := broker.Subscribe(campaignID)
+		defer unsubscribe()
+
+		setup := func() (*campaignStream, error) {
+			if _, ok := w.(http.Flusher); !ok {
+				return nil, errors.New("streaming is not supported")
+			}
+			c, nodes, err := getCampaign(dyn, campaignID)
+			if err != nil {
+				return nil, err
+			}
+			return newCampaignStream(c, nodes), nil
+		}
+		stream, err := setup()
+		if err != nil {
+			logger.Error(err)
+			response.WriteError(w, fmt.Sprintf("[CampaignStreamHandler] %s", err))
+			return
+		}
+
+		handleStream := func() error {
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Header().Set("Cache-Control", "no-cache")
+			w.Header().Set("X-Accel-Buffering", "no")
+			for _, cn := range stream.nodes {
+				if cn.State == osquery_types.CampaignAnswered || cn.State == osquery_types.CampaignErrored {
+					if err := writeEvent(w, "result", cn.Public()); err != nil {
+						return err
+					}
+				}
+			}
+
+			ticker := time.NewTicker(CampaignStreamPollInterval)
+			defer ticker.Stop()
+			for {
+				progress := stream.progress(time.Now())
+				if err := writeEvent(w, "progress", progress); err != nil {
+					return err
+				}
+				if progress.Done {
+					return writeEvent(w, "done", progress)
+				}
+
+				// wait for a node to change, or for the next poll, which sends the progress anyway as
+				// nodes time out without changing
+				changed := []osquery_types.CampaignNode{}
+				for polled := false; len(changed) == 0 && !polled; {
+					select {
+					case <-r.Context().Done():
+						return nil
+					case cn := <-updates:
+						if stream.update(cn) {
+							changed = append(changed, cn)
+						}
+					case <-ticker.C:
+						polled = true
+						nodes, err := dyn.GetCampaignNodes(campaignID)
+						if err != nil {
+							return fmt.Errorf("could not get nodes for campaign [%s]: %s", campaignID, err)
+						}
+						for _, cn := range nodes {
+							if stream.update(cn) {
+								changed = append(changed, cn)
+							}
+						}
+					}
+				}
+				for _, cn := range changed {
+					if cn.State == osquery_types.CampaignAnswered || cn.State == osquery_types.CampaignErrored {
+						if err := writeEvent(w, "result", cn.Public()); err != nil {
+							return err
+						}
+					}
+				}
+			}
+		}
+		if err := handleStream(); err != nil {
+			logger.Error(fmt.Sprintf("[CampaignStreamHandler] campaign [%s]: %s", campaignID, err))
+		}
+	})
+}
@@ -0,0 +1,130 @@
+package distributed
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/oktasecuritylabs/sgt/handlers/helpers"
+	"github.com/oktasecuritylabs/sgt/osquery_types"
+)
+
+// liveCampaignDB has a campaign that has just started, with one node waiting to answer
+type liveCampaignDB struct {
+	*helpers.MockDB
+	campaign osquery_types.Campaign
+	node     osquery_types.CampaignNode
+}
+
+func (db *liveCampaignDB) GetCampaign(campaignID string) (osquery_types.Campaign, error) {
+	return db.campaign, nil
+}
+
+func (db *liveCampaignDB) GetCampaignNodes(campaignID string) ([]osquery_types.CampaignNode, error) {
+	return []osquery_types.CampaignNode{db.node}, nil
+}
+
+type sseEvent struct {
+	event string
+	data  string
+}
+
+func readEvent(t *testing.T, r *bufio.Reader) sseEvent {
+	e := sseEvent{}
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("stream ended: %s", err)
+		}
+		line = strings.TrimRight(line, "\n")
+		switch {
+		case line == "" && e.event != "":
+			return e
+		case strings.HasPrefix(line, "event: "):
+			e.event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			e.data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+func TestCampaignStreamHandler(t *testing.T) {
+	db := &liveCampaignDB{
+		MockDB: helpers.NewMockDB(),
+		campaign: osquery_types.Campaign{
+			CampaignID: "c1",
+			Query:      "select * from users;",
+			CreatedAt:  time.Now().UTC().Format(time.RFC3339),
+			Timeout:    3600,
+		},
+		node: osquery_types.CampaignNode{CampaignID: "c1", NodeKey: "n1", HostIdentifier: "host1", State: osquery_types.CampaignDelivered},
+	}
+	broker := NewCampaignBroker()
+	router := mux.NewRouter()
+	router.Handle("/campaigns/{campaign_id}/stream", CampaignStreamHandler(db, broker))
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/campaigns/c1/stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("got content type %s", ct)
+	}
+	r := bufio.NewReader(resp.Body)
+
+	progress := campaignProgress{}
+	e := readEvent(t, r)
+	if err := json.Unmarshal([]byte(e.data), &progress); err != nil || e.event != "progress" {
+		t.Fatalf("got %+v %v", e, err)
+	}
+	if progress.Targeted != 1 || progress.Responded != 0 || progress.Done {
+		t.Errorf("got %+v", progress)
+	}
+
+	answered := db.node
+	answered.SetRows([]map[string]string{{"username": "root"}}, osquery_types.MaxCampaignResultBytes)
+	answered.Status = 1
+	answered.Message = "no such table: userz"
+	answered.SetState(osquery_types.CampaignErrored, time.Now())
+	broker.Publish(answered)
+
+	if e := readEvent(t, r); e.event != "result" || !strings.Contains(e.data, "no such table: userz") ||
+		strings.Contains(e.data, "node_key") {
+		t.Errorf("expected the node's result without its node key, got %+v", e)
+	}
+	e = readEvent(t, r)
+	if err := json.Unmarshal([]byte(e.data), &progress); err != nil || e.event != "progress" {
+		t.Fatalf("got %+v %v", e, err)
+	}
+	if progress.Responded != 1 || progress.Errored != 1 || !progress.Done {
+		t.Errorf("got %+v", progress)
+	}
+	if e := readEvent(t, r); e.event != "done" {
+		t.Errorf("expected the stream to end, got %+v", e)
+	}
+}
+
+func TestCampaignBroker(t *testing.T) {
+	broker := NewCampaignBroker()
+	updates, unsubscribe := broker.Subscribe("c1")
+	broker.Publish(osquery_types.CampaignNode{CampaignID: "c2", NodeKey: "n1"})
+	broker.Publish(osquery_types.CampaignNode{CampaignID: "c1", NodeKey: "n1"})
+	if cn := <-updates; cn.CampaignID != "c1" {
+		t.Errorf("expected only the subscribed campaign, got %+v", cn)
+	}
+	unsubscribe()
+	if len(broker.subs) != 0 {
+		t.Errorf("expected no subscribers left, got %v", broker.subs)
+	}
+
+	var nilBroker *CampaignBroker
+	nilBroker.Publish(osquery_types.CampaignNode{CampaignID: "c1"})
+}
@@ -0,0 +1,114 @@
+package distributed
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/oktasecuritylabs/sgt/logger"
+	"github.com/oktasecuritylabs/sgt/osquery_types"
+)
+
+// SweepInterval is how often the server gives up on distributed queries that nodes haven't answered.
+// Queries are also given up on when the node next reads its queries, the sweep covers nodes that have
+// gone offline
+const SweepInterval = 5 * time.Minute
+
+// sweeperLease is the lease held by the server sweeping distributed queries
+const sweeperLease = "distributed_sweeper"
+
+// SweeperDB is the storage used to sweep distributed queries
+type SweeperDB interface {
+	DistributedDB
+	AcquireLease(name, owner string, ttl time.Duration, now time.Time) (bool, error)
+}
+
+// StartSweeper sweeps the distributed queries of every node each interval, in the background.  Like the
+// scheduler, only the server holding the sweeper lease sweeps
+func StartSweeper(dyn SweeperDB, policy osquery_types.DeliveryPolicy, interval time.Duration) {
+	owner := leaseOwner()
+	go func() {
+		for now := range time.Tick(interval) {
+			leader, err := dyn.AcquireLease(sweeperLease, owner, 3*interval, now)
+			if err != nil {
+				logger.Error(err)
+				continue
+			}
+			if !leader {
+				continue
+			}
+			expired, err := SweepDistributedQueries(dyn, policy, now)
+			if err != nil {
+				logger.Error(err)
+			}
+			if expired > 0 {
+				logger.Info(fmt.Sprintf("gave up on %d distributed queries", expired))
+			}
+		}
+	}()
+}
+
+// SweepDistributedQueries gives up on the queries of every node that have passed their TTL or been
+// sent too many times, records their outcomes and returns how many there were.  Nodes whose queries
+// have expired are read again when their queries are saved, so queries added or answered since the
+// scan aren't lost
+func SweepDistributedQueries(dyn DistributedDB, policy osquery_types.DeliveryPolicy, now time.Time) (int, error) {
+	queries, err := dyn.GetDistributedQueries()
+	if err != nil {
+		return 0, fmt.Errorf("could not get distributed queries: %s", err)
+	}
+	count := 0
+	for _, dq := range queries {
+		if len(dq.Expire(now, policy)) == 0 {
+			continue
+		}
+		var expired []osquery_types.ExpiredQuery
+		err := dyn.UpdateDistributedQuery(dq.NodeKey, func(dq *osquery_types.DistributedQuery) bool {
+			expired = dq.Expire(now, policy)
+			return len(expired) > 0
+		})
+		if err != nil {
+			return count, fmt.Errorf("could not save queries for node [%s]: %s", dq.NodeKey, err)
+		}
+		recordExpiredQueries(dyn, dq.NodeKey, expired, now)
+		count += len(expired)
+	}
+	return count, nil
+}
+
+// recordExpiredQueries records the outcome of each query that was given up on, and times the node out
+// of campaigns it hadn't answered.  The queries have already been removed, so errors are only logged
+func recordExpiredQueries(dyn DistributedDB, nodeKey string, expired []osquery_types.ExpiredQuery, now time.Time) {
+	if len(expired) == 0 {
+		return
+	}
+	client, err := dyn.SearchByNodeKey(nodeKey)
+	if err != nil {
+		logger.Error(err)
+	}
+	if client.NodeKey == "" {
+		client.NodeKey = nodeKey
+	}
+	for _, eq := range expired {
+		ds := osquery_types.NewExpiredStatus(client, eq, now)
+		logger.Warn(fmt.Sprintf("distributed query [%s] for node [%s] %s: %s", ds.Name, ds.HostIdentifier, ds.Outcome, ds.Message))
+		if err := dyn.NewDistributedStatus(ds); err != nil {
+			logger.Error(err)
+		}
+		if eq.CampaignID == "" {
+			continue
+		}
+		cn, err := dyn.GetCampaignNode(eq.CampaignID, nodeKey)
+		if err != nil {
+			logger.Error(err)
+			continue
+		}
+		if cn.State != osquery_types.CampaignQueued && cn.State != osquery_types.CampaignDelivered {
+			continue
+		}
+		cn.SetState(osquery_types.CampaignTimedOut, now)
+		cn.Message = eq.Reason
+		if err := dyn.UpsertCampaignNode(cn); err != nil {
+			logger.Error(err)
+		}
+	}
+}
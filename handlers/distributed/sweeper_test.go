@@ -0,0 +1,53 @@
+package distributed
+
+import (
+	"testing"
+	"time"
+
+	"github.com/oktasecuritylabs/sgt/handlers/helpers"
+	"github.com/oktasecuritylabs/sgt/osquery_types"
+)
+
+// queueDB keeps one node's distributed queries, and returns scanned as the result of a scan
+type queueDB struct {
+	*helpers.MockDB
+	stored  osquery_types.DistributedQuery
+	scanned osquery_types.DistributedQuery
+}
+
+func (db *queueDB) GetDistributedQueries() ([]osquery_types.DistributedQuery, error) {
+	return []osquery_types.DistributedQuery{db.scanned}, nil
+}
+
+func (db *queueDB) UpdateDistributedQuery(nodeKey string, update func(dq *osquery_types.DistributedQuery) bool) error {
+	dq := db.stored
+	dq.Pending = append([]osquery_types.PendingQuery(nil), dq.Pending...)
+	if update(&dq) {
+		dq.Revision++
+		db.stored = dq
+	}
+	return nil
+}
+
+func TestSweepDistributedQueries(t *testing.T) {
+	policy := osquery_types.DeliveryPolicy{RedeliverAfter: time.Minute, MaxDeliveries: 3, TTL: time.Hour}
+	start := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	old := osquery_types.PendingQuery{Name: "id1", QueuedAt: start.Format(time.RFC3339)}
+	added := osquery_types.PendingQuery{Name: "id2", QueuedAt: start.Add(time.Hour).Format(time.RFC3339)}
+	// a query was added to the node after the scan
+	db := &queueDB{
+		MockDB:  helpers.NewMockDB(),
+		scanned: osquery_types.DistributedQuery{NodeKey: "3lkjsdf0jdfoiasdjf", Pending: []osquery_types.PendingQuery{old}, Revision: 1},
+		stored:  osquery_types.DistributedQuery{NodeKey: "3lkjsdf0jdfoiasdjf", Pending: []osquery_types.PendingQuery{old, added}, Revision: 2},
+	}
+	count, err := SweepDistributedQueries(db, policy, start.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("expected 1 query given up on, got %d", count)
+	}
+	if len(db.stored.Pending) != 1 || db.stored.Pending[0].Name != "id2" || db.stored.Revision != 3 {
+		t.Errorf("expected the added query kept, got %+v", db.stored)
+	}
+}
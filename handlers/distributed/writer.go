@@ -0,0 +1,341 @@
+package distributed
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/credentials/ec2rolecreds"
+	"github.com/aws/aws-sdk-go/aws/ec2metadata"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/firehose"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/oktasecuritylabs/sgt/logger"
+	"github.com/oktasecuritylabs/sgt/osquery_types"
+)
+
+// ResultWriter sends the results and statuses nodes write for distributed queries to where they are
+// logged
+type ResultWriter interface {
+	Write(results []osquery_types.DistributedQueryResult) error
+}
+
+// The distributed query loggers that can be listed in the server config's distributed_query_logger
+const (
+	LoggerFirehose   = "firehose"
+	LoggerS3         = "s3"
+	LoggerFilesystem = "filesystem"
+	LoggerStdout     = "stdout"
+)
+
+// DistributedResultsFile is the name of the file the filesystem logger writes to, in the configured
+// directory
+const DistributedResultsFile = "distributed_results.log"
+
+// NewResultWriter returns a writer for every logger listed in the server config, which writes results
+// to each of them.  Blank entries are ignored, and firehose is used when none are listed
+func NewResultWriter(config *osquery_types.ServerConfig) (ResultWriter, error) {
+	names := []string{}
+	for _, name := range config.DistributedQueryLogger {
+		if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		names = []string{LoggerFirehose}
+	}
+
+	writers := MultiWriter{}
+	for _, name := range names {
+		switch name {
+		case LoggerFirehose:
+			if config.DistributedQueryLoggerFirehoseStreamName == "" {
+				return nil, fmt.Errorf("the firehose logger needs distributed_query_logger_firehose_stream_name")
+			}
+			writers = append(writers, &FirehoseWriter{
+				StreamName: config.DistributedQueryLoggerFirehoseStreamName,
+				Svc:        FirehoseService(),
+			})
+		case LoggerS3:
+			if config.DistributedQueryLoggerS3BucketName == "" {
+				return nil, fmt.Errorf("the s3 logger needs distributed_query_logger_s3_bucket_name")
+			}
+			w := NewS3Writer(S3Service(), config.DistributedQueryLoggerS3BucketName)
+			w.Start(S3FlushInterval)
+			writers = append(writers, w)
+		// the setting was misspelled filesytem, which is still accepted
+		case LoggerFilesystem, "filesytem":
+			path := config.DistributedQueryLoggerPath()
+			if path == "" {
+				return nil, fmt.Errorf("the filesystem logger needs distributed_query_logger_filesystem_path")
+			}
+			w, err := NewFileWriter(filepath.Join(path, DistributedResultsFile), DefaultFileMaxBytes, DefaultFileBackups)
+			if err != nil {
+				return nil, err
+			}
+			writers = append(writers, w)
+		case LoggerStdout:
+			writers = append(writers, NewStreamWriter(os.Stdout))
+		default:
+			return nil, fmt.Errorf("unknown distributed query logger %q, expected one of %s, %s, %s or %s",
+				name, LoggerFirehose, LoggerS3, LoggerFilesystem, LoggerStdout)
+		}
+	}
+	if len(writers) == 1 {
+		return writers[0], nil
+	}
+	return writers, nil
+}
+
+// MultiWriter writes results to every writer.  A writer that fails doesn't stop the results being
+// written to the others
+type MultiWriter []ResultWriter
+
+func (mw MultiWriter) Write(results []osquery_types.DistributedQueryResult) error {
+	failed := []string{}
+	for _, w := range mw {
+		if err := w.Write(results); err != nil {
+			failed = append(failed, err.Error())
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("could not write results: %s", strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// writeNDJSON writes each result as a line of JSON
+func writeNDJSON(w io.Writer, results []osquery_types.DistributedQueryResult) error {
+	enc := json.NewEncoder(w)
+	for _, r := range results {
+		if err := enc.Encode(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FirehoseWriter puts results to a Firehose delivery stream
+type FirehoseWriter struct {
+	StreamName string
+	Svc        *firehose.Firehose
+}
+
+func (fw *FirehoseWriter) Write(results []osquery_types.DistributedQueryResult) error {
+	if len(results) == 0 {
+		return nil
+	}
+	return PutFirehoseBatch(results, fw.StreamName, fw.Svc)
+}
+
+// StreamWriter writes results to a stream, such as stdout, a line of JSON each
+type StreamWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewStreamWriter returns a writer to w
+func NewStreamWriter(w io.Writer) *StreamWriter {
+	return &StreamWriter{w: w}
+}
+
+func (sw *StreamWriter) Write(results []osquery_types.DistributedQueryResult) error {
+	buf := &bytes.Buffer{}
+	if err := writeNDJSON(buf, results); err != nil {
+		return err
+	}
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	_, err := sw.w.Write(buf.Bytes())
+	return err
+}
+
+// The size a results file grows to before it is rotated, and how many rotated files are kept
+const (
+	DefaultFileMaxBytes = 100 * 1024 * 1024
+	DefaultFileBackups  = 5
+)
+
+// FileWriter appends results to a file, a line of JSON each.  When the file passes MaxBytes it is
+// renamed with the suffix .1, older files moving up to .2 and so on, and files past Backups are removed
+type FileWriter struct {
+	mu       sync.Mutex
+	path     string
+	maxBytes int64
+	backups  int
+	file     *os.File
+	size     int64
+}
+
+// NewFileWriter opens the file at path for appending, creating it and its directory if needed
+func NewFileWriter(path string, maxBytes int64, backups int) (*FileWriter, error) {
+	fw := &FileWriter{path: path, maxBytes: maxBytes, backups: backups}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("could not create results directory: %s", err)
+	}
+	if err := fw.open(); err != nil {
+		return nil, err
+	}
+	return fw, nil
+}
+
+func (fw *FileWriter) open() error {
+	f, err := os.OpenFile(fw.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0640)
+	if err != nil {
+		return fmt.Errorf("could not open results file: %s", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("could not open results file: %s", err)
+	}
+	fw.file = f
+	fw.size = info.Size()
+	return nil
+}
+
+func (fw *FileWriter) rotate() error {
+	if err := fw.file.Close(); err != nil {
+		return err
+	}
+	os.Remove(fmt.Sprintf("%s.%d", fw.path, fw.backups))
+	for i := fw.backups - 1; i >= 1; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", fw.path, i), fmt.Sprintf("%s.%d", fw.path, i+1))
+	}
+	if fw.backups > 0 {
+		if err := os.Rename(fw.path, fw.path+".1"); err != nil {
+			return err
+		}
+	} else if err := os.Remove(fw.path); err != nil {
+		return err
+	}
+	return fw.open()
+}
+
+func (fw *FileWriter) Write(results []osquery_types.DistributedQueryResult) error {
+	buf := &bytes.Buffer{}
+	if err := writeNDJSON(buf, results); err != nil {
+		return err
+	}
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+	if fw.size > 0 && fw.size+int64(buf.Len()) > fw.maxBytes {
+		if err := fw.rotate(); err != nil {
+			return fmt.Errorf("could not rotate results file: %s", err)
+		}
+	}
+	n, err := fw.file.Write(buf.Bytes())
+	fw.size += int64(n)
+	return err
+}
+
+// Close closes the file
+func (fw *FileWriter) Close() error {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+	return fw.file.Close()
+}
+
+// S3 objects are written once this many bytes of results are buffered, or every S3FlushInterval
+const (
+	S3MaxObjectBytes = 5 * 1024 * 1024
+	S3FlushInterval  = time.Minute
+)
+
+// S3Writer buffers results and writes them to S3 in batches, as objects of a line of JSON per result
+// keyed distributed/<year>/<month>/<day>/<hour>/<time>-<random>.ndjson.  Buffered results are lost if
+// the server stops before they are flushed
+type S3Writer struct {
+	mu       sync.Mutex
+	svc      s3iface.S3API
+	bucket   string
+	buf      bytes.Buffer
+	maxBytes int
+}
+
+// NewS3Writer returns a writer to the bucket.  Call Start to flush it periodically
+func NewS3Writer(svc s3iface.S3API, bucket string) *S3Writer {
+	return &S3Writer{svc: svc, bucket: bucket, maxBytes: S3MaxObjectBytes}
+}
+
+func (s *S3Writer) Write(results []osquery_types.DistributedQueryResult) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := writeNDJSON(&s.buf, results); err != nil {
+		return err
+	}
+	if s.buf.Len() >= s.maxBytes {
+		return s.flush(time.Now())
+	}
+	return nil
+}
+
+// Flush writes the buffered results to S3
+func (s *S3Writer) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.flush(time.Now())
+}
+
+func (s *S3Writer) flush(now time.Time) error {
+	if s.buf.Len() == 0 {
+		return nil
+	}
+	b := make([]byte, 4)
+	rand.Read(b)
+	now = now.UTC()
+	key := fmt.Sprintf("distributed/%s/%s-%s.ndjson", now.Format("2006/01/02/15"),
+		now.Format("20060102T150405.000000000Z"), hex.EncodeToString(b))
+	_, err := s.svc.PutObject(&s3.PutObjectInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(s.buf.Bytes()),
+		ContentType: aws.String("application/x-ndjson"),
+	})
+	if err != nil {
+		return fmt.Errorf("could not write results to s3://%s/%s: %s", s.bucket, key, err)
+	}
+	s.buf.Reset()
+	return nil
+}
+
+// Start flushes the writer each interval, in the background
+func (s *S3Writer) Start(interval time.Duration) {
+	go func() {
+		for range time.Tick(interval) {
+			if err := s.Flush(); err != nil {
+				logger.Error(err)
+			}
+		}
+	}()
+}
+
+// S3Service returns an S3 client with credentials from the environment or the instance role
+func S3Service() *s3.S3 {
+	sess := session.Must(session.NewSession(
+		&aws.Config{
+			Region: aws.String("us-east-1"),
+		}))
+	creds := credentials.NewChainCredentials(
+		[]credentials.Provider{
+			&credentials.EnvProvider{},
+			&ec2rolecreds.EC2RoleProvider{
+				Client: ec2metadata.New(sess),
+			},
+		})
+	return s3.New(session.Must(session.NewSession(&aws.Config{
+		Region:      aws.String("us-east-1"),
+		Credentials: creds,
+	})))
+}
@@ -0,0 +1,133 @@
+package distributed
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/oktasecuritylabs/sgt/osquery_types"
+)
+
+var testResults = []osquery_types.DistributedQueryResult{
+	{Name: "id1", LogType: "result", HostIdentifier: "host1", Columns: map[string]string{"user": "root"}},
+	{Name: "id1", LogType: "result", HostIdentifier: "host1", Columns: map[string]string{"user": "admin"}},
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(results []osquery_types.DistributedQueryResult) error {
+	return errors.New("unavailable")
+}
+
+func TestMultiWriter(t *testing.T) {
+	buf := &bytes.Buffer{}
+	mw := MultiWriter{failingWriter{}, NewStreamWriter(buf)}
+	if err := mw.Write(testResults); err == nil || !strings.Contains(err.Error(), "unavailable") {
+		t.Errorf("expected the failing writer's error, got %v", err)
+	}
+	if lines := strings.Split(strings.TrimSpace(buf.String()), "\n"); len(lines) != 2 {
+		t.Errorf("expected the results written after a writer failed, got %q", buf.String())
+	}
+}
+
+func TestNewResultWriter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sgt-results")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	config := &osquery_types.ServerConfig{
+		DistributedQueryLogger:              []string{"stdout", "filesytem", ""},
+		DistributedQueryLoggerFilesytemPath: dir,
+	}
+	w, err := NewResultWriter(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mw, ok := w.(MultiWriter); !ok || len(mw) != 2 {
+		t.Errorf("expected a stdout and a filesystem writer, got %#v", w)
+	}
+
+	config.DistributedQueryLogger = []string{"kafka"}
+	if _, err := NewResultWriter(config); err == nil {
+		t.Error("expected an error for an unknown logger")
+	}
+	config.DistributedQueryLogger = []string{"s3"}
+	if _, err := NewResultWriter(config); err == nil {
+		t.Error("expected an error for the s3 logger without a bucket")
+	}
+}
+
+func TestFileWriter_Rotate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sgt-results")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "results", DistributedResultsFile)
+	fw, err := NewFileWriter(path, 100, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fw.Close()
+	for i := 0; i < 3; i++ {
+		if err := fw.Write(testResults); err != nil {
+			t.Fatal(err)
+		}
+	}
+	current, _ := ioutil.ReadFile(path)
+	rotated, _ := ioutil.ReadFile(path + ".1")
+	if len(current) == 0 || len(rotated) == 0 {
+		t.Errorf("expected the file rotated, got %q and %q", current, rotated)
+	}
+	if _, err := os.Stat(path + ".2"); !os.IsNotExist(err) {
+		t.Errorf("expected only one rotated file kept, got %v", err)
+	}
+}
+
+type recordingS3 struct {
+	s3iface.S3API
+	objects map[string]string
+}
+
+func (r *recordingS3) PutObject(in *s3.PutObjectInput) (*s3.PutObjectOutput, error) {
+	body, _ := ioutil.ReadAll(in.Body)
+	r.objects[*in.Key] = string(body)
+	return &s3.PutObjectOutput{}, nil
+}
+
+func TestS3Writer(t *testing.T) {
+	svc := &recordingS3{objects: map[string]string{}}
+	w := NewS3Writer(svc, "results")
+	if err := w.Write(testResults); err != nil {
+		t.Fatal(err)
+	}
+	if len(svc.objects) != 0 {
+		t.Errorf("expected results buffered until flushed, got %v", svc.objects)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if len(svc.objects) != 1 {
+		t.Fatalf("expected one object, got %v", svc.objects)
+	}
+	for key, body := range svc.objects {
+		if !strings.HasPrefix(key, "distributed/") || !strings.HasSuffix(key, ".ndjson") {
+			t.Errorf("got key %s", key)
+		}
+		if strings.Count(body, "\n") != 2 {
+			t.Errorf("expected a line per result, got %q", body)
+		}
+	}
+	if err := w.Flush(); err != nil || len(svc.objects) != 1 {
+		t.Errorf("expected nothing written with nothing buffered, got %v %v", svc.objects, err)
+	}
+}
@@ -2,6 +2,8 @@ package helpers
 
 import (
 	"encoding/json"
+	"time"
+
 	"github.com/oktasecuritylabs/sgt/osquery_types"
 )
 
@@ -16,23 +18,23 @@ func NewMockDB() *MockDB {
 var testPackQuery1 = osquery_types.PackQuery{
 	QueryName:   "test1",
 	Query:       "select * from users;",
-	Interval:    "60",
+	Interval:    60,
 	Version:     "1.1.1.1",
 	Description: "test1 description",
 	Value:       "some value",
 }
 var testPackQuery2 = osquery_types.PackQuery{
-	"test2",
-	"select * from installed_packages",
-	"60",
-	"1.1.1",
-	"test2 description",
-	"some value",
-	"true",
+	QueryName:   "test2",
+	Query:       "select * from installed_packages",
+	Interval:    60,
+	Version:     "1.1.1",
+	Description: "test2 description",
+	Value:       "some value",
+	Snapshot:    osquery_types.Bool(true),
 }
 var testQueryPack1 = osquery_types.QueryPack{
-	"test-pack",
-	[]string{"select * from users"},
+	PackName: "test-pack",
+	Queries:  []string{"select * from users"},
 }
 var testUser1 = osquery_types.User{
 	"testuser1",
@@ -40,21 +42,59 @@ var testUser1 = osquery_types.User{
 	"user",
 }
 var testClient1 = osquery_types.OsqueryClient{
-	"host1",
-	"3lkjsdf0jdfoiasdjf",
-	false,
-	"testhost",
-	map[string]map[string]string{},
-	false,
-	[]string{"a", "b"},
-	"default",
-	"default",
-	"erlkjer",
+	HostIdentifier:     "host1",
+	NodeKey:            "3lkjsdf0jdfoiasdjf",
+	HostName:           "testhost",
+	HostDetails:        map[string]map[string]string{},
+	Tags:               []string{"a", "b"},
+	ConfigurationGroup: "default",
+	ConfigName:         "default",
+	LastUpdated:        "erlkjer",
+}
+var testCampaign = osquery_types.Campaign{
+	CampaignID: "5f0c6e1a9d2b4c7e8a1f3b6d",
+	Query:      "select * from users;",
+	Targets:    osquery_types.CampaignTargets{Tags: []string{"a"}},
+	CreatedAt:  "2019-01-01T00:00:00Z",
+	Timeout:    3600,
+}
+var testCampaignNode1 = osquery_types.CampaignNode{
+	CampaignID:     testCampaign.CampaignID,
+	NodeKey:        testClient1.NodeKey,
+	HostIdentifier: testClient1.HostIdentifier,
+	State:          osquery_types.CampaignAnswered,
+	UpdatedAt:      "2019-01-01T00:01:00Z",
+	RowCount:       1,
+	Rows:           []map[string]string{{"username": "root"}},
+}
+var testDistributedStatus1 = osquery_types.DistributedStatus{
+	NodeKey:        testClient1.NodeKey,
+	StatusID:       "2019-01-01T00:02:00Z id1",
+	HostIdentifier: testClient1.HostIdentifier,
+	Name:           "id1",
+	Status:         1,
+	Message:        "no such table: userz",
+	ReceivedAt:     "2019-01-01T00:02:00Z",
+}
+var testDistributedStatus2 = osquery_types.DistributedStatus{
+	NodeKey:        testClient1.NodeKey,
+	StatusID:       "2019-01-01T00:01:00Z " + testCampaign.CampaignID,
+	HostIdentifier: testClient1.HostIdentifier,
+	Name:           testCampaign.CampaignID,
+	CampaignID:     testCampaign.CampaignID,
+	RowCount:       1,
+	ReceivedAt:     "2019-01-01T00:01:00Z",
 }
 var testDistributedQuery = osquery_types.DistributedQuery{
-	"dlfkjadflikjerkj",
-	[]string{"select * from users;"},
-	false,
+	NodeKey:     "dlfkjadflikjerkj",
+	Queries:     []string{"select * from users;"},
+	NodeInvalid: false,
+	Pending: []osquery_types.PendingQuery{{
+		Name:       testCampaign.CampaignID,
+		Query:      testCampaign.Query,
+		CampaignID: testCampaign.CampaignID,
+		QueuedAt:   time.Now().UTC().Format(time.RFC3339),
+	}},
 }
 
 func (m MockDB) GetNamedConfigs() ([]osquery_types.OsqueryNamedConfig, error) {
@@ -105,18 +145,30 @@ func (m MockDB) DeleteDistributedQuery(dq osquery_types.DistributedQuery) error
 	return nil
 }
 
+func (m MockDB) DeleteNamedConfig(configName string) error {
+	return nil
+}
+
+func (m MockDB) DeletePackQuery(queryName string) error {
+	return nil
+}
+
 func (m MockDB) DeleteQueryPack(queryPackName string) error {
 	return nil
 }
 
 func (m MockDB) GetPackByName(packName string) (osquery_types.Pack, error) {
 	p := osquery_types.Pack{
-		"pack1",
-		[]osquery_types.PackQuery{testPackQuery1},
+		PackName: "pack1",
+		Queries:  []osquery_types.PackQuery{testPackQuery1},
 	}
 	return p, nil
 }
 
+func (m MockDB) GetQueryPack(packName string) (osquery_types.QueryPack, error) {
+	return testQueryPack1, nil
+}
+
 func (m MockDB) GetPackQuery(queryName string) (osquery_types.PackQuery, error) {
 	return testPackQuery1, nil
 }
@@ -161,6 +213,13 @@ func (m MockDB) UpsertDistributedQuery(dq osquery_types.DistributedQuery) error
 	return nil
 }
 
+func (m MockDB) UpdateDistributedQuery(nodeKey string, update func(dq *osquery_types.DistributedQuery) bool) error {
+	dq := testDistributedQuery
+	dq.Pending = append([]osquery_types.PendingQuery(nil), dq.Pending...)
+	update(&dq)
+	return nil
+}
+
 func (m MockDB) UpsertPackQuery(pq osquery_types.PackQuery) error {
 	return nil
 }
@@ -177,7 +236,7 @@ func (m MockDB) BuildOsqueryPackAsJSON(nc osquery_types.OsqueryNamedConfig) json
 	return json.RawMessage{}
 }
 
-func (m MockDB) BuildNamedConfig(configName string) (osquery_types.OsqueryNamedConfig, error) {
+func (m MockDB) BuildNamedConfig(configName string, target osquery_types.RenderTarget, now time.Time) (osquery_types.OsqueryNamedConfig, error) {
 	return osquery_types.OsqueryNamedConfig{}, nil
 }
 
@@ -196,3 +255,127 @@ func (m MockDB) CarveDataExists(data *osquery_types.CarveData) (bool, error) {
 func (m MockDB) DeleteNodeByNodekey(nodeKey string) error {
 	return nil
 }
+
+func (m MockDB) NewCampaign(c osquery_types.Campaign, nodes []osquery_types.CampaignNode) error {
+	return nil
+}
+
+func (m MockDB) GetCampaign(campaignID string) (osquery_types.Campaign, error) {
+	if campaignID != testCampaign.CampaignID {
+		return osquery_types.Campaign{}, nil
+	}
+	return testCampaign, nil
+}
+
+func (m MockDB) GetCampaigns() ([]osquery_types.Campaign, error) {
+	return []osquery_types.Campaign{testCampaign}, nil
+}
+
+func (m MockDB) GetCampaignNode(campaignID, nodeKey string) (osquery_types.CampaignNode, error) {
+	if campaignID != testCampaign.CampaignID || nodeKey != testClient1.NodeKey {
+		return osquery_types.CampaignNode{}, nil
+	}
+	return testCampaignNode1, nil
+}
+
+func (m MockDB) GetCampaignNodes(campaignID string) ([]osquery_types.CampaignNode, error) {
+	if campaignID != testCampaign.CampaignID {
+		return []osquery_types.CampaignNode{}, nil
+	}
+	return []osquery_types.CampaignNode{testCampaignNode1}, nil
+}
+
+func (m MockDB) UpsertCampaignNode(cn osquery_types.CampaignNode) error {
+	return nil
+}
+
+func (m MockDB) NewDistributedStatus(ds osquery_types.DistributedStatus) error {
+	return nil
+}
+
+func (m MockDB) GetDistributedStatuses(nodeKey string) ([]osquery_types.DistributedStatus, error) {
+	if nodeKey != testClient1.NodeKey {
+		return []osquery_types.DistributedStatus{}, nil
+	}
+	return []osquery_types.DistributedStatus{testDistributedStatus1, testDistributedStatus2}, nil
+}
+
+func (m MockDB) GetDistributedQueries() ([]osquery_types.DistributedQuery, error) {
+	return []osquery_types.DistributedQuery{testDistributedQuery}, nil
+}
+
+var testDistributedSchedule = osquery_types.DistributedSchedule{
+	ScheduleID: "7a1c2e3f4b5d6e7f8a9b0c1d",
+	Name:       "hunt",
+	Query:      testCampaign.Query,
+	Targets:    osquery_types.CampaignTargets{Tags: []string{"a"}},
+	Cron:       "0 */4 * * *",
+	Timeout:    600,
+	CreatedAt:  "2019-01-01T00:00:00Z",
+	EndsAt:     "2019-02-01T00:00:00Z",
+	NextRunAt:  "2019-01-01T04:00:00Z",
+}
+
+func (m MockDB) UpsertDistributedSchedule(ds osquery_types.DistributedSchedule) error {
+	return nil
+}
+
+func (m MockDB) GetDistributedSchedule(scheduleID string) (osquery_types.DistributedSchedule, error) {
+	if scheduleID != testDistributedSchedule.ScheduleID {
+		return osquery_types.DistributedSchedule{}, nil
+	}
+	return testDistributedSchedule, nil
+}
+
+func (m MockDB) GetDistributedSchedules() ([]osquery_types.DistributedSchedule, error) {
+	return []osquery_types.DistributedSchedule{testDistributedSchedule}, nil
+}
+
+func (m MockDB) DeleteDistributedSchedule(scheduleID string) error {
+	return nil
+}
+
+func (m MockDB) NewDistributedScheduleRun(run osquery_types.DistributedScheduleRun) error {
+	return nil
+}
+
+func (m MockDB) GetDistributedScheduleRuns(scheduleID string) ([]osquery_types.DistributedScheduleRun, error) {
+	return []osquery_types.DistributedScheduleRun{}, nil
+}
+
+func (m MockDB) AcquireLease(name, owner string, ttl time.Duration, now time.Time) (bool, error) {
+	return true, nil
+}
+
+var testSavedQuery = osquery_types.SavedQuery{
+	Name:        "file_hash",
+	Description: "files under a directory with a sha256",
+	Author:      "analyst",
+	Tags:        []string{"ir"},
+	Query:       "SELECT path FROM hash WHERE directory = {{directory}} AND sha256 = {{sha256}};",
+	Params: []osquery_types.SavedQueryParam{
+		{Name: "directory", Type: osquery_types.ParamPath, Default: "/tmp"},
+		{Name: "sha256", Type: osquery_types.ParamSHA256},
+	},
+	CreatedAt: "2019-01-01T00:00:00Z",
+	UpdatedAt: "2019-01-01T00:00:00Z",
+}
+
+func (m MockDB) UpsertSavedQuery(sq osquery_types.SavedQuery) error {
+	return nil
+}
+
+func (m MockDB) GetSavedQuery(name string) (osquery_types.SavedQuery, error) {
+	if name != testSavedQuery.Name {
+		return osquery_types.SavedQuery{}, nil
+	}
+	return testSavedQuery, nil
+}
+
+func (m MockDB) GetSavedQueries() ([]osquery_types.SavedQuery, error) {
+	return []osquery_types.SavedQuery{testSavedQuery}, nil
+}
+
+func (m MockDB) DeleteSavedQuery(name string) error {
+	return nil
+}
@@ -8,6 +8,7 @@ import (
 	"math/rand"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/oktasecuritylabs/sgt/handlers/auth"
 	"github.com/oktasecuritylabs/sgt/handlers/response"
@@ -24,7 +25,8 @@ type NodeDB interface {
 	SearchByNodeKey(nk string) (osquery_types.OsqueryClient, error)
 	GetNamedConfig(configName string) (osquery_types.OsqueryNamedConfig, error)
 	//BuildOsqueryPackAsJSON(nc osquery_types.OsqueryNamedConfig) (json.RawMessage)
-	BuildNamedConfig(configName string) (osquery_types.OsqueryNamedConfig, error)
+	BuildNamedConfig(configName string, target osquery_types.RenderTarget, now time.Time) (osquery_types.OsqueryNamedConfig, error)
+	GetPackByName(packName string) (osquery_types.Pack, error)
 }
 
 const (
@@ -247,40 +249,41 @@ func NodeConfigureRequest(dyn NodeDB, config *osquery_types.ServerConfig) http.H
 				return nil, fmt.Errorf("could not find node with key '%s': %s", data.NodeKey, err)
 			}
 
+			if osqNode.ConfigOverride != nil && osqNode.ConfigOverride.Expired(time.Now().UTC()) {
+				handlerLogger.WithFields(log.Fields{
+					"hostname": osqNode.HostIdentifier,
+					"expires":  osqNode.ConfigOverride.Expires,
+				}).Info("config override expired, reverting to named config")
+				osqNode.ConfigOverride = nil
+			}
+
+			if osqNode.ConfigName == "" {
+				handlerLogger.Info("No named config found, setting default config")
+			}
+			now := time.Now().UTC()
+			namedConfig, err := RenderNodeConfig(dyn, osqNode, config, now)
+			if err != nil {
+				return nil, err
+			}
+
+			//record what the node was sent so config rollouts can be tracked
+			osqNode.ConfigDelivery, err = newConfigDelivery(namedConfig, now)
+			if err != nil {
+				return nil, err
+			}
+
 			osqNode.SetTimestamp()
 			err = dyn.UpsertClient(osqNode)
 			if err != nil {
 				return nil, fmt.Errorf("node upsert failed: %s", err)
 			}
 
-			namedConfig := osquery_types.OsqueryNamedConfig{}
-			if osqNode.ConfigName != "" {
-				namedConfig, err = dyn.BuildNamedConfig(osqNode.ConfigName)
-				if err != nil {
-					return nil, fmt.Errorf("could not get config with name '%s': \n %s", osqNode.ConfigName, err)
-				}
-			} else {
-				handlerLogger.Info("No named config found, setting default config")
-				namedConfig, err = dyn.BuildNamedConfig("default")
-				if err != nil {
-					return nil, fmt.Errorf("could not get default config: %s", err)
-				}
-			}
-
-			//config, err := osquery_types.GetServerConfig("config.json")
-			//if err != nil {
-			//return nil, fmt.Errorf("could not get server config: %s", err)
-			//}
-			//oc, err := dyn.BuildNamedConfig(osqNode.ConfigName)
-			//logger.Infof("OC: %+v", oc)
-			//if err != nil {
-			//logger.Error(err)
-			//}
-
-			namedConfig.OsqueryConfig.Options.AwsAccessKeyID = config.FirehoseAWSAccessKeyID
-			namedConfig.OsqueryConfig.Options.AwsSecretAccessKey = config.FirehoseAWSSecretAccessKey
-			if namedConfig.OsqueryConfig.Options.AwsFirehoseStream == "" {
-				namedConfig.OsqueryConfig.Options.AwsFirehoseStream = config.FirehoseStreamName
+			for _, f := range namedConfig.Filtered {
+				handlerLogger.WithFields(log.Fields{
+					"hostname": osqNode.HostIdentifier,
+					"pack":     f.Pack,
+					"query":    f.Query,
+				}).Debugf("left out of config: %s", f.Reason)
 			}
 
 			//namedConfig.OsqueryConfig = oc
@@ -300,3 +303,32 @@ func NodeConfigureRequest(dyn NodeDB, config *osquery_types.ServerConfig) http.H
 
 	})
 }
+
+// applyConfigOverride merges a node's config override into nc, looking up any extra packs by name.  Extra
+// packs are filtered for the target the same way the named config's packs are
+func applyConfigOverride(dyn RenderDB, nc *osquery_types.OsqueryNamedConfig, override osquery_types.NodeConfigOverride, target osquery_types.RenderTarget) error {
+	oc := &nc.OsqueryConfig
+	err := override.Apply(oc)
+	if err != nil {
+		return err
+	}
+	for _, packName := range override.Packs {
+		if _, ok := oc.Packs[packName]; ok {
+			continue
+		}
+		p, err := dyn.GetPackByName(packName)
+		if err != nil {
+			return fmt.Errorf("could not get pack '%s': %s", packName, err)
+		}
+		p, ok, filtered := p.ForTarget(target)
+		nc.Filtered = append(nc.Filtered, filtered...)
+		if !ok {
+			continue
+		}
+		if oc.Packs == nil {
+			oc.Packs = make(map[string]map[string]interface{})
+		}
+		oc.Packs[packName] = p.AsMap()
+	}
+	return nil
+}
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"net/url"
 	"testing"
+	"time"
 )
 
 func init() {
@@ -25,3 +26,48 @@ func TestNodeEnrollRequest(t *testing.T) {
 		t.Errorf("NodeEnrollRequest returned: %+v, expected: %+v", w.Code, http.StatusOK)
 	}
 }
+
+func TestRollout(t *testing.T) {
+	config := &osquery_types.ServerConfig{}
+	mockdb := helpers.NewMockDB()
+	now := time.Now().UTC()
+	namedConfig := osquery_types.OsqueryNamedConfig{ConfigName: "default", Revision: 2}
+
+	current := osquery_types.OsqueryClient{NodeKey: "a", HostIdentifier: "host-a", ConfigName: "default"}
+	rendered, err := RenderNodeConfig(mockdb, current, config, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rendered.Revision = 2
+	current.ConfigDelivery, err = newConfigDelivery(rendered, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nodes := []osquery_types.OsqueryClient{
+		current,
+		{NodeKey: "b", HostIdentifier: "host-b", ConfigDelivery: &osquery_types.ConfigDelivery{
+			ConfigName: "default", Revision: 1, Hash: "old", FetchedAt: "2018-06-01T17:00:00Z"}},
+		{NodeKey: "c", HostIdentifier: "host-c", ConfigName: "default"},
+		{NodeKey: "d", HostIdentifier: "host-d", ConfigName: "default", PendingRegistrationApproval: true},
+		{NodeKey: "e", HostIdentifier: "host-e", ConfigName: "mac"},
+	}
+
+	rollout, err := Rollout(mockdb, namedConfig, nodes, config, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rollout.Nodes != 4 || rollout.Current != 1 || rollout.Stale != 1 || rollout.Never != 1 || rollout.Pending != 1 {
+		t.Errorf("unexpected counts: %+v", rollout)
+	}
+	if len(rollout.Laggards) != 2 {
+		t.Fatalf("expected 2 laggards, got %+v", rollout.Laggards)
+	}
+	stale := rollout.Laggards[0]
+	if stale.NodeKey != "b" || stale.Status != osquery_types.RolloutStale || stale.Reason != "last fetched revision 1" {
+		t.Errorf("unexpected stale node: %+v", stale)
+	}
+	if never := rollout.Laggards[1]; never.NodeKey != "c" || never.Status != osquery_types.RolloutNever {
+		t.Errorf("unexpected never fetched node: %+v", never)
+	}
+}
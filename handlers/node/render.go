@@ -0,0 +1,60 @@
+package node
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/oktasecuritylabs/sgt/osquery_types"
+)
+
+// RenderDB is the subset of the database needed to build the config sent to a node
+type RenderDB interface {
+	BuildNamedConfig(configName string, target osquery_types.RenderTarget, now time.Time) (osquery_types.OsqueryNamedConfig, error)
+	GetPackByName(packName string) (osquery_types.Pack, error)
+}
+
+// RenderNamedConfig builds the named config for target at now with the server's logging credentials
+// added, as a node without a config override would receive it
+func RenderNamedConfig(dyn RenderDB, configName string, target osquery_types.RenderTarget, config *osquery_types.ServerConfig, now time.Time) (osquery_types.OsqueryNamedConfig, error) {
+	namedConfig, err := dyn.BuildNamedConfig(configName, target, now)
+	if err != nil {
+		return namedConfig, fmt.Errorf("could not get config with name '%s': %s", configName, err)
+	}
+
+	if namedConfig.OsqueryConfig.Options == nil {
+		namedConfig.OsqueryConfig.Options = osquery_types.OsqueryOptions{}
+	}
+	namedConfig.OsqueryConfig.Options["aws_access_key_id"] = config.FirehoseAWSAccessKeyID
+	namedConfig.OsqueryConfig.Options["aws_secret_access_key"] = config.FirehoseAWSSecretAccessKey
+	if namedConfig.OsqueryConfig.Options.String("aws_firehose_stream") == "" {
+		namedConfig.OsqueryConfig.Options["aws_firehose_stream"] = config.FirehoseStreamName
+	}
+
+	return namedConfig, nil
+}
+
+// RenderNodeConfig builds the config osqNode receives when it next asks for one: its named config (or
+// the default config if it has none) built for its platform, osquery version and tags, with the server's
+// logging credentials added and its config override applied unless it has expired by now
+func RenderNodeConfig(dyn RenderDB, osqNode osquery_types.OsqueryClient, config *osquery_types.ServerConfig, now time.Time) (osquery_types.OsqueryNamedConfig, error) {
+	configName := osqNode.ConfigName
+	if configName == "" {
+		configName = "default"
+	}
+
+	target := osqNode.RenderTarget()
+	namedConfig, err := RenderNamedConfig(dyn, configName, target, config, now)
+	if err != nil {
+		return namedConfig, err
+	}
+
+	//node specific overrides are applied last so they win over everything above
+	if osqNode.ConfigOverride != nil && !osqNode.ConfigOverride.Expired(now) {
+		err = applyConfigOverride(dyn, &namedConfig, *osqNode.ConfigOverride, target)
+		if err != nil {
+			return namedConfig, fmt.Errorf("could not apply config override for node '%s': %s", osqNode.HostIdentifier, err)
+		}
+	}
+
+	return namedConfig, nil
+}
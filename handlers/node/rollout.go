@@ -0,0 +1,117 @@
+package node
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/oktasecuritylabs/sgt/osquery_types"
+)
+
+// newConfigDelivery records that the rendered config was sent to a node at now
+func newConfigDelivery(rendered osquery_types.OsqueryNamedConfig, now time.Time) (*osquery_types.ConfigDelivery, error) {
+	hash, err := osquery_types.ConfigHash(rendered.OsqueryConfig)
+	if err != nil {
+		return nil, fmt.Errorf("could not hash config: %s", err)
+	}
+	return &osquery_types.ConfigDelivery{
+		ConfigName: rendered.ConfigName,
+		Revision:   rendered.Revision,
+		Hash:       hash,
+		FetchedAt:  now.Format(time.RFC3339),
+	}, nil
+}
+
+// Rollout compares the config each node assigned to namedConfig last fetched with the config it would
+// be sent now.  A node is current only if the two are identical, so changes to packs, credentials and
+// overrides are picked up as well as new revisions of the named config
+func Rollout(dyn RenderDB, namedConfig osquery_types.OsqueryNamedConfig, nodes []osquery_types.OsqueryClient, config *osquery_types.ServerConfig, now time.Time) (osquery_types.ConfigRollout, error) {
+	rollout := osquery_types.ConfigRollout{
+		ConfigName: namedConfig.ConfigName,
+		Revision:   namedConfig.Revision,
+		Laggards:   []osquery_types.RolloutNode{},
+	}
+
+	// nodes without an override are sent the same config as every other node of their platform,
+	// osquery version and tags, so only render it once for each
+	hashes := map[string]string{}
+	expectedHash := func(osqNode osquery_types.OsqueryClient) (string, error) {
+		hasOverride := osqNode.ConfigOverride != nil && !osqNode.ConfigOverride.Expired(now)
+		target := osqNode.RenderTarget().Key()
+		if hash, ok := hashes[target]; ok && !hasOverride {
+			return hash, nil
+		}
+		rendered, err := RenderNodeConfig(dyn, osqNode, config, now)
+		if err != nil {
+			return "", err
+		}
+		hash, err := osquery_types.ConfigHash(rendered.OsqueryConfig)
+		if err != nil {
+			return "", err
+		}
+		if !hasOverride {
+			hashes[target] = hash
+		}
+		return hash, nil
+	}
+
+	for _, osqNode := range nodes {
+		configName := osqNode.ConfigName
+		if configName == "" {
+			configName = "default"
+		}
+		if configName != namedConfig.ConfigName {
+			continue
+		}
+
+		rollout.Nodes++
+		if osqNode.PendingRegistrationApproval {
+			rollout.Pending++
+			continue
+		}
+
+		laggard := osquery_types.RolloutNode{
+			NodeKey:        osqNode.NodeKey,
+			HostIdentifier: osqNode.HostIdentifier,
+			Status:         osquery_types.RolloutNever,
+		}
+		d := osqNode.ConfigDelivery
+		if d == nil {
+			rollout.Never++
+			rollout.Laggards = append(rollout.Laggards, laggard)
+			continue
+		}
+
+		hash, err := expectedHash(osqNode)
+		if err != nil {
+			return rollout, fmt.Errorf("could not render config for node '%s': %s", osqNode.HostIdentifier, err)
+		}
+		if d.Hash == hash {
+			rollout.Current++
+			continue
+		}
+
+		rollout.Stale++
+		laggard.Status = osquery_types.RolloutStale
+		laggard.Revision = d.Revision
+		laggard.FetchedAt = d.FetchedAt
+		switch {
+		case d.ConfigName != namedConfig.ConfigName:
+			laggard.Reason = fmt.Sprintf("last fetched config '%s'", d.ConfigName)
+		case d.Revision != namedConfig.Revision:
+			laggard.Reason = fmt.Sprintf("last fetched revision %d", d.Revision)
+		default:
+			laggard.Reason = "packs, credentials or config override changed since last fetch"
+		}
+		rollout.Laggards = append(rollout.Laggards, laggard)
+	}
+
+	sort.Slice(rollout.Laggards, func(i, j int) bool {
+		a, b := rollout.Laggards[i], rollout.Laggards[j]
+		if a.Status != b.Status {
+			return a.Status == osquery_types.RolloutStale
+		}
+		return a.HostIdentifier < b.HostIdentifier
+	})
+	return rollout, nil
+}
@@ -64,3 +64,14 @@ func WriteSuccess(respWriter http.ResponseWriter, optionalMessage string) {
 func WriteCustomJSON(respWriter http.ResponseWriter, resp interface{}) {
 	writeResponseJSON(respWriter, resp)
 }
+
+// WriteRawJSON will write json that has already been marshalled to the http response writer as is
+func WriteRawJSON(respWriter http.ResponseWriter, respJSON []byte) {
+	respWriter.Header().Set("Content-Type", "application/json")
+	_, err := respWriter.Write(respJSON)
+	if err != nil {
+		errString := fmt.Sprintf("failed to write response: %s", err)
+		logger.Error(errString)
+		http.Error(respWriter, errString, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,45 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSchedule_Next(t *testing.T) {
+	// a Wednesday
+	from := time.Date(2019, 1, 2, 10, 30, 0, 0, time.UTC)
+	tests := []struct {
+		spec string
+		next time.Time
+	}{
+		{"* * * * *", time.Date(2019, 1, 2, 10, 31, 0, 0, time.UTC)},
+		{"0 */4 * * *", time.Date(2019, 1, 2, 12, 0, 0, 0, time.UTC)},
+		{"15,45 * * * *", time.Date(2019, 1, 2, 10, 45, 0, 0, time.UTC)},
+		{"0 9 * * 1-5", time.Date(2019, 1, 3, 9, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2019, 1, 6, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2019, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2020, 2, 29, 0, 0, 0, 0, time.UTC)},
+		// either day field matches when both are restricted: the 15th, or a Friday
+		{"0 0 15 * 5", time.Date(2019, 1, 4, 0, 0, 0, 0, time.UTC)},
+		{"30 10/6 * * *", time.Date(2019, 1, 2, 16, 30, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Time{}},
+	}
+	for _, tt := range tests {
+		s, err := Parse(tt.spec)
+		if err != nil {
+			t.Errorf("%s: %s", tt.spec, err)
+			continue
+		}
+		if next := s.Next(from); !next.Equal(tt.next) {
+			t.Errorf("%s: expected %s, got %s", tt.spec, tt.next, next)
+		}
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* * 0 * *", "*/0 * * * *", "5-1 * * * *", "a * * * *", "@sometimes"} {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("expected %q to be invalid", spec)
+		}
+	}
+}
@@ -0,0 +1,445 @@
+package fleetspec
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/oktasecuritylabs/sgt/osquery_types"
+)
+
+// configSections are the sections of an osquery config, other than options and packs, that are
+// converted as they are
+var configSections = []string{
+	"auto_table_construction",
+	"decorators",
+	"events",
+	"exclude_paths",
+	"file_accesses",
+	"file_paths",
+	"prometheus_targets",
+	"schedule",
+	"views",
+	"yara",
+}
+
+// Report lists what couldn't be converted.  Nothing in it stops a conversion, it is for the user
+// to review afterwards
+type Report struct {
+	Unmapped []string `json:"unmapped"`
+}
+
+// Add records something that couldn't be converted
+func (r *Report) Add(format string, args ...interface{}) {
+	r.Unmapped = append(r.Unmapped, fmt.Sprintf(format, args...))
+}
+
+// String returns the report one entry per line
+func (r Report) String() string {
+	return strings.Join(r.Unmapped, "\n")
+}
+
+// Content is what a set of fleetctl specs converts to in SGT
+type Content struct {
+	// Config holds the osquery config sections of the options spec, nil if there was none
+	Config *osquery_types.OsqueryConfig
+	// ConfigPacks are the packs that Fleet runs on every host, to be added to the config's pack list
+	ConfigPacks []string
+	Packs       []osquery_types.QueryPack
+	// Queries holds the queries of the packs, namespaced by pack, and any saved queries no pack uses
+	Queries []osquery_types.PackQuery
+}
+
+// transportOption returns true for options that tell osquery how to reach its server.  They point
+// at Fleet in Fleet's specs and at SGT in SGT's configs, so they are never converted
+func transportOption(name string) bool {
+	return strings.HasSuffix(name, "_plugin") || strings.Contains(name, "tls_") || strings.HasPrefix(name, "enroll_")
+}
+
+// ToSGT converts fleetctl specs to SGT content.  Each pack's queries become pack queries namespaced
+// by the pack, with the pack's settings for the query applied.  Labels, teams, per platform option
+// overrides and anything else SGT has no equivalent for are listed in the report
+func ToSGT(specs []Spec) (Content, Report, error) {
+	content := Content{}
+	report := Report{}
+	queries := map[string]QuerySpec{}
+	packs := []PackSpec{}
+	var options *OptionsSpec
+
+	for _, s := range specs {
+		switch s.Kind {
+		case KindQuery:
+			q := QuerySpec{}
+			if err := s.Decode(&q); err != nil {
+				return content, report, err
+			}
+			if q.Name == "" || q.Query == "" {
+				return content, report, fmt.Errorf("query spec [%s] needs a name and a query", q.Name)
+			}
+			if _, ok := queries[q.Name]; ok {
+				return content, report, fmt.Errorf("query [%s] is specified more than once", q.Name)
+			}
+			queries[q.Name] = q
+		case KindPack:
+			p := PackSpec{}
+			if err := s.Decode(&p); err != nil {
+				return content, report, err
+			}
+			if p.Name == "" {
+				return content, report, fmt.Errorf("pack spec has no name")
+			}
+			packs = append(packs, p)
+		case KindLabel:
+			l := LabelSpec{}
+			if err := s.Decode(&l); err != nil {
+				return content, report, err
+			}
+			report.Add("label [%s] was not imported: SGT has no labels, packs run on the nodes whose config lists them", l.Name)
+		case KindOptions:
+			if options != nil {
+				return content, report, fmt.Errorf("options are specified more than once")
+			}
+			options = &OptionsSpec{}
+			if err := s.Decode(options); err != nil {
+				return content, report, err
+			}
+		default:
+			report.Add("spec of kind [%s] was not imported", s.Kind)
+		}
+	}
+
+	used := map[string]bool{}
+	for _, ps := range packs {
+		qp := osquery_types.QueryPack{PackName: ps.Name}
+		qp.Platform = ps.Platform
+		if ps.Description != "" {
+			report.Add("pack [%s] description was not imported: SGT packs have no description", ps.Name)
+		}
+		inPack := map[string]bool{}
+		for _, entry := range ps.Queries {
+			name := entry.Name
+			if name == "" {
+				name = entry.QueryName
+			}
+			q, ok := queries[entry.QueryName]
+			if !ok {
+				report.Add("pack [%s] query [%s] was not imported: query [%s] is not in the specs", ps.Name, name, entry.QueryName)
+				continue
+			}
+			if inPack[name] {
+				return content, report, fmt.Errorf("pack [%s] has more than one query named [%s]", ps.Name, name)
+			}
+			inPack[name] = true
+			used[entry.QueryName] = true
+
+			pq := osquery_types.PackQuery{
+				QueryName:   osquery_types.NamespacedQueryName(ps.Name, name),
+				Query:       q.Query,
+				Interval:    entry.Interval,
+				Platform:    entry.Platform,
+				Version:     entry.Version,
+				Description: entry.Description,
+				Snapshot:    entry.Snapshot,
+				Removed:     entry.Removed,
+				Shard:       entry.Shard,
+				Denylist:    entry.Denylist,
+			}
+			if pq.Description == "" {
+				pq.Description = q.Description
+			}
+			if err := osquery_types.ValidatePlatform(pq.Platform); err != nil {
+				return content, report, fmt.Errorf("pack [%s] query [%s]: %s", ps.Name, name, err)
+			}
+			content.Queries = append(content.Queries, pq)
+			qp.Queries = append(qp.Queries, pq.QueryName)
+		}
+		if len(qp.Queries) == 0 {
+			report.Add("pack [%s] was not imported: none of its queries could be imported", ps.Name)
+			continue
+		}
+		content.Packs = append(content.Packs, qp)
+
+		switch {
+		case ps.Disabled:
+			report.Add("pack [%s] is disabled in Fleet, it was imported but not added to the config", ps.Name)
+		case containsString(ps.Targets.Labels, AllHostsLabel):
+			content.ConfigPacks = append(content.ConfigPacks, ps.Name)
+		case len(ps.Targets.Labels) == 0 && len(ps.Targets.Teams) == 0:
+			report.Add("pack [%s] has no targets in Fleet, it was imported but not added to the config", ps.Name)
+		default:
+			report.Add("pack [%s] targets labels %v and teams %v, SGT has neither so it was imported but not added to the config",
+				ps.Name, ps.Targets.Labels, ps.Targets.Teams)
+		}
+	}
+
+	// saved queries that no pack uses are kept under their own name, ready to be added to packs
+	names := make([]string, 0, len(queries))
+	for name := range queries {
+		if !used[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		q := queries[name]
+		content.Queries = append(content.Queries, osquery_types.PackQuery{QueryName: q.Name, Query: q.Query, Description: q.Description})
+	}
+
+	if options != nil {
+		oc, err := configFromOptions(*options, &report)
+		if err != nil {
+			return content, report, err
+		}
+		content.Config = oc
+	}
+	return content, report, nil
+}
+
+// configFromOptions converts the config of an options spec.  Options that don't validate against
+// the osquery flag schema are reported and left out, the other sections must be valid
+func configFromOptions(spec OptionsSpec, report *Report) (*osquery_types.OsqueryConfig, error) {
+	oc := &osquery_types.OsqueryConfig{Options: osquery_types.OsqueryOptions{}}
+	known := map[string]bool{}
+	for _, section := range configSections {
+		known[section] = true
+	}
+
+	sections := make([]string, 0, len(spec.Config))
+	for section := range spec.Config {
+		sections = append(sections, section)
+	}
+	sort.Strings(sections)
+	for _, section := range sections {
+		raw := spec.Config[section]
+		switch {
+		case section == "options":
+			fleetOptions := map[string]interface{}{}
+			if err := json.Unmarshal(raw, &fleetOptions); err != nil {
+				return nil, fmt.Errorf("invalid options: %s", err)
+			}
+			for _, name := range sortedKeys(fleetOptions) {
+				v := fleetOptions[name]
+				if f, ok := v.(float64); ok && f == math.Trunc(f) {
+					v = int(f)
+				}
+				if transportOption(name) {
+					report.Add("option [%s] was not imported: it tells osquery how to reach Fleet, SGT sets its own", name)
+					continue
+				}
+				if err := (osquery_types.OsqueryOptions{name: v}).Validate(); err != nil {
+					report.Add("option [%s] was not imported: %s", name, err)
+					continue
+				}
+				oc.Options[name] = v
+			}
+		case section == "packs":
+			report.Add("config section [packs] was not imported: use pack specs instead")
+		case known[section]:
+			js, err := json.Marshal(map[string]json.RawMessage{section: raw})
+			if err != nil {
+				return nil, err
+			}
+			if err := json.Unmarshal(js, oc); err != nil {
+				return nil, fmt.Errorf("invalid config section [%s]: %s", section, err)
+			}
+		default:
+			report.Add("config section [%s] was not imported: SGT does not support it", section)
+		}
+	}
+	for _, platform := range sortedKeys(spec.Overrides.Platforms) {
+		report.Add("options overrides for platform [%s] were not imported: SGT has no per platform options", platform)
+	}
+
+	if err := oc.Decorators.Validate(); err != nil {
+		return nil, err
+	}
+	if err := oc.Schedule.Validate(); err != nil {
+		return nil, err
+	}
+	return oc, nil
+}
+
+// ApplyTo updates a named config with the converted content.  Imported options are set over the
+// config's own, imported config sections replace the config's and packs are added to its pack list
+func (c Content) ApplyTo(nc *osquery_types.OsqueryNamedConfig) {
+	oc := &nc.OsqueryConfig
+	if oc.Options == nil {
+		oc.Options = osquery_types.NewOsqueryOptions()
+	}
+	if imported := c.Config; imported != nil {
+		for name, v := range imported.Options {
+			oc.Options[name] = v
+		}
+		d := imported.Decorators
+		if len(d.Load) > 0 || len(d.Always) > 0 || len(d.Interval) > 0 {
+			oc.Decorators = d
+		}
+		if len(imported.Schedule) > 0 {
+			oc.Schedule = imported.Schedule
+		}
+		if len(imported.FilePaths) > 0 {
+			oc.FilePaths = imported.FilePaths
+		}
+		if len(imported.FileAccesses) > 0 {
+			oc.FileAccesses = imported.FileAccesses
+		}
+		if len(imported.ExcludePaths) > 0 {
+			oc.ExcludePaths = imported.ExcludePaths
+		}
+		if imported.Yara != nil {
+			oc.Yara = imported.Yara
+		}
+		if imported.Events != nil {
+			oc.Events = imported.Events
+		}
+		if len(imported.Views) > 0 {
+			oc.Views = imported.Views
+		}
+		if len(imported.AutoTableConstruction) > 0 {
+			oc.AutoTableConstruction = imported.AutoTableConstruction
+		}
+		if imported.PrometheusTargets != nil {
+			oc.PrometheusTargets = imported.PrometheusTargets
+		}
+	}
+	for _, packName := range c.ConfigPacks {
+		if !nc.PackList.Contains(packName) {
+			nc.PackList = append(nc.PackList, osquery_types.PackListEntry{PackName: packName})
+		}
+	}
+}
+
+// FromSGT converts a named config, its packs and their queries, keyed by query name, to fleetctl
+// specs: an options spec, a query spec for each query and a pack spec for each pack targeting every
+// host.  Queries keep their SGT name, so a pack's namespaced queries come back to the same name when
+// the specs are imported again
+func FromSGT(nc osquery_types.OsqueryNamedConfig, packs []osquery_types.QueryPack, queries map[string]osquery_types.PackQuery) ([]Spec, Report, error) {
+	report := Report{}
+
+	options := map[string]interface{}{}
+	redacted := nc.OsqueryConfig.Options.Redacted()
+	for _, name := range sortedKeys(redacted) {
+		v := redacted[name]
+		switch {
+		case transportOption(name):
+			report.Add("option [%s] was not exported: it tells osquery how to reach SGT, Fleet sets its own", name)
+		case v == osquery_types.RedactedValue:
+			report.Add("option [%s] was not exported: it is sensitive", name)
+		default:
+			options[name] = v
+		}
+	}
+	config := map[string]interface{}{"options": options}
+	js, err := json.Marshal(nc.OsqueryConfig)
+	if err != nil {
+		return nil, report, err
+	}
+	all := map[string]json.RawMessage{}
+	if err := json.Unmarshal(js, &all); err != nil {
+		return nil, report, err
+	}
+	for _, section := range configSections {
+		switch raw, ok := all[section]; {
+		case !ok, string(raw) == "null", string(raw) == "{}", string(raw) == "[]":
+		default:
+			config[section] = raw
+		}
+	}
+	optionsSpec, err := NewSpec(KindOptions, map[string]interface{}{"config": config, "overrides": OptionsOverrides{}})
+	if err != nil {
+		return nil, report, err
+	}
+
+	querySpecs := []Spec{}
+	packSpecs := []Spec{}
+	exported := map[string]bool{}
+	for _, qp := range packs {
+		ps := PackSpec{
+			Name:     qp.PackName,
+			Platform: qp.Platform,
+			Targets:  PackTargets{Labels: []string{AllHostsLabel}},
+			Queries:  []PackSpecQuery{},
+		}
+		if len(qp.Discovery) > 0 {
+			report.Add("pack [%s] discovery queries were not exported: Fleet packs have no discovery queries", qp.PackName)
+		}
+		for _, queryName := range qp.Queries {
+			pq, ok := queries[queryName]
+			if !ok {
+				report.Add("pack [%s] query [%s] was not exported: the query does not exist", qp.PackName, queryName)
+				continue
+			}
+			resolved := qp.ResolveQuery(pq)
+			if resolved.Value != "" {
+				report.Add("pack [%s] query [%s] value was not exported: Fleet queries have no value", qp.PackName, queryName)
+			}
+			// Fleet has no pack level version or shard, so they are set on each query instead
+			if resolved.Version == "" {
+				resolved.Version = qp.Version
+			}
+			if resolved.Shard == 0 {
+				resolved.Shard = qp.Shard
+			}
+			ps.Queries = append(ps.Queries, PackSpecQuery{
+				QueryName: pq.QueryName,
+				Name:      resolved.QueryName,
+				Interval:  resolved.Interval,
+				Snapshot:  resolved.Snapshot,
+				Removed:   resolved.Removed,
+				Shard:     resolved.Shard,
+				Platform:  resolved.Platform,
+				Version:   resolved.Version,
+				Denylist:  resolved.Denylist,
+			})
+			if exported[pq.QueryName] {
+				continue
+			}
+			exported[pq.QueryName] = true
+			s, err := NewSpec(KindQuery, QuerySpec{Name: pq.QueryName, Description: pq.Description, Query: pq.Query})
+			if err != nil {
+				return nil, report, err
+			}
+			querySpecs = append(querySpecs, s)
+		}
+		s, err := NewSpec(KindPack, ps)
+		if err != nil {
+			return nil, report, err
+		}
+		packSpecs = append(packSpecs, s)
+	}
+
+	// queries come before the packs that use them, as fleetctl apply needs them to exist first
+	specs := append([]Spec{optionsSpec}, querySpecs...)
+	return append(specs, packSpecs...), report, nil
+}
+
+func sortedKeys(m interface{}) []string {
+	keys := []string{}
+	switch m := m.(type) {
+	case map[string]interface{}:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	case osquery_types.OsqueryOptions:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	case map[string]json.RawMessage:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,169 @@
+// Package fleetspec reads and writes fleetctl specs, the YAML documents used by Kolide Fleet's
+// fleetctl apply and get commands, and converts them to and from SGT's named configs, packs and
+// pack queries
+package fleetspec
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/oktasecuritylabs/sgt/osquery_types"
+	"gopkg.in/yaml.v2"
+)
+
+// APIVersion is the only fleetctl spec version there is
+const APIVersion = "v1"
+
+// The kinds of fleetctl spec
+const (
+	KindQuery   = "query"
+	KindPack    = "pack"
+	KindLabel   = "label"
+	KindOptions = "options"
+)
+
+// AllHostsLabel is the Fleet label every host belongs to
+const AllHostsLabel = "All Hosts"
+
+// Spec is a single fleetctl spec document, with the spec itself held as JSON to be decoded by kind
+type Spec struct {
+	APIVersion string          `json:"apiVersion"`
+	Kind       string          `json:"kind"`
+	Spec       json.RawMessage `json:"spec"`
+}
+
+// QuerySpec is a saved query.  Fleet queries are shared by name between packs
+type QuerySpec struct {
+	Name        string `json:"name" yaml:"name"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	Query       string `json:"query" yaml:"query"`
+}
+
+// PackSpec is a pack, which schedules saved queries on the hosts it targets
+type PackSpec struct {
+	Name        string          `json:"name" yaml:"name"`
+	Description string          `json:"description,omitempty" yaml:"description,omitempty"`
+	Platform    string          `json:"platform,omitempty" yaml:"platform,omitempty"`
+	Disabled    bool            `json:"disabled,omitempty" yaml:"disabled,omitempty"`
+	Targets     PackTargets     `json:"targets" yaml:"targets"`
+	Queries     []PackSpecQuery `json:"queries" yaml:"queries"`
+}
+
+// PackTargets are the labels and teams of the hosts a pack runs on
+type PackTargets struct {
+	Labels []string `json:"labels,omitempty" yaml:"labels,omitempty"`
+	Teams  []string `json:"teams,omitempty" yaml:"teams,omitempty"`
+}
+
+// PackSpecQuery schedules the saved query named by QueryName in a pack, under the name Name
+type PackSpecQuery struct {
+	QueryName   string                  `json:"query" yaml:"query"`
+	Name        string                  `json:"name" yaml:"name"`
+	Description string                  `json:"description,omitempty" yaml:"description,omitempty"`
+	Interval    osquery_types.FlexInt   `json:"interval" yaml:"interval"`
+	Snapshot    *osquery_types.FlexBool `json:"snapshot,omitempty" yaml:"snapshot,omitempty"`
+	Removed     *osquery_types.FlexBool `json:"removed,omitempty" yaml:"removed,omitempty"`
+	Shard       osquery_types.FlexInt   `json:"shard,omitempty" yaml:"shard,omitempty"`
+	Platform    string                  `json:"platform,omitempty" yaml:"platform,omitempty"`
+	Version     string                  `json:"version,omitempty" yaml:"version,omitempty"`
+	Denylist    *osquery_types.FlexBool `json:"denylist,omitempty" yaml:"denylist,omitempty"`
+}
+
+// LabelSpec is a label, a query whose results decide which hosts belong to it
+type LabelSpec struct {
+	Name        string `json:"name" yaml:"name"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	Query       string `json:"query,omitempty" yaml:"query,omitempty"`
+	Platform    string `json:"platform,omitempty" yaml:"platform,omitempty"`
+}
+
+// OptionsSpec is the osquery config Fleet gives every host, with per platform overrides
+type OptionsSpec struct {
+	Config    map[string]json.RawMessage `json:"config"`
+	Overrides OptionsOverrides           `json:"overrides"`
+}
+
+// OptionsOverrides replace the whole config for hosts of a platform
+type OptionsOverrides struct {
+	Platforms map[string]json.RawMessage `json:"platforms,omitempty"`
+}
+
+// Parse reads the fleetctl specs in data, which holds one or more YAML documents separated by ---
+func Parse(data []byte) ([]Spec, error) {
+	specs := []Spec{}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	for i := 1; ; i++ {
+		s := Spec{}
+		err := dec.Decode(&s)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("spec %d: %s", i, err)
+		}
+		switch {
+		case s.Spec == nil && s.Kind == "" && s.APIVersion == "":
+			// an empty document
+			i--
+			continue
+		case s.APIVersion != APIVersion:
+			return nil, fmt.Errorf("spec %d: unsupported apiVersion %q", i, s.APIVersion)
+		case s.Kind == "":
+			return nil, fmt.Errorf("spec %d: no kind", i)
+		case len(s.Spec) == 0 || string(s.Spec) == "null":
+			return nil, fmt.Errorf("spec %d: %s spec is empty", i, s.Kind)
+		}
+		specs = append(specs, s)
+	}
+	if len(specs) == 0 {
+		return nil, errors.New("no specs found")
+	}
+	return specs, nil
+}
+
+// Decode reads the spec into v, which should be the spec type for its kind
+func (s Spec) Decode(v interface{}) error {
+	if err := json.Unmarshal(s.Spec, v); err != nil {
+		return fmt.Errorf("invalid %s spec: %s", s.Kind, err)
+	}
+	return nil
+}
+
+// NewSpec returns a spec of the given kind holding v
+func NewSpec(kind string, v interface{}) (Spec, error) {
+	js, err := json.Marshal(v)
+	if err != nil {
+		return Spec{}, err
+	}
+	return Spec{APIVersion: APIVersion, Kind: kind, Spec: js}, nil
+}
+
+// Marshal writes specs as YAML documents that fleetctl apply accepts.  Mapping keys are sorted so the
+// same content always gives the same output
+func Marshal(specs []Spec) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	enc := yaml.NewEncoder(buf)
+	for _, s := range specs {
+		js, err := json.Marshal(s)
+		if err != nil {
+			return nil, err
+		}
+		// numbers are kept as written, which the yaml encoder understands
+		dec := json.NewDecoder(bytes.NewReader(js))
+		dec.UseNumber()
+		var doc interface{}
+		if err := dec.Decode(&doc); err != nil {
+			return nil, err
+		}
+		if err := enc.Encode(doc); err != nil {
+			return nil, err
+		}
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
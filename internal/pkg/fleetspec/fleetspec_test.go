@@ -0,0 +1,291 @@
+package fleetspec
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/oktasecuritylabs/sgt/osquery_types"
+)
+
+const fleetSpecs = `---
+apiVersion: v1
+kind: options
+spec:
+  config:
+    options:
+      distributed_interval: 3
+      distributed_plugin: tls
+      logger_tls_period: 10
+      pack_delimiter: /
+      not_a_flag: true
+    decorators:
+      load:
+        - SELECT uuid AS host_uuid FROM system_info;
+    packs:
+      legacy: {}
+  overrides:
+    platforms:
+      darwin:
+        options:
+          distributed_interval: 10
+---
+apiVersion: v1
+kind: query
+spec:
+  name: osquery_info
+  description: "Which osquery is running # and where"
+  query: SELECT * FROM osquery_info
+---
+apiVersion: v1
+kind: query
+spec:
+  name: processes
+  query: |
+    SELECT name, path
+    FROM processes
+---
+apiVersion: v1
+kind: query
+spec:
+  name: unused
+  query: 'SELECT ''x'' AS quoted'
+---
+apiVersion: v1
+kind: label
+spec:
+  name: slack_not_running
+  query: SELECT * FROM system_info WHERE NOT EXISTS (SELECT * FROM processes WHERE name LIKE '%Slack%')
+---
+apiVersion: v1
+kind: pack
+spec:
+  name: monitoring
+  targets:
+    labels:
+    - All Hosts
+  queries:
+  - query: osquery_info
+    name: info
+    interval: 3600
+    snapshot: true
+    version: 4.0
+  - query: processes
+    name: procs
+    interval: 60
+    platform: darwin
+  - query: missing
+    interval: 60
+---
+apiVersion: v1
+kind: pack
+spec:
+  name: slack
+  targets:
+    labels: [slack_not_running]
+  queries:
+    - {query: processes, name: processes, interval: 600}
+`
+
+func TestParse_YAML(t *testing.T) {
+	specs, err := Parse([]byte(`# leading comment
+---
+---
+apiVersion: v1
+kind: pack
+spec:
+  name: 2019
+  platform: darwin # trailing comment
+  targets: {labels: [All Hosts]}
+  queries:
+  - &info
+    query: osquery_info
+    name: info
+    interval: "3600"
+    snapshot: yes
+    version: 4.0
+  - <<: *info
+    name: info_again
+    removed: "false"
+---
+apiVersion: v1
+kind: options
+spec:
+  config:
+    options: {distributed_interval: 3}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(specs) != 2 {
+		t.Fatalf("expected the empty documents to be skipped, got %d specs", len(specs))
+	}
+	pack := PackSpec{}
+	if err = specs[0].Decode(&pack); err != nil {
+		t.Fatal(err)
+	}
+	if pack.Name != "2019" || pack.Platform != "darwin" || !reflect.DeepEqual(pack.Targets.Labels, []string{AllHostsLabel}) {
+		t.Errorf("unexpected pack %+v", pack)
+	}
+	expected := []PackSpecQuery{
+		{QueryName: "osquery_info", Name: "info", Interval: 3600, Snapshot: osquery_types.Bool(true), Version: "4.0"},
+		{QueryName: "osquery_info", Name: "info_again", Interval: 3600, Snapshot: osquery_types.Bool(true), Version: "4.0",
+			Removed: osquery_types.Bool(false)},
+	}
+	if !reflect.DeepEqual(pack.Queries, expected) {
+		t.Errorf("got %+v", pack.Queries)
+	}
+	if string(specs[1].Spec) != `{"config":{"options":{"distributed_interval":3}}}` {
+		t.Errorf("got options %s", specs[1].Spec)
+	}
+
+	for _, bad := range []string{
+		"",
+		"apiVersion: v1\nkind: query\nspec:\n  name: \"unterminated",
+		"apiVersion: v1\nkind: pack\nspec:\n  queries:\n  - interval: often",
+		"apiVersion: v2\nkind: query\nspec: {name: x}",
+		"apiVersion: v1\nkind: query",
+	} {
+		if _, err := Parse([]byte(bad)); err == nil {
+			t.Errorf("%q: expected an error", bad)
+		}
+	}
+}
+
+func TestToSGT(t *testing.T) {
+	specs, err := Parse([]byte(fleetSpecs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, report, err := ToSGT(specs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedPacks := []osquery_types.QueryPack{
+		{PackName: "monitoring", Queries: []string{"monitoring/info", "monitoring/procs"}},
+		{PackName: "slack", Queries: []string{"slack/processes"}},
+	}
+	if !reflect.DeepEqual(content.Packs, expectedPacks) {
+		t.Errorf("packs: got %+v", content.Packs)
+	}
+	if !reflect.DeepEqual(content.ConfigPacks, []string{"monitoring"}) {
+		t.Errorf("config packs: got %v", content.ConfigPacks)
+	}
+	expectedQueries := []osquery_types.PackQuery{
+		{QueryName: "monitoring/info", Query: "SELECT * FROM osquery_info", Interval: 3600,
+			Snapshot: osquery_types.Bool(true), Version: "4.0", Description: "Which osquery is running # and where"},
+		{QueryName: "monitoring/procs", Query: "SELECT name, path\nFROM processes\n", Interval: 60, Platform: "darwin"},
+		{QueryName: "slack/processes", Query: "SELECT name, path\nFROM processes\n", Interval: 600},
+		{QueryName: "unused", Query: "SELECT 'x' AS quoted"},
+	}
+	if !reflect.DeepEqual(content.Queries, expectedQueries) {
+		t.Errorf("queries: got %+v", content.Queries)
+	}
+
+	if content.Config == nil {
+		t.Fatal("expected a config from the options spec")
+	}
+	expectedOptions := osquery_types.OsqueryOptions{"distributed_interval": 3, "pack_delimiter": "/"}
+	if !reflect.DeepEqual(content.Config.Options, expectedOptions) {
+		t.Errorf("options: got %v", content.Config.Options)
+	}
+	if len(content.Config.Decorators.Load) != 1 {
+		t.Errorf("decorators: got %+v", content.Config.Decorators)
+	}
+
+	for _, expected := range []string{
+		"label [slack_not_running]",
+		"pack [monitoring] query [missing]",
+		"pack [slack] targets labels [slack_not_running]",
+		"option [distributed_plugin]",
+		"option [logger_tls_period]",
+		"option [not_a_flag]",
+		"config section [packs]",
+		"platform [darwin]",
+	} {
+		if !strings.Contains(report.String(), expected) {
+			t.Errorf("expected report to mention %q, got:\n%s", expected, report)
+		}
+	}
+
+	nc := osquery_types.OsqueryNamedConfig{ConfigName: "default", PackList: osquery_types.NewPackList("existing", "monitoring")}
+	content.ApplyTo(&nc)
+	if nc.OsqueryConfig.Options["distributed_interval"] != 3 || nc.OsqueryConfig.Options["logger_plugin"] != "firehose" {
+		t.Errorf("expected imported options over the defaults, got %v", nc.OsqueryConfig.Options)
+	}
+	if !reflect.DeepEqual(nc.PackList.Names(), []string{"existing", "monitoring"}) {
+		t.Errorf("pack list: got %v", nc.PackList)
+	}
+}
+
+func TestFromSGT_RoundTrip(t *testing.T) {
+	nc := osquery_types.OsqueryNamedConfig{
+		ConfigName: "default",
+		OsqueryConfig: osquery_types.OsqueryConfig{
+			Options: osquery_types.OsqueryOptions{
+				"config_refresh":        300,
+				"logger_plugin":         "firehose",
+				"aws_secret_access_key": "secret",
+			},
+			Decorators: osquery_types.OsqueryDecorators{Always: []string{"SELECT hostname FROM system_info"}},
+		},
+		PackList: osquery_types.NewPackList("monitoring"),
+	}
+	packs := []osquery_types.QueryPack{{
+		PackName:     "monitoring",
+		PackSettings: osquery_types.PackSettings{Platform: "linux", Version: "3.3.0"},
+		Queries:      []string{"monitoring/info", "shared"},
+		Overrides:    map[string]osquery_types.PackQueryOverride{"shared": {Interval: 120}},
+	}}
+	queries := map[string]osquery_types.PackQuery{
+		"monitoring/info": {QueryName: "monitoring/info", Query: "SELECT *\nFROM osquery_info", Interval: 3600, Description: "info"},
+		"shared":          {QueryName: "shared", Query: "SELECT 1", Interval: 60},
+	}
+
+	specs, report, err := FromSGT(nc, packs, queries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kinds := []string{}
+	for _, s := range specs {
+		kinds = append(kinds, s.Kind)
+	}
+	if !reflect.DeepEqual(kinds, []string{KindOptions, KindQuery, KindQuery, KindPack}) {
+		t.Errorf("kinds: got %v", kinds)
+	}
+	for _, expected := range []string{"option [logger_plugin]", "option [aws_secret_access_key]"} {
+		if !strings.Contains(report.String(), expected) {
+			t.Errorf("expected report to mention %q, got:\n%s", expected, report)
+		}
+	}
+
+	out, err := Marshal(specs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := Parse(out)
+	if err != nil {
+		t.Fatalf("%s\n%s", err, out)
+	}
+	content, _, err := ToSGT(parsed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedQueries := []osquery_types.PackQuery{
+		{QueryName: "monitoring/info", Query: "SELECT *\nFROM osquery_info", Interval: 3600, Version: "3.3.0", Description: "info"},
+		{QueryName: "monitoring/shared", Query: "SELECT 1", Interval: 120, Version: "3.3.0"},
+	}
+	if !reflect.DeepEqual(content.Queries, expectedQueries) {
+		t.Errorf("queries: got %+v\n%s", content.Queries, out)
+	}
+	if len(content.Packs) != 1 || content.Packs[0].Platform != "linux" {
+		t.Errorf("packs: got %+v", content.Packs)
+	}
+	if !reflect.DeepEqual(content.ConfigPacks, []string{"monitoring"}) {
+		t.Errorf("config packs: got %v", content.ConfigPacks)
+	}
+	if content.Config.Options["config_refresh"] != 300 || len(content.Config.Decorators.Always) != 1 {
+		t.Errorf("config: got %+v", content.Config)
+	}
+}
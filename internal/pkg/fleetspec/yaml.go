@@ -0,0 +1,80 @@
+package fleetspec
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// UnmarshalYAML reads a spec document, decoding the spec into the type for its kind so that fields
+// that are always strings keep the text that was written, eg version: 4.0 stays "4.0" rather than
+// becoming the number 4.  Options and kinds SGT doesn't know are decoded as plain YAML
+func (s *Spec) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	header := struct {
+		APIVersion string `yaml:"apiVersion"`
+		Kind       string `yaml:"kind"`
+	}{}
+	if err := unmarshal(&header); err != nil {
+		return err
+	}
+
+	var spec interface{}
+	var err error
+	switch header.Kind {
+	case KindQuery:
+		doc := struct {
+			Spec *QuerySpec `yaml:"spec"`
+		}{}
+		err = unmarshal(&doc)
+		spec = doc.Spec
+	case KindPack:
+		doc := struct {
+			Spec *PackSpec `yaml:"spec"`
+		}{}
+		err = unmarshal(&doc)
+		spec = doc.Spec
+	case KindLabel:
+		doc := struct {
+			Spec *LabelSpec `yaml:"spec"`
+		}{}
+		err = unmarshal(&doc)
+		spec = doc.Spec
+	default:
+		doc := struct {
+			Spec interface{} `yaml:"spec"`
+		}{}
+		err = unmarshal(&doc)
+		spec = jsonValue(doc.Spec)
+	}
+	if err != nil {
+		return err
+	}
+
+	js, err := json.Marshal(spec)
+	if err != nil {
+		return err
+	}
+	s.APIVersion = header.APIVersion
+	s.Kind = header.Kind
+	s.Spec = js
+	return nil
+}
+
+// jsonValue converts a value decoded from YAML to one encoding/json can write.  YAML mappings are
+// decoded with interface{} keys, which become strings
+func jsonValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, child := range v {
+			m[fmt.Sprint(k)] = jsonValue(child)
+		}
+		return m
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i, child := range v {
+			list[i] = jsonValue(child)
+		}
+		return list
+	}
+	return v
+}
@@ -0,0 +1,312 @@
+package jsonpatch
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// DecodePatch parses a JSON Patch document
+func DecodePatch(patch []byte) ([]Operation, error) {
+	ops := []Operation{}
+	err := json.Unmarshal(patch, &ops)
+	if err != nil {
+		return nil, fmt.Errorf("invalid json patch: %s", err)
+	}
+	return ops, nil
+}
+
+// Apply applies the operations to the JSON document doc in order and returns the patched document.
+// If any operation fails, including a failed test, the whole patch fails
+func Apply(doc []byte, ops []Operation) ([]byte, error) {
+	var root interface{}
+	err := json.Unmarshal(doc, &root)
+	if err != nil {
+		return nil, fmt.Errorf("invalid json document: %s", err)
+	}
+
+	for i, op := range ops {
+		root, err = apply(root, op)
+		if err != nil {
+			return nil, fmt.Errorf("operation %d (%s %s): %s", i, op.Op, op.Path, err)
+		}
+	}
+	return json.Marshal(root)
+}
+
+// MergePatch applies a JSON Merge Patch to the JSON document doc and returns the patched document.
+// Objects in the patch are merged recursively, null removes a member and anything else replaces it
+func MergePatch(doc, patch []byte) ([]byte, error) {
+	var target, p interface{}
+	err := json.Unmarshal(doc, &target)
+	if err != nil {
+		return nil, fmt.Errorf("invalid json document: %s", err)
+	}
+	err = json.Unmarshal(patch, &p)
+	if err != nil {
+		return nil, fmt.Errorf("invalid merge patch: %s", err)
+	}
+	return json.Marshal(mergePatch(target, p))
+}
+
+func mergePatch(target, patch interface{}) interface{} {
+	pm, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	tm, ok := target.(map[string]interface{})
+	if !ok {
+		tm = map[string]interface{}{}
+	}
+	for k, v := range pm {
+		if v == nil {
+			delete(tm, k)
+			continue
+		}
+		tm[k] = mergePatch(tm[k], v)
+	}
+	return tm
+}
+
+func apply(root interface{}, op Operation) (interface{}, error) {
+	path, err := parsePointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op.Op {
+	case "add":
+		return add(root, path, op.Value)
+
+	case "remove":
+		return remove(root, path)
+
+	case "replace":
+		if _, err := get(root, path); err != nil {
+			return nil, err
+		}
+		if len(path) == 0 {
+			return op.Value, nil
+		}
+		return update(root, path, func(parent interface{}, key string) (interface{}, error) {
+			switch p := parent.(type) {
+			case map[string]interface{}:
+				p[key] = op.Value
+				return p, nil
+			case []interface{}:
+				i, err := arrayIndex(key, len(p)-1)
+				if err != nil {
+					return nil, err
+				}
+				p[i] = op.Value
+				return p, nil
+			}
+			return nil, fmt.Errorf("cannot replace a member of a %s", kind(parent))
+		})
+
+	case "move", "copy":
+		from, err := parsePointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+		value, err := get(root, from)
+		if err != nil {
+			return nil, fmt.Errorf("from: %s", err)
+		}
+		if op.Op == "move" {
+			if isPrefix(from, path) && len(from) < len(path) {
+				return nil, fmt.Errorf("cannot move a value into one of its own children")
+			}
+			root, err = remove(root, from)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			value, err = normalize(value)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return add(root, path, value)
+
+	case "test":
+		value, err := get(root, path)
+		if err != nil {
+			return nil, err
+		}
+		expected, err := normalize(op.Value)
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(value, expected) {
+			return nil, fmt.Errorf("test failed")
+		}
+		return root, nil
+	}
+
+	return nil, fmt.Errorf("unknown operation %q", op.Op)
+}
+
+func add(root interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	return update(root, path, func(parent interface{}, key string) (interface{}, error) {
+		switch p := parent.(type) {
+		case map[string]interface{}:
+			p[key] = value
+			return p, nil
+		case []interface{}:
+			if key == "-" {
+				return append(p, value), nil
+			}
+			i, err := arrayIndex(key, len(p))
+			if err != nil {
+				return nil, err
+			}
+			p = append(p, nil)
+			copy(p[i+1:], p[i:])
+			p[i] = value
+			return p, nil
+		}
+		return nil, fmt.Errorf("cannot add a member to a %s", kind(parent))
+	})
+}
+
+func remove(root interface{}, path []string) (interface{}, error) {
+	if len(path) == 0 {
+		return nil, fmt.Errorf("cannot remove the whole document")
+	}
+	return update(root, path, func(parent interface{}, key string) (interface{}, error) {
+		switch p := parent.(type) {
+		case map[string]interface{}:
+			if _, ok := p[key]; !ok {
+				return nil, fmt.Errorf("member %q does not exist", key)
+			}
+			delete(p, key)
+			return p, nil
+		case []interface{}:
+			i, err := arrayIndex(key, len(p)-1)
+			if err != nil {
+				return nil, err
+			}
+			return append(p[:i], p[i+1:]...), nil
+		}
+		return nil, fmt.Errorf("cannot remove a member of a %s", kind(parent))
+	})
+}
+
+// update walks to the parent of the last token in path and calls fn with it, storing whatever fn
+// returns back in place of the parent.  Arrays change length when added to or removed from, so the
+// new value has to be written back into its own parent
+func update(node interface{}, path []string, fn func(parent interface{}, key string) (interface{}, error)) (interface{}, error) {
+	if len(path) == 1 {
+		return fn(node, path[0])
+	}
+
+	next, err := child(node, path[0])
+	if err != nil {
+		return nil, err
+	}
+	next, err = update(next, path[1:], fn)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n := node.(type) {
+	case map[string]interface{}:
+		n[path[0]] = next
+	case []interface{}:
+		i, _ := arrayIndex(path[0], len(n)-1)
+		n[i] = next
+	}
+	return node, nil
+}
+
+func get(node interface{}, path []string) (interface{}, error) {
+	var err error
+	for _, token := range path {
+		node, err = child(node, token)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return node, nil
+}
+
+func child(node interface{}, token string) (interface{}, error) {
+	switch n := node.(type) {
+	case map[string]interface{}:
+		v, ok := n[token]
+		if !ok {
+			return nil, fmt.Errorf("member %q does not exist", token)
+		}
+		return v, nil
+	case []interface{}:
+		i, err := arrayIndex(token, len(n)-1)
+		if err != nil {
+			return nil, err
+		}
+		return n[i], nil
+	}
+	return nil, fmt.Errorf("cannot look up %q in a %s", token, kind(node))
+}
+
+// parsePointer splits a JSON pointer into its unescaped reference tokens
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return []string{}, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid json pointer %q", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = strings.Replace(strings.Replace(t, "~1", "/", -1), "~0", "~", -1)
+	}
+	return tokens, nil
+}
+
+// arrayIndex parses an array index token, which must be between 0 and max
+func arrayIndex(token string, max int) (int, error) {
+	i, err := strconv.Atoi(token)
+	if err != nil || (len(token) > 1 && token[0] == '0') || strings.HasPrefix(token, "+") {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	if i < 0 || i > max {
+		return 0, fmt.Errorf("array index %d out of range", i)
+	}
+	return i, nil
+}
+
+func isPrefix(prefix, path []string) bool {
+	if len(prefix) > len(path) {
+		return false
+	}
+	for i := range prefix {
+		if prefix[i] != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func kind(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	}
+	return fmt.Sprintf("%T", v)
+}
@@ -0,0 +1,127 @@
+// Package jsonpatch works with JSON Patch (RFC 6902) and JSON Merge Patch (RFC 7386) documents
+package jsonpatch
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Operation is a single JSON Patch operation
+type Operation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	From  string      `json:"from,omitempty"`
+	Value interface{} `json:"value"`
+}
+
+// MarshalJSON leaves out the value of operations that don't take one, while keeping null values for
+// those that do
+func (o Operation) MarshalJSON() ([]byte, error) {
+	switch o.Op {
+	case "add", "replace", "test":
+		type withValue Operation
+		return json.Marshal(withValue(o))
+	}
+	return json.Marshal(struct {
+		Op   string `json:"op"`
+		Path string `json:"path"`
+		From string `json:"from,omitempty"`
+	}{o.Op, o.Path, o.From})
+}
+
+// EscapePointer escapes a single reference token of a JSON pointer
+func EscapePointer(token string) string {
+	return strings.Replace(strings.Replace(token, "~", "~0", -1), "/", "~1", -1)
+}
+
+// normalize round trips v through JSON so that structs, typed maps and numbers compare as plain
+// JSON values
+func normalize(v interface{}) (interface{}, error) {
+	js, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var result interface{}
+	err = json.Unmarshal(js, &result)
+	return result, err
+}
+
+// Diff returns the operations that turn from into to.  Both are marshalled to JSON first, so any
+// values that marshal can be compared.  Objects are compared key by key, arrays that differ are
+// replaced whole
+func Diff(from, to interface{}) ([]Operation, error) {
+	a, err := normalize(from)
+	if err != nil {
+		return nil, err
+	}
+	b, err := normalize(to)
+	if err != nil {
+		return nil, err
+	}
+	ops := []Operation{}
+	diff("", a, b, &ops)
+	return ops, nil
+}
+
+// ValuesFrom returns a copy of ops with the value of each add and replace operation taken from the
+// same path in doc.  Diffing two documents and then taking the values from a redacted copy of the
+// second shows that a redacted value changed without showing the value
+func ValuesFrom(ops []Operation, doc interface{}) ([]Operation, error) {
+	root, err := normalize(doc)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]Operation, len(ops))
+	for i, op := range ops {
+		result[i] = op
+		if op.Op != "add" && op.Op != "replace" {
+			continue
+		}
+		path, err := parsePointer(op.Path)
+		if err != nil {
+			return nil, err
+		}
+		if result[i].Value, err = get(root, path); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func diff(path string, a, b interface{}, ops *[]Operation) {
+	am, aIsObject := a.(map[string]interface{})
+	bm, bIsObject := b.(map[string]interface{})
+	if !aIsObject || !bIsObject {
+		if !reflect.DeepEqual(a, b) {
+			*ops = append(*ops, Operation{Op: "replace", Path: path, Value: b})
+		}
+		return
+	}
+
+	keys := []string{}
+	for k := range am {
+		keys = append(keys, k)
+	}
+	for k := range bm {
+		if _, ok := am[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		childPath := path + "/" + EscapePointer(k)
+		av, inA := am[k]
+		bv, inB := bm[k]
+		switch {
+		case !inB:
+			*ops = append(*ops, Operation{Op: "remove", Path: childPath})
+		case !inA:
+			*ops = append(*ops, Operation{Op: "add", Path: childPath, Value: bv})
+		default:
+			diff(childPath, av, bv, ops)
+		}
+	}
+}
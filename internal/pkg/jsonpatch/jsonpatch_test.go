@@ -0,0 +1,153 @@
+package jsonpatch
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	from := map[string]interface{}{
+		"config_name": "default",
+		"pack_list":   []string{"a", "b"},
+		"osquery_config": map[string]interface{}{
+			"options": map[string]interface{}{"verbose": false, "config_refresh": 300},
+		},
+		"a/b": 1,
+	}
+	to := map[string]interface{}{
+		"config_name": "default",
+		"pack_list":   []string{"a"},
+		"osquery_config": map[string]interface{}{
+			"options": map[string]interface{}{"verbose": true, "utc": true},
+		},
+	}
+
+	ops, err := Diff(from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	js, err := json.Marshal(ops)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `[{"op":"remove","path":"/a~1b"},` +
+		`{"op":"remove","path":"/osquery_config/options/config_refresh"},` +
+		`{"op":"add","path":"/osquery_config/options/utc","value":true},` +
+		`{"op":"replace","path":"/osquery_config/options/verbose","value":true},` +
+		`{"op":"replace","path":"/pack_list","value":["a"]}]`
+	if string(js) != expected {
+		t.Errorf("Got: \n\t%s, expected: \n\t%s", js, expected)
+	}
+
+	ops, err = Diff(from, from)
+	if err != nil || len(ops) != 0 {
+		t.Errorf("expected no operations, got %v (%v)", ops, err)
+	}
+}
+
+func TestValuesFrom(t *testing.T) {
+	from := map[string]interface{}{"secret": "old", "name": "a"}
+	to := map[string]interface{}{"secret": "new", "name": "a", "added": map[string]interface{}{"secret": "new"}}
+	redacted := map[string]interface{}{"secret": "X", "name": "a", "added": map[string]interface{}{"secret": "X"}}
+
+	ops, err := Diff(from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ops, err = ValuesFrom(ops, redacted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	js, err := json.Marshal(ops)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `[{"op":"add","path":"/added","value":{"secret":"X"}},{"op":"replace","path":"/secret","value":"X"}]`
+	if string(js) != expected {
+		t.Errorf("Got: \n\t%s, expected: \n\t%s", js, expected)
+	}
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		doc      string
+		patch    string
+		expected string
+		err      bool
+	}{
+		{`{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux"}]`, `{"baz":"qux","foo":"bar"}`, false},
+		{`{"foo":["bar","baz"]}`, `[{"op":"add","path":"/foo/1","value":"qux"}]`, `{"foo":["bar","qux","baz"]}`, false},
+		{`{"foo":["bar"]}`, `[{"op":"add","path":"/foo/-","value":"qux"}]`, `{"foo":["bar","qux"]}`, false},
+		{`{"baz":"qux","foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, `{"foo":"bar"}`, false},
+		{`{"foo":["bar","qux","baz"]}`, `[{"op":"remove","path":"/foo/1"}]`, `{"foo":["bar","baz"]}`, false},
+		{`{"baz":"qux","foo":"bar"}`, `[{"op":"replace","path":"/baz","value":"boo"}]`, `{"baz":"boo","foo":"bar"}`, false},
+		{`{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`, `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`,
+			`{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`, false},
+		{`{"foo":["all","grass","cows","eat"]}`, `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`, `{"foo":["all","cows","eat","grass"]}`, false},
+		{`{"a":{"b":[1]}}`, `[{"op":"copy","from":"/a/b","path":"/c"},{"op":"add","path":"/c/-","value":2}]`, `{"a":{"b":[1]},"c":[1,2]}`, false},
+		{`{"a/b":1,"m~n":2}`, `[{"op":"replace","path":"/a~1b","value":3},{"op":"remove","path":"/m~0n"}]`, `{"a/b":3}`, false},
+		{`{"baz":"qux","foo":["a",2,"c"]}`, `[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2}]`,
+			`{"baz":"qux","foo":["a",2,"c"]}`, false},
+		{`{"baz":"qux"}`, `[{"op":"test","path":"/baz","value":"bar"}]`, "", true},
+		{`{"foo":"bar"}`, `[{"op":"add","path":"/baz/bat","value":"qux"}]`, "", true},
+		{`{"foo":"bar"}`, `[{"op":"replace","path":"/baz","value":"qux"}]`, "", true},
+		{`{"foo":["bar"]}`, `[{"op":"add","path":"/foo/01","value":"qux"}]`, "", true},
+		{`{"foo":{"bar":1}}`, `[{"op":"move","from":"/foo","path":"/foo/bar/baz"}]`, "", true},
+		{`{"foo":"bar"}`, `[{"op":"bogus","path":"/foo"}]`, "", true},
+		{`{"foo":"bar"}`, `[{"op":"add","path":"","value":[1]}]`, `[1]`, false},
+	}
+
+	for _, test := range tests {
+		ops, err := DecodePatch([]byte(test.patch))
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err := Apply([]byte(test.doc), ops)
+		if test.err {
+			if err == nil {
+				t.Errorf("%s: expected an error, got %s", test.patch, result)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", test.patch, err)
+			continue
+		}
+		if string(result) != test.expected {
+			t.Errorf("%s: Got: \n\t%s, expected: \n\t%s", test.patch, result, test.expected)
+		}
+	}
+}
+
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		doc      string
+		patch    string
+		expected string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+
+	for _, test := range tests {
+		result, err := MergePatch([]byte(test.doc), []byte(test.patch))
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", test.patch, err)
+			continue
+		}
+		if string(result) != test.expected {
+			t.Errorf("%s: Got: \n\t%s, expected: \n\t%s", test.patch, result, test.expected)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+// Package osquerypack reads and writes packs in the osquery pack file format
+package osquerypack
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/oktasecuritylabs/sgt/osquery_types"
+)
+
+// Pack is a pack as it appears in an osquery pack file, with its queries keyed by name
+type Pack struct {
+	osquery_types.PackSettings
+	Queries map[string]osquery_types.PackQuery `json:"queries"`
+}
+
+// QueryNames returns the names of the pack's queries in sorted order
+func (p Pack) QueryNames() []string {
+	names := make([]string, 0, len(p.Queries))
+	for name := range p.Queries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Parse reads an osquery pack file.  Pack files are JSON with the extensions osquery accepts: # and
+// // line comments, /* */ block comments and queries split over several lines with a trailing backslash
+func Parse(data []byte) (Pack, error) {
+	pack := Pack{}
+	js, err := Normalize(data)
+	if err != nil {
+		return pack, err
+	}
+	err = json.Unmarshal(js, &pack)
+	if err != nil {
+		return pack, fmt.Errorf("invalid pack: %s", err)
+	}
+	if len(pack.Queries) == 0 {
+		return pack, errors.New("pack has no queries")
+	}
+	for _, name := range pack.QueryNames() {
+		pq := pack.Queries[name]
+		if pq.Query == "" {
+			return pack, fmt.Errorf("query [%s] has no query", name)
+		}
+		pq.QueryName = name
+		pack.Queries[name] = pq
+	}
+	return pack, nil
+}
+
+// Normalize turns an osquery pack file into plain JSON by removing comments and joining lines split
+// with a trailing backslash.  A line continuation inside a string becomes a single space
+func Normalize(data []byte) ([]byte, error) {
+	out := make([]byte, 0, len(data))
+	inString := false
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		if inString {
+			switch {
+			case c == '\\' && continuation(data, i+1) > 0:
+				next := i + 1 + continuation(data, i+1)
+				for next < len(data) && (data[next] == ' ' || data[next] == '\t') {
+					next++
+				}
+				if len(out) > 0 && out[len(out)-1] != ' ' && next < len(data) && data[next] != '"' {
+					out = append(out, ' ')
+				}
+				i = next - 1
+			case c == '\\' && i+1 < len(data):
+				out = append(out, c, data[i+1])
+				i++
+			case c == '\r' || c == '\n':
+				// a raw line break isn't valid in a JSON string, osquery treats it as whitespace
+				if len(out) > 0 && out[len(out)-1] != ' ' {
+					out = append(out, ' ')
+				}
+			default:
+				if c == '"' {
+					inString = false
+				}
+				out = append(out, c)
+			}
+			continue
+		}
+
+		switch {
+		case c == '"':
+			inString = true
+			out = append(out, c)
+		case c == '#' || (c == '/' && i+1 < len(data) && data[i+1] == '/'):
+			for i < len(data) && data[i] != '\n' {
+				i++
+			}
+			i--
+		case c == '/' && i+1 < len(data) && data[i+1] == '*':
+			end := bytes.Index(data[i+2:], []byte("*/"))
+			if end < 0 {
+				return nil, errors.New("invalid pack: unterminated comment")
+			}
+			i += end + 3
+		case c == '\\' && continuation(data, i+1) > 0:
+			i += continuation(data, i+1)
+		default:
+			out = append(out, c)
+		}
+	}
+	if inString {
+		return nil, errors.New("invalid pack: unterminated string")
+	}
+	return out, nil
+}
+
+// continuation returns the length of the line break starting at data[i], or 0 if there isn't one
+func continuation(data []byte, i int) int {
+	switch {
+	case i < len(data) && data[i] == '\n':
+		return 1
+	case i+1 < len(data) && data[i] == '\r' && data[i+1] == '\n':
+		return 2
+	}
+	return 0
+}
+
+// Marshal writes the pack as an osquery pack file.  Keys are sorted and nothing is escaped that
+// doesn't need to be, so the same pack always produces the same file
+func Marshal(p osquery_types.Pack) ([]byte, error) {
+	buf := bytes.Buffer{}
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	err := enc.Encode(p.AsMap())
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,105 @@
+package osquerypack
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/oktasecuritylabs/sgt/osquery_types"
+)
+
+const upstreamPack = `{
+  // Monitor for attacks
+  # hash comment
+  "platform": "darwin",
+  "version": "1.4.5",
+  "queries": {
+    "launchd": {
+      "query" : "select * from launchd \
+        where name like '%evil%' \
+          and path != 'a // b';",
+      "interval" : "3600",
+      /* block
+         comment */
+      "snapshot": "true",
+      "description" : "Escaped \"quotes\" and a regex: \\d+"
+    },
+    "kext": {"query": "select * from kernel_extensions where size > 0 & 1;", "interval": 60}
+  }
+}
+`
+
+func TestParse(t *testing.T) {
+	pack, err := Parse([]byte(upstreamPack))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pack.Platform != "darwin" || pack.Version != "1.4.5" {
+		t.Errorf("unexpected pack settings: %+v", pack.PackSettings)
+	}
+	if !reflect.DeepEqual(pack.QueryNames(), []string{"kext", "launchd"}) {
+		t.Errorf("unexpected queries: %v", pack.QueryNames())
+	}
+
+	expected := osquery_types.PackQuery{
+		QueryName:   "launchd",
+		Query:       "select * from launchd where name like '%evil%' and path != 'a // b';",
+		Interval:    3600,
+		Snapshot:    osquery_types.Bool(true),
+		Description: `Escaped "quotes" and a regex: \d+`,
+	}
+	if !reflect.DeepEqual(pack.Queries["launchd"], expected) {
+		t.Errorf("Got: \n\t%+v, expected: \n\t%+v", pack.Queries["launchd"], expected)
+	}
+
+	for _, invalid := range []string{`{"queries": {}}`, `{"queries": {"a": {"interval": 60}}}`, `{"queries": /* open`, `{"platform": "darwin`} {
+		if _, err := Parse([]byte(invalid)); err == nil {
+			t.Errorf("expected %s to be invalid", invalid)
+		}
+	}
+}
+
+func TestMarshal_RoundTrip(t *testing.T) {
+	files, err := filepath.Glob("../../../packs/*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no bundled packs found")
+	}
+
+	for _, fn := range files {
+		data, err := ioutil.ReadFile(fn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		parsed, err := Parse(data)
+		if err != nil {
+			t.Errorf("%s: %s", fn, err)
+			continue
+		}
+
+		pack := osquery_types.Pack{PackSettings: parsed.PackSettings}
+		for _, name := range parsed.QueryNames() {
+			pack.Queries = append(pack.Queries, parsed.Queries[name])
+		}
+		exported, err := Marshal(pack)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		reparsed, err := Parse(exported)
+		if err != nil {
+			t.Errorf("%s: exported pack doesn't parse: %s", fn, err)
+			continue
+		}
+		if !reflect.DeepEqual(parsed, reparsed) {
+			t.Errorf("%s: pack changed after export", fn)
+		}
+		again, _ := Marshal(pack)
+		if string(again) != string(exported) {
+			t.Errorf("%s: export is not stable", fn)
+		}
+	}
+}
@@ -0,0 +1,1151 @@
+// Code generated by gen.go from flags.json. DO NOT EDIT.
+
+package osqueryschema
+
+const osqueryVersion = "3.3.2"
+
+var flags = map[string]Flag{
+	"alarm_timeout": {
+		Name:        "alarm_timeout",
+		Type:        TypeInt,
+		Default:     4,
+		Description: "Seconds to allow for shutdown before forcing",
+	},
+	"allow_unsafe": {
+		Name:        "allow_unsafe",
+		Type:        TypeBool,
+		Default:     false,
+		Description: "Allow unsafe executable permissions",
+	},
+	"audit_allow_config": {
+		Name:        "audit_allow_config",
+		Type:        TypeBool,
+		Default:     false,
+		Description: "Allow the audit publisher to change auditing configuration",
+		Platforms:   []string{"linux"},
+	},
+	"audit_allow_fim_events": {
+		Name:        "audit_allow_fim_events",
+		Type:        TypeBool,
+		Default:     false,
+		Description: "Allow the audit publisher to install file event monitoring rules",
+		Platforms:   []string{"linux"},
+	},
+	"audit_allow_process_events": {
+		Name:        "audit_allow_process_events",
+		Type:        TypeBool,
+		Default:     true,
+		Description: "Allow the audit publisher to install process event monitoring rules",
+		Platforms:   []string{"linux"},
+	},
+	"audit_allow_selinux_events": {
+		Name:        "audit_allow_selinux_events",
+		Type:        TypeBool,
+		Default:     false,
+		Description: "Allow the audit publisher to process audit events containing SELinux records",
+		Platforms:   []string{"linux"},
+	},
+	"audit_allow_sockets": {
+		Name:        "audit_allow_sockets",
+		Type:        TypeBool,
+		Default:     false,
+		Description: "Allow the audit publisher to install socket-related rules",
+		Platforms:   []string{"linux"},
+	},
+	"audit_allow_user_events": {
+		Name:        "audit_allow_user_events",
+		Type:        TypeBool,
+		Default:     true,
+		Description: "Allow the audit publisher to install user-related rules",
+		Platforms:   []string{"linux"},
+	},
+	"audit_backlog_limit": {
+		Name:        "audit_backlog_limit",
+		Type:        TypeInt,
+		Default:     4096,
+		Description: "The audit backlog limit",
+		Platforms:   []string{"linux"},
+	},
+	"audit_backlog_wait_time": {
+		Name:        "audit_backlog_wait_time",
+		Type:        TypeInt,
+		Default:     0,
+		Description: "The audit backlog wait time",
+		Platforms:   []string{"linux"},
+	},
+	"audit_debug": {
+		Name:        "audit_debug",
+		Type:        TypeBool,
+		Default:     false,
+		Description: "Debug Linux audit messages",
+		Platforms:   []string{"linux"},
+	},
+	"audit_fim_debug": {
+		Name:        "audit_fim_debug",
+		Type:        TypeBool,
+		Default:     false,
+		Description: "Show audit file event monitoring debug information",
+		Platforms:   []string{"linux"},
+	},
+	"audit_fim_show_accesses": {
+		Name:        "audit_fim_show_accesses",
+		Type:        TypeBool,
+		Default:     false,
+		Description: "Also show file accesses in process_file_events",
+		Platforms:   []string{"linux"},
+	},
+	"audit_force_reconfigure": {
+		Name:        "audit_force_reconfigure",
+		Type:        TypeBool,
+		Default:     false,
+		Description: "Configure the audit subsystem from scratch",
+		Platforms:   []string{"linux"},
+	},
+	"audit_force_unconfigure": {
+		Name:        "audit_force_unconfigure",
+		Type:        TypeBool,
+		Default:     false,
+		Description: "Clear all audit rules on exit",
+		Platforms:   []string{"linux"},
+	},
+	"audit_persist": {
+		Name:        "audit_persist",
+		Type:        TypeBool,
+		Default:     true,
+		Description: "Attempt to retain control of audit",
+		Platforms:   []string{"linux"},
+	},
+	"audit_show_partial_fim_events": {
+		Name:        "audit_show_partial_fim_events",
+		Type:        TypeBool,
+		Default:     false,
+		Description: "Allow the audit publisher to show partial file events",
+		Platforms:   []string{"linux"},
+	},
+	"audit_show_untracked_res_warnings": {
+		Name:        "audit_show_untracked_res_warnings",
+		Type:        TypeBool,
+		Default:     false,
+		Description: "Show warnings about untracked resources",
+		Platforms:   []string{"linux"},
+	},
+	"augeas_lenses": {
+		Name:        "augeas_lenses",
+		Type:        TypeString,
+		Default:     "/usr/share/osquery/lenses",
+		Description: "Directory that contains augeas lenses files",
+		Platforms:   []string{"linux", "darwin"},
+	},
+	"aws_access_key_id": {
+		Name:        "aws_access_key_id",
+		Type:        TypeString,
+		Default:     "",
+		Description: "AWS access key ID",
+		Sensitive:   true,
+	},
+	"aws_debug": {
+		Name:        "aws_debug",
+		Type:        TypeBool,
+		Default:     false,
+		Description: "Enable AWS SDK debug logging",
+	},
+	"aws_enable_proxy": {
+		Name:        "aws_enable_proxy",
+		Type:        TypeBool,
+		Default:     false,
+		Description: "Enable proxying of HTTP/HTTPS requests in the AWS client config",
+	},
+	"aws_firehose_endpoint": {
+		Name:        "aws_firehose_endpoint",
+		Type:        TypeString,
+		Default:     "",
+		Description: "Custom Firehose endpoint",
+	},
+	"aws_firehose_period": {
+		Name:        "aws_firehose_period",
+		Type:        TypeInt,
+		Default:     10,
+		Description: "Seconds between flushing logs to Firehose",
+		Runtime:     true,
+	},
+	"aws_firehose_stream": {
+		Name:        "aws_firehose_stream",
+		Type:        TypeString,
+		Default:     "",
+		Description: "Name of Firehose stream for logging",
+	},
+	"aws_kinesis_disable_log_status": {
+		Name:        "aws_kinesis_disable_log_status",
+		Type:        TypeBool,
+		Default:     false,
+		Description: "Disable status logs processing",
+	},
+	"aws_kinesis_endpoint": {
+		Name:        "aws_kinesis_endpoint",
+		Type:        TypeString,
+		Default:     "",
+		Description: "Custom Kinesis endpoint",
+	},
+	"aws_kinesis_period": {
+		Name:        "aws_kinesis_period",
+		Type:        TypeInt,
+		Default:     1,
+		Description: "Seconds between flushing logs to Kinesis",
+		Runtime:     true,
+	},
+	"aws_kinesis_random_partition_key": {
+		Name:        "aws_kinesis_random_partition_key",
+		Type:        TypeBool,
+		Default:     false,
+		Description: "Enable random kinesis partition keys",
+	},
+	"aws_kinesis_stream": {
+		Name:        "aws_kinesis_stream",
+		Type:        TypeString,
+		Default:     "",
+		Description: "Name of Kinesis stream for logging",
+	},
+	"aws_profile_name": {
+		Name:        "aws_profile_name",
+		Type:        TypeString,
+		Default:     "",
+		Description: "AWS profile for authentication and region configuration",
+	},
+	"aws_proxy_host": {
+		Name:        "aws_proxy_host",
+		Type:        TypeString,
+		Default:     "",
+		Description: "Proxy host for AWS requests",
+	},
+	"aws_proxy_password": {
+		Name:        "aws_proxy_password",
+		Type:        TypeString,
+		Default:     "",
+		Description: "Proxy password for use in AWS requests",
+		Sensitive:   true,
+	},
+	"aws_proxy_port": {
+		Name:        "aws_proxy_port",
+		Type:        TypeInt,
+		Default:     0,
+		Description: "Proxy port for AWS requests",
+	},
+	"aws_proxy_scheme": {
+		Name:        "aws_proxy_scheme",
+		Type:        TypeString,
+		Default:     "https",
+		Description: "Proxy HTTP scheme for AWS requests",
+	},
+	"aws_proxy_username": {
+		Name:        "aws_proxy_username",
+		Type:        TypeString,
+		Default:     "",
+		Description: "Proxy username for AWS requests",
+	},
+	"aws_region": {
+		Name:        "aws_region",
+		Type:        TypeString,
+		Default:     "",
+		Description: "AWS region",
+	},
+	"aws_secret_access_key": {
+		Name:        "aws_secret_access_key",
+		Type:        TypeString,
+		Default:     "",
+		Description: "AWS secret access key",
+		Sensitive:   true,
+	},
+	"aws_session_token": {
+		Name:        "aws_session_token",
+		Type:        TypeString,
+		Default:     "",
+		Description: "AWS session token",
+		Sensitive:   true,
+	},
+	"aws_sts_arn_role": {
+		Name:        "aws_sts_arn_role",
+		Type:        TypeString,
+		Default:     "",
+		Description: "AWS STS ARN role",
+	},
+	"aws_sts_region": {
+		Name:        "aws_sts_region",
+		Type:        TypeString,
+		Default:     "",
+		Description: "AWS STS region",
+	},
+	"aws_sts_session_name": {
+		Name:        "aws_sts_session_name",
+		Type:        TypeString,
+		Default:     "default",
+		Description: "AWS STS session name",
+	},
+	"aws_sts_timeout": {
+		Name:        "aws_sts_timeout",
+		Type:        TypeInt,
+		Default:     3600,
+		Description: "AWS STS assume role credential validity in seconds",
+	},
+	"buffered_log_max": {
+		Name:        "buffered_log_max",
+		Type:        TypeInt,
+		Default:     1000000,
+		Description: "Maximum number of logs buffered by a buffered logger plugin, 0 is unlimited",
+		Runtime:     true,
+	},
+	"carver_block_size": {
+		Name:        "carver_block_size",
+		Type:        TypeInt,
+		Default:     8192,
+		Description: "Size of blocks used for POSTing carved files",
+		Runtime:     true,
+	},
+	"carver_compression": {
+		Name:        "carver_compression",
+		Type:        TypeBool,
+		Default:     false,
+		Description: "Compress archives using zstd before uploading",
+		Runtime:     true,
+	},
+	"carver_continue_endpoint": {
+		Name:        "carver_continue_endpoint",
+		Type:        TypeString,
+		Default:     "",
+		Description: "TLS/HTTPS endpoint that receives carved content",
+	},
+	"carver_disable_function": {
+		Name:        "carver_disable_function",
+		Type:        TypeBool,
+		Default:     true,
+		Description: "Disable the osquery file carver function",
+		Runtime:     true,
+	},
+	"carver_start_endpoint": {
+		Name:        "carver_start_endpoint",
+		Type:        TypeString,
+		Default:     "",
+		Description: "TLS/HTTPS init endpoint for forensic carver",
+	},
+	"config_accelerated_refresh": {
+		Name:        "config_accelerated_refresh",
+		Type:        TypeInt,
+		Default:     300,
+		Description: "Interval to wait if reading a configuration fails",
+		Runtime:     true,
+	},
+	"config_check": {
+		Name:        "config_check",
+		Type:        TypeBool,
+		Default:     false,
+		Description: "Check the format of an osquery config and exit",
+	},
+	"config_dump": {
+		Name:        "config_dump",
+		Type:        TypeBool,
+		Default:     false,
+		Description: "Dump the contents of the configuration",
+	},
+	"config_enable_backup": {
+		Name:        "config_enable_backup",
+		Type:        TypeBool,
+		Default:     false,
+		Description: "Backup config and use it when refresh fails",
+	},
+	"config_path": {
+		Name:        "config_path",
+		Type:        TypeString,
+		Default:     "/etc/osquery/osquery.conf",
+		Description: "Path to JSON config file",
+	},
+	"config_plugin": {
+		Name:        "config_plugin",
+		Type:        TypeString,
+		Default:     "filesystem",
+		Description: "Config plugin name",
+	},
+	"config_refresh": {
+		Name:        "config_refresh",
+		Type:        TypeInt,
+		Default:     0,
+		Description: "Optional interval in seconds to re-read configuration",
+		Runtime:     true,
+	},
+	"config_tls_endpoint": {
+		Name:        "config_tls_endpoint",
+		Type:        TypeString,
+		Default:     "",
+		Description: "TLS/HTTPS endpoint for config retrieval",
+	},
+	"config_tls_max_attempts": {
+		Name:        "config_tls_max_attempts",
+		Type:        TypeInt,
+		Default:     3,
+		Description: "Number of attempts to retry a TLS config request",
+	},
+	"csv": {
+		Name:        "csv",
+		Type:        TypeBool,
+		Default:     false,
+		Description: "Set output mode to 'csv'",
+	},
+	"daemonize": {
+		Name:        "daemonize",
+		Type:        TypeBool,
+		Default:     false,
+		Description: "Run as daemon",
+	},
+	"database_dump": {
+		Name:        "database_dump",
+		Type:        TypeBool,
+		Default:     false,
+		Description: "Dump the contents of the backing store",
+	},
+	"database_path": {
+		Name:        "database_path",
+		Type:        TypeString,
+		Default:     "/var/osquery/osquery.db",
+		Description: "If using a disk-based backing store, specify a path",
+	},
+	"decorations_top_level": {
+		Name:        "decorations_top_level",
+		Type:        TypeBool,
+		Default:     false,
+		Description: "Add decorators as top level JSON objects",
+		Runtime:     true,
+	},
+	"disable_audit": {
+		Name:        "disable_audit",
+		Type:        TypeBool,
+		Default:     true,
+		Description: "Disable receiving events from the audit subsystem",
+		Platforms:   []string{"linux", "darwin"},
+	},
+	"disable_caching": {
+		Name:        "disable_caching",
+		Type:        TypeBool,
+		Default:     false,
+		Description: "Disable scheduled query caching",
+		Runtime:     true,
+	},
+	"disable_carver": {
+		Name:        "disable_carver",
+		Type:        TypeBool,
+		Default:     true,
+		Description: "Disable the osquery file carver",
+	},
+	"disable_database": {
+		Name:        "disable_database",
+		Type:        TypeBool,
+		Default:     false,
+		Description: "Disable the persistent RocksDB storage",
+	},
+	"disable_decorators": {
+		Name:        "disable_decorators",
+		Type:        TypeBool,
+		Default:     false,
+		Description: "Disable log result decoration",
+		Runtime:     true,
+	},
+	"disable_distributed": {
+		Name:        "disable_distributed",
+		Type:        TypeBool,
+		Default:     true,
+		Description: "Disable distributed queries",
+	},
+	"disable_enrollment": {
+		Name:        "disable_enrollment",
+		Type:        TypeBool,
+		Default:     false,
+		Description: "Disable enrollment functions on related config/logger plugins",
+	},
+	"disable_events": {
+		Name:        "disable_events",
+		Type:        TypeBool,
+		Default:     false,
+		Description: "Disable osquery publish/subscribe system",
+	},
+	"disable_extensions": {
+		Name:        "disable_extensions",
+		Type:        TypeBool,
+		Default:     false,
+		Description: "Disable extension API",
+	},
+	"disable_forensic": {
+		Name:        "disable_forensic",
+		Type:        TypeBool,
+		Default:     true,
+		Description: "Disable forensic table functions",
+	},
+	"disable_hash_cache": {
+		Name:        "disable_hash_cache",
+		Type:        TypeBool,
+		Default:     false,
+		Description: "Cache calculated file hashes, re-calculate only if inode times change",
+	},
+	"disable_kernel": {
+		Name:        "disable_kernel",
+		Type:        TypeBool,
+		Default:     false,
+		Description: "Disable osquery kernel extension",
+		Platforms:   []string{"darwin"},
+	},
+	"disable_logging": {
+		Name:        "disable_logging",
+		Type:        TypeBool,
+		Default:     false,
+		Description: "Disable ERROR/INFO logging",
+		Runtime:     true,
+	},
+	"disable_memory": {
+		Name:        "disable_memory",
+		Type:        TypeBool,
+		Default:     false,
+		Description: "Disable physical memory reads",
+	},
+	"disable_reenrollment": {
+		Name:        "disable_reenrollment",
+		Type:        TypeBool,
+		Default:     false,
+		Description: "Disable re-enrollment attempts if related plugins return invalid",
+	},
+	"disable_tables": {
+		Name:        "disable_tables",
+		Type:        TypeString,
+		Default:     "",
+		Description: "Comma-delimited list of table names to be disabled",
+	},
+	"disable_watchdog": {
+		Name:        "disable_watchdog",
+		Type:        TypeBool,
+		Default:     false,
+		Description: "Disable userland watchdog process",
+	},
+	"distributed_interval": {
+		Name:        "distributed_interval",
+		Type:        TypeInt,
+		Default:     60,
+		Description: "Seconds between polling for new queries",
+		Runtime:     true,
+	},
+	"distributed_plugin": {
+		Name:        "distributed_plugin",
+		Type:        TypeString,
+		Default:     "tls",
+		Description: "Distributed plugin name",
+	},
+	"distributed_tls_max_attempts": {
+		Name:        "distributed_tls_max_attempts",
+		Type:        TypeInt,
+		Default:     3,
+		Description: "Number of times to attempt a request",
+		Runtime:     true,
+	},
+	"distributed_tls_read_endpoint": {
+		Name:        "distributed_tls_read_endpoint",
+		Type:        TypeString,
+		Default:     "",
+		Description: "URI path to use for retrieving distributed queries",
+	},
+	"distributed_tls_write_endpoint": {
+		Name:        "distributed_tls_write_endpoint",
+		Type:        TypeString,
+		Default:     "",
+		Description: "URI path to use for writing distributed query results",
+	},
+	"docker_socket": {
+		Name:        "docker_socket",
+		Type:        TypeString,
+		Default:     "/var/run/docker.sock",
+		Description: "Docker UNIX domain socket path",
+		Platforms:   []string{"linux", "darwin", "freebsd"},
+	},
+	"enable_file_events": {
+		Name:        "enable_file_events",
+		Type:        TypeBool,
+		Default:     false,
+		Description: "Enable the file events publishers",
+		Platforms:   []string{"linux", "darwin", "freebsd"},
+	},
+	"enable_foreign": {
+		Name:        "enable_foreign",
+		Type:        TypeBool,
+		Default:     false,
+		Description: "Enable no-op foreign virtual tables",
+	},
+	"enable_keyboard_events": {
+		Name:        "enable_keyboard_events",
+		Type:        TypeBool,
+		Default:     false,
+		Description: "Enable listening for keyboard events",
+		Platforms:   []string{"darwin"},
+	},
+	"enable_monitor": {
+		Name:        "enable_monitor",
+		Type:        TypeBool,
+		Default:     false,
+		Description: "Enable the schedule monitor",
+	},
+	"enable_mouse_events": {
+		Name:        "enable_mouse_events",
+		Type:        TypeBool,
+		Default:     false,
+		Description: "Enable listening for mouse events",
+		Platforms:   []string{"darwin"},
+	},
+	"enable_ntfs_event_publisher": {
+		Name:        "enable_ntfs_event_publisher",
+		Type:        TypeBool,
+		Default:     false,
+		Description: "Enable the NTFS event publisher",
+		Platforms:   []string{"windows"},
+	},
+	"enable_powershell_events_subscriber": {
+		Name:        "enable_powershell_events_subscriber",
+		Type:        TypeBool,
+		Default:     false,
+		Description: "Enable the PowerShell events subscriber",
+		Platforms:   []string{"windows"},
+	},
+	"enable_syslog": {
+		Name:        "enable_syslog",
+		Type:        TypeBool,
+		Default:     false,
+		Description: "Enable the syslog ingestion event publisher",
+		Platforms:   []string{"linux"},
+	},
+	"enable_windows_events_publisher": {
+		Name:        "enable_windows_events_publisher",
+		Type:        TypeBool,
+		Default:     false,
+		Description: "Enable the Windows events publisher",
+		Platforms:   []string{"windows"},
+	},
+	"enable_windows_events_subscriber": {
+		Name:        "enable_windows_events_subscriber",
+		Type:        TypeBool,
+		Default:     false,
+		Description: "Enable Windows Event Log events",
+		Platforms:   []string{"windows"},
+	},
+	"enroll_always": {
+		Name:        "enroll_always",
+		Type:        TypeBool,
+		Default:     false,
+		Description: "On startup, send a new enrollment request",
+	},
+	"enroll_secret_env": {
+		Name:        "enroll_secret_env",
+		Type:        TypeString,
+		Default:     "",
+		Description: "Name of environment variable holding enrollment-auth secret",
+	},
+	"enroll_secret_path": {
+		Name:        "enroll_secret_path",
+		Type:        TypeString,
+		Default:     "",
+		Description: "Path to an optional client enrollment-auth secret",
+	},
+	"enroll_tls_endpoint": {
+		Name:        "enroll_tls_endpoint",
+		Type:        TypeString,
+		Default:     "",
+		Description: "TLS/HTTPS endpoint for client enrollment",
+	},
+	"ephemeral": {
+		Name:        "ephemeral",
+		Type:        TypeBool,
+		Default:     false,
+		Description: "Skip pidfile and database state checks",
+	},
+	"events_expiry": {
+		Name:        "events_expiry",
+		Type:        TypeInt,
+		Default:     3600,
+		Description: "Timeout to expire event subscriber results",
+		Runtime:     true,
+	},
+	"events_max": {
+		Name:        "events_max",
+		Type:        TypeInt,
+		Default:     50000,
+		Description: "Maximum number of events per type to buffer",
+		Runtime:     true,
+	},
+	"events_optimize": {
+		Name:        "events_optimize",
+		Type:        TypeBool,
+		Default:     true,
+		Description: "Optimize subscriber select queries (scheduler only)",
+		Runtime:     true,
+	},
+	"extensions_autoload": {
+		Name:        "extensions_autoload",
+		Type:        TypeString,
+		Default:     "/etc/osquery/extensions.load",
+		Description: "Optional path to a list of autoloaded and managed extensions",
+	},
+	"extensions_default_index": {
+		Name:        "extensions_default_index",
+		Type:        TypeBool,
+		Default:     true,
+		Description: "Enable INDEX on all extension table columns",
+	},
+	"extensions_interval": {
+		Name:        "extensions_interval",
+		Type:        TypeInt,
+		Default:     3,
+		Description: "Seconds delay between connectivity checks",
+	},
+	"extensions_require": {
+		Name:        "extensions_require",
+		Type:        TypeString,
+		Default:     "",
+		Description: "Comma-separated list of required extensions",
+	},
+	"extensions_socket": {
+		Name:        "extensions_socket",
+		Type:        TypeString,
+		Default:     "/var/osquery/osquery.em",
+		Description: "Path to the extensions UNIX domain socket",
+	},
+	"extensions_timeout": {
+		Name:        "extensions_timeout",
+		Type:        TypeInt,
+		Default:     3,
+		Description: "Seconds to wait for autoloaded extensions",
+	},
+	"flagfile": {
+		Name:        "flagfile",
+		Type:        TypeString,
+		Default:     "",
+		Description: "Line-delimited file of additional flags",
+	},
+	"force": {
+		Name:        "force",
+		Type:        TypeBool,
+		Default:     false,
+		Description: "Force osqueryd to kill previously-running daemons",
+	},
+	"hardware_disabled_types": {
+		Name:        "hardware_disabled_types",
+		Type:        TypeString,
+		Default:     "partition",
+		Description: "List of disabled hardware event types",
+		Platforms:   []string{"linux"},
+	},
+	"hash_cache_max": {
+		Name:        "hash_cache_max",
+		Type:        TypeInt,
+		Default:     500,
+		Description: "Size of LRU file hash cache",
+		Runtime:     true,
+	},
+	"hash_delay": {
+		Name:        "hash_delay",
+		Type:        TypeInt,
+		Default:     20,
+		Description: "Number of milliseconds to delay after hashing",
+		Runtime:     true,
+	},
+	"header": {
+		Name:        "header",
+		Type:        TypeBool,
+		Default:     true,
+		Description: "Toggle the column header line",
+	},
+	"host_identifier": {
+		Name:        "host_identifier",
+		Type:        TypeString,
+		Default:     "hostname",
+		Description: "Field used to identify the host running osquery (hostname, uuid, instance, ephemeral, specified)",
+	},
+	"json": {
+		Name:        "json",
+		Type:        TypeBool,
+		Default:     false,
+		Description: "Set output mode to 'json'",
+	},
+	"line": {
+		Name:        "line",
+		Type:        TypeBool,
+		Default:     false,
+		Description: "Set output mode to 'line'",
+	},
+	"list": {
+		Name:        "list",
+		Type:        TypeBool,
+		Default:     false,
+		Description: "Set output mode to 'list'",
+	},
+	"logger_event_type": {
+		Name:        "logger_event_type",
+		Type:        TypeBool,
+		Default:     true,
+		Description: "Log scheduled results as events",
+		Runtime:     true,
+	},
+	"logger_kafka_acks": {
+		Name:        "logger_kafka_acks",
+		Type:        TypeString,
+		Default:     "all",
+		Description: "The number of acknowledgments the leader has to receive (0, 1, 'all')",
+	},
+	"logger_kafka_brokers": {
+		Name:        "logger_kafka_brokers",
+		Type:        TypeString,
+		Default:     "localhost",
+		Description: "Bootstrap broker(s) as a comma-separated list of host or host:port",
+	},
+	"logger_kafka_compression": {
+		Name:        "logger_kafka_compression",
+		Type:        TypeString,
+		Default:     "none",
+		Description: "Compression codec to use for compressing message sets ('none' or 'gzip')",
+	},
+	"logger_kafka_topic": {
+		Name:        "logger_kafka_topic",
+		Type:        TypeString,
+		Default:     "osquery",
+		Description: "Kafka topic to publish logs under",
+	},
+	"logger_min_status": {
+		Name:        "logger_min_status",
+		Type:        TypeInt,
+		Default:     0,
+		Description: "Minimum level for status log recording",
+		Runtime:     true,
+	},
+	"logger_min_stderr": {
+		Name:        "logger_min_stderr",
+		Type:        TypeInt,
+		Default:     0,
+		Description: "Minimum level for statuses written to stderr",
+		Runtime:     true,
+	},
+	"logger_mode": {
+		Name:        "logger_mode",
+		Type:        TypeInt,
+		Default:     416,
+		Description: "Octal mode for log files, given here in decimal",
+	},
+	"logger_path": {
+		Name:        "logger_path",
+		Type:        TypeString,
+		Default:     "/var/log/osquery/",
+		Description: "Directory path for ERROR/WARN/INFO and results logging",
+	},
+	"logger_plugin": {
+		Name:        "logger_plugin",
+		Type:        TypeString,
+		Default:     "filesystem",
+		Description: "Logger plugin name",
+	},
+	"logger_rotate": {
+		Name:        "logger_rotate",
+		Type:        TypeBool,
+		Default:     false,
+		Description: "Use log file rotation",
+	},
+	"logger_rotate_max_files": {
+		Name:        "logger_rotate_max_files",
+		Type:        TypeInt,
+		Default:     25,
+		Description: "Number of files to keep when rotating",
+	},
+	"logger_rotate_size": {
+		Name:        "logger_rotate_size",
+		Type:        TypeInt,
+		Default:     26214400,
+		Description: "Size in bytes after which log files are rotated",
+	},
+	"logger_secondary_status_only": {
+		Name:        "logger_secondary_status_only",
+		Type:        TypeBool,
+		Default:     false,
+		Description: "Only send status logs to secondary logger plugins",
+	},
+	"logger_snapshot_event_type": {
+		Name:        "logger_snapshot_event_type",
+		Type:        TypeBool,
+		Default:     false,
+		Description: "Log scheduled snapshot results as events",
+		Runtime:     true,
+	},
+	"logger_status_sync": {
+		Name:        "logger_status_sync",
+		Type:        TypeBool,
+		Default:     false,
+		Description: "Always send status logs synchronously",
+	},
+	"logger_syslog_facility": {
+		Name:        "logger_syslog_facility",
+		Type:        TypeInt,
+		Default:     19,
+		Description: "Syslog facility for status and results logs",
+		Platforms:   []string{"linux", "darwin", "freebsd"},
+	},
+	"logger_syslog_prepend_cee": {
+		Name:        "logger_syslog_prepend_cee",
+		Type:        TypeBool,
+		Default:     false,
+		Description: "Prepend @cee: tag to logged JSON messages",
+		Platforms:   []string{"linux", "darwin", "freebsd"},
+	},
+	"logger_tls_compress": {
+		Name:        "logger_tls_compress",
+		Type:        TypeBool,
+		Default:     false,
+		Description: "GZip compress TLS/HTTPS request body",
+		Runtime:     true,
+	},
+	"logger_tls_endpoint": {
+		Name:        "logger_tls_endpoint",
+		Type:        TypeString,
+		Default:     "",
+		Description: "TLS/HTTPS endpoint for results logging",
+	},
+	"logger_tls_max": {
+		Name:        "logger_tls_max",
+		Type:        TypeInt,
+		Default:     1048576,
+		Description: "Max size in bytes allowed per log line",
+		Runtime:     true,
+	},
+	"logger_tls_period": {
+		Name:        "logger_tls_period",
+		Type:        TypeInt,
+		Default:     4,
+		Description: "Seconds between flushing logs over TLS/HTTPS",
+		Runtime:     true,
+	},
+	"logtostderr": {
+		Name:        "logtostderr",
+		Type:        TypeBool,
+		Default:     true,
+		Description: "Log messages to stderr in addition to the logger plugin(s)",
+	},
+	"pack_delimiter": {
+		Name:        "pack_delimiter",
+		Type:        TypeString,
+		Default:     "_",
+		Description: "Delimiter for pack and query names",
+		Runtime:     true,
+	},
+	"pack_refresh_interval": {
+		Name:        "pack_refresh_interval",
+		Type:        TypeInt,
+		Default:     3600,
+		Description: "Cache expiration for a packs discovery queries",
+		Runtime:     true,
+	},
+	"pidfile": {
+		Name:        "pidfile",
+		Type:        TypeString,
+		Default:     "/var/osquery/osqueryd.pidfile",
+		Description: "Path to the daemon pidfile mutex",
+	},
+	"read_max": {
+		Name:        "read_max",
+		Type:        TypeInt,
+		Default:     52428800,
+		Description: "Maximum file read size",
+		Runtime:     true,
+	},
+	"schedule_default_interval": {
+		Name:        "schedule_default_interval",
+		Type:        TypeInt,
+		Default:     3600,
+		Description: "Query interval to use if none is provided",
+		Runtime:     true,
+	},
+	"schedule_max_drift": {
+		Name:        "schedule_max_drift",
+		Type:        TypeInt,
+		Default:     60,
+		Description: "Max time drift in seconds the scheduler tries to compensate for",
+		Runtime:     true,
+	},
+	"schedule_splay_percent": {
+		Name:        "schedule_splay_percent",
+		Type:        TypeInt,
+		Default:     10,
+		Description: "Percent to splay config times",
+		Runtime:     true,
+	},
+	"schedule_timeout": {
+		Name:        "schedule_timeout",
+		Type:        TypeInt,
+		Default:     0,
+		Description: "Limit the schedule, 0 for no limit",
+		Runtime:     true,
+	},
+	"specified_identifier": {
+		Name:        "specified_identifier",
+		Type:        TypeString,
+		Default:     "",
+		Description: "Field used to specify the host_identifier when set to \"specified\"",
+	},
+	"syslog_events_expiry": {
+		Name:        "syslog_events_expiry",
+		Type:        TypeInt,
+		Default:     30,
+		Description: "Timeout to expire syslog events",
+		Platforms:   []string{"linux"},
+		Runtime:     true,
+	},
+	"syslog_events_max": {
+		Name:        "syslog_events_max",
+		Type:        TypeInt,
+		Default:     100000,
+		Description: "Maximum number of syslog events to buffer",
+		Platforms:   []string{"linux"},
+		Runtime:     true,
+	},
+	"syslog_pipe_path": {
+		Name:        "syslog_pipe_path",
+		Type:        TypeString,
+		Default:     "/var/osquery/syslog_pipe",
+		Description: "Path to the named pipe used for forwarding rsyslog events",
+		Platforms:   []string{"linux"},
+	},
+	"syslog_rate_limit": {
+		Name:        "syslog_rate_limit",
+		Type:        TypeInt,
+		Default:     100,
+		Description: "Maximum number of lines to read from pipe each cycle",
+		Platforms:   []string{"linux"},
+	},
+	"table_delay": {
+		Name:        "table_delay",
+		Type:        TypeInt,
+		Default:     0,
+		Description: "Add an optional microsecond delay between table scans",
+		Runtime:     true,
+	},
+	"thrift_timeout": {
+		Name:        "thrift_timeout",
+		Type:        TypeInt,
+		Default:     1,
+		Description: "Thrift client timeout for extension communication",
+	},
+	"tls_client_cert": {
+		Name:        "tls_client_cert",
+		Type:        TypeString,
+		Default:     "",
+		Description: "Optional path to a TLS client-auth PEM certificate",
+	},
+	"tls_client_key": {
+		Name:        "tls_client_key",
+		Type:        TypeString,
+		Default:     "",
+		Description: "Optional path to a TLS client-auth PEM private key",
+	},
+	"tls_dump": {
+		Name:        "tls_dump",
+		Type:        TypeBool,
+		Default:     false,
+		Description: "Print remote requests and responses",
+	},
+	"tls_enroll_max_attempts": {
+		Name:        "tls_enroll_max_attempts",
+		Type:        TypeInt,
+		Default:     12,
+		Description: "The total number of attempts that will be made to the enroll endpoint",
+	},
+	"tls_enroll_max_interval": {
+		Name:        "tls_enroll_max_interval",
+		Type:        TypeInt,
+		Default:     3600,
+		Description: "Maximum wait time in seconds between enroll retry attempts",
+	},
+	"tls_hostname": {
+		Name:        "tls_hostname",
+		Type:        TypeString,
+		Default:     "",
+		Description: "TLS/HTTPS hostname for Config, Logger, and Enroll plugins",
+	},
+	"tls_server_certs": {
+		Name:        "tls_server_certs",
+		Type:        TypeString,
+		Default:     "",
+		Description: "Optional path to a TLS server PEM certificate(s) bundle",
+	},
+	"tls_session_reuse": {
+		Name:        "tls_session_reuse",
+		Type:        TypeBool,
+		Default:     true,
+		Description: "Reuse TLS session sockets",
+	},
+	"tls_session_timeout": {
+		Name:        "tls_session_timeout",
+		Type:        TypeInt,
+		Default:     3600,
+		Description: "TLS session keep alive timeout in seconds",
+	},
+	"utc": {
+		Name:        "utc",
+		Type:        TypeBool,
+		Default:     true,
+		Description: "Convert all UNIX times to UTC",
+	},
+	"verbose": {
+		Name:        "verbose",
+		Type:        TypeBool,
+		Default:     false,
+		Description: "Enable verbose informational messages",
+		Runtime:     true,
+	},
+	"watchdog_delay": {
+		Name:        "watchdog_delay",
+		Type:        TypeInt,
+		Default:     60,
+		Description: "Initial delay in seconds before watchdog starts enforcing limits",
+	},
+	"watchdog_level": {
+		Name:        "watchdog_level",
+		Type:        TypeInt,
+		Default:     0,
+		Description: "Performance limit level (0=normal, 1=restrictive, -1=off)",
+	},
+	"watchdog_memory_limit": {
+		Name:        "watchdog_memory_limit",
+		Type:        TypeInt,
+		Default:     0,
+		Description: "Override watchdog profile memory limit (e.g., 300, for 300MB)",
+	},
+	"watchdog_utilization_limit": {
+		Name:        "watchdog_utilization_limit",
+		Type:        TypeInt,
+		Default:     0,
+		Description: "Override watchdog profile CPU utilization limit",
+	},
+	"windows_event_channels": {
+		Name:        "windows_event_channels",
+		Type:        TypeString,
+		Default:     "System,Application,Setup,Security",
+		Description: "Comma-separated list of Windows event log channels",
+		Platforms:   []string{"windows"},
+	},
+	"worker_threads": {
+		Name:        "worker_threads",
+		Type:        TypeInt,
+		Default:     4,
+		Description: "Number of work dispatch threads",
+	},
+	"yara_delay": {
+		Name:        "yara_delay",
+		Type:        TypeInt,
+		Default:     50,
+		Description: "Time in ms to sleep after scan of each file (default 50) to reduce memory spikes",
+		Platforms:   []string{"linux", "darwin", "freebsd"},
+		Runtime:     true,
+	},
+	"yara_malloc_trim": {
+		Name:        "yara_malloc_trim",
+		Type:        TypeBool,
+		Default:     true,
+		Description: "Run malloc_trim after yara scans of files",
+		Platforms:   []string{"linux"},
+		Runtime:     true,
+	},
+}
@@ -0,0 +1,110 @@
+//go:build ignore
+// +build ignore
+
+// gen.go generates flags_gen.go from flags.json
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"log"
+	"sort"
+)
+
+type flag struct {
+	Name        string      `json:"name"`
+	Type        string      `json:"type"`
+	Default     interface{} `json:"default"`
+	Description string      `json:"description"`
+	Platforms   []string    `json:"platforms"`
+	Runtime     bool        `json:"runtime"`
+	Sensitive   bool        `json:"sensitive"`
+}
+
+type schema struct {
+	OsqueryVersion string `json:"osquery_version"`
+	Flags          []flag `json:"flags"`
+}
+
+func main() {
+	js, err := ioutil.ReadFile("flags.json")
+	if err != nil {
+		log.Fatal(err)
+	}
+	s := schema{}
+	decoder := json.NewDecoder(bytes.NewReader(js))
+	decoder.UseNumber()
+	if err = decoder.Decode(&s); err != nil {
+		log.Fatal(err)
+	}
+	sort.Slice(s.Flags, func(i, j int) bool { return s.Flags[i].Name < s.Flags[j].Name })
+
+	buf := &bytes.Buffer{}
+	fmt.Fprintln(buf, "// Code generated by gen.go from flags.json. DO NOT EDIT.")
+	fmt.Fprintln(buf)
+	fmt.Fprintln(buf, "package osqueryschema")
+	fmt.Fprintln(buf)
+	fmt.Fprintf(buf, "const osqueryVersion = %q\n\n", s.OsqueryVersion)
+	fmt.Fprintln(buf, "var flags = map[string]Flag{")
+	for _, f := range s.Flags {
+		def, err := literal(f)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Fprintf(buf, "%q: {\nName: %q,\nType: %s,\nDefault: %s,\nDescription: %q,\n", f.Name, f.Name, typeConst(f.Type), def, f.Description)
+		if len(f.Platforms) > 0 {
+			fmt.Fprintf(buf, "Platforms: %#v,\n", f.Platforms)
+		}
+		if f.Runtime {
+			fmt.Fprintln(buf, "Runtime: true,")
+		}
+		if f.Sensitive {
+			fmt.Fprintln(buf, "Sensitive: true,")
+		}
+		fmt.Fprintln(buf, "},")
+	}
+	fmt.Fprintln(buf, "}")
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err = ioutil.WriteFile("flags_gen.go", src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func typeConst(t string) string {
+	switch t {
+	case "bool":
+		return "TypeBool"
+	case "int":
+		return "TypeInt"
+	case "string":
+		return "TypeString"
+	}
+	log.Fatalf("unknown flag type %q", t)
+	return ""
+}
+
+// literal returns the go source for the flag's default, checking it matches the flag's type
+func literal(f flag) (string, error) {
+	switch v := f.Default.(type) {
+	case bool:
+		if f.Type == "bool" {
+			return fmt.Sprintf("%t", v), nil
+		}
+	case string:
+		if f.Type == "string" {
+			return fmt.Sprintf("%q", v), nil
+		}
+	case json.Number:
+		if _, err := v.Int64(); err == nil && f.Type == "int" {
+			return v.String(), nil
+		}
+	}
+	return "", fmt.Errorf("default %v of flag %q is not a %s", f.Default, f.Name, f.Type)
+}
@@ -0,0 +1,141 @@
+// Package osqueryschema describes the flags osquery accepts in the options section of its config.
+// The flags are generated from flags.json, which is refreshed from the osquery release being targeted
+// (run go generate after updating it)
+package osqueryschema
+
+//go:generate go run gen.go
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Type is the type of value a flag takes
+type Type string
+
+const (
+	TypeBool   Type = "bool"
+	TypeInt    Type = "int"
+	TypeString Type = "string"
+)
+
+// Flag describes a single osquery flag
+type Flag struct {
+	Name        string      `json:"name"`
+	Type        Type        `json:"type"`
+	Default     interface{} `json:"default"`
+	Description string      `json:"description"`
+	// Platforms the flag has an effect on, empty for all platforms
+	Platforms []string `json:"platforms,omitempty"`
+	// Runtime is true if osquery applies a change to the flag from a config refresh without restarting
+	Runtime bool `json:"runtime,omitempty"`
+	// Sensitive is true if the value is a secret that should not be shown back to users
+	Sensitive bool `json:"sensitive,omitempty"`
+}
+
+// OsqueryVersion returns the osquery release the bundled schema was taken from
+func OsqueryVersion() string {
+	return osqueryVersion
+}
+
+// Lookup returns the flag with the given name
+func Lookup(name string) (Flag, bool) {
+	f, ok := flags[name]
+	return f, ok
+}
+
+// Flags returns every known flag, sorted by name
+func Flags() []Flag {
+	result := make([]Flag, 0, len(flags))
+	for _, f := range flags {
+		result = append(result, f)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// AppliesTo returns true if the flag has an effect on the given platform
+func (f Flag) AppliesTo(platform string) bool {
+	if len(f.Platforms) == 0 || platform == "" {
+		return true
+	}
+	for _, p := range f.Platforms {
+		if p == platform {
+			return true
+		}
+	}
+	return false
+}
+
+// Check returns an error if v is not a valid value for the flag.  Values are expected as decoded from
+// JSON or DynamoDB, so integers may be any go integer type, a whole float64 or a json.Number
+func (f Flag) Check(v interface{}) error {
+	switch f.Type {
+	case TypeBool:
+		if _, ok := v.(bool); ok {
+			return nil
+		}
+	case TypeString:
+		if _, ok := v.(string); ok {
+			return nil
+		}
+	case TypeInt:
+		if _, ok := ToInt(v); ok {
+			return nil
+		}
+	}
+	return fmt.Errorf("option %q must be a %s, got %T (%v)", f.Name, f.Type, v, v)
+}
+
+// ToInt returns v as an int if it holds a whole number
+func ToInt(v interface{}) (int, bool) {
+	switch n := v.(type) {
+	case int:
+		return n, true
+	case int32:
+		return int(n), true
+	case int64:
+		return int(n), true
+	case uint32:
+		return int(n), true
+	case float64:
+		if n == math.Trunc(n) {
+			return int(n), true
+		}
+	case json.Number:
+		i, err := n.Int64()
+		if err == nil {
+			return int(i), true
+		}
+	}
+	return 0, false
+}
+
+// Validate checks that every option is a known flag with a value of the right type.  All problems
+// are reported together, in name order
+func Validate(options map[string]interface{}) error {
+	names := make([]string, 0, len(options))
+	for name := range options {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	problems := []string{}
+	for _, name := range names {
+		f, ok := Lookup(name)
+		if !ok {
+			problems = append(problems, fmt.Sprintf("unknown option %q", name))
+			continue
+		}
+		if err := f.Check(options[name]); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid options: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
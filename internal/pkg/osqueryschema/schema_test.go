@@ -0,0 +1,37 @@
+package osqueryschema
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	valid := map[string]interface{}{}
+	err := json.Unmarshal([]byte(`{"verbose": true, "config_refresh": 300, "host_identifier": "uuid", "disable_tables": "curl"}`), &valid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = Validate(valid); err != nil {
+		t.Errorf("expected options to be valid: %s", err)
+	}
+
+	invalid := []map[string]interface{}{
+		{"extenstions_autoload": "/etc/osquery/extensions.load"},
+		{"verbose": "true"},
+		{"config_refresh": 1.5},
+		{"host_identifier": 1},
+	}
+	for _, options := range invalid {
+		if err = Validate(options); err == nil {
+			t.Errorf("expected %v to be invalid", options)
+		}
+	}
+}
+
+func TestFlagDefaults(t *testing.T) {
+	for _, f := range Flags() {
+		if err := f.Check(f.Default); err != nil {
+			t.Errorf("default of %s: %s", f.Name, err)
+		}
+	}
+}
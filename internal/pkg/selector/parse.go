@@ -0,0 +1,198 @@
+package selector
+
+import (
+	"fmt"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokenWord tokenKind = iota
+	// tokenString is a "double" or 'single' quoted value, with the quotes removed
+	tokenString
+	tokenOp
+	tokenLParen
+	tokenRParen
+	tokenEnd
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	// pos is the offset of the token in the selector, for error messages
+	pos int
+}
+
+// keyword returns true if the token is the given keyword, which is matched case insensitively
+func (t token) keyword(word string) bool {
+	return t.kind == tokenWord && strings.EqualFold(t.text, word)
+}
+
+// ops are the operators between a field and a value, longest first
+var ops = []string{"!=", "<=", ">=", ":", "=", "<", ">"}
+
+// tokenize splits a selector into tokens
+func tokenize(s string) ([]token, error) {
+	tokens := []token{}
+	i := 0
+	for i < len(s) {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+
+		case c == '(':
+			tokens = append(tokens, token{kind: tokenLParen, text: "(", pos: i})
+			i++
+
+		case c == ')':
+			tokens = append(tokens, token{kind: tokenRParen, text: ")", pos: i})
+			i++
+
+		case c == '"' || c == '\'':
+			text := strings.Builder{}
+			j := i + 1
+			for ; j < len(s) && s[j] != c; j++ {
+				if s[j] == '\\' && j+1 < len(s) {
+					j++
+				}
+				text.WriteByte(s[j])
+			}
+			if j >= len(s) {
+				return nil, fmt.Errorf("unterminated string at %d", i)
+			}
+			tokens = append(tokens, token{kind: tokenString, text: text.String(), pos: i})
+			i = j + 1
+
+		case strings.IndexByte("!<>:=", c) >= 0:
+			op := ""
+			for _, o := range ops {
+				if strings.HasPrefix(s[i:], o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected %q at %d", c, i)
+			}
+			tokens = append(tokens, token{kind: tokenOp, text: op, pos: i})
+			i += len(op)
+
+		default:
+			j := i
+			for j < len(s) && strings.IndexByte(" \t\n\r()\"'!<>:=", s[j]) < 0 {
+				j++
+			}
+			tokens = append(tokens, token{kind: tokenWord, text: s[i:j], pos: i})
+			i = j
+		}
+	}
+	return append(tokens, token{kind: tokenEnd, pos: len(s)}), nil
+}
+
+// parser is a recursive descent parser for the grammar
+//
+//	expr    = and { "OR" and }
+//	and     = not { "AND" not }
+//	not     = "NOT" not | primary
+//	primary = "(" expr ")" | field op value
+type parser struct {
+	tokens []token
+	i      int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.i]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.i]
+	if t.kind != tokenEnd {
+		p.i++
+	}
+	return t
+}
+
+func (p *parser) expr() (node, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().keyword("or") {
+		p.next()
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) and() (node, error) {
+	left, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().keyword("and") {
+		p.next()
+		right, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) not() (node, error) {
+	if p.peek().keyword("not") {
+		p.next()
+		n, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{n}, nil
+	}
+	return p.primary()
+}
+
+func (p *parser) primary() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenLParen:
+		n, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokenRParen {
+			return nil, fmt.Errorf("expected ) at %d", closing.pos)
+		}
+		return n, nil
+	case tokenWord:
+		if t.keyword("and") || t.keyword("or") {
+			return nil, fmt.Errorf("unexpected %s at %d", t.text, t.pos)
+		}
+		return p.term(t)
+	case tokenEnd:
+		return nil, fmt.Errorf("unexpected end of selector")
+	}
+	return nil, fmt.Errorf("unexpected %q at %d", t.text, t.pos)
+}
+
+func (p *parser) term(field token) (node, error) {
+	f, err := lookupField(field.text)
+	if err != nil {
+		return nil, fmt.Errorf("%s at %d", err, field.pos)
+	}
+	op := p.next()
+	if op.kind != tokenOp {
+		return nil, fmt.Errorf("expected an operator after %s at %d", field.text, op.pos)
+	}
+	value := p.next()
+	if value.kind != tokenWord && value.kind != tokenString {
+		return nil, fmt.Errorf("expected a value after %s%s at %d", field.text, op.text, value.pos)
+	}
+	return termNode{field: f, op: op.text, value: value.text}, nil
+}
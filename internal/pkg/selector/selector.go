@@ -0,0 +1,266 @@
+// Package selector parses and evaluates node selectors, expressions that pick out nodes by their
+// tags, platform, config and enrollment details, for example
+//
+//	tag:prod AND platform:darwin AND NOT config:legacy AND host.os_version >= 12
+//
+// A term is a field, an operator and a value.  ":" matches a value, with * matching any run of
+// characters, and = and != compare values exactly.  <, <=, > and >= compare versions (dotted numbers,
+// compared part by part) when both sides are versions, and text otherwise.  Terms are combined with
+// AND, OR, NOT and parentheses, and values containing spaces or operators can be quoted
+package selector
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/oktasecuritylabs/sgt/osquery_types"
+)
+
+// Selector is a parsed selector
+type Selector struct {
+	source string
+	root   node
+}
+
+// Parse parses a selector
+func Parse(s string) (*Selector, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, fmt.Errorf("selector is empty")
+	}
+	tokens, err := tokenize(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector: %s", err)
+	}
+	p := parser{tokens: tokens}
+	root, err := p.expr()
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector: %s", err)
+	}
+	if t := p.peek(); t.kind != tokenEnd {
+		return nil, fmt.Errorf("invalid selector: unexpected %q at %d", t.text, t.pos)
+	}
+	return &Selector{source: s, root: root}, nil
+}
+
+// String returns the selector as it was written
+func (s *Selector) String() string {
+	return s.source
+}
+
+// Matches returns true if the node is selected
+func (s *Selector) Matches(oc osquery_types.OsqueryClient) bool {
+	return s.root.eval(oc)
+}
+
+// Filter returns the selected nodes
+func (s *Selector) Filter(clients []osquery_types.OsqueryClient) []osquery_types.OsqueryClient {
+	selected := []osquery_types.OsqueryClient{}
+	for _, oc := range clients {
+		if s.Matches(oc) {
+			selected = append(selected, oc)
+		}
+	}
+	return selected
+}
+
+type node interface {
+	eval(oc osquery_types.OsqueryClient) bool
+}
+
+type andNode struct{ left, right node }
+
+func (n andNode) eval(oc osquery_types.OsqueryClient) bool {
+	return n.left.eval(oc) && n.right.eval(oc)
+}
+
+type orNode struct{ left, right node }
+
+func (n orNode) eval(oc osquery_types.OsqueryClient) bool {
+	return n.left.eval(oc) || n.right.eval(oc)
+}
+
+type notNode struct{ n node }
+
+func (n notNode) eval(oc osquery_types.OsqueryClient) bool {
+	return !n.n.eval(oc)
+}
+
+// termNode is true if any of the field's values satisfies the operator, except for != which is true
+// if none of them equal the value
+type termNode struct {
+	field field
+	op    string
+	value string
+}
+
+func (n termNode) eval(oc osquery_types.OsqueryClient) bool {
+	values := n.field.values(oc)
+	if n.op == "!=" {
+		return !termNode{field: n.field, op: "=", value: n.value}.eval(oc)
+	}
+	for _, v := range values {
+		if n.field.match(n.op, v, n.value) {
+			return true
+		}
+	}
+	return false
+}
+
+// field is something a selector can test about a node
+type field struct {
+	name   string
+	values func(oc osquery_types.OsqueryClient) []string
+	// matchValue overrides how ":" matches, for platforms which have groupings like posix
+	matchValue func(v, pattern string) bool
+}
+
+func (f field) match(op, v, value string) bool {
+	switch op {
+	case ":":
+		if f.matchValue != nil {
+			return f.matchValue(v, value)
+		}
+		return globMatch(value, v)
+	case "=":
+		return v == value
+	}
+	c := compare(v, value)
+	switch op {
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	}
+	return false
+}
+
+func single(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return []string{s}
+}
+
+// fields are the named fields.  Enrollment details are read with host., see hostField
+var fields = map[string]field{
+	"tag": {values: func(oc osquery_types.OsqueryClient) []string { return oc.Tags }},
+	"platform": {
+		values: func(oc osquery_types.OsqueryClient) []string { return single(oc.RenderTarget().Platform) },
+		matchValue: func(v, pattern string) bool {
+			return globMatch(pattern, v) || !strings.Contains(pattern, "*") && osquery_types.PlatformMatches(pattern, v)
+		},
+	},
+	"config":          {values: func(oc osquery_types.OsqueryClient) []string { return single(oc.ConfigName) }},
+	"group":           {values: func(oc osquery_types.OsqueryClient) []string { return single(oc.ConfigurationGroup) }},
+	"hostname":        {values: func(oc osquery_types.OsqueryClient) []string { return single(oc.HostName) }},
+	"host_identifier": {values: func(oc osquery_types.OsqueryClient) []string { return single(oc.HostIdentifier) }},
+	"node_key":        {values: func(oc osquery_types.OsqueryClient) []string { return single(oc.NodeKey) }},
+	"pending": {values: func(oc osquery_types.OsqueryClient) []string {
+		return []string{strconv.FormatBool(oc.PendingRegistrationApproval)}
+	}},
+}
+
+// hostAliases are host. fields that are shorthand for a table and column of the enrollment details
+var hostAliases = map[string][2]string{
+	"os_version":      {"os_version", "version"},
+	"osquery_version": {"osquery_info", "version"},
+}
+
+// Fields returns the names of the fields a selector can use
+func Fields() []string {
+	names := []string{}
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return append(names, "host.<table>.<column>", "host.<column>")
+}
+
+func lookupField(name string) (field, error) {
+	if f, ok := fields[strings.ToLower(name)]; ok {
+		f.name = name
+		return f, nil
+	}
+	if strings.HasPrefix(strings.ToLower(name), "host.") {
+		return hostField(name, name[len("host."):])
+	}
+	return field{}, fmt.Errorf("unknown field %q, expected one of %s", name, strings.Join(Fields(), ", "))
+}
+
+// hostField reads the details osquery reported when the node enrolled.  host.<table>.<column> reads a
+// column of a table, host.os_version and host.osquery_version are the OS and osquery versions and
+// host.<column> reads the column from whichever table has it
+func hostField(name, path string) (field, error) {
+	table, column := "", path
+	if alias, ok := hostAliases[path]; ok {
+		table, column = alias[0], alias[1]
+	} else if i := strings.IndexByte(path, '.'); i >= 0 {
+		table, column = path[:i], path[i+1:]
+	}
+	if column == "" || strings.Contains(column, ".") {
+		return field{}, fmt.Errorf("invalid host field %q, expected host.<table>.<column> or host.<column>", name)
+	}
+	return field{name: name, values: func(oc osquery_types.OsqueryClient) []string {
+		if table != "" {
+			if v, ok := oc.HostDetails[table][column]; ok {
+				return []string{v}
+			}
+			return nil
+		}
+		values := []string{}
+		for _, columns := range oc.HostDetails {
+			if v, ok := columns[column]; ok {
+				values = append(values, v)
+			}
+		}
+		return values
+	}}, nil
+}
+
+// globMatch matches s against a pattern where * matches any run of characters
+func globMatch(pattern, s string) bool {
+	if !strings.Contains(pattern, "*") {
+		return pattern == s
+	}
+	parts := strings.Split(pattern, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	return regexp.MustCompile("^" + strings.Join(parts, ".*") + "$").MatchString(s)
+}
+
+var versionRe = regexp.MustCompile(`^\d+(\.\d+)*`)
+
+// compare compares a and b by their leading dotted version numbers, so that values such as
+// "20.04.3 LTS (Focal Fossa)" compare by version.  Values with the same version, and values where
+// neither starts with one, are compared as text
+func compare(a, b string) int {
+	av, bv := versionRe.FindString(a), versionRe.FindString(b)
+	if av == "" && bv == "" {
+		return strings.Compare(a, b)
+	}
+	as, bs := strings.Split(av, "."), strings.Split(bv, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		x, y := 0, 0
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return strings.Compare(a[len(av):], b[len(bv):])
+}
@@ -0,0 +1,105 @@
+package selector
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/oktasecuritylabs/sgt/osquery_types"
+)
+
+var clients = []osquery_types.OsqueryClient{
+	{
+		NodeKey:    "mac-prod",
+		HostName:   "mac-prod.example.com",
+		Tags:       []string{"prod", "web"},
+		ConfigName: "default",
+		HostDetails: map[string]map[string]string{
+			"os_version":   {"platform": "darwin", "version": "12.3.1"},
+			"osquery_info": {"version": "4.9.0"},
+		},
+	},
+	{
+		NodeKey:    "mac-legacy",
+		HostName:   "mac-legacy.example.com",
+		Tags:       []string{"prod"},
+		ConfigName: "legacy",
+		HostDetails: map[string]map[string]string{
+			"os_version":   {"platform": "darwin", "version": "10.15.7"},
+			"osquery_info": {"version": "3.3.2"},
+		},
+	},
+	{
+		NodeKey:            "linux-dev",
+		HostName:           "build 1",
+		Tags:               []string{"dev"},
+		ConfigName:         "default",
+		ConfigurationGroup: "servers",
+		HostDetails: map[string]map[string]string{
+			"os_version":   {"platform": "ubuntu", "version": "20.04.3 LTS (Focal Fossa)"},
+			"osquery_info": {"version": "4.9.0"},
+		},
+	},
+}
+
+func selected(t *testing.T, s string) []string {
+	sel, err := Parse(s)
+	if err != nil {
+		t.Fatalf("%s: %s", s, err)
+	}
+	keys := []string{}
+	for _, oc := range sel.Filter(clients) {
+		keys = append(keys, oc.NodeKey)
+	}
+	return keys
+}
+
+func TestSelector(t *testing.T) {
+	for _, test := range []struct {
+		selector string
+		expected []string
+	}{
+		{"tag:prod AND platform:darwin AND NOT config:legacy AND host.os_version >= 12", []string{"mac-prod"}},
+		{"tag:prod", []string{"mac-prod", "mac-legacy"}},
+		{"tag:dev OR tag:web", []string{"mac-prod", "linux-dev"}},
+		{"not (tag:dev or tag:web)", []string{"mac-legacy"}},
+		{"platform:posix AND NOT platform:darwin", []string{"linux-dev"}},
+		{"platform:ubuntu", []string{"linux-dev"}},
+		{"hostname:mac-*.example.com", []string{"mac-prod", "mac-legacy"}},
+		{`hostname = "build 1"`, []string{"linux-dev"}},
+		{"tag != prod", []string{"linux-dev"}},
+		{"host.osquery_version < 4", []string{"mac-legacy"}},
+		{"host.os_version.version >= 10.15.7 AND host.os_version.version < 12", []string{"mac-legacy"}},
+		{"host.os_version.version >= 20.4 AND host.os_version.version < 20.10", []string{"linux-dev"}},
+		{"host.os_version.version > 9", []string{"mac-prod", "mac-legacy", "linux-dev"}},
+		{"hostname < c", []string{"linux-dev"}},
+		{"host.platform:darwin", []string{"mac-prod", "mac-legacy"}},
+		{"group:servers", []string{"linux-dev"}},
+		{"host.system_info.hostname:*", []string{}},
+		{"tag:prod AND tag:web OR tag:dev", []string{"mac-prod", "linux-dev"}},
+	} {
+		if got := selected(t, test.selector); !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("%s: got %v, expected %v", test.selector, got, test.expected)
+		}
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	for _, bad := range []string{
+		"",
+		"tag",
+		"tag:",
+		"tag:prod AND",
+		"(tag:prod",
+		"tag:prod)",
+		"color:red",
+		"host.a.b.c = 1",
+		`tag:"unterminated`,
+		"tag:prod tag:dev",
+		"AND tag:prod",
+		"tag ! prod",
+	} {
+		if _, err := Parse(bad); err == nil {
+			t.Errorf("%q: expected an error", bad)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+// Package shell is an interactive SQL shell for responders.  Each statement is run as a distributed
+// query campaign on the shell's target nodes through the SGT API, and the nodes' results are printed
+// once they have answered
+package shell
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/oktasecuritylabs/sgt/osquery_types"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// invalidCredentials is the message the API responds with when a token has expired or a login fails
+const invalidCredentials = "Invalid username or password"
+
+// Client calls the SGT API as a user.  The token from Login is sent with every request, and the user
+// is logged in again once if it expires
+type Client struct {
+	URL  string
+	HTTP *http.Client
+
+	token    string
+	username string
+	password string
+}
+
+// NewClient returns a client for the SGT server at serverURL, eg https://sgt.example.com.  insecure
+// skips verifying the server's certificate, for servers using a self signed certificate
+func NewClient(serverURL string, insecure bool) *Client {
+	return &Client{
+		URL: strings.TrimRight(serverURL, "/"),
+		HTTP: &http.Client{
+			Timeout: 30 * time.Second,
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: insecure},
+			},
+		},
+	}
+}
+
+// StartedCampaign is the response to starting a campaign
+type StartedCampaign struct {
+	osquery_types.CampaignStatus
+	UnknownNodeKeys []string `json:"unknown_node_keys"`
+}
+
+// apiError is the body of an API error response
+type apiError struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+// Login gets a token for the user from /api/v1/get-token
+func (c *Client) Login(username, password string) error {
+	body, err := json.Marshal(map[string]string{"username": username, "password": password})
+	if err != nil {
+		return err
+	}
+	resp, err := c.HTTP.Post(c.URL+"/api/v1/get-token", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	token := struct {
+		Authorization string `json:"Authorization"`
+	}{}
+	if err := decodeResponse(resp, &token); err != nil {
+		return fmt.Errorf("login failed: %s", err)
+	}
+	if token.Authorization == "" {
+		return errors.New("login failed: no token in response")
+	}
+	c.token = token.Authorization
+	c.username = username
+	c.password = password
+	return nil
+}
+
+// decodeResponse unmarshals the response body into out, or returns the error the API responded with.
+// The API responds to errors with a 200 and an error status in the body
+func decodeResponse(resp *http.Response, out interface{}) error {
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(body), []byte("{")) {
+		ae := apiError{}
+		if json.Unmarshal(body, &ae) == nil && (ae.Status == "error" || ae.Status == "failed") {
+			return errors.New(ae.Message)
+		}
+	}
+	return json.Unmarshal(body, out)
+}
+
+// call makes an API request under /api/v1/configuration, sending body as JSON if it isn't nil, and
+// unmarshals the response into out
+func (c *Client) call(method, path string, body, out interface{}) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+	for retried := false; ; retried = true {
+		req, err := http.NewRequest(method, c.URL+"/api/v1/configuration"+path, bytes.NewReader(payload))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+c.token)
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		resp, err := c.HTTP.Do(req)
+		if err != nil {
+			return err
+		}
+		err = decodeResponse(resp, out)
+		resp.Body.Close()
+		if err == nil || err.Error() != invalidCredentials || retried || c.username == "" {
+			return err
+		}
+		// the token has expired, tokens last a few hours
+		if err := c.Login(c.username, c.password); err != nil {
+			return err
+		}
+	}
+}
+
+// StartCampaign runs the query on the targeted nodes, which have timeout seconds to answer
+func (c *Client) StartCampaign(query string, targets osquery_types.CampaignTargets, timeout int) (StartedCampaign, error) {
+	started := StartedCampaign{}
+	err := c.call(http.MethodPost, "/distributed/campaigns", map[string]interface{}{
+		"query":   query,
+		"targets": targets,
+		"timeout": timeout,
+	}, &started)
+	return started, err
+}
+
+// Campaign returns the campaign with the state of each of its nodes
+func (c *Client) Campaign(campaignID string) (osquery_types.CampaignStatus, error) {
+	status := osquery_types.CampaignStatus{}
+	err := c.call(http.MethodGet, "/distributed/campaigns/"+url.PathEscape(campaignID), nil, &status)
+	return status, err
+}
+
+// CampaignResults returns the campaign's nodes with the rows of those that have answered
+func (c *Client) CampaignResults(campaignID string) ([]osquery_types.CampaignNode, error) {
+	results := struct {
+		Results []osquery_types.CampaignNode `json:"results"`
+	}{}
+	err := c.call(http.MethodGet, "/distributed/campaigns/"+url.PathEscape(campaignID)+"/results", nil, &results)
+	return results.Results, err
+}
+
+// PasswordEnv is the environment variable the password is read from when the shell isn't run from a
+// terminal, for scripts
+const PasswordEnv = "SGT_PASSWORD"
+
+// ReadPassword returns the password from PasswordEnv if it is set, and otherwise prompts for it on the
+// terminal in
+func ReadPassword(in *os.File) (string, error) {
+	if password := os.Getenv(PasswordEnv); password != "" {
+		return password, nil
+	}
+	fd := int(in.Fd())
+	if !terminal.IsTerminal(fd) {
+		return "", fmt.Errorf("no terminal to read the password from, set %s", PasswordEnv)
+	}
+	fmt.Fprint(os.Stderr, "Password: ")
+	password, err := terminal.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	return string(password), err
+}
@@ -0,0 +1,131 @@
+package shell
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/oktasecuritylabs/sgt/internal/pkg/sqllint"
+)
+
+const (
+	keyCtrlC = 3
+	keyTab   = '\t'
+)
+
+var sqlKeywords = []string{
+	"SELECT", "DISTINCT", "FROM", "WHERE", "JOIN", "LEFT", "CROSS", "USING", "ON", "AND", "OR", "NOT",
+	"LIKE", "GLOB", "IN", "IS", "NULL", "BETWEEN", "GROUP", "BY", "HAVING", "ORDER", "ASC", "DESC",
+	"LIMIT", "AS", "UNION", "ALL", "WITH", "CASE", "WHEN", "THEN", "ELSE", "END", "COUNT",
+}
+
+// completer completes the word before the cursor from the bundled osquery schema when tab is
+// pressed.  After FROM or JOIN it completes table names, and elsewhere the columns of the tables named
+// in the statement, keywords and table names
+type completer struct {
+	// statement returns the lines of the statement typed before the current line
+	statement func() string
+	// list shows the candidates when the word can't be completed any further
+	list func(candidates []string)
+}
+
+func isWordChar(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// candidates returns the completions of word, sorted, given the statement text before it
+func candidates(before, word string) []string {
+	words := strings.FieldsFunc(strings.ToLower(before), func(r rune) bool {
+		return r > 127 || !isWordChar(byte(r))
+	})
+	previous := ""
+	if trimmed := strings.TrimRight(before, " \t\r\n"); len(words) > 0 && trimmed != "" && isWordChar(trimmed[len(trimmed)-1]) {
+		previous = words[len(words)-1]
+	}
+
+	options := map[string]bool{}
+	if strings.HasPrefix(word, ".") && strings.TrimSpace(before) == "" {
+		for _, c := range metaCommands {
+			options[c] = true
+		}
+	} else {
+		for _, t := range sqllint.Tables() {
+			options[t.Name] = true
+		}
+		if previous != "from" && previous != "join" {
+			for _, w := range words {
+				if t, ok := sqllint.LookupTable(w); ok {
+					for _, c := range t.Columns {
+						options[c] = true
+					}
+				}
+			}
+			upper := word != "" && word[0] >= 'A' && word[0] <= 'Z'
+			for _, k := range sqlKeywords {
+				if !upper {
+					k = strings.ToLower(k)
+				}
+				options[k] = true
+			}
+		}
+	}
+
+	matches := []string{}
+	for o := range options {
+		if strings.HasPrefix(strings.ToLower(o), strings.ToLower(word)) {
+			matches = append(matches, o)
+		}
+	}
+	sort.Strings(matches)
+	return matches
+}
+
+// commonPrefix returns the longest prefix the strings share
+func commonPrefix(words []string) string {
+	prefix := words[0]
+	for _, w := range words[1:] {
+		for !strings.HasPrefix(w, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return prefix
+}
+
+// complete is a terminal.Terminal AutoCompleteCallback.  It also clears the line on ctrl-c, which the
+// terminal otherwise ignores
+func (c completer) complete(line string, pos int, key rune) (string, int, bool) {
+	if key == keyCtrlC {
+		return "", 0, true
+	}
+	if key != keyTab {
+		return "", 0, false
+	}
+	start := pos
+	for start > 0 && isWordChar(line[start-1]) {
+		start--
+	}
+	// a dot at the start of a statement begins a shell command, elsewhere it follows a table name
+	if start > 0 && line[start-1] == '.' && strings.TrimSpace(line[:start-1]) == "" {
+		start--
+	}
+	word := line[start:pos]
+	before := line[:start]
+	if c.statement != nil {
+		before = c.statement() + before
+	}
+	matches := candidates(before, word)
+	switch {
+	case len(matches) == 0:
+		return line, pos, true
+	case len(matches) == 1:
+		completion := matches[0] + " "
+		return line[:start] + completion + line[pos:], start + len(completion), true
+	}
+	prefix := commonPrefix(matches)
+	if len(prefix) > len(word) {
+		return line[:start] + prefix + line[pos:], start + len(prefix), true
+	}
+	if c.list != nil {
+		c.list(matches)
+	}
+	return line, pos, true
+}
@@ -0,0 +1,112 @@
+package shell
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/oktasecuritylabs/sgt/osquery_types"
+)
+
+// Format is how results are printed
+type Format string
+
+// Results are printed as an aligned table, a JSON array of rows or CSV with a header row
+const (
+	FormatTable Format = "table"
+	FormatJSON  Format = "json"
+	FormatCSV   Format = "csv"
+)
+
+// ParseFormat returns the format named by s
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(strings.TrimSpace(s))); f {
+	case FormatTable, FormatJSON, FormatCSV:
+		return f, nil
+	}
+	return "", fmt.Errorf("unknown format %q, use table, json or csv", s)
+}
+
+// hostColumn is added to each row to say which node it came from
+const hostColumn = "host_identifier"
+
+// resultRows returns the rows of the nodes, each with the node's host identifier unless the query
+// returned a host_identifier column of its own, and the columns of the rows.  osquery rows don't keep
+// the order of the query's columns, so the host is first and the other columns are sorted
+func resultRows(nodes []osquery_types.CampaignNode) ([]map[string]string, []string) {
+	rows := []map[string]string{}
+	seen := map[string]bool{}
+	for _, cn := range nodes {
+		for _, row := range cn.Rows {
+			r := map[string]string{}
+			for k, v := range row {
+				r[k] = v
+				seen[k] = true
+			}
+			if _, ok := r[hostColumn]; !ok {
+				r[hostColumn] = cn.HostIdentifier
+			}
+			rows = append(rows, r)
+		}
+	}
+	delete(seen, hostColumn)
+	columns := make([]string, 0, len(seen))
+	for k := range seen {
+		columns = append(columns, k)
+	}
+	sort.Strings(columns)
+	return rows, append([]string{hostColumn}, columns...)
+}
+
+// cleanCell keeps a value on one line of a table
+var cleanCell = strings.NewReplacer("\t", " ", "\r", " ", "\n", " ")
+
+// WriteResults prints the rows of the nodes in the format
+func WriteResults(w io.Writer, format Format, nodes []osquery_types.CampaignNode) error {
+	rows, columns := resultRows(nodes)
+	switch format {
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(rows)
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(columns); err != nil {
+			return err
+		}
+		for _, row := range rows {
+			record := make([]string, len(columns))
+			for i, c := range columns {
+				record[i] = row[c]
+			}
+			if err := cw.Write(record); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+
+	if len(rows) == 0 {
+		return nil
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	underline := make([]string, len(columns))
+	for i, c := range columns {
+		underline[i] = strings.Repeat("-", len(c))
+	}
+	fmt.Fprintln(tw, strings.Join(columns, "\t"))
+	fmt.Fprintln(tw, strings.Join(underline, "\t"))
+	for _, row := range rows {
+		cells := make([]string, len(columns))
+		for i, c := range columns {
+			cells[i] = cleanCell.Replace(row[c])
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,425 @@
+package shell
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/oktasecuritylabs/sgt/internal/pkg/sqllint"
+	"github.com/oktasecuritylabs/sgt/osquery_types"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+const (
+	prompt         = "sgt> "
+	continuePrompt = "  -> "
+)
+
+// DefaultTimeout is how long, in seconds, nodes have to answer a statement unless the shell is given
+// another timeout
+const DefaultTimeout = 60
+
+// DefaultPollInterval is how often the shell checks whether the nodes have answered
+const DefaultPollInterval = 2 * time.Second
+
+// metaCommands are the shell's own commands, which start with a dot
+var metaCommands = []string{".exit", ".help", ".mode", ".quit", ".schema", ".tables", ".targets", ".timeout"}
+
+const helpText = `Statements end with a semicolon and are run on the target nodes.  Tab completes table and
+column names.  Press ctrl-c to stop waiting for nodes and print the results so far.
+
+.mode [table|json|csv]   show or set how results are printed
+.timeout [seconds]       show or set how long nodes have to answer
+.tables [prefix]         list the osquery tables
+.schema <table>          list a table's columns and platforms
+.targets                 show the target nodes
+.help                    show this help
+.exit, .quit             leave the shell
+`
+
+// Shell runs each SQL statement it reads as a campaign on its targets, waits for the nodes to answer
+// and prints their rows
+type Shell struct {
+	Client  *Client
+	Targets osquery_types.CampaignTargets
+	Format  Format
+	// Timeout is how long, in seconds, nodes have to answer each statement
+	Timeout      int
+	PollInterval time.Duration
+	// Out is where results are printed, and Err where progress and errors are
+	Out io.Writer
+	Err io.Writer
+
+	// interactive is set when the shell reads from a terminal, where ctrl-c stops waiting for nodes
+	// rather than leaving the shell
+	interactive bool
+}
+
+// New returns a shell that prints to stdout and stderr
+func New(client *Client, targets osquery_types.CampaignTargets, format Format, timeout int) *Shell {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	return &Shell{
+		Client:       client,
+		Targets:      targets,
+		Format:       format,
+		Timeout:      timeout,
+		PollInterval: DefaultPollInterval,
+		Out:          os.Stdout,
+		Err:          os.Stderr,
+	}
+}
+
+// Targets returns the campaign targets for the shell's flags, exactly one of which must be set.  A
+// node is matched by its host identifier, hostname or node key
+func Targets(node, tag, selector string) (osquery_types.CampaignTargets, error) {
+	set := 0
+	for _, v := range []string{node, tag, selector} {
+		if strings.TrimSpace(v) != "" {
+			set++
+		}
+	}
+	if set != 1 {
+		return osquery_types.CampaignTargets{}, errors.New("specify one of a node, a tag or a selector to run queries on")
+	}
+	switch {
+	case strings.TrimSpace(tag) != "":
+		return osquery_types.CampaignTargets{Tags: []string{strings.TrimSpace(tag)}}, nil
+	case strings.TrimSpace(selector) != "":
+		return osquery_types.CampaignTargets{Selector: selector}, nil
+	}
+	node = strings.TrimSpace(node)
+	quote := `"`
+	if strings.Contains(node, quote) {
+		quote = `'`
+		if strings.Contains(node, quote) {
+			return osquery_types.CampaignTargets{}, fmt.Errorf("invalid node %q", node)
+		}
+	}
+	value := quote + node + quote
+	return osquery_types.CampaignTargets{
+		Selector: fmt.Sprintf("host_identifier = %[1]s OR hostname = %[1]s OR node_key = %[1]s", value),
+	}, nil
+}
+
+// Run reads statements from in until it ends or the shell is left.  When in is a terminal the shell is
+// interactive, with a prompt, history and completion, otherwise it runs the statements in in
+func (s *Shell) Run(in *os.File) error {
+	fd := int(in.Fd())
+	if !terminal.IsTerminal(fd) {
+		return s.runScript(in)
+	}
+	s.interactive = true
+
+	t := terminal.NewTerminal(struct {
+		io.Reader
+		io.Writer
+	}{in, os.Stdout}, prompt)
+	if width, height, err := terminal.GetSize(fd); err == nil {
+		t.SetSize(width, height)
+	}
+	pending := ""
+	t.AutoCompleteCallback = completer{
+		statement: func() string { return pending },
+		list: func(candidates []string) {
+			width, _, err := terminal.GetSize(fd)
+			if err != nil {
+				width = 80
+			}
+			t.Write([]byte(columnize(candidates, width)))
+		},
+	}.complete
+	fmt.Fprintln(s.Err, `Enter ".help" for help`)
+
+	for {
+		if strings.TrimSpace(pending) == "" {
+			t.SetPrompt(prompt)
+		} else {
+			t.SetPrompt(continuePrompt)
+		}
+		// the terminal is only raw while a line is read, so ctrl-c interrupts waiting for results
+		state, err := terminal.MakeRaw(fd)
+		if err != nil {
+			return err
+		}
+		line, err := t.ReadLine()
+		terminal.Restore(fd, state)
+		if err == io.EOF {
+			fmt.Fprintln(s.Out)
+			return nil
+		}
+		if err != nil && err != terminal.ErrPasteIndicator {
+			return err
+		}
+		var quit bool
+		if pending, quit = s.handleLine(pending, line); quit {
+			return nil
+		}
+	}
+}
+
+// runScript runs the statements read from r, and a final statement without a semicolon
+func (s *Shell) runScript(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	pending := ""
+	for scanner.Scan() {
+		var quit bool
+		if pending, quit = s.handleLine(pending, scanner.Text()); quit {
+			return nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if strings.TrimSpace(pending) != "" {
+		s.handleLine(pending, ";")
+	}
+	return nil
+}
+
+// handleLine runs a shell command, or adds the line to the pending statement text and runs the
+// statements it completes.  It returns the text of the next, unfinished, statement and whether the
+// shell should be left
+func (s *Shell) handleLine(pending, line string) (string, bool) {
+	if strings.TrimSpace(pending) == "" && strings.HasPrefix(strings.TrimSpace(line), ".") {
+		quit, err := s.command(strings.Fields(line))
+		if err != nil {
+			fmt.Fprintf(s.Err, "error: %s\n", err)
+		}
+		return "", quit
+	}
+	statements, rest := splitStatements(pending + line + "\n")
+	for _, statement := range statements {
+		stop := make(chan os.Signal, 1)
+		if s.interactive {
+			signal.Notify(stop, os.Interrupt)
+		}
+		err := s.query(statement, stop)
+		signal.Stop(stop)
+		if err != nil {
+			fmt.Fprintf(s.Err, "error: %s\n", err)
+		}
+	}
+	return rest, false
+}
+
+// splitStatements returns the statements ended by semicolons in text, and the text after the last
+// one.  Semicolons in quotes and comments don't end a statement
+func splitStatements(text string) ([]string, string) {
+	statements := []string{}
+	start := 0
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '[':
+			quote = ']'
+		case strings.HasPrefix(text[i:], "--"):
+			if end := strings.IndexByte(text[i:], '\n'); end >= 0 {
+				i += end
+			} else {
+				i = len(text)
+			}
+		case strings.HasPrefix(text[i:], "/*"):
+			if end := strings.Index(text[i+2:], "*/"); end >= 0 {
+				i += end + 3
+			} else {
+				i = len(text)
+			}
+		case c == ';':
+			if statement := strings.TrimSpace(text[start:i]); statement != "" {
+				statements = append(statements, statement)
+			}
+			start = i + 1
+		}
+	}
+	rest := text[start:]
+	if strings.TrimSpace(rest) == "" {
+		rest = ""
+	}
+	return statements, rest
+}
+
+// unfinished returns the number of the campaign's nodes that may still answer
+func unfinished(status osquery_types.CampaignStatus) int {
+	return status.Counts[osquery_types.CampaignQueued] + status.Counts[osquery_types.CampaignDelivered]
+}
+
+// query runs the statement as a campaign and prints the rows of the nodes that answer.  It waits until
+// every node has answered, errored or timed out, or until stop receives
+func (s *Shell) query(statement string, stop <-chan os.Signal) error {
+	started, err := s.Client.StartCampaign(statement, s.Targets, s.Timeout)
+	if err != nil {
+		return err
+	}
+	for _, nk := range started.UnknownNodeKeys {
+		fmt.Fprintf(s.Err, "warning: no node has node key %s\n", nk)
+	}
+	status := started.CampaignStatus
+	fmt.Fprintf(s.Err, "campaign %s: waiting up to %ds for %d nodes\n", status.CampaignID, status.Timeout, len(status.Nodes))
+
+	ticker := time.NewTicker(s.PollInterval)
+	defer ticker.Stop()
+	stopped := false
+	// nodes still waiting are shown as timed out once the campaign's deadline passes, so this ends
+	for unfinished(status) > 0 && !stopped {
+		select {
+		case <-stop:
+			stopped = true
+		case <-ticker.C:
+			if status, err = s.Client.Campaign(status.CampaignID); err != nil {
+				return err
+			}
+		}
+	}
+
+	nodes, err := s.Client.CampaignResults(status.CampaignID)
+	if err != nil {
+		return err
+	}
+	answered := []osquery_types.CampaignNode{}
+	for _, cn := range nodes {
+		if cn.State == osquery_types.CampaignAnswered {
+			answered = append(answered, cn)
+		}
+	}
+	if err := WriteResults(s.Out, s.Format, answered); err != nil {
+		return err
+	}
+	s.summarize(status, answered, stopped)
+	return nil
+}
+
+// summarize prints how many rows the nodes answered with, and why the other nodes didn't
+func (s *Shell) summarize(status osquery_types.CampaignStatus, answered []osquery_types.CampaignNode, stopped bool) {
+	rows := 0
+	for _, cn := range answered {
+		rows += len(cn.Rows)
+		if cn.Truncated {
+			fmt.Fprintf(s.Err, "%s: results truncated, %d of %d rows were kept\n", cn.HostIdentifier, len(cn.Rows), cn.RowCount)
+		}
+	}
+	for _, cn := range status.Nodes {
+		switch cn.State {
+		case osquery_types.CampaignErrored:
+			message := cn.Message
+			if message == "" {
+				message = cn.Error
+			}
+			fmt.Fprintf(s.Err, "%s: error: %s\n", cn.HostIdentifier, message)
+		case osquery_types.CampaignTimedOut:
+			fmt.Fprintf(s.Err, "%s: timed out\n", cn.HostIdentifier)
+		case osquery_types.CampaignQueued, osquery_types.CampaignDelivered:
+			fmt.Fprintf(s.Err, "%s: %s, not answered yet\n", cn.HostIdentifier, cn.State)
+		}
+	}
+	fmt.Fprintf(s.Err, "%d rows from %d of %d nodes\n", rows, len(answered), len(status.Nodes))
+	if stopped {
+		fmt.Fprintf(s.Err, "stopped waiting, the results of nodes that answer later are kept with campaign %s\n", status.CampaignID)
+	}
+}
+
+// command runs a shell command, and returns true if the shell should be left
+func (s *Shell) command(args []string) (bool, error) {
+	switch args[0] {
+	case ".exit", ".quit":
+		return true, nil
+	case ".help":
+		fmt.Fprint(s.Out, helpText)
+	case ".mode":
+		if len(args) == 1 {
+			fmt.Fprintln(s.Out, s.Format)
+			break
+		}
+		format, err := ParseFormat(args[1])
+		if err != nil {
+			return false, err
+		}
+		s.Format = format
+	case ".timeout":
+		if len(args) == 1 {
+			fmt.Fprintln(s.Out, s.Timeout)
+			break
+		}
+		timeout, err := strconv.Atoi(args[1])
+		if err != nil || timeout <= 0 {
+			return false, fmt.Errorf("timeout must be a positive number of seconds, got %q", args[1])
+		}
+		s.Timeout = timeout
+	case ".tables":
+		names := []string{}
+		for _, t := range sqllint.Tables() {
+			if len(args) == 1 || strings.HasPrefix(t.Name, args[1]) {
+				names = append(names, t.Name)
+			}
+		}
+		fmt.Fprint(s.Out, columnize(names, 80))
+	case ".schema":
+		if len(args) == 1 {
+			return false, errors.New("usage: .schema <table>")
+		}
+		t, ok := sqllint.LookupTable(args[1])
+		if !ok {
+			return false, fmt.Errorf("no such table: %s", args[1])
+		}
+		fmt.Fprintf(s.Out, "%s (%s)\n", t.Name, strings.Join(t.Platforms, ", "))
+		for _, c := range t.Columns {
+			fmt.Fprintf(s.Out, "  %s\n", c)
+		}
+	case ".targets":
+		js, err := json.Marshal(s.Targets)
+		if err != nil {
+			return false, err
+		}
+		fmt.Fprintln(s.Out, string(js))
+	default:
+		return false, fmt.Errorf("unknown command %s, enter .help for help", args[0])
+	}
+	return false, nil
+}
+
+// columnize lays the words out in sorted columns that fit in width
+func columnize(words []string, width int) string {
+	if len(words) == 0 {
+		return ""
+	}
+	words = append([]string{}, words...)
+	sort.Strings(words)
+	longest := 0
+	for _, w := range words {
+		if len(w) > longest {
+			longest = len(w)
+		}
+	}
+	perLine := width / (longest + 2)
+	if perLine < 1 {
+		perLine = 1
+	}
+	lines := (len(words) + perLine - 1) / perLine
+	b := &strings.Builder{}
+	for line := 0; line < lines; line++ {
+		cells := []string{}
+		for i := line; i < len(words); i += lines {
+			cells = append(cells, fmt.Sprintf("%-*s", longest+2, words[i]))
+		}
+		b.WriteString(strings.TrimRight(strings.Join(cells, ""), " "))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
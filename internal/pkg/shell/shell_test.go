@@ -0,0 +1,174 @@
+package shell
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oktasecuritylabs/sgt/osquery_types"
+)
+
+func TestSplitStatements(t *testing.T) {
+	statements, rest := splitStatements("select ';' from users; -- a comment; still\nselect 1 /* ; */;\nselect * from\n")
+	expected := []string{"select ';' from users", "-- a comment; still\nselect 1 /* ; */"}
+	if !reflect.DeepEqual(statements, expected) {
+		t.Errorf("expected %q, got %q", expected, statements)
+	}
+	if rest != "\nselect * from\n" {
+		t.Errorf("got rest %q", rest)
+	}
+	if _, rest := splitStatements("select 1;  \n"); rest != "" {
+		t.Errorf("expected no rest, got %q", rest)
+	}
+}
+
+func TestTargets(t *testing.T) {
+	targets, err := Targets("web-1", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if targets.Selector != `host_identifier = "web-1" OR hostname = "web-1" OR node_key = "web-1"` {
+		t.Errorf("got %+v", targets)
+	}
+	if targets, _ := Targets("", "prod", ""); !reflect.DeepEqual(targets.Tags, []string{"prod"}) {
+		t.Errorf("got %+v", targets)
+	}
+	for _, args := range [][]string{{"", "", ""}, {"web-1", "prod", ""}, {`a"b'c`, "", ""}} {
+		if _, err := Targets(args[0], args[1], args[2]); err == nil {
+			t.Errorf("expected %q to be rejected", args)
+		}
+	}
+}
+
+func TestWriteResults(t *testing.T) {
+	nodes := []osquery_types.CampaignNode{
+		{HostIdentifier: "host1", Rows: []map[string]string{{"username": "root", "uid": "0"}}},
+		{HostIdentifier: "host2", Rows: []map[string]string{{"username": "a,b", "shell": "/bin/sh"}}},
+	}
+	out := &bytes.Buffer{}
+	if err := WriteResults(out, FormatCSV, nodes); err != nil {
+		t.Fatal(err)
+	}
+	expected := "host_identifier,shell,uid,username\nhost1,,0,root\nhost2,/bin/sh,,\"a,b\"\n"
+	if out.String() != expected {
+		t.Errorf("expected %q, got %q", expected, out.String())
+	}
+
+	out.Reset()
+	if err := WriteResults(out, FormatTable, nodes[:1]); err != nil {
+		t.Fatal(err)
+	}
+	expected = "host_identifier  uid  username\n---------------  ---  --------\nhost1            0    root\n"
+	if out.String() != expected {
+		t.Errorf("expected %q, got %q", expected, out.String())
+	}
+}
+
+func TestComplete(t *testing.T) {
+	c := completer{}
+	tests := []struct {
+		line, expected string
+	}{
+		{"select * from proce", "select * from process"},
+		{"select * from etc_ho", "select * from etc_hosts "},
+		{"select pi", "select pi"},
+		{"select * from users where usern", "select * from users where username "},
+		{"SEL", "SELECT "},
+		{".ti", ".timeout "},
+		{"select * from nosuchtable", "select * from nosuchtable"},
+	}
+	for _, tt := range tests {
+		line, _, ok := c.complete(tt.line, len(tt.line), keyTab)
+		if !ok || line != tt.expected {
+			t.Errorf("%q: expected %q, got %q", tt.line, tt.expected, line)
+		}
+	}
+
+	listed := []string{}
+	c.list = func(candidates []string) { listed = candidates }
+	if line, _, _ := c.complete("select * from users where u", 27, keyTab); line != "select * from users where u" || len(listed) < 2 {
+		t.Errorf("expected the candidates listed, got %q %v", line, listed)
+	}
+	if _, _, ok := c.complete("select", 6, 'x'); ok {
+		t.Error("expected other keys left to the terminal")
+	}
+}
+
+// fakeServer answers the API calls the shell makes.  The node answers on the second poll
+func fakeServer(t *testing.T) *httptest.Server {
+	polls := 0
+	tokens := 0
+	status := osquery_types.CampaignStatus{
+		Campaign: osquery_types.Campaign{CampaignID: "abc", Timeout: 60},
+		Counts:   map[osquery_types.CampaignNodeState]int{osquery_types.CampaignQueued: 1},
+		Nodes:    []osquery_types.CampaignNode{{HostIdentifier: "host1", State: osquery_types.CampaignQueued}},
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/get-token", func(w http.ResponseWriter, r *http.Request) {
+		tokens++
+		json.NewEncoder(w).Encode(map[string]string{"Authorization": "token" + string(rune('0'+tokens))})
+	})
+	mux.HandleFunc("/api/v1/configuration/", func(w http.ResponseWriter, r *http.Request) {
+		// the first token expires after starting the campaign
+		if r.Header.Get("Authorization") == "Bearer token1" && r.Method == http.MethodGet {
+			json.NewEncoder(w).Encode(apiError{Status: "error", Message: invalidCredentials})
+			return
+		}
+		switch r.URL.Path {
+		case "/api/v1/configuration/distributed/campaigns":
+			req := map[string]interface{}{}
+			json.NewDecoder(r.Body).Decode(&req)
+			if req["query"] != "select username\nfrom users" || req["timeout"] != float64(30) {
+				t.Errorf("got campaign request %v", req)
+			}
+			json.NewEncoder(w).Encode(status)
+		case "/api/v1/configuration/distributed/campaigns/abc":
+			if polls++; polls == 2 {
+				status.Counts = map[osquery_types.CampaignNodeState]int{osquery_types.CampaignAnswered: 1}
+				status.Nodes[0].State = osquery_types.CampaignAnswered
+			}
+			json.NewEncoder(w).Encode(status)
+		case "/api/v1/configuration/distributed/campaigns/abc/results":
+			node := status.Nodes[0]
+			node.Rows = []map[string]string{{"username": "root"}}
+			json.NewEncoder(w).Encode(map[string]interface{}{"results": []osquery_types.CampaignNode{node}})
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestShell_RunScript(t *testing.T) {
+	server := fakeServer(t)
+	defer server.Close()
+
+	client := NewClient(server.URL, false)
+	if err := client.Login("analyst", "secret"); err != nil {
+		t.Fatal(err)
+	}
+	out, errOut := &bytes.Buffer{}, &bytes.Buffer{}
+	s := New(client, osquery_types.CampaignTargets{Tags: []string{"prod"}}, FormatTable, 30)
+	s.PollInterval = time.Millisecond
+	s.Out, s.Err = out, errOut
+
+	script := ".mode json\nselect username\nfrom users;\n.mode nosuchmode\n"
+	if err := s.runScript(strings.NewReader(script)); err != nil {
+		t.Fatal(err)
+	}
+	expected := "[\n  {\n    \"host_identifier\": \"host1\",\n    \"username\": \"root\"\n  }\n]\n"
+	if out.String() != expected {
+		t.Errorf("expected %q, got %q", expected, out.String())
+	}
+	if !strings.Contains(errOut.String(), "1 rows from 1 of 1 nodes") || !strings.Contains(errOut.String(), "unknown format") {
+		t.Errorf("got %q", errOut.String())
+	}
+	if client.token != "token2" {
+		t.Errorf("expected the expired token replaced, got %s", client.token)
+	}
+}
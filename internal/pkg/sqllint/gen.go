@@ -0,0 +1,71 @@
+//go:build ignore
+// +build ignore
+
+// gen.go generates tables_gen.go from tables.json
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"log"
+	"sort"
+)
+
+type table struct {
+	Name      string   `json:"name"`
+	Platforms []string `json:"platforms"`
+	Since     string   `json:"since"`
+	Columns   []string `json:"columns"`
+	Required  []string `json:"required"`
+}
+
+type schema struct {
+	OsqueryVersion string  `json:"osquery_version"`
+	Tables         []table `json:"tables"`
+}
+
+func main() {
+	js, err := ioutil.ReadFile("tables.json")
+	if err != nil {
+		log.Fatal(err)
+	}
+	s := schema{}
+	if err = json.Unmarshal(js, &s); err != nil {
+		log.Fatal(err)
+	}
+	sort.Slice(s.Tables, func(i, j int) bool { return s.Tables[i].Name < s.Tables[j].Name })
+
+	buf := &bytes.Buffer{}
+	fmt.Fprintln(buf, "// Code generated by gen.go from tables.json. DO NOT EDIT.")
+	fmt.Fprintln(buf)
+	fmt.Fprintln(buf, "package sqllint")
+	fmt.Fprintln(buf)
+	fmt.Fprintf(buf, "const osqueryVersion = %q\n\n", s.OsqueryVersion)
+	fmt.Fprintln(buf, "var tables = map[string]Table{")
+	for _, t := range s.Tables {
+		if len(t.Platforms) == 0 || len(t.Columns) == 0 {
+			log.Fatalf("table %q must have platforms and columns", t.Name)
+		}
+		fmt.Fprintf(buf, "%q: {\nName: %q,\nPlatforms: %#v,\n", t.Name, t.Name, t.Platforms)
+		if t.Since != "" {
+			fmt.Fprintf(buf, "Since: %q,\n", t.Since)
+		}
+		fmt.Fprintf(buf, "Columns: %#v,\n", t.Columns)
+		if len(t.Required) > 0 {
+			fmt.Fprintf(buf, "Required: %#v,\n", t.Required)
+		}
+		fmt.Fprintln(buf, "},")
+	}
+	fmt.Fprintln(buf, "}")
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err = ioutil.WriteFile("tables_gen.go", src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,150 @@
+package sqllint
+
+import (
+	"fmt"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokenIdent tokenKind = iota
+	// tokenQuotedIdent is a "double quoted", `backticked` or [bracketed] identifier
+	tokenQuotedIdent
+	tokenString
+	tokenNumber
+	tokenSymbol
+)
+
+type token struct {
+	kind tokenKind
+	// text is lower cased for identifiers, and has the quotes removed for strings and quoted identifiers
+	text string
+}
+
+func (t token) is(kind tokenKind, text string) bool {
+	return t.kind == kind && t.text == text
+}
+
+func (t token) keyword(words ...string) bool {
+	if t.kind != tokenIdent {
+		return false
+	}
+	for _, w := range words {
+		if t.text == w {
+			return true
+		}
+	}
+	return false
+}
+
+// twoCharSymbols are the multi character operators sqlite understands
+var twoCharSymbols = []string{"==", "!=", "<>", "<=", ">=", "||", "<<", ">>"}
+
+// tokenize splits a query into tokens, dropping comments and whitespace
+func tokenize(query string) ([]token, error) {
+	tokens := []token{}
+	i := 0
+	for i < len(query) {
+		c := query[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+
+		case strings.HasPrefix(query[i:], "--"):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				i = len(query)
+			} else {
+				i += end + 1
+			}
+
+		case strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated comment")
+			}
+			i += end + 4
+
+		case c == '\'' || c == '"' || c == '`':
+			text, n, err := quoted(query[i:], c, c)
+			if err != nil {
+				return nil, err
+			}
+			kind := tokenQuotedIdent
+			if c == '\'' {
+				kind = tokenString
+			}
+			tokens = append(tokens, token{kind: kind, text: text})
+			i += n
+
+		case c == '[':
+			text, n, err := quoted(query[i:], '[', ']')
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{kind: tokenQuotedIdent, text: text})
+			i += n
+
+		case isDigit(c) || (c == '.' && i+1 < len(query) && isDigit(query[i+1])):
+			start := i
+			for i < len(query) && (isIdentChar(query[i]) || query[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: query[start:i]})
+
+		case isIdentStart(c):
+			start := i
+			for i < len(query) && isIdentChar(query[i]) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: strings.ToLower(query[start:i])})
+
+		default:
+			symbol := string(c)
+			for _, s := range twoCharSymbols {
+				if strings.HasPrefix(query[i:], s) {
+					symbol = s
+					break
+				}
+			}
+			tokens = append(tokens, token{kind: tokenSymbol, text: symbol})
+			i += len(symbol)
+		}
+	}
+	return tokens, nil
+}
+
+// quoted reads a quoted string or identifier starting at s[0], where a doubled closing quote is an
+// escaped quote.  It returns the unquoted text and the number of bytes read
+func quoted(s string, open, close byte) (string, int, error) {
+	b := strings.Builder{}
+	for i := 1; i < len(s); i++ {
+		if s[i] != close {
+			b.WriteByte(s[i])
+			continue
+		}
+		if open == close && i+1 < len(s) && s[i+1] == close {
+			b.WriteByte(close)
+			i++
+			continue
+		}
+		return b.String(), i + 1, nil
+	}
+	if open == '\'' {
+		return "", 0, fmt.Errorf("unterminated string")
+	}
+	return "", 0, fmt.Errorf("unterminated quoted identifier")
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= 0x80
+}
+
+func isIdentChar(c byte) bool {
+	return isIdentStart(c) || isDigit(c) || c == '$'
+}
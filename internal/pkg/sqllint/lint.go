@@ -0,0 +1,544 @@
+package sqllint
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/oktasecuritylabs/sgt/osquery_types"
+)
+
+// Severity is how serious a finding is.  Errors are queries osquery will fail to run, warnings are
+// queries that will run but probably shouldn't be sent to every node as they are
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Finding is a single problem found in a query
+type Finding struct {
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+	Table    string   `json:"table,omitempty"`
+	Column   string   `json:"column,omitempty"`
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s: %s", f.Severity, f.Message)
+}
+
+// Target is where a query will run.  Platform is an osquery platform constraint (eg "darwin" or
+// "posix,windows") and Version the minimum osquery version, either may be empty if unknown
+type Target struct {
+	Platform string
+	Version  string
+}
+
+// PackQueryTarget returns where a pack query will run.  The query's own platform and version take
+// precedence over those of the pack it's in
+func PackQueryTarget(pq osquery_types.PackQuery, settings osquery_types.PackSettings) Target {
+	target := Target{Platform: pq.Platform, Version: pq.Version}
+	if target.Platform == "" {
+		target.Platform = settings.Platform
+	}
+	if target.Version == "" {
+		target.Version = settings.Version
+	}
+	return target
+}
+
+// NodeTarget returns the target for a query run on a node.  A platform that isn't recognised is left
+// out, so the query is only checked against what is known about the node
+func NodeTarget(rt osquery_types.RenderTarget) Target {
+	target := Target{Platform: rt.Platform, Version: rt.OsqueryVersion}
+	if !osquery_types.KnownPlatform(target.Platform) {
+		target.Platform = ""
+	}
+	return target
+}
+
+// HasErrors returns true if any of the findings is an error
+func HasErrors(findings []Finding) bool {
+	for _, f := range findings {
+		if f.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// keywords are the sqlite keywords, type names and literals that can appear where a column name
+// could.  An identifier is only reported as an unknown column if it isn't one of these
+var keywords = map[string]bool{}
+
+func init() {
+	for _, k := range strings.Fields(`abort action add after all alter analyze and as asc attach autoincrement
+		before begin between by cascade case cast check collate column commit conflict constraint create cross
+		current current_date current_time current_timestamp database default deferrable deferred delete desc
+		detach distinct do drop each else end escape except exclusive exists explain fail filter first following
+		for foreign from full glob group groups having if ignore immediate in index indexed initially inner insert
+		instead intersect into is isnull join key last left like limit match natural no not nothing notnull null
+		nulls of offset on or order others outer over partition plan pragma preceding primary query raise range
+		recursive references regexp reindex release rename replace restrict right rollback row rows savepoint
+		select set table temp temporary then ties to transaction trigger unbounded union unique update using
+		vacuum values view virtual when where window with without true false
+		integer int text blob real numeric varchar bigint unsigned double float boolean`) {
+		keywords[k] = true
+	}
+}
+
+// sourceStop are the words that end a table reference in a FROM or JOIN clause, so can't be an alias
+var sourceStop = map[string]bool{
+	"where": true, "join": true, "on": true, "using": true, "left": true, "right": true, "inner": true,
+	"outer": true, "cross": true, "natural": true, "full": true, "group": true, "order": true, "limit": true,
+	"union": true, "intersect": true, "except": true, "having": true, "window": true, "as": true,
+	"indexed": true, "not": true, "offset": true,
+}
+
+// rootGlob matches LIKE patterns that recurse from the root of the filesystem
+var rootGlob = regexp.MustCompile(`^(/|[a-z]:\\)?%%$`)
+
+type source struct {
+	name  string
+	alias string
+	table Table
+	known bool
+}
+
+type linter struct {
+	tokens   []token
+	target   Target
+	findings []Finding
+	// consumed marks tokens that are table names or aliases rather than possible columns
+	consumed map[int]bool
+	sources  []source
+	// qualifiers maps table names and aliases to their source
+	qualifiers map[string]int
+	// ctes holds the names and declared columns of common table expressions
+	ctes map[string]bool
+	// aliases holds result column aliases, which can be used in place of column names
+	aliases map[string]bool
+	// opaque is true if the query reads from a subquery or CTE, whose columns aren't known
+	opaque bool
+}
+
+// Lint checks a query for syntax problems, unknown tables and columns, tables that aren't available
+// on the target's platforms or version and constraints missing from expensive tables
+func Lint(query string, target Target) []Finding {
+	tokens, err := tokenize(query)
+	if err != nil {
+		return []Finding{{Severity: SeverityError, Message: fmt.Sprintf("syntax error: %s", err)}}
+	}
+	l := linter{
+		tokens:     tokens,
+		target:     target,
+		findings:   []Finding{},
+		consumed:   map[int]bool{},
+		qualifiers: map[string]int{},
+		ctes:       map[string]bool{},
+		aliases:    map[string]bool{},
+	}
+	if !l.checkStructure() {
+		return l.findings
+	}
+	l.collectCTEs()
+	l.collectSources()
+	l.collectAliases()
+	l.checkTables()
+	l.checkColumns()
+	l.checkCost()
+	return l.findings
+}
+
+func (l *linter) add(severity Severity, table, column, format string, args ...interface{}) {
+	l.findings = append(l.findings, Finding{
+		Severity: severity,
+		Message:  fmt.Sprintf(format, args...),
+		Table:    table,
+		Column:   column,
+	})
+}
+
+func (l *linter) at(i int) token {
+	if i < 0 || i >= len(l.tokens) {
+		return token{kind: tokenSymbol}
+	}
+	return l.tokens[i]
+}
+
+// checkStructure makes sure the query is a single SELECT with balanced parentheses, returning false
+// if it isn't worth checking any further
+func (l *linter) checkStructure() bool {
+	if len(l.tokens) == 0 {
+		l.add(SeverityError, "", "", "query is empty")
+		return false
+	}
+	depth := 0
+	for i, t := range l.tokens {
+		switch {
+		case t.is(tokenSymbol, "("):
+			depth++
+		case t.is(tokenSymbol, ")"):
+			depth--
+			if depth < 0 {
+				l.add(SeverityError, "", "", "syntax error: unbalanced parentheses")
+				return false
+			}
+		case t.is(tokenSymbol, ";"):
+			for _, rest := range l.tokens[i+1:] {
+				if !rest.is(tokenSymbol, ";") {
+					l.add(SeverityError, "", "", "query contains more than one statement")
+					return false
+				}
+			}
+		}
+	}
+	if depth != 0 {
+		l.add(SeverityError, "", "", "syntax error: unbalanced parentheses")
+		return false
+	}
+	if !l.tokens[0].keyword("select", "with", "values") {
+		l.add(SeverityError, "", "", "only SELECT statements can be run by osquery")
+		return false
+	}
+	return true
+}
+
+// skipParens returns the index of the parenthesis closing the one at i
+func (l *linter) skipParens(i int) int {
+	depth := 0
+	for ; i < len(l.tokens); i++ {
+		switch {
+		case l.tokens[i].is(tokenSymbol, "("):
+			depth++
+		case l.tokens[i].is(tokenSymbol, ")"):
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(l.tokens) - 1
+}
+
+func isName(t token) bool {
+	return t.kind == tokenIdent || t.kind == tokenQuotedIdent
+}
+
+// collectCTEs records the names and column lists of WITH clauses
+func (l *linter) collectCTEs() {
+	for i, t := range l.tokens {
+		if !t.keyword("with") {
+			continue
+		}
+		j := i + 1
+		if l.at(j).keyword("recursive") {
+			j++
+		}
+		for isName(l.at(j)) {
+			l.ctes[l.at(j).text] = true
+			l.consumed[j] = true
+			j++
+			if l.at(j).is(tokenSymbol, "(") {
+				end := l.skipParens(j)
+				for k := j + 1; k < end; k++ {
+					if isName(l.at(k)) {
+						l.ctes[l.at(k).text] = true
+						l.consumed[k] = true
+					}
+				}
+				j = end + 1
+			}
+			if !l.at(j).keyword("as") || !l.at(j+1).is(tokenSymbol, "(") {
+				break
+			}
+			j = l.skipParens(j+1) + 1
+			if !l.at(j).is(tokenSymbol, ",") {
+				break
+			}
+			j++
+		}
+	}
+}
+
+// collectSources records every table read in a FROM or JOIN clause, along with its alias
+func (l *linter) collectSources() {
+	for i, t := range l.tokens {
+		if !t.keyword("from", "join") {
+			continue
+		}
+		j := i + 1
+		for {
+			s := source{}
+			switch {
+			case l.at(j).is(tokenSymbol, "("):
+				// a subquery, its own FROM clause is picked up separately
+				l.opaque = true
+				j = l.skipParens(j) + 1
+			case isName(l.at(j)):
+				if l.at(j+1).is(tokenSymbol, ".") && isName(l.at(j+2)) {
+					// schema qualified, eg main.processes
+					l.consumed[j] = true
+					j += 2
+				}
+				s.name = l.at(j).text
+				l.consumed[j] = true
+				j++
+			default:
+				return
+			}
+
+			if l.at(j).keyword("as") && isName(l.at(j+1)) {
+				s.alias = l.at(j + 1).text
+				l.consumed[j+1] = true
+				j += 2
+			} else if isName(l.at(j)) && !sourceStop[l.at(j).text] {
+				s.alias = l.at(j).text
+				l.consumed[j] = true
+				j++
+			}
+
+			if s.name != "" {
+				if l.ctes[s.name] {
+					l.opaque = true
+				} else {
+					s.table, s.known = LookupTable(s.name)
+				}
+				l.sources = append(l.sources, s)
+				l.qualifiers[s.name] = len(l.sources) - 1
+			}
+			if s.alias != "" {
+				if s.name == "" {
+					l.ctes[s.alias] = true
+				} else {
+					l.qualifiers[s.alias] = len(l.sources) - 1
+				}
+			}
+
+			if !l.at(j).is(tokenSymbol, ",") {
+				break
+			}
+			j++
+		}
+	}
+}
+
+// collectAliases records result column aliases, both "expr AS alias" and the bare "expr alias" form.
+// Collation names, as in "expr COLLATE nocase", are skipped the same way
+func (l *linter) collectAliases() {
+	for i, t := range l.tokens {
+		if !isName(t) || l.consumed[i] {
+			continue
+		}
+		prev := l.at(i - 1)
+		if prev.keyword("collate") {
+			l.consumed[i] = true
+			continue
+		}
+		switch {
+		case prev.keyword("as"):
+		case keywords[t.text] || l.at(i+1).is(tokenSymbol, "(") || l.at(i+1).is(tokenSymbol, "."):
+			continue
+		case prev.is(tokenSymbol, ")") || prev.kind == tokenString || prev.kind == tokenNumber:
+		case isName(prev) && !keywords[prev.text] && !l.at(i-2).is(tokenSymbol, "."):
+		default:
+			continue
+		}
+		l.aliases[t.text] = true
+		l.consumed[i] = true
+	}
+}
+
+// expandPlatforms turns a platform constraint into the platforms it names explicitly and those it
+// only covers through a group such as posix
+func expandPlatforms(constraint string) (named, grouped map[string]bool) {
+	named = map[string]bool{}
+	grouped = map[string]bool{}
+	for _, p := range strings.Split(constraint, ",") {
+		p = strings.ToLower(strings.TrimSpace(p))
+		switch p {
+		case "":
+		case "all", "any":
+			grouped["darwin"], grouped["linux"], grouped["windows"], grouped["freebsd"] = true, true, true, true
+		case "posix":
+			grouped["darwin"], grouped["linux"], grouped["freebsd"] = true, true, true
+		default:
+			named[osquery_types.NormalizePlatform(p)] = true
+		}
+	}
+	return named, grouped
+}
+
+func sortedSet(set map[string]bool) []string {
+	result := []string{}
+	for k := range set {
+		result = append(result, k)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// checkTables reports unknown tables and tables missing from the target platforms or version.  A
+// table missing from a platform named in the constraint is an error, while one that only misses some
+// of the platforms in a group like posix is a warning, as upstream packs commonly do this
+func (l *linter) checkTables() {
+	named, grouped := expandPlatforms(l.target.Platform)
+	seen := map[string]bool{}
+	for _, s := range l.sources {
+		if seen[s.name] || l.ctes[s.name] {
+			continue
+		}
+		seen[s.name] = true
+		if !s.known {
+			l.add(SeverityError, s.name, "", "unknown table %q", s.name)
+			continue
+		}
+		missingNamed := map[string]bool{}
+		missingGrouped := map[string]bool{}
+		availableGrouped := false
+		for p := range named {
+			if !s.table.AvailableOn(p) {
+				missingNamed[p] = true
+			}
+		}
+		for p := range grouped {
+			if named[p] {
+				continue
+			}
+			if s.table.AvailableOn(p) {
+				availableGrouped = true
+			} else {
+				missingGrouped[p] = true
+			}
+		}
+		switch {
+		case len(missingNamed) > 0:
+			for p := range missingGrouped {
+				missingNamed[p] = true
+			}
+			l.add(SeverityError, s.name, "", "table %q is not available on %s", s.name, strings.Join(sortedSet(missingNamed), ", "))
+		case len(missingGrouped) > 0 && !availableGrouped && len(named) == 0:
+			l.add(SeverityError, s.name, "", "table %q is not available on %s", s.name, strings.Join(sortedSet(missingGrouped), ", "))
+		case len(missingGrouped) > 0:
+			l.add(SeverityWarning, s.name, "", "table %q is not available on %s", s.name, strings.Join(sortedSet(missingGrouped), ", "))
+		}
+		if l.target.Version != "" && s.table.Since != "" && osquery_types.CompareVersions(l.target.Version, s.table.Since) < 0 {
+			l.add(SeverityError, s.name, "", "table %q requires osquery %s or later, the query targets %s", s.name, s.table.Since, l.target.Version)
+		}
+	}
+}
+
+func (l *linter) checkColumns() {
+	knownSources := []Table{}
+	for _, s := range l.sources {
+		if !s.known {
+			l.opaque = true
+			continue
+		}
+		knownSources = append(knownSources, s.table)
+	}
+
+	reported := map[string]bool{}
+	for i, t := range l.tokens {
+		if !isName(t) || l.consumed[i] || l.at(i-1).is(tokenSymbol, ".") {
+			continue
+		}
+
+		if l.at(i+1).is(tokenSymbol, ".") {
+			column := l.at(i + 2)
+			if !isName(column) {
+				continue
+			}
+			idx, ok := l.qualifiers[t.text]
+			switch {
+			case !ok && !l.ctes[t.text]:
+				if !reported[t.text] {
+					l.add(SeverityError, "", column.text, "unknown table or alias %q", t.text)
+					reported[t.text] = true
+				}
+			case ok && l.sources[idx].known && !l.sources[idx].table.HasColumn(column.text):
+				s := l.sources[idx]
+				if !reported[s.name+"."+column.text] {
+					l.add(SeverityError, s.name, column.text, "table %q has no column %q", s.name, column.text)
+					reported[s.name+"."+column.text] = true
+				}
+			}
+			continue
+		}
+
+		// unqualified names can only be checked when every table read is known
+		if t.kind == tokenQuotedIdent || l.opaque || len(knownSources) == 0 {
+			continue
+		}
+		if keywords[t.text] || l.aliases[t.text] || l.ctes[t.text] || l.at(i+1).is(tokenSymbol, "(") {
+			continue
+		}
+		if _, ok := l.qualifiers[t.text]; ok {
+			continue
+		}
+		found := false
+		names := []string{}
+		for _, table := range knownSources {
+			names = append(names, table.Name)
+			if table.HasColumn(t.text) {
+				found = true
+				break
+			}
+		}
+		if !found && !reported[t.text] {
+			l.add(SeverityError, "", t.text, "no column %q in %s", t.text, strings.Join(names, ", "))
+			reported[t.text] = true
+		}
+	}
+}
+
+// checkCost warns about tables that scan the filesystem (or similar) unless one of their required
+// columns is constrained in a WHERE or ON clause, and about patterns that recurse from the root
+func (l *linter) checkCost() {
+	// constrained holds the columns named in WHERE and ON clauses, both bare and qualified
+	constrained := map[string]bool{}
+	inConstraint := false
+	stack := []bool{}
+	for i, t := range l.tokens {
+		switch {
+		case t.is(tokenSymbol, "("):
+			stack = append(stack, inConstraint)
+		case t.is(tokenSymbol, ")"):
+			if len(stack) > 0 {
+				inConstraint = stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+			}
+		case t.keyword("where", "on"):
+			inConstraint = true
+		case t.keyword("select", "from", "join", "group", "order", "limit", "union", "intersect", "except", "having"):
+			inConstraint = false
+		case inConstraint && isName(t):
+			if l.at(i+1).is(tokenSymbol, ".") && isName(l.at(i+2)) {
+				constrained[t.text+"."+l.at(i+2).text] = true
+			} else if !l.at(i-1).is(tokenSymbol, ".") {
+				constrained[t.text] = true
+			}
+		case t.kind == tokenString && l.at(i-1).keyword("like", "glob") && rootGlob.MatchString(strings.ToLower(t.text)):
+			l.add(SeverityWarning, "", "", "pattern %q searches the whole filesystem", t.text)
+		}
+	}
+
+	for _, s := range l.sources {
+		if !s.known || len(s.table.Required) == 0 {
+			continue
+		}
+		ok := false
+		for _, r := range s.table.Required {
+			if constrained[r] || constrained[s.name+"."+r] || (s.alias != "" && constrained[s.alias+"."+r]) {
+				ok = true
+			}
+		}
+		if !ok {
+			l.add(SeverityWarning, s.name, "", "table %q should be constrained on %s in a WHERE or JOIN ... ON clause",
+				s.name, strings.Join(s.table.Required, " or "))
+		}
+	}
+}
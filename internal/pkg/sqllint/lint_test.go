@@ -0,0 +1,100 @@
+package sqllint
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/oktasecuritylabs/sgt/osquery_types"
+)
+
+func TestLint(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		target   Target
+		expected []string
+	}{
+		{"valid", "select name, pid from processes where on_disk = 0;", Target{}, nil},
+		{"aliases", "select p.name as process_name, l.port from listening_ports l join processes p using (pid) order by process_name;", Target{}, nil},
+		{"cte", "with recent(path) as (select path from processes) select path from recent;", Target{}, nil},
+		{"empty", "  ", Target{}, []string{"query is empty"}},
+		{"not select", "delete from processes;", Target{}, []string{"only SELECT"}},
+		{"two statements", "select * from time; select * from uptime;", Target{}, []string{"more than one statement"}},
+		{"unterminated", "select * from file where path = '/etc", Target{}, []string{"unterminated string"}},
+		{"unknown table", "select * from proceses;", Target{}, []string{`unknown table "proceses"`}},
+		{"unknown column", "select nmae from processes;", Target{}, []string{`no column "nmae"`}},
+		{"unknown qualified column", "select p.nmae from processes p;", Target{}, []string{`has no column "nmae"`}},
+		{"platform", "select * from launchd;", Target{Platform: "linux"}, []string{"not available on linux"}},
+		{"platform group", "select * from launchd;", Target{Platform: "posix"}, []string{"not available on freebsd, linux"}},
+		{"windows only", "select * from programs;", Target{Platform: "posix"}, []string{"not available on darwin, freebsd, linux"}},
+		{"version", "select * from windows_events;", Target{Platform: "windows", Version: "2.9.0"}, []string{"requires osquery 3.3.0"}},
+		{"unconstrained file", "select * from file;", Target{}, []string{`"file" should be constrained`}},
+		{"root glob", "select * from hash where path like '/%%';", Target{}, []string{"searches the whole filesystem"}},
+		{"collate", "select username from users where username = 'root' COLLATE NOCASE or shell = 'x' collate binary order by username collate rtrim;", Target{}, nil},
+		{"collate alias", "select username collate nocase user from users order by user;", Target{}, nil},
+		{"constrained join", "select h.sha256 from file f join hash h on h.path = f.path where f.directory = '/etc';", Target{}, nil},
+	}
+
+	for _, test := range tests {
+		findings := Lint(test.query, test.target)
+		if len(findings) != len(test.expected) {
+			t.Errorf("%s: expected %d findings, got %v", test.name, len(test.expected), findings)
+			continue
+		}
+		for i, f := range findings {
+			if !strings.Contains(f.Message, test.expected[i]) {
+				t.Errorf("%s: expected finding containing %q, got %q", test.name, test.expected[i], f.Message)
+			}
+		}
+	}
+}
+
+func TestPolicy_Check(t *testing.T) {
+	bad := "select * from proceses;"
+	if _, err := PolicyWarn.Check(bad, Target{}); err != nil {
+		t.Errorf("warn policy should not reject: %s", err)
+	}
+	if _, err := PolicyReject.Check(bad, Target{}); err == nil {
+		t.Error("reject policy should reject unknown tables")
+	}
+	if _, err := PolicyReject.Check("select * from file;", Target{}); err != nil {
+		t.Errorf("reject policy should not reject warnings: %s", err)
+	}
+	if findings, _ := PolicyOff.Check(bad, Target{}); len(findings) != 0 {
+		t.Error("off policy should not lint")
+	}
+	if _, err := ParsePolicy("sometimes"); err == nil {
+		t.Error("expected invalid policy to fail to parse")
+	}
+}
+
+// TestLint_BundledPacks makes sure the packs shipped with sgt don't trip the linter
+func TestLint_BundledPacks(t *testing.T) {
+	files, err := filepath.Glob("../../../packs/*.json")
+	if err != nil || len(files) == 0 {
+		t.Fatalf("could not find bundled packs: %v", err)
+	}
+	for _, fn := range files {
+		js, err := ioutil.ReadFile(fn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pack := struct {
+			osquery_types.PackSettings
+			Queries map[string]osquery_types.PackQuery `json:"queries"`
+		}{}
+		if err = json.Unmarshal(js, &pack); err != nil {
+			t.Fatalf("%s: %s", fn, err)
+		}
+		for name, q := range pack.Queries {
+			for _, f := range Lint(q.Query, PackQueryTarget(q, pack.PackSettings)) {
+				if f.Severity == SeverityError {
+					t.Errorf("%s %s: %s\n\t%s", filepath.Base(fn), name, f, q.Query)
+				}
+			}
+		}
+	}
+}
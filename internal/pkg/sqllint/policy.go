@@ -0,0 +1,48 @@
+package sqllint
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Policy decides what happens to a query with findings when it is saved or scheduled
+type Policy string
+
+const (
+	// PolicyOff skips linting entirely
+	PolicyOff Policy = "off"
+	// PolicyWarn lints queries and reports the findings but saves them anyway
+	PolicyWarn Policy = "warn"
+	// PolicyReject refuses queries with errors, warnings are reported but don't block
+	PolicyReject Policy = "reject"
+)
+
+// ParsePolicy reads a policy from the server config, an empty value is PolicyWarn
+func ParsePolicy(s string) (Policy, error) {
+	switch p := Policy(strings.ToLower(strings.TrimSpace(s))); p {
+	case "":
+		return PolicyWarn, nil
+	case PolicyOff, PolicyWarn, PolicyReject:
+		return p, nil
+	}
+	return "", fmt.Errorf("invalid sql lint policy %q, expected off, warn or reject", s)
+}
+
+// Check lints the query as the policy requires.  Under PolicyReject an error is returned if any of
+// the findings are errors
+func (p Policy) Check(query string, target Target) ([]Finding, error) {
+	if p == PolicyOff {
+		return nil, nil
+	}
+	findings := Lint(query, target)
+	if p == PolicyReject && HasErrors(findings) {
+		messages := []string{}
+		for _, f := range findings {
+			if f.Severity == SeverityError {
+				messages = append(messages, f.Message)
+			}
+		}
+		return findings, fmt.Errorf("query rejected by sql lint: %s", strings.Join(messages, "; "))
+	}
+	return findings, nil
+}
@@ -0,0 +1,62 @@
+// Package sqllint checks osquery SQL against the bundled osquery table schema before it is sent to
+// nodes.  The schema is generated from tables.json, which is refreshed from the osquery release being
+// targeted (run go generate after updating it)
+package sqllint
+
+//go:generate go run gen.go
+
+import "sort"
+
+// Table describes an osquery table
+type Table struct {
+	Name string
+	// Platforms the table is available on
+	Platforms []string
+	// Since is the first osquery version with the table, empty if it predates the versions tracked
+	Since   string
+	Columns []string
+	// Required lists columns, any one of which should be constrained to keep the table from scanning
+	// the whole system
+	Required []string
+}
+
+// OsqueryVersion returns the osquery release the bundled schema was taken from
+func OsqueryVersion() string {
+	return osqueryVersion
+}
+
+// LookupTable returns the table with the given name
+func LookupTable(name string) (Table, bool) {
+	t, ok := tables[name]
+	return t, ok
+}
+
+// Tables returns every known table, sorted by name
+func Tables() []Table {
+	result := make([]Table, 0, len(tables))
+	for _, t := range tables {
+		result = append(result, t)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// HasColumn returns true if the table has the named column
+func (t Table) HasColumn(name string) bool {
+	for _, c := range t.Columns {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
+// AvailableOn returns true if the table exists on the platform
+func (t Table) AvailableOn(platform string) bool {
+	for _, p := range t.Platforms {
+		if p == platform {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,556 @@
+// Code generated by gen.go from tables.json. DO NOT EDIT.
+
+package sqllint
+
+const osqueryVersion = "3.3.2"
+
+var tables = map[string]Table{
+	"acpi_tables": {
+		Name:      "acpi_tables",
+		Platforms: []string{"darwin", "linux"},
+		Columns:   []string{"name", "size", "md5"},
+	},
+	"ad_config": {
+		Name:      "ad_config",
+		Platforms: []string{"darwin"},
+		Columns:   []string{"name", "domain", "option", "value"},
+	},
+	"alf": {
+		Name:      "alf",
+		Platforms: []string{"darwin"},
+		Columns:   []string{"allow_signed_enabled", "firewall_unload", "global_state", "logging_enabled", "logging_option", "stealth_enabled", "version"},
+	},
+	"alf_exceptions": {
+		Name:      "alf_exceptions",
+		Platforms: []string{"darwin"},
+		Columns:   []string{"path", "state"},
+	},
+	"alf_explicit_auths": {
+		Name:      "alf_explicit_auths",
+		Platforms: []string{"darwin"},
+		Columns:   []string{"process"},
+	},
+	"alf_services": {
+		Name:      "alf_services",
+		Platforms: []string{"darwin"},
+		Columns:   []string{"service", "process", "state"},
+	},
+	"app_schemes": {
+		Name:      "app_schemes",
+		Platforms: []string{"darwin"},
+		Columns:   []string{"scheme", "handler", "enabled", "external", "protected"},
+	},
+	"apps": {
+		Name:      "apps",
+		Platforms: []string{"darwin"},
+		Columns:   []string{"name", "path", "bundle_executable", "bundle_identifier", "bundle_name", "bundle_short_version", "bundle_version", "bundle_package_type", "environment", "element", "compiler", "development_region", "display_name", "info_string", "minimum_system_version", "category", "applescript_enabled", "copyright", "last_opened_time"},
+	},
+	"apt_sources": {
+		Name:      "apt_sources",
+		Platforms: []string{"linux"},
+		Columns:   []string{"name", "source", "base_uri", "release", "version", "maintainer", "components", "architectures"},
+	},
+	"arp_cache": {
+		Name:      "arp_cache",
+		Platforms: []string{"darwin", "linux", "windows", "freebsd"},
+		Columns:   []string{"address", "mac", "interface", "permanent"},
+	},
+	"augeas": {
+		Name:      "augeas",
+		Platforms: []string{"darwin", "linux"},
+		Columns:   []string{"node", "value", "label", "path"},
+		Required:  []string{"path", "node"},
+	},
+	"authorized_keys": {
+		Name:      "authorized_keys",
+		Platforms: []string{"darwin", "linux", "freebsd"},
+		Columns:   []string{"uid", "algorithm", "key", "key_file"},
+	},
+	"block_devices": {
+		Name:      "block_devices",
+		Platforms: []string{"darwin", "linux"},
+		Columns:   []string{"name", "parent", "vendor", "model", "size", "block_size", "uuid", "type", "label"},
+	},
+	"browser_plugins": {
+		Name:      "browser_plugins",
+		Platforms: []string{"darwin"},
+		Columns:   []string{"uid", "name", "identifier", "version", "sdk", "description", "development_region", "native", "path", "disabled"},
+	},
+	"certificates": {
+		Name:      "certificates",
+		Platforms: []string{"darwin", "windows"},
+		Columns:   []string{"common_name", "subject", "issuer", "ca", "self_signed", "not_valid_before", "not_valid_after", "signing_algorithm", "key_algorithm", "key_strength", "key_usage", "subject_key_id", "authority_key_id", "sha1", "path", "serial", "sid", "store_location", "store", "store_id", "username"},
+	},
+	"chrome_extensions": {
+		Name:      "chrome_extensions",
+		Platforms: []string{"darwin", "linux", "windows", "freebsd"},
+		Columns:   []string{"uid", "name", "identifier", "version", "description", "locale", "update_url", "author", "persistent", "path", "permissions", "profile"},
+	},
+	"cpuid": {
+		Name:      "cpuid",
+		Platforms: []string{"darwin", "linux", "windows", "freebsd"},
+		Columns:   []string{"feature", "value", "output_register", "output_bit", "input_eax"},
+	},
+	"crontab": {
+		Name:      "crontab",
+		Platforms: []string{"darwin", "linux", "freebsd"},
+		Columns:   []string{"event", "minute", "hour", "day_of_month", "month", "day_of_week", "command", "path"},
+	},
+	"curl": {
+		Name:      "curl",
+		Platforms: []string{"darwin", "linux", "windows", "freebsd"},
+		Columns:   []string{"url", "method", "user_agent", "response_code", "round_trip_time", "bytes", "result"},
+		Required:  []string{"url"},
+	},
+	"deb_packages": {
+		Name:      "deb_packages",
+		Platforms: []string{"linux"},
+		Columns:   []string{"name", "version", "source", "size", "arch", "revision"},
+	},
+	"disk_encryption": {
+		Name:      "disk_encryption",
+		Platforms: []string{"darwin", "linux"},
+		Columns:   []string{"name", "uuid", "encrypted", "type", "uid", "user_uuid", "encryption_status"},
+	},
+	"dns_resolvers": {
+		Name:      "dns_resolvers",
+		Platforms: []string{"darwin", "linux", "freebsd"},
+		Columns:   []string{"id", "type", "address", "netmask", "options"},
+	},
+	"docker_containers": {
+		Name:      "docker_containers",
+		Platforms: []string{"darwin", "linux"},
+		Columns:   []string{"id", "name", "image", "image_id", "command", "created", "state", "status", "pid", "path", "config_entrypoint", "started_at", "finished_at", "privileged", "security_options", "env", "readonly_rootfs", "cgroup_namespace", "ipc_namespace", "mnt_namespace", "net_namespace", "pid_namespace", "user_namespace", "uts_namespace"},
+	},
+	"docker_images": {
+		Name:      "docker_images",
+		Platforms: []string{"darwin", "linux"},
+		Columns:   []string{"id", "created", "size_bytes", "tags"},
+	},
+	"drivers": {
+		Name:      "drivers",
+		Platforms: []string{"windows"},
+		Columns:   []string{"device_id", "device_name", "image", "description", "service", "service_key", "version", "inf", "class", "provider", "manufacturer", "driver_key", "date", "signed"},
+	},
+	"etc_hosts": {
+		Name:      "etc_hosts",
+		Platforms: []string{"darwin", "linux", "windows", "freebsd"},
+		Columns:   []string{"address", "hostnames"},
+	},
+	"etc_protocols": {
+		Name:      "etc_protocols",
+		Platforms: []string{"darwin", "linux", "windows", "freebsd"},
+		Columns:   []string{"name", "number", "alias", "comment"},
+	},
+	"etc_services": {
+		Name:      "etc_services",
+		Platforms: []string{"darwin", "linux", "windows", "freebsd"},
+		Columns:   []string{"name", "port", "protocol", "aliases", "comment"},
+	},
+	"fan_speed_sensors": {
+		Name:      "fan_speed_sensors",
+		Platforms: []string{"darwin"},
+		Columns:   []string{"fan", "name", "actual", "min", "max", "target"},
+	},
+	"file": {
+		Name:      "file",
+		Platforms: []string{"darwin", "linux", "windows", "freebsd"},
+		Columns:   []string{"path", "directory", "filename", "inode", "uid", "gid", "mode", "device", "size", "block_size", "atime", "mtime", "ctime", "btime", "hard_links", "symlink", "type", "attributes", "volume_serial", "file_id"},
+		Required:  []string{"path", "directory"},
+	},
+	"file_events": {
+		Name:      "file_events",
+		Platforms: []string{"darwin", "linux"},
+		Columns:   []string{"target_path", "category", "action", "transaction_id", "inode", "uid", "gid", "mode", "size", "atime", "mtime", "ctime", "md5", "sha1", "sha256", "hashed", "time", "eid"},
+	},
+	"firefox_addons": {
+		Name:      "firefox_addons",
+		Platforms: []string{"darwin", "linux", "windows", "freebsd"},
+		Columns:   []string{"uid", "name", "identifier", "creator", "type", "version", "description", "source_url", "visible", "active", "disabled", "autoupdate", "native", "location", "path"},
+	},
+	"groups": {
+		Name:      "groups",
+		Platforms: []string{"darwin", "linux", "windows", "freebsd"},
+		Columns:   []string{"gid", "gid_signed", "groupname", "group_sid", "comment"},
+	},
+	"hardware_events": {
+		Name:      "hardware_events",
+		Platforms: []string{"darwin", "linux"},
+		Columns:   []string{"action", "path", "type", "driver", "vendor", "vendor_id", "model", "model_id", "serial", "revision", "time", "eid"},
+	},
+	"hash": {
+		Name:      "hash",
+		Platforms: []string{"darwin", "linux", "windows", "freebsd"},
+		Columns:   []string{"path", "directory", "md5", "sha1", "sha256", "ssdeep"},
+		Required:  []string{"path", "directory"},
+	},
+	"homebrew_packages": {
+		Name:      "homebrew_packages",
+		Platforms: []string{"darwin"},
+		Columns:   []string{"name", "path", "version"},
+	},
+	"interface_addresses": {
+		Name:      "interface_addresses",
+		Platforms: []string{"darwin", "linux", "windows", "freebsd"},
+		Columns:   []string{"interface", "address", "mask", "broadcast", "point_to_point", "type", "friendly_name"},
+	},
+	"interface_details": {
+		Name:      "interface_details",
+		Platforms: []string{"darwin", "linux", "windows", "freebsd"},
+		Columns:   []string{"interface", "mac", "type", "mtu", "metric", "flags", "ipackets", "opackets", "ibytes", "obytes", "ierrors", "oerrors", "idrops", "odrops", "collisions", "last_change", "link_speed", "pci_slot", "friendly_name", "description", "manufacturer", "connection_id", "connection_status", "enabled", "physical_adapter", "speed", "service", "dhcp_enabled", "dhcp_lease_expires", "dhcp_lease_obtained", "dhcp_server", "dns_domain", "dns_domain_suffix_search_order", "dns_host_name", "dns_server_search_order"},
+	},
+	"iokit_devicetree": {
+		Name:      "iokit_devicetree",
+		Platforms: []string{"darwin"},
+		Columns:   []string{"name", "class", "id", "parent", "device_path", "service", "busy", "retain_count", "depth"},
+	},
+	"iptables": {
+		Name:      "iptables",
+		Platforms: []string{"linux"},
+		Columns:   []string{"filter_name", "chain", "policy", "target", "protocol", "src_port", "dst_port", "src_ip", "src_mask", "iniface", "iniface_mask", "dst_ip", "dst_mask", "outiface", "outiface_mask", "match", "packets", "bytes"},
+	},
+	"kernel_extensions": {
+		Name:      "kernel_extensions",
+		Platforms: []string{"darwin"},
+		Columns:   []string{"idx", "refs", "size", "name", "version", "linked_against", "path"},
+	},
+	"kernel_info": {
+		Name:      "kernel_info",
+		Platforms: []string{"darwin", "linux", "windows", "freebsd"},
+		Columns:   []string{"version", "arguments", "path", "device"},
+	},
+	"kernel_modules": {
+		Name:      "kernel_modules",
+		Platforms: []string{"linux"},
+		Columns:   []string{"name", "size", "used_by", "status", "address"},
+	},
+	"keychain_items": {
+		Name:      "keychain_items",
+		Platforms: []string{"darwin"},
+		Columns:   []string{"label", "description", "comment", "created", "modified", "type", "path"},
+	},
+	"last": {
+		Name:      "last",
+		Platforms: []string{"darwin", "linux", "freebsd"},
+		Columns:   []string{"username", "tty", "pid", "type", "time", "host"},
+	},
+	"launchd": {
+		Name:      "launchd",
+		Platforms: []string{"darwin"},
+		Columns:   []string{"path", "name", "label", "program", "run_at_load", "keep_alive", "on_demand", "disabled", "username", "groupname", "stdout_path", "stderr_path", "start_interval", "program_arguments", "watch_paths", "queue_directories", "inetd_compatibility", "start_on_mount", "root_directory", "working_directory", "process_type"},
+	},
+	"listening_ports": {
+		Name:      "listening_ports",
+		Platforms: []string{"darwin", "linux", "windows", "freebsd"},
+		Columns:   []string{"pid", "port", "protocol", "family", "address", "fd", "socket", "path"},
+	},
+	"logged_in_users": {
+		Name:      "logged_in_users",
+		Platforms: []string{"darwin", "linux", "windows", "freebsd"},
+		Columns:   []string{"type", "user", "tty", "host", "time", "pid"},
+	},
+	"magic": {
+		Name:      "magic",
+		Platforms: []string{"darwin", "linux"},
+		Columns:   []string{"path", "data", "mime_type", "mime_encoding"},
+		Required:  []string{"path"},
+	},
+	"memory_info": {
+		Name:      "memory_info",
+		Platforms: []string{"linux"},
+		Columns:   []string{"memory_total", "memory_free", "buffers", "cached", "swap_cached", "active", "inactive", "swap_total", "swap_free"},
+	},
+	"mounts": {
+		Name:      "mounts",
+		Platforms: []string{"darwin", "linux", "freebsd"},
+		Columns:   []string{"device", "device_alias", "path", "type", "blocks_size", "blocks", "blocks_free", "blocks_available", "inodes", "inodes_free", "flags"},
+	},
+	"nfs_shares": {
+		Name:      "nfs_shares",
+		Platforms: []string{"darwin"},
+		Columns:   []string{"share", "options", "readonly"},
+	},
+	"nvram": {
+		Name:      "nvram",
+		Platforms: []string{"darwin"},
+		Columns:   []string{"name", "type", "value"},
+	},
+	"opera_extensions": {
+		Name:      "opera_extensions",
+		Platforms: []string{"darwin", "linux"},
+		Columns:   []string{"uid", "name", "identifier", "version", "description", "locale", "update_url", "author", "persistent", "path"},
+	},
+	"os_version": {
+		Name:      "os_version",
+		Platforms: []string{"darwin", "linux", "windows", "freebsd"},
+		Columns:   []string{"name", "version", "major", "minor", "patch", "build", "platform", "platform_like", "codename", "install_date"},
+	},
+	"osquery_events": {
+		Name:      "osquery_events",
+		Platforms: []string{"darwin", "linux", "windows", "freebsd"},
+		Columns:   []string{"name", "publisher", "type", "subscriptions", "events", "refreshes", "active"},
+	},
+	"osquery_extensions": {
+		Name:      "osquery_extensions",
+		Platforms: []string{"darwin", "linux", "windows", "freebsd"},
+		Columns:   []string{"uuid", "name", "version", "sdk_version", "path", "type"},
+	},
+	"osquery_flags": {
+		Name:      "osquery_flags",
+		Platforms: []string{"darwin", "linux", "windows", "freebsd"},
+		Columns:   []string{"name", "type", "description", "default_value", "value", "shell_only"},
+	},
+	"osquery_info": {
+		Name:      "osquery_info",
+		Platforms: []string{"darwin", "linux", "windows", "freebsd"},
+		Columns:   []string{"pid", "uuid", "instance_id", "version", "config_hash", "config_valid", "extensions", "build_platform", "build_distro", "start_time", "watcher", "platform_mask"},
+	},
+	"osquery_packs": {
+		Name:      "osquery_packs",
+		Platforms: []string{"darwin", "linux", "windows", "freebsd"},
+		Columns:   []string{"name", "platform", "version", "shard", "discovery_cache_hits", "discovery_executions", "active"},
+	},
+	"osquery_registry": {
+		Name:      "osquery_registry",
+		Platforms: []string{"darwin", "linux", "windows", "freebsd"},
+		Columns:   []string{"registry", "name", "owner_uuid", "internal", "active"},
+	},
+	"osquery_schedule": {
+		Name:      "osquery_schedule",
+		Platforms: []string{"darwin", "linux", "windows", "freebsd"},
+		Columns:   []string{"name", "query", "interval", "executions", "last_executed", "denylisted", "blacklisted", "output_size", "wall_time", "user_time", "system_time", "average_memory"},
+	},
+	"package_receipts": {
+		Name:      "package_receipts",
+		Platforms: []string{"darwin"},
+		Columns:   []string{"package_id", "package_filename", "version", "location", "install_time", "installer_name", "path"},
+	},
+	"patches": {
+		Name:      "patches",
+		Platforms: []string{"windows"},
+		Columns:   []string{"csname", "hotfix_id", "caption", "description", "fix_comments", "installed_by", "install_date", "installed_on"},
+	},
+	"pci_devices": {
+		Name:      "pci_devices",
+		Platforms: []string{"darwin", "linux"},
+		Columns:   []string{"pci_slot", "pci_class", "driver", "vendor", "vendor_id", "model", "model_id", "pci_class_id", "pci_subclass_id", "pci_subclass", "subsystem_vendor_id", "subsystem_vendor", "subsystem_model_id", "subsystem_model"},
+	},
+	"platform_info": {
+		Name:      "platform_info",
+		Platforms: []string{"darwin", "linux", "windows", "freebsd"},
+		Columns:   []string{"vendor", "version", "date", "revision", "address", "size", "volume_size", "extra"},
+	},
+	"plist": {
+		Name:      "plist",
+		Platforms: []string{"darwin"},
+		Columns:   []string{"key", "subkey", "value", "parent", "path"},
+		Required:  []string{"path"},
+	},
+	"portage_packages": {
+		Name:      "portage_packages",
+		Platforms: []string{"linux"},
+		Columns:   []string{"package", "version", "slot", "build_time", "repository", "eapi", "size", "world"},
+	},
+	"portage_use": {
+		Name:      "portage_use",
+		Platforms: []string{"linux"},
+		Columns:   []string{"package", "version", "use"},
+	},
+	"process_envs": {
+		Name:      "process_envs",
+		Platforms: []string{"darwin", "linux", "freebsd"},
+		Columns:   []string{"pid", "key", "value"},
+	},
+	"process_events": {
+		Name:      "process_events",
+		Platforms: []string{"darwin", "linux"},
+		Columns:   []string{"pid", "path", "mode", "cmdline", "cmdline_size", "env", "env_count", "env_size", "cwd", "auid", "uid", "euid", "gid", "egid", "owner_uid", "owner_gid", "atime", "mtime", "ctime", "btime", "overflows", "parent", "time", "uptime", "eid", "syscall"},
+	},
+	"process_memory_map": {
+		Name:      "process_memory_map",
+		Platforms: []string{"darwin", "linux", "windows"},
+		Columns:   []string{"pid", "start", "end", "permissions", "offset", "device", "inode", "path", "pseudo"},
+	},
+	"process_open_files": {
+		Name:      "process_open_files",
+		Platforms: []string{"darwin", "linux", "freebsd"},
+		Columns:   []string{"pid", "fd", "path"},
+	},
+	"process_open_sockets": {
+		Name:      "process_open_sockets",
+		Platforms: []string{"darwin", "linux", "windows", "freebsd"},
+		Columns:   []string{"pid", "fd", "socket", "family", "protocol", "local_address", "remote_address", "local_port", "remote_port", "path", "state", "net_namespace"},
+	},
+	"processes": {
+		Name:      "processes",
+		Platforms: []string{"darwin", "linux", "windows", "freebsd"},
+		Columns:   []string{"pid", "name", "path", "cmdline", "state", "cwd", "root", "uid", "gid", "euid", "egid", "suid", "sgid", "on_disk", "wired_size", "resident_size", "total_size", "user_time", "system_time", "disk_bytes_read", "disk_bytes_written", "start_time", "parent", "pgroup", "threads", "nice", "is_elevated_token", "elapsed_time", "handle_count", "percent_processor_time", "upid", "uppid", "cpu_type", "cpu_subtype"},
+	},
+	"programs": {
+		Name:      "programs",
+		Platforms: []string{"windows"},
+		Columns:   []string{"name", "version", "install_location", "install_source", "language", "publisher", "uninstall_string", "install_date", "identifying_number"},
+	},
+	"python_packages": {
+		Name:      "python_packages",
+		Platforms: []string{"darwin", "linux", "windows", "freebsd"},
+		Columns:   []string{"name", "version", "summary", "author", "license", "path", "directory"},
+	},
+	"registry": {
+		Name:      "registry",
+		Platforms: []string{"windows"},
+		Columns:   []string{"key", "path", "name", "type", "data", "mtime"},
+	},
+	"routes": {
+		Name:      "routes",
+		Platforms: []string{"darwin", "linux", "windows", "freebsd"},
+		Columns:   []string{"destination", "netmask", "gateway", "source", "flags", "interface", "mtu", "metric", "type", "hopcount"},
+	},
+	"rpm_packages": {
+		Name:      "rpm_packages",
+		Platforms: []string{"linux"},
+		Columns:   []string{"name", "version", "release", "source", "size", "sha1", "arch"},
+	},
+	"safari_extensions": {
+		Name:      "safari_extensions",
+		Platforms: []string{"darwin"},
+		Columns:   []string{"uid", "name", "identifier", "version", "sdk", "update_url", "author", "developer_id", "description", "path"},
+	},
+	"sandboxes": {
+		Name:      "sandboxes",
+		Platforms: []string{"darwin"},
+		Columns:   []string{"label", "user", "enabled", "build_id", "bundle_path", "path"},
+	},
+	"scheduled_tasks": {
+		Name:      "scheduled_tasks",
+		Platforms: []string{"windows"},
+		Columns:   []string{"name", "action", "path", "enabled", "state", "hidden", "last_run_time", "next_run_time", "last_run_message", "last_run_code"},
+	},
+	"services": {
+		Name:      "services",
+		Platforms: []string{"windows"},
+		Columns:   []string{"name", "service_type", "display_name", "status", "pid", "start_type", "win32_exit_code", "service_exit_code", "path", "module_path", "description", "user_account"},
+	},
+	"shared_folders": {
+		Name:      "shared_folders",
+		Platforms: []string{"darwin"},
+		Columns:   []string{"name", "path"},
+	},
+	"shared_resources": {
+		Name:      "shared_resources",
+		Platforms: []string{"windows"},
+		Columns:   []string{"description", "install_date", "status", "allow_maximum", "maximum_allowed", "name", "path", "type"},
+	},
+	"shell_history": {
+		Name:      "shell_history",
+		Platforms: []string{"darwin", "linux", "freebsd"},
+		Columns:   []string{"uid", "time", "command", "history_file"},
+	},
+	"sip_config": {
+		Name:      "sip_config",
+		Platforms: []string{"darwin"},
+		Columns:   []string{"config_flag", "enabled", "enabled_nvram"},
+	},
+	"smbios_tables": {
+		Name:      "smbios_tables",
+		Platforms: []string{"darwin", "linux"},
+		Columns:   []string{"number", "type", "description", "handle", "header_size", "size", "md5"},
+	},
+	"startup_items": {
+		Name:      "startup_items",
+		Platforms: []string{"darwin", "windows"},
+		Columns:   []string{"name", "path", "args", "type", "source", "status", "username"},
+	},
+	"sudoers": {
+		Name:      "sudoers",
+		Platforms: []string{"darwin", "linux", "freebsd"},
+		Columns:   []string{"header", "rule_details"},
+	},
+	"suid_bin": {
+		Name:      "suid_bin",
+		Platforms: []string{"darwin", "linux", "freebsd"},
+		Columns:   []string{"path", "username", "groupname", "permissions"},
+	},
+	"system_controls": {
+		Name:      "system_controls",
+		Platforms: []string{"darwin", "linux"},
+		Columns:   []string{"name", "oid", "subsystem", "current_value", "config_value", "type", "field_name"},
+	},
+	"system_info": {
+		Name:      "system_info",
+		Platforms: []string{"darwin", "linux", "windows", "freebsd"},
+		Columns:   []string{"hostname", "uuid", "cpu_type", "cpu_subtype", "cpu_brand", "cpu_physical_cores", "cpu_logical_cores", "cpu_microcode", "physical_memory", "hardware_vendor", "hardware_model", "hardware_version", "hardware_serial", "computer_name", "local_hostname"},
+	},
+	"temperature_sensors": {
+		Name:      "temperature_sensors",
+		Platforms: []string{"darwin"},
+		Columns:   []string{"key", "name", "celsius", "fahrenheit"},
+	},
+	"time": {
+		Name:      "time",
+		Platforms: []string{"darwin", "linux", "windows", "freebsd"},
+		Columns:   []string{"weekday", "year", "month", "day", "hour", "minutes", "seconds", "timezone", "local_time", "local_timezone", "unix_time", "timestamp", "datetime", "iso_8601", "win_timestamp"},
+	},
+	"uptime": {
+		Name:      "uptime",
+		Platforms: []string{"darwin", "linux", "windows", "freebsd"},
+		Columns:   []string{"days", "hours", "minutes", "seconds", "total_seconds"},
+	},
+	"usb_devices": {
+		Name:      "usb_devices",
+		Platforms: []string{"darwin", "linux"},
+		Columns:   []string{"usb_address", "usb_port", "vendor", "vendor_id", "version", "model", "model_id", "serial", "class", "subclass", "protocol", "removable"},
+	},
+	"user_groups": {
+		Name:      "user_groups",
+		Platforms: []string{"darwin", "linux", "windows", "freebsd"},
+		Columns:   []string{"uid", "gid"},
+	},
+	"user_ssh_keys": {
+		Name:      "user_ssh_keys",
+		Platforms: []string{"darwin", "linux", "freebsd"},
+		Columns:   []string{"uid", "path", "encrypted"},
+	},
+	"users": {
+		Name:      "users",
+		Platforms: []string{"darwin", "linux", "windows", "freebsd"},
+		Columns:   []string{"uid", "gid", "uid_signed", "gid_signed", "username", "description", "directory", "shell", "uuid", "type"},
+	},
+	"wifi_networks": {
+		Name:      "wifi_networks",
+		Platforms: []string{"darwin"},
+		Columns:   []string{"ssid", "network_name", "security_type", "last_connected", "passpoint", "possibly_hidden", "roaming", "roaming_profile", "captive_portal", "auto_login", "temporarily_disabled", "disabled"},
+	},
+	"windows_events": {
+		Name:      "windows_events",
+		Platforms: []string{"windows"},
+		Since:     "3.3.0",
+		Columns:   []string{"time", "datetime", "source", "provider_name", "provider_guid", "eventid", "task", "level", "keywords", "data", "eid"},
+	},
+	"wmi_cli_event_consumers": {
+		Name:      "wmi_cli_event_consumers",
+		Platforms: []string{"windows"},
+		Columns:   []string{"name", "command_line_template", "executable_path", "class", "relative_path"},
+	},
+	"xprotect_entries": {
+		Name:      "xprotect_entries",
+		Platforms: []string{"darwin"},
+		Columns:   []string{"name", "launch_type", "identity", "filename", "filetype", "optional", "uses_pattern"},
+	},
+	"xprotect_reports": {
+		Name:      "xprotect_reports",
+		Platforms: []string{"darwin"},
+		Columns:   []string{"name", "user_action", "time"},
+	},
+	"yara": {
+		Name:      "yara",
+		Platforms: []string{"darwin", "linux", "freebsd"},
+		Columns:   []string{"path", "matches", "count", "sig_group", "sigfile", "strings", "tags"},
+		Required:  []string{"path"},
+	},
+	"yara_events": {
+		Name:      "yara_events",
+		Platforms: []string{"darwin", "linux", "freebsd"},
+		Columns:   []string{"target_path", "category", "action", "transaction_id", "matches", "count", "strings", "tags", "time", "eid"},
+	},
+}
@@ -0,0 +1,274 @@
+package osquery_types
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sort"
+	"time"
+)
+
+// DefaultCampaignTimeout is how long, in seconds, a campaign waits for its nodes to answer when the
+// request doesn't say
+const DefaultCampaignTimeout = 3600
+
+// MaxCampaignResultBytes limits the rows stored for one node's answer, so that the stored result
+// stays under the DynamoDB item size limit.  Rows past the limit are dropped and the result is marked
+// truncated
+const MaxCampaignResultBytes = 350 * 1024
+
+// CampaignTargets selects the nodes a campaign runs on.  A node is targeted if its node key is
+// listed, or if it matches every other kind of target given: one of the tags, one of the
+// configuration groups, one of the platforms and the selector
+type CampaignTargets struct {
+	NodeKeys  []string `json:"node_keys,omitempty"`
+	Tags      []string `json:"tags,omitempty"`
+	Groups    []string `json:"groups,omitempty"`
+	Platforms []string `json:"platforms,omitempty"`
+	// Selector is a node selector, see internal/pkg/selector
+	Selector string `json:"selector,omitempty"`
+}
+
+// Empty returns true if no targets are given
+func (ct CampaignTargets) Empty() bool {
+	return len(ct.NodeKeys) == 0 && len(ct.Tags) == 0 && len(ct.Groups) == 0 && len(ct.Platforms) == 0 &&
+		ct.Selector == ""
+}
+
+// Matches returns true if the node is targeted.  selected is the parsed Selector, which is nil if there
+// isn't one
+func (ct CampaignTargets) Matches(oc OsqueryClient, selected func(OsqueryClient) bool) bool {
+	if containsString(ct.NodeKeys, oc.NodeKey) {
+		return true
+	}
+	if len(ct.Tags) == 0 && len(ct.Groups) == 0 && len(ct.Platforms) == 0 && selected == nil {
+		return false
+	}
+	if selected != nil && !selected(oc) {
+		return false
+	}
+	rt := oc.RenderTarget()
+	if len(ct.Tags) > 0 {
+		tagged := false
+		for _, tag := range ct.Tags {
+			if rt.HasTag(tag) {
+				tagged = true
+				break
+			}
+		}
+		if !tagged {
+			return false
+		}
+	}
+	if len(ct.Groups) > 0 && !containsString(ct.Groups, oc.ConfigurationGroup) {
+		return false
+	}
+	if len(ct.Platforms) > 0 {
+		matched := false
+		for _, platform := range ct.Platforms {
+			if rt.Platform != "" && PlatformMatches(platform, rt.Platform) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// Resolve returns the targeted nodes among clients, and any listed node keys that aren't a node.
+// Nodes that are invalid or pending registration approval don't fetch queries, so they are never
+// targeted.  selected is the parsed Selector, which is nil if there isn't one
+func (ct CampaignTargets) Resolve(clients []OsqueryClient, selected func(OsqueryClient) bool) ([]OsqueryClient, []string) {
+	targeted := []OsqueryClient{}
+	found := map[string]bool{}
+	for _, oc := range clients {
+		found[oc.NodeKey] = true
+		if oc.NodeInvalid || oc.PendingRegistrationApproval {
+			continue
+		}
+		if ct.Matches(oc, selected) {
+			targeted = append(targeted, oc)
+		}
+	}
+	unknown := []string{}
+	for _, nk := range ct.NodeKeys {
+		if !found[nk] {
+			unknown = append(unknown, nk)
+		}
+	}
+	return targeted, unknown
+}
+
+// Campaign is a distributed query sent to a set of nodes.  The campaign ID is the name the query is
+// sent to each node under, so results are matched back to the campaign when the node writes them
+type Campaign struct {
+	CampaignID string          `json:"campaign_id"`
+	Query      string          `json:"query"`
+	Targets    CampaignTargets `json:"targets"`
+	// CreatedAt is in RFC 3339 format
+	CreatedAt string `json:"created_at"`
+	// Timeout is the number of seconds after CreatedAt that nodes which haven't answered are timed out
+	Timeout int `json:"timeout"`
+	// ScheduleID is the schedule that started the campaign, if any
+	ScheduleID string `json:"schedule_id,omitempty"`
+	// SavedQuery is the name of the saved query launched by the campaign, if any
+	SavedQuery string `json:"saved_query,omitempty"`
+}
+
+// NewCampaignID returns a random campaign ID
+func NewCampaignID() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Validate checks the campaign has an ID, a query, targets and a timeout
+func (c Campaign) Validate() error {
+	switch {
+	case c.CampaignID == "":
+		return errors.New("campaign has no ID")
+	case c.Query == "":
+		return errors.New("campaign has no query")
+	case c.Targets.Empty():
+		return errors.New("campaign has no targets")
+	case c.Timeout <= 0:
+		return errors.New("campaign timeout must be a positive number of seconds")
+	}
+	return nil
+}
+
+// Deadline returns when nodes that haven't answered are timed out.  A campaign with an unreadable
+// creation time has no deadline
+func (c Campaign) Deadline() time.Time {
+	created, err := time.Parse(time.RFC3339, c.CreatedAt)
+	if err != nil {
+		return time.Time{}
+	}
+	return created.Add(time.Duration(c.Timeout) * time.Second)
+}
+
+// CampaignNodeState is where a targeted node is in answering a campaign's query
+type CampaignNodeState string
+
+const (
+	// CampaignQueued nodes have not yet fetched the query
+	CampaignQueued CampaignNodeState = "queued"
+	// CampaignDelivered nodes have fetched the query but not written its results
+	CampaignDelivered CampaignNodeState = "delivered"
+	// CampaignAnswered nodes ran the query and wrote its results
+	CampaignAnswered CampaignNodeState = "answered"
+	// CampaignErrored nodes reported that the query failed, or the query couldn't be queued for them
+	CampaignErrored CampaignNodeState = "errored"
+	// CampaignTimedOut nodes hadn't answered by the campaign's deadline, or weren't sent the query
+	// again after it went unanswered too many times.  Queued and delivered nodes are shown as timed out
+	// once the deadline has passed, before the query is given up on
+	CampaignTimedOut CampaignNodeState = "timed_out"
+)
+
+// CampaignNode is a targeted node's state in a campaign, and its results once it has answered.  The
+// node key is only stored, see Public
+type CampaignNode struct {
+	CampaignID     string            `json:"campaign_id"`
+	NodeKey        string            `json:"node_key,omitempty"`
+	HostIdentifier string            `json:"host_identifier"`
+	State          CampaignNodeState `json:"state"`
+	// UpdatedAt is when the state last changed, in RFC 3339 format
+	UpdatedAt string `json:"updated_at"`
+	// Status is the status osquery reported for the query, anything but 0 is an error, and Message is
+	// osquery's error message
+	Status  int    `json:"status,omitempty"`
+	Message string `json:"message,omitempty"`
+	// Error says why the query couldn't be queued for the node
+	Error     string              `json:"error,omitempty"`
+	RowCount  int                 `json:"row_count"`
+	Rows      []map[string]string `json:"rows,omitempty"`
+	Truncated bool                `json:"truncated,omitempty"`
+}
+
+// Public returns the node as it is shown to users, without its node key.  The node key is the
+// credential the node authenticates with, so it is never sent in API responses
+func (cn CampaignNode) Public() CampaignNode {
+	cn.NodeKey = ""
+	return cn
+}
+
+// SetState sets the node's state and when it changed
+func (cn *CampaignNode) SetState(state CampaignNodeState, now time.Time) {
+	cn.State = state
+	cn.UpdatedAt = now.UTC().Format(time.RFC3339)
+}
+
+// SetRows stores the rows the node answered with, dropping rows once their marshalled size passes
+// maxBytes
+func (cn *CampaignNode) SetRows(rows []map[string]string, maxBytes int) {
+	cn.RowCount = len(rows)
+	cn.Rows = []map[string]string{}
+	cn.Truncated = false
+	size := 0
+	for _, row := range rows {
+		js, _ := json.Marshal(row)
+		size += len(js)
+		if size > maxBytes {
+			cn.Truncated = true
+			return
+		}
+		cn.Rows = append(cn.Rows, row)
+	}
+}
+
+// StateAt returns the node's state at now, which is timed out for nodes still queued or delivered
+// after the campaign's deadline
+func (cn CampaignNode) StateAt(c Campaign, now time.Time) CampaignNodeState {
+	deadline := c.Deadline()
+	if (cn.State == CampaignQueued || cn.State == CampaignDelivered) && !deadline.IsZero() && !now.Before(deadline) {
+		return CampaignTimedOut
+	}
+	return cn.State
+}
+
+// CampaignStatus is a campaign with the state of each of its nodes, without their results
+type CampaignStatus struct {
+	Campaign
+	Counts map[CampaignNodeState]int `json:"counts"`
+	Nodes  []CampaignNode            `json:"nodes"`
+}
+
+// NewCampaignStatus returns the campaign's status at now.  Nodes are sorted by host identifier
+func NewCampaignStatus(c Campaign, nodes []CampaignNode, now time.Time) CampaignStatus {
+	cs := CampaignStatus{
+		Campaign: c,
+		Counts: map[CampaignNodeState]int{
+			CampaignQueued:    0,
+			CampaignDelivered: 0,
+			CampaignAnswered:  0,
+			CampaignErrored:   0,
+			CampaignTimedOut:  0,
+		},
+		Nodes: []CampaignNode{},
+	}
+	for _, cn := range nodes {
+		cn.State = cn.StateAt(c, now)
+		cn.Rows = nil
+		cs.Counts[cn.State]++
+		cs.Nodes = append(cs.Nodes, cn.Public())
+	}
+	sort.SliceStable(cs.Nodes, func(i, j int) bool {
+		return cs.Nodes[i].HostIdentifier < cs.Nodes[j].HostIdentifier
+	})
+	return cs
+}
+
+func containsString(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
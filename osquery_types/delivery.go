@@ -0,0 +1,64 @@
+package osquery_types
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+)
+
+// ConfigDelivery records the config a node was last sent
+type ConfigDelivery struct {
+	ConfigName string `json:"config_name"`
+	Revision   int    `json:"revision"`
+	// Hash is the sha256 of the config as it was sent, see ConfigHash
+	Hash string `json:"hash"`
+	// FetchedAt is when the node fetched the config, in RFC 3339 format
+	FetchedAt string `json:"fetched_at"`
+}
+
+// ConfigHash returns a hash of the config as it is sent to a node.  Map keys are marshalled in sorted
+// order, so equal configs always have the same hash
+func ConfigHash(oc OsqueryConfig) (string, error) {
+	js, err := json.Marshal(oc)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(js)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// RolloutStatus says whether a node has fetched the latest version of its config
+type RolloutStatus string
+
+const (
+	// RolloutCurrent nodes last fetched exactly the config they would be sent now
+	RolloutCurrent RolloutStatus = "current"
+	// RolloutStale nodes last fetched a config that has changed since
+	RolloutStale RolloutStatus = "stale"
+	// RolloutNever nodes have not fetched a config since delivery tracking began
+	RolloutNever RolloutStatus = "never"
+)
+
+// RolloutNode is a node that has not fetched the latest version of its config
+type RolloutNode struct {
+	NodeKey        string        `json:"node_key"`
+	HostIdentifier string        `json:"host_identifier"`
+	Status         RolloutStatus `json:"status"`
+	// Reason says what has changed for stale nodes
+	Reason    string `json:"reason,omitempty"`
+	Revision  int    `json:"revision,omitempty"`
+	FetchedAt string `json:"fetched_at,omitempty"`
+}
+
+// ConfigRollout summarizes how far the current version of a named config has rolled out to the nodes
+// assigned to it.  Nodes pending registration approval can't fetch a config, so they are only counted
+type ConfigRollout struct {
+	ConfigName string        `json:"config_name"`
+	Revision   int           `json:"revision"`
+	Nodes      int           `json:"nodes"`
+	Current    int           `json:"current"`
+	Stale      int           `json:"stale"`
+	Never      int           `json:"never"`
+	Pending    int           `json:"pending_approval"`
+	Laggards   []RolloutNode `json:"laggards"`
+}
@@ -0,0 +1,180 @@
+package osquery_types
+
+import (
+	"fmt"
+	"time"
+)
+
+// DeliveryPolicy says when a distributed query a node hasn't answered is sent again, and when it is
+// given up on
+type DeliveryPolicy struct {
+	// RedeliverAfter is how long after a query is sent that it is sent again if the node hasn't
+	// answered it
+	RedeliverAfter time.Duration
+	// MaxDeliveries is how many times a query is sent before it is given up on
+	MaxDeliveries int
+	// TTL is how long after it was queued that a query is given up on, whether or not it was sent
+	TTL time.Duration
+}
+
+// The delivery policy used when the server config doesn't set one
+const (
+	DefaultDistributedRedeliverAfter = 5 * time.Minute
+	DefaultDistributedMaxDeliveries  = 3
+	DefaultDistributedTTL            = 24 * time.Hour
+)
+
+// DistributedDeliveryPolicy returns the delivery policy set in the server config, with defaults for
+// anything it doesn't set
+func (c ServerConfig) DistributedDeliveryPolicy() DeliveryPolicy {
+	policy := DeliveryPolicy{
+		RedeliverAfter: DefaultDistributedRedeliverAfter,
+		MaxDeliveries:  DefaultDistributedMaxDeliveries,
+		TTL:            DefaultDistributedTTL,
+	}
+	if c.DistributedQueryRedeliverAfter > 0 {
+		policy.RedeliverAfter = time.Duration(c.DistributedQueryRedeliverAfter) * time.Second
+	}
+	if c.DistributedQueryMaxDeliveries > 0 {
+		policy.MaxDeliveries = c.DistributedQueryMaxDeliveries
+	}
+	if c.DistributedQueryTTL > 0 {
+		policy.TTL = time.Duration(c.DistributedQueryTTL) * time.Second
+	}
+	return policy
+}
+
+// PendingQuery is a distributed query that a node hasn't answered yet.  It is sent to the node under
+// Name, and stays pending until the node writes results or a status for that name
+type PendingQuery struct {
+	Name       string `json:"name"`
+	Query      string `json:"query"`
+	CampaignID string `json:"campaign_id,omitempty"`
+	// QueuedAt, DeliveredAt and ExpiresAt are in RFC 3339 format.  DeliveredAt is when the query was
+	// last sent.  ExpiresAt is optional, the query expires at the earlier of it and the policy's TTL
+	QueuedAt    string `json:"queued_at"`
+	DeliveredAt string `json:"delivered_at,omitempty"`
+	ExpiresAt   string `json:"expires_at,omitempty"`
+	Deliveries  int    `json:"deliveries"`
+}
+
+// ExpiredQuery is a pending query that was given up on
+type ExpiredQuery struct {
+	PendingQuery
+	Outcome DistributedOutcome
+	// Reason says why the query was given up on
+	Reason string
+}
+
+func parseQueueTime(s string) time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// expiry returns the outcome of the query if it should be given up on at now
+func (pq PendingQuery) expiry(now time.Time, policy DeliveryPolicy) (DistributedOutcome, string) {
+	expires := parseQueueTime(pq.QueuedAt).Add(policy.TTL)
+	if at := parseQueueTime(pq.ExpiresAt); !at.IsZero() && at.Before(expires) {
+		expires = at
+	}
+	if !now.Before(expires) {
+		return DistributedExpired, fmt.Sprintf("not answered by %s, after %d deliveries",
+			expires.UTC().Format(time.RFC3339), pq.Deliveries)
+	}
+	if pq.Deliveries >= policy.MaxDeliveries && !now.Before(parseQueueTime(pq.DeliveredAt).Add(policy.RedeliverAfter)) {
+		return DistributedRetriesExhausted, fmt.Sprintf("not answered after %d deliveries", pq.Deliveries)
+	}
+	return "", ""
+}
+
+// due returns true if the query should be sent at now: it hasn't been sent, or it was sent and not
+// answered in time
+func (pq PendingQuery) due(now time.Time, policy DeliveryPolicy) bool {
+	return pq.Deliveries == 0 || !now.Before(parseQueueTime(pq.DeliveredAt).Add(policy.RedeliverAfter))
+}
+
+// AddPending queues queries, replacing any pending query with the same name
+func (dq *DistributedQuery) AddPending(queries ...PendingQuery) {
+	for _, pq := range queries {
+		replaced := false
+		for i := range dq.Pending {
+			if dq.Pending[i].Name == pq.Name {
+				dq.Pending[i] = pq
+				replaced = true
+			}
+		}
+		if !replaced {
+			dq.Pending = append(dq.Pending, pq)
+		}
+	}
+}
+
+// Promote turns the queries added by /distributed/add into pending queries, named id1, id2... in the
+// order they were added to the node
+func (dq *DistributedQuery) Promote(now time.Time) {
+	for _, q := range dq.Queries {
+		dq.NextID++
+		dq.Pending = append(dq.Pending, PendingQuery{
+			Name:     fmt.Sprintf("id%d", dq.NextID),
+			Query:    q,
+			QueuedAt: now.UTC().Format(time.RFC3339),
+		})
+	}
+	dq.Queries = nil
+}
+
+// Expire removes and returns the pending queries that are given up on at now
+func (dq *DistributedQuery) Expire(now time.Time, policy DeliveryPolicy) []ExpiredQuery {
+	expired := []ExpiredQuery{}
+	pending := []PendingQuery{}
+	for _, pq := range dq.Pending {
+		if outcome, reason := pq.expiry(now, policy); outcome != "" {
+			expired = append(expired, ExpiredQuery{PendingQuery: pq, Outcome: outcome, Reason: reason})
+			continue
+		}
+		pending = append(pending, pq)
+	}
+	dq.Pending = pending
+	return expired
+}
+
+// Deliver returns the queries to send to the node at now and marks them as sent, after expiring the
+// queries that are given up on, which are also returned.  Queries sent earlier stay pending until
+// they are answered
+func (dq *DistributedQuery) Deliver(now time.Time, policy DeliveryPolicy) ([]PendingQuery, []ExpiredQuery) {
+	dq.Promote(now)
+	expired := dq.Expire(now, policy)
+	sent := []PendingQuery{}
+	for i, pq := range dq.Pending {
+		if !pq.due(now, policy) {
+			continue
+		}
+		dq.Pending[i].Deliveries++
+		dq.Pending[i].DeliveredAt = now.UTC().Format(time.RFC3339)
+		sent = append(sent, dq.Pending[i])
+	}
+	return sent, expired
+}
+
+// Acknowledge removes the named queries, which the node has answered, and returns the ones it
+// removed
+func (dq *DistributedQuery) Acknowledge(names []string) []PendingQuery {
+	answered := map[string]bool{}
+	for _, name := range names {
+		answered[name] = true
+	}
+	acked := []PendingQuery{}
+	pending := []PendingQuery{}
+	for _, pq := range dq.Pending {
+		if answered[pq.Name] {
+			acked = append(acked, pq)
+			continue
+		}
+		pending = append(pending, pq)
+	}
+	dq.Pending = pending
+	return acked
+}
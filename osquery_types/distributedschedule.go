@@ -0,0 +1,88 @@
+package osquery_types
+
+import (
+	"errors"
+	"time"
+)
+
+// DistributedSchedule is a distributed query run on a set of nodes on a cron schedule until it ends,
+// for hunts that need to be repeated without adding the query to a pack.  Each run starts a campaign
+type DistributedSchedule struct {
+	ScheduleID  string          `json:"schedule_id"`
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Query       string          `json:"query"`
+	Targets     CampaignTargets `json:"targets"`
+	// Cron is when the query runs, in UTC, see internal/pkg/cron
+	Cron string `json:"cron"`
+	// Timeout is how many seconds each run's campaign waits for nodes to answer
+	Timeout int `json:"timeout"`
+	// CreatedAt, EndsAt, LastRunAt and NextRunAt are in RFC 3339 format.  The query doesn't run after
+	// EndsAt, and NextRunAt is empty once it has ended
+	CreatedAt string `json:"created_at"`
+	EndsAt    string `json:"ends_at"`
+	LastRunAt string `json:"last_run_at,omitempty"`
+	NextRunAt string `json:"next_run_at,omitempty"`
+}
+
+// Validate checks the schedule has an ID, a query, targets, a cron schedule, an end and a timeout
+func (ds DistributedSchedule) Validate() error {
+	switch {
+	case ds.ScheduleID == "":
+		return errors.New("schedule has no ID")
+	case ds.Query == "":
+		return errors.New("schedule has no query")
+	case ds.Targets.Empty():
+		return errors.New("schedule has no targets")
+	case ds.Cron == "":
+		return errors.New("schedule has no cron schedule")
+	case ds.Timeout <= 0:
+		return errors.New("schedule timeout must be a positive number of seconds")
+	}
+	if _, err := time.Parse(time.RFC3339, ds.EndsAt); err != nil {
+		return errors.New("schedule end must be an RFC 3339 time")
+	}
+	return nil
+}
+
+// Ended returns true if the schedule has no more runs at now
+func (ds DistributedSchedule) Ended(now time.Time) bool {
+	ends, err := time.Parse(time.RFC3339, ds.EndsAt)
+	return ds.NextRunAt == "" || err != nil || now.After(ends)
+}
+
+// Due returns true if the schedule should run at now
+func (ds DistributedSchedule) Due(now time.Time) bool {
+	next, err := time.Parse(time.RFC3339, ds.NextRunAt)
+	return err == nil && !ds.Ended(now) && !now.Before(next)
+}
+
+// SetNextRun sets when the schedule next runs, which is never if next is zero or after the schedule
+// ends
+func (ds *DistributedSchedule) SetNextRun(next time.Time) {
+	ends, err := time.Parse(time.RFC3339, ds.EndsAt)
+	if next.IsZero() || err != nil || next.After(ends) {
+		ds.NextRunAt = ""
+		return
+	}
+	ds.NextRunAt = next.UTC().Format(time.RFC3339)
+}
+
+// DistributedScheduleRun is a run of a schedule.  CampaignID is the campaign the run started, whose
+// results are the run's results, or Error says why the run couldn't start
+type DistributedScheduleRun struct {
+	ScheduleID string `json:"schedule_id"`
+	// RunAt is in RFC 3339 format
+	RunAt      string `json:"run_at"`
+	CampaignID string `json:"campaign_id,omitempty"`
+	Targeted   int    `json:"targeted"`
+	Error      string `json:"error,omitempty"`
+}
+
+// Lease is held by one server at a time, to elect the server that does work only one server should
+// do.  ExpiresAt is a Unix time, after which another server can take the lease
+type Lease struct {
+	LeaseName string `json:"lease_name"`
+	Owner     string `json:"owner"`
+	ExpiresAt int64  `json:"expires_at"`
+}
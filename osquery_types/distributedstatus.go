@@ -0,0 +1,77 @@
+package osquery_types
+
+import (
+	"fmt"
+	"time"
+)
+
+// DistributedStatusRetention is how long distributed query statuses are kept before DynamoDB expires
+// them
+const DistributedStatusRetention = 30 * 24 * time.Hour
+
+// DistributedOutcome says how a distributed query ended for a node
+type DistributedOutcome string
+
+const (
+	// DistributedAnswered queries were run by the node, which wrote their results and status
+	DistributedAnswered DistributedOutcome = "answered"
+	// DistributedExpired queries weren't answered before their TTL
+	DistributedExpired DistributedOutcome = "expired"
+	// DistributedRetriesExhausted queries were sent the maximum number of times without an answer
+	DistributedRetriesExhausted DistributedOutcome = "retries_exhausted"
+)
+
+// DistributedStatus is how a distributed query ended for a node.  For answered queries, Status is the
+// osquery status code, anything but 0 is an error and Message is osquery's error message.  A query that
+// failed and a query that returned no rows can only be told apart by their status.  For queries that
+// weren't answered, Message says why they were given up on
+type DistributedStatus struct {
+	NodeKey string `json:"node_key"`
+	// StatusID orders a node's statuses, it is ReceivedAt followed by Name
+	StatusID       string `json:"status_id"`
+	HostIdentifier string `json:"host_identifier"`
+	// Name is the name the query was sent under, which is the campaign ID for campaign queries
+	Name       string `json:"name"`
+	CampaignID string `json:"campaign_id,omitempty"`
+	// Query is the SQL, when it is known
+	Query    string             `json:"query,omitempty"`
+	Outcome  DistributedOutcome `json:"outcome"`
+	Status   int                `json:"status"`
+	Message  string             `json:"message,omitempty"`
+	RowCount int                `json:"row_count"`
+	// ReceivedAt is in RFC 3339 format
+	ReceivedAt string `json:"received_at"`
+	TimeToLive int64  `json:"time_to_live"`
+}
+
+// NewDistributedStatus returns the status of the named query, answered by the node at now
+func NewDistributedStatus(oc OsqueryClient, name string, status int, message string, rowCount int, now time.Time) DistributedStatus {
+	now = now.UTC()
+	return DistributedStatus{
+		NodeKey:        oc.NodeKey,
+		StatusID:       fmt.Sprintf("%s %s", now.Format(time.RFC3339Nano), name),
+		HostIdentifier: oc.HostIdentifier,
+		Name:           name,
+		Outcome:        DistributedAnswered,
+		Status:         status,
+		Message:        message,
+		RowCount:       rowCount,
+		ReceivedAt:     now.Format(time.RFC3339),
+		TimeToLive:     now.Add(DistributedStatusRetention).Unix(),
+	}
+}
+
+// NewExpiredStatus returns the status of a query that was given up on at now
+func NewExpiredStatus(oc OsqueryClient, eq ExpiredQuery, now time.Time) DistributedStatus {
+	ds := NewDistributedStatus(oc, eq.Name, 0, eq.Reason, 0, now)
+	ds.CampaignID = eq.CampaignID
+	ds.Query = eq.Query
+	ds.Outcome = eq.Outcome
+	return ds
+}
+
+// Failed returns true if osquery reported an error, or the query wasn't answered.  Statuses stored
+// before outcomes were recorded were all answered
+func (ds DistributedStatus) Failed() bool {
+	return ds.Status != 0 || ds.Outcome != "" && ds.Outcome != DistributedAnswered
+}
@@ -0,0 +1,130 @@
+package osquery_types
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// FlexInt is an int that can also be read from a quoted string.  Upstream osquery packs (and pack
+// queries stored before fields were typed) use values like "interval": "3600", while osquery itself
+// expects a number, so anything read is normalised and always written back out as a number
+type FlexInt int
+
+// UnmarshalJSON accepts either a JSON number or a string containing one
+func (i *FlexInt) UnmarshalJSON(b []byte) error {
+	s := strings.TrimSpace(string(b))
+	if s == "null" {
+		return nil
+	}
+	if strings.HasPrefix(s, `"`) {
+		if err := json.Unmarshal(b, &s); err != nil {
+			return err
+		}
+	}
+	return i.parse(s)
+}
+
+// UnmarshalDynamoDBAttributeValue accepts either a number or a string attribute
+func (i *FlexInt) UnmarshalDynamoDBAttributeValue(av *dynamodb.AttributeValue) error {
+	switch {
+	case av == nil || av.NULL != nil:
+		return nil
+	case av.N != nil:
+		return i.parse(*av.N)
+	case av.S != nil:
+		return i.parse(*av.S)
+	}
+	return fmt.Errorf("cannot unmarshal %s into an integer", av)
+}
+
+// UnmarshalYAML accepts either a YAML number or a string containing one
+func (i *FlexInt) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	return i.parse(s)
+}
+
+func (i *FlexInt) parse(s string) error {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		*i = 0
+		return nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return fmt.Errorf("invalid integer value %q", s)
+	}
+	*i = FlexInt(n)
+	return nil
+}
+
+// FlexBool is a bool that can also be read from a quoted string such as "true"
+type FlexBool bool
+
+// UnmarshalJSON accepts either a JSON bool or a string containing one
+func (b *FlexBool) UnmarshalJSON(data []byte) error {
+	s := strings.TrimSpace(string(data))
+	if s == "null" {
+		return nil
+	}
+	if strings.HasPrefix(s, `"`) {
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+	}
+	return b.parse(s)
+}
+
+// UnmarshalDynamoDBAttributeValue accepts either a bool or a string attribute
+func (b *FlexBool) UnmarshalDynamoDBAttributeValue(av *dynamodb.AttributeValue) error {
+	switch {
+	case av == nil || av.NULL != nil:
+		return nil
+	case av.BOOL != nil:
+		*b = FlexBool(*av.BOOL)
+		return nil
+	case av.S != nil:
+		return b.parse(*av.S)
+	}
+	return fmt.Errorf("cannot unmarshal %s into a bool", av)
+}
+
+// UnmarshalYAML accepts either a YAML bool, including yes and no, or a string containing one
+func (b *FlexBool) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var v bool
+	if err := unmarshal(&v); err == nil {
+		*b = FlexBool(v)
+		return nil
+	}
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	return b.parse(s)
+}
+
+func (b *FlexBool) parse(s string) error {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		*b = false
+		return nil
+	}
+	v, err := strconv.ParseBool(s)
+	if err != nil {
+		return fmt.Errorf("invalid boolean value %q", s)
+	}
+	*b = FlexBool(v)
+	return nil
+}
+
+// Bool returns a pointer to a FlexBool holding v, for use in optional fields
+func Bool(v bool) *FlexBool {
+	b := FlexBool(v)
+	return &b
+}
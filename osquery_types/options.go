@@ -0,0 +1,168 @@
+package osquery_types
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/oktasecuritylabs/sgt/internal/pkg/osqueryschema"
+)
+
+// OsqueryOptions is the options section of an osquery config, keyed by osquery flag name.  Any flag
+// in the bundled osquery flag schema may be set, see internal/pkg/osqueryschema
+type OsqueryOptions map[string]interface{}
+
+// NewOsqueryOptions returns some default options for osquery.  Flags whose osquery default doesn't
+// suit SGT, such as disable_distributed, are set explicitly
+func NewOsqueryOptions() OsqueryOptions {
+	return OsqueryOptions{
+		"audit_allow_config":           false,
+		"audit_allow_sockets":          false,
+		"audit_persist":                true,
+		"carver_disable_function":      false,
+		"config_refresh":               300,
+		"disable_audit":                true,
+		"disable_caching":              false,
+		"disable_carver":               true,
+		"disable_database":             false,
+		"disable_decorators":           false,
+		"disable_distributed":          false,
+		"disable_enrollment":           false,
+		"disable_events":               false,
+		"disable_extensions":           false,
+		"disable_forensic":             false,
+		"disable_kernel":               false,
+		"disable_logging":              false,
+		"disable_memory":               false,
+		"disable_reenrollment":         false,
+		"disable_watchdog":             false,
+		"distributed_interval":         60,
+		"distributed_tls_max_attempts": 5,
+		"enable_foreign":               false,
+		"enable_monitor":               false,
+		"enable_syslog":                false,
+		"events_expiry":                14400,
+		"events_max":                   100000,
+		"events_optimize":              true,
+		"host_identifier":              "hostname",
+		"logger_plugin":                "firehose",
+		"verbose":                      false,
+	}
+}
+
+// Validate checks that every option is a known osquery flag with a value of the right type
+func (o OsqueryOptions) Validate() error {
+	return osqueryschema.Validate(o)
+}
+
+// Copy returns a shallow copy of the options
+func (o OsqueryOptions) Copy() OsqueryOptions {
+	result := make(OsqueryOptions, len(o))
+	for k, v := range o {
+		result[k] = v
+	}
+	return result
+}
+
+// RestartRequired returns the names of the options that differ from previous, sorted, whose flags
+// osquery only reads at startup.  Nodes pick up the change to them only after osquery is restarted
+func (o OsqueryOptions) RestartRequired(previous OsqueryOptions) []string {
+	seen := map[string]bool{}
+	for name := range o {
+		seen[name] = true
+	}
+	for name := range previous {
+		seen[name] = true
+	}
+	names := []string{}
+	for name := range seen {
+		f, ok := osqueryschema.Lookup(name)
+		if !ok || f.Runtime {
+			continue
+		}
+		// numbers read from JSON and DynamoDB are floats, so compare the values as printed
+		old, wasSet := previous[name]
+		current, isSet := o[name]
+		if wasSet != isSet || fmt.Sprint(old) != fmt.Sprint(current) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// String returns the value of a string option, or an empty string if it isn't set
+func (o OsqueryOptions) String(name string) string {
+	s, _ := o[name].(string)
+	return s
+}
+
+// Int returns the value of an integer option and whether it is set
+func (o OsqueryOptions) Int(name string) (int, bool) {
+	return osqueryschema.ToInt(o[name])
+}
+
+// Bool returns the value of a boolean option, or false if it isn't set
+func (o OsqueryOptions) Bool(name string) bool {
+	b, _ := o[name].(bool)
+	return b
+}
+
+// UnmarshalDynamoDBAttributeValue reads stored options, cleaning up values written by earlier
+// versions of SGT so that configs stored before options were schema driven still validate
+func (o *OsqueryOptions) UnmarshalDynamoDBAttributeValue(av *dynamodb.AttributeValue) error {
+	if av == nil || av.NULL != nil {
+		return nil
+	}
+	m := map[string]interface{}{}
+	if err := dynamodbattribute.Unmarshal(av, &m); err != nil {
+		return err
+	}
+	*o = OsqueryOptions(m)
+	o.upgradeLegacy()
+	return nil
+}
+
+// upgradeLegacy fixes values stored when options were a fixed struct.  Empty strings were stored as
+// NULL, extenstions_autoload was misspelled (so osquery never saw it), disable_tables was a bool
+// rather than a list of tables and some integers were stored as strings
+func (o OsqueryOptions) upgradeLegacy() {
+	delete(o, "extenstions_autoload")
+	if _, ok := o["disable_tables"].(bool); ok {
+		delete(o, "disable_tables")
+	}
+	for name, v := range o {
+		if v == nil {
+			delete(o, name)
+			continue
+		}
+		f, ok := osqueryschema.Lookup(name)
+		if !ok || f.Type != osqueryschema.TypeInt {
+			continue
+		}
+		if s, ok := v.(string); ok {
+			var n FlexInt
+			if err := n.parse(s); err == nil {
+				o[name] = int(n)
+			}
+		}
+	}
+}
+
+// RedactedValue replaces the values of sensitive options when configs are shown to users
+const RedactedValue = "REDACTED"
+
+// Redacted returns a copy of the options with the values of sensitive flags, such as AWS secret keys,
+// replaced by RedactedValue.  Unset and empty values are left as they are
+func (o OsqueryOptions) Redacted() OsqueryOptions {
+	result := o.Copy()
+	for name, v := range result {
+		f, ok := osqueryschema.Lookup(name)
+		if !ok || !f.Sensitive || v == nil || v == "" {
+			continue
+		}
+		result[name] = RedactedValue
+	}
+	return result
+}
@@ -21,6 +21,8 @@ type OsqueryClient struct {
 	ConfigurationGroup          string                       `json:"configuration_group,omitempty"`
 	ConfigName                  string                       `json:"config_name"`
 	LastUpdated                 string                       `json:"last_updated"`
+	ConfigOverride              *NodeConfigOverride          `json:"config_override,omitempty"`
+	ConfigDelivery              *ConfigDelivery              `json:"config_delivery,omitempty"`
 }
 
 // SetTimestamp sets the current timestamp with the proper format
@@ -28,175 +30,49 @@ func (os *OsqueryClient) SetTimestamp() {
 	os.LastUpdated = time.Now().UTC().Format("Mon, 01/02/06, 03:04:05PM")
 }
 
-type OsqueryOptions struct {
-	//Audit
-	AuditAllowConfig  bool `json:"audit_allow_config"`
-	AuditAllowSockets bool `json:"audit_allow_sockets"`
-	AuditPersist      bool `json:"audit_persist"`
-	//aws options
-	AwsAccessKeyID               string `json:"aws_access_key_id,omitempty"`
-	AwsFirehosePeriod            int    `json:"aws_firehose_period,omitempty"`
-	AwsFirehoseStream            string `json:"aws_firehose_stream"`
-	AwsKinesisPeriod             int    `json:"aws_kinesis_period,omitempty"`
-	AwsKinesisRandomPartitionKey bool   `json:"aws_kinesis_random_partition_key,omitempty"`
-	AwsKinesisStream             string `json:"aws_kinesis_stream,omitempty"`
-	AwsProfileName               string `json:"aws_profile_name,omitempty"`
-	AwsRegion                    string `json:"aws_region,omitempty"`
-	AwsSecretAccessKey           string `json:"aws_secret_access_key,omitempty"`
-	AwsSTSARNRole                string `json:"aws_sts_arn_role,omitempty"`
-	AwsSTSRegion                 string `json:"aws_sts_region,omitempty"`
-	AwsSTSSessionName            string `json:"aws_sts_session_name,omitempty"`
-	AwsSTSTimeout                string `json:"aws_sts_timeout,omitempty"`
-	//Carver settings
-	CarverBlockSize        int    `json:"carver_block_size,omitempty"`
-	CarverContinueEndpoint string `json:"carver_continue_endpoint,omitempty"`
-	CarverStartEndpoint    string `json:"carver_start_endpoint,omitempty"`
-	CarverDisableFunction  bool   `json:"carver_disable_function"`
-	//config_settings
-	ConfigRefresh int  `json:"config_refresh"`
-	CSV           bool `json:"csv,omitempty"`
-
-	//Disables
-	DisableAudit        bool `json:"disable_audit"`
-	DisableCaching      bool `json:"disable_caching"`
-	DisableCarver       bool `json:"disable_carver"`
-	DisableDatabase     bool `json:"disable_database"`
-	DisableDecorators   bool `json:"disable_decorators"`
-	DisableDistributed  bool `json:"disable_distributed"`
-	DisableEnrollment   bool `json:"disable_enrollment"`
-	DisableEvents       bool `json:"disable_events"`
-	DisableExtensions   bool `json:"disable_extensions"`
-	DisableForensic     bool `json:"disable_forensic"`
-	DisableKernel       bool `json:"disable_kernel"`
-	DisableLogging      bool `json:"disable_logging"`
-	DisableMemory       bool `json:"disable_memory"`
-	DisableReenrollment bool `json:"disable_reenrollment"`
-	DisableTables       bool `json:"disable_tables"`
-	DisableWatchdog     bool `json:"disable_watchdog"`
-
-	//Distributed
-	DistributedInterval         int    `json:"distributed_interval,omitempty"`
-	DistributedPlugin           string `json:"distributed_plugin,omitempty"`
-	DistributedTLSMaxAttempts   int    `json:"distributed_tls_max_attempts,omitempty"`
-	DistributedTLSReadEndpoint  string `json:"distributed_tls_read_endpoint,omitempty"`
-	DistributedTLSWriteEndpoint string `json:"distributed_tls_write_endpoint,omitempty"`
-	//Enables
-
-	EnableForeign bool `json:"enable_foreign"`
-	EnableMonitor bool `json:"enable_monitor"`
-	EnableSyslog  bool `json:"enable_syslog"`
-
-	//Enroll (these are handled by flags on host, not set in tls config
-
-	//Events
-	EventsExpiry   int  `json:"events_expiry"`
-	EventsMax      int  `json:"events_max"`
-	EventsOptimize bool `json:"events_optimize"`
-
-	//Extensions
-	ExtensionsAutoload bool   `json:"extenstions_autoload,omitempty"`
-	ExtensionsInterval int    `json:"extensions_interval,omitempty"`
-	ExtensionsRequire  string `json:"extensions_require,omitempty"`
-	ExtensionsTimeout  int    `json:"extensions_timeout,omitempty"`
-
-	Force                 bool   `json:"force,omitempty"`
-	HardwareDisabledTypes string `json:"hardware_disabled_types,omitempty"`
-	Header                bool   `json:"header,omitempty"`
-	HostIdentifier        string `json:"host_identifier"`
-	//output
-	JSON bool `json:"json,omitempty"`
-	Line bool `json:"line,omitempty"`
-	List bool `json:"list,omitempty"`
-
-	//Logger
-	LoggerEventType bool   `json:"logger_event_type,omitempty"`
-	LoggerMinStatus int    `json:"logger_min_status,omitempty"`
-	LoggerMode      int    `json:"logger_mode,omitempty"`
-	LoggerPath      string `json:"logger_path,omitempty"`
-	LoggerPlugin    string `json:"logger_plugin"`
-
-	LoggerSecondaryStatusOnly bool `json:"logger_secondary_status_only,omitempty"`
-	LoggerSnapshotEventType   bool `json:"logger_snapshot_event_type,omitempty"`
-	LoggerStatusSync          bool `json:"logger_status_sync,omitempty"`
-
-	LoggerSyslogFacility   int  `json:"logger_syslog_facility,omitempty"`
-	LoggerSyslogPrependCee bool `json:"logger_syslog_prepend_cee,omitempty"`
-	LoggerTLSCompress      bool `json:"logger_tls_compress,omitempty"`
-	//Endpoints provided by flags
-	LoggerTLSMax    int  `json:"logger_tls_max,omitempty"`
-	LoggerTLSPeriod int  `json:"logger_tls_period,omitempty"`
-	Logtostderr     bool `json:"logtostderr,omitempty"`
-	//Schedule
-	ScheduleDefaultInterval int `json:"schedule_default_interval,omitempty"`
-	ScheduleSplayPercent    int `json:"schedule_splay_percent,omitempty"`
-	//Syslog
-	SyslogEventsExpiry int    `json:"syslog_events_expiry,omitempty"`
-	SyslogEventsMax    int    `json:"syslog_events_max,omitempty"`
-	SyslogPipePath     string `json:"syslog_pipe_path,omitempty"`
-	SyslogRateLimit    int    `json:"syslog_rate_limit,omitempty"`
-	//TLS settings should be specified in flags file, since there is no guarantee of tls communcation without it
-	UTC     bool `json:"utc,omitempty"`
-	Verbose bool `json:"verbose"`
-	//Watchdog
-	WatchdogLevel            int `json:"watchdog_level,omitempty"`
-	WatchdogMemoryLimit      int `json:"watchdog_memory_limit,omitempty"`
-	WatchdogUtilizationLimit int `json:"watchdog_utilization_limit,omitempty"`
-}
-
-// NewOsqueryOptions returns some default options for osquery
-func NewOsqueryOptions() OsqueryOptions {
-	options := OsqueryOptions{
-		AuditPersist:              true,
-		ConfigRefresh:             300,
-		DisableAudit:              true,
-		DisableCarver:             true,
-		DistributedInterval:       60,
-		DistributedTLSMaxAttempts: 5,
-		EventsExpiry:              14400,
-		EventsMax:                 100000,
-		EventsOptimize:            true,
-		HostIdentifier:            "hostname",
-		LoggerPlugin:              "firehose",
-		//LoggerSnapshotEventType:	true,
-	}
-	return options
-}
-
 type OsqueryDecorators struct {
 	Load   []string `json:"load,omitempty"`
 	Always []string `json:"always,omitempty"`
+	// Interval maps a number of seconds (as a string, eg "3600") to the decorators run at that interval
+	Interval map[string][]string `json:"interval,omitempty"`
 }
 type OsqueryQuery struct {
 	Query string `json:"query"`
 }
 
-type Time struct {
-	Query    string `json:"query"`
-	Interval int    `json:"interval"`
-	Removed  string `json:"removed"`
-}
-
-type OsquerySchedule struct {
-	Time Time `json:"time"`
-}
-
 type OsqueryConfig struct {
 	//Node_invalid string
-	NodeInvalid bool
-	Options     OsqueryOptions    `json:"options"`
-	Decorators  OsqueryDecorators `json:"decorators,omitemtpy"`
-	Schedule    OsquerySchedule   `json:"schedule,omitempty"`
+	NodeInvalid           bool
+	Options               OsqueryOptions      `json:"options"`
+	Decorators            OsqueryDecorators   `json:"decorators,omitempty"`
+	Schedule              OsquerySchedule     `json:"schedule,omitempty"`
+	FilePaths             map[string][]string `json:"file_paths,omitempty"`
+	FileAccesses          []string            `json:"file_accesses,omitempty"`
+	ExcludePaths          map[string][]string `json:"exclude_paths,omitempty"`
+	Yara                  *OsqueryYara        `json:"yara,omitempty"`
+	Events                *OsqueryEvents      `json:"events,omitempty"`
+	Views                 map[string]string   `json:"views,omitempty"`
+	AutoTableConstruction map[string]ATCTable `json:"auto_table_construction,omitempty"`
+	PrometheusTargets     *PrometheusTargets  `json:"prometheus_targets,omitempty"`
 	//Packs OsqueryPacks `json:"packs"`
-	Packs map[string]map[string]map[string]map[string]string `json:"packs"`
+	Packs map[string]map[string]interface{} `json:"packs"`
 }
 
 type OsqueryUploadConfig struct {
 	//Node_invalid string
-	NodeInvalid bool
-	Options     OsqueryOptions    `json:"options"`
-	Decorators  OsqueryDecorators `json:"decorators,omitemtpy"`
-	Schedule    OsquerySchedule   `json:"schedule,omitempty"`
-	Packs       []string          `json:"packs"`
+	NodeInvalid           bool
+	Options               OsqueryOptions      `json:"options"`
+	Decorators            OsqueryDecorators   `json:"decorators,omitempty"`
+	Schedule              OsquerySchedule     `json:"schedule,omitempty"`
+	FilePaths             map[string][]string `json:"file_paths,omitempty"`
+	FileAccesses          []string            `json:"file_accesses,omitempty"`
+	ExcludePaths          map[string][]string `json:"exclude_paths,omitempty"`
+	Yara                  *OsqueryYara        `json:"yara,omitempty"`
+	Events                *OsqueryEvents      `json:"events,omitempty"`
+	Views                 map[string]string   `json:"views,omitempty"`
+	AutoTableConstruction map[string]ATCTable `json:"auto_table_construction,omitempty"`
+	PrometheusTargets     *PrometheusTargets  `json:"prometheus_targets,omitempty"`
+	Packs                 []string            `json:"packs"`
 	//Packs OsqueryPacks `json:"packs"`
 }
 
@@ -204,11 +80,46 @@ type OsqueryNamedConfig struct {
 	ConfigName    string        `json:"config_name"`
 	OsqueryConfig OsqueryConfig `json:"osquery_config"`
 	OsType        string        `json:"os_type"`
-	PackList      []string      `json:"pack_list"`
+	PackList      PackList      `json:"pack_list"`
+	// Revision is incremented each time the config is saved
+	Revision int `json:"revision"`
+	// Filtered lists the packs and queries left out when the config was built for a node, it is never stored
+	Filtered []FilteredEntry `json:"filtered,omitempty" dynamodbav:"-"`
+}
+
+// PackSettings holds the pack level fields of the osquery pack format.  They apply to every query
+// in the pack
+type PackSettings struct {
+	Platform  string   `json:"platform,omitempty"`
+	Version   string   `json:"version,omitempty"`
+	Shard     FlexInt  `json:"shard,omitempty"`
+	Discovery []string `json:"discovery,omitempty"`
+}
+
+// IsZero returns true if none of the pack level fields are set
+func (ps PackSettings) IsZero() bool {
+	return ps.Platform == "" && ps.Version == "" && ps.Shard == 0 && len(ps.Discovery) == 0
+}
+
+// asMap adds any set pack level fields to m
+func (ps PackSettings) asMap(m map[string]interface{}) {
+	if ps.Platform != "" {
+		m["platform"] = ps.Platform
+	}
+	if ps.Version != "" {
+		m["version"] = ps.Version
+	}
+	if ps.Shard != 0 {
+		m["shard"] = int(ps.Shard)
+	}
+	if len(ps.Discovery) > 0 {
+		m["discovery"] = ps.Discovery
+	}
 }
 
 type Pack struct {
 	PackName string `json:"pack_name"`
+	PackSettings
 	//QueryList []string `json:"query_list"`
 	Queries []PackQuery `json:"queries"`
 }
@@ -227,86 +138,123 @@ type Pack struct {
 //return json.RawMessage(p.AsString())
 //}
 
-func (p Pack) AsMap() map[string]map[string]map[string]string {
-	m := map[string]map[string]map[string]string{}
-	m["queries"] = map[string]map[string]string{}
+// AsMap returns the pack in the format osquery expects in the packs section of a config.  Fields
+// that are not set are left out so osquery applies its own defaults
+func (p Pack) AsMap() map[string]interface{} {
+	m := map[string]interface{}{}
+	p.PackSettings.asMap(m)
+	queries := map[string]interface{}{}
 	for _, packQuery := range p.Queries {
-		pq := map[string]string{}
-		pq["query"] = packQuery.Query
-		pq["interval"] = packQuery.Interval
-		//if len(packQuery.Version) > 0 {
-		pq["version"] = packQuery.Version
-		//}
-		//if len(packQuery.Description) > 0 {
-		pq["description"] = packQuery.Description
-		//}
-		//if len(packQuery.Value) > 0 {
-		pq["value"] = packQuery.Value
-		//}
-		//if len(packQuery.Snapshot) > 0 {
-		pq["snapshot"] = packQuery.Snapshot
-		//}
-		m["queries"][packQuery.QueryName] = pq
+		queries[packQuery.QueryName] = packQuery.AsMap()
 	}
+	m["queries"] = queries
 	return m
 }
 
 type QueryPack struct {
-	PackName string   `json:"pack_name"`
-	Queries  []string `json:"queries"`
+	PackName string `json:"pack_name"`
+	PackSettings
+	Queries []string `json:"queries"`
+	// Overrides change how queries shared with other packs run in this pack, keyed by query name
+	Overrides map[string]PackQueryOverride `json:"overrides,omitempty"`
+	// Deployed are the queries the last deploy of the pack's file created, which the next deploy may
+	// remove.  Queries added to the pack any other way are kept
+	Deployed []string `json:"deployed,omitempty"`
 }
 
+// PackQuery is a single query of a pack, covering every field of a query in the osquery pack format
 type PackQuery struct {
-	QueryName   string `json:"query_name"`
-	Query       string `json:"query"`
-	Interval    string `json:"interval"`
-	Version     string `json:"version"`
-	Description string `json:"description"`
-	Value       string `json:"value"`
-	Snapshot    string `json:"snapshot"`
+	QueryName   string    `json:"query_name"`
+	Query       string    `json:"query"`
+	Interval    FlexInt   `json:"interval"`
+	Platform    string    `json:"platform,omitempty"`
+	Version     string    `json:"version,omitempty"`
+	Description string    `json:"description,omitempty"`
+	Value       string    `json:"value,omitempty"`
+	Snapshot    *FlexBool `json:"snapshot,omitempty"`
+	Removed     *FlexBool `json:"removed,omitempty"`
+	Shard       FlexInt   `json:"shard,omitempty"`
+	Denylist    *FlexBool `json:"denylist,omitempty"`
+}
+
+// AsMap returns the query as osquery expects it inside a pack, leaving out unset fields
+func (pq PackQuery) AsMap() map[string]interface{} {
+	m := map[string]interface{}{
+		"query":    pq.Query,
+		"interval": int(pq.Interval),
+	}
+	if pq.Platform != "" {
+		m["platform"] = pq.Platform
+	}
+	if pq.Version != "" {
+		m["version"] = pq.Version
+	}
+	if pq.Description != "" {
+		m["description"] = pq.Description
+	}
+	if pq.Value != "" {
+		m["value"] = pq.Value
+	}
+	if pq.Snapshot != nil {
+		m["snapshot"] = bool(*pq.Snapshot)
+	}
+	if pq.Removed != nil {
+		m["removed"] = bool(*pq.Removed)
+	}
+	if pq.Shard != 0 {
+		m["shard"] = int(pq.Shard)
+	}
+	if pq.Denylist != nil {
+		m["denylist"] = bool(*pq.Denylist)
+	}
+	return m
 }
 
 func (pq PackQuery) AsString() string {
-	s := fmt.Sprintf(`"%s": {"query": %q, "interval": %q, "version": %q, "description": %q, "value": %q}`, pq.QueryName, pq.Query, pq.Interval, pq.Version, pq.Description, pq.Value)
-	return s
+	js, _ := json.Marshal(pq.AsMap())
+	return fmt.Sprintf("%q: %s", pq.QueryName, js)
 }
 
 func PackQueryToString(p *PackQuery) string {
-	s := fmt.Sprintf(`"%s": {"query": %q, "interval": %q, "version": %q, "description": %q, "value": %q}`, p.QueryName, p.Query, p.Interval, p.Version, p.Description, p.Value)
-	return s
+	return p.AsString()
 }
 
 func BuildPackQueries(pqs []PackQuery) string {
 	queriesString := "{"
 	for c, i := range pqs {
-		switch c {
-		case 0:
-			queriesString += i.AsString()
-		case len(pqs):
-			queriesString += i.AsString()
-			queriesString += "}"
-			return queriesString
-		default:
+		if c > 0 {
 			queriesString += ", "
-			queriesString += i.AsString()
 		}
+		queriesString += i.AsString()
 	}
+	queriesString += "}"
 	return queriesString
 }
 
+// DistributedQuery holds the distributed queries for a node.  Queries are the queries added by
+// /distributed/add that haven't been sent yet, Pending are the queries that the node hasn't answered
+// yet, see Deliver.  NextID numbers the node's queries, so a name is never reused for another query.
+// Revision is incremented each time the queries are saved
 type DistributedQuery struct {
-	NodeKey     string   `json:"node_key"`
-	Queries     []string `json:"queries"`
-	NodeInvalid bool     `json:"node_invalid"`
+	NodeKey     string         `json:"node_key"`
+	Queries     []string       `json:"queries"`
+	NodeInvalid bool           `json:"node_invalid"`
+	Pending     []PendingQuery `json:"pending,omitempty"`
+	NextID      int            `json:"next_id,omitempty"`
+	Revision    int            `json:"revision,omitempty"`
 }
 
-// ToJSON returns a formatted version of the DistributedQuery
+// ToJSON returns a formatted version of the DistributedQuery.  Queries are numbered id1, id2... and
+// pending queries are sent under their names
 func (dq DistributedQuery) ToJSON() string {
 	result := make(map[string]interface{})
 	querylist := make(map[string]string)
 	for i, j := range dq.Queries {
 		querylist[fmt.Sprintf("id%d", i+1)] = j
 	}
+	for _, pq := range dq.Pending {
+		querylist[pq.Name] = pq.Query
+	}
 	result["queries"] = querylist
 	result["node_invalid"] = strconv.FormatBool(dq.NodeInvalid)
 
@@ -316,14 +264,37 @@ func (dq DistributedQuery) ToJSON() string {
 }
 
 type ServerConfig struct {
-	FirehoseAWSAccessKeyID                   string   `json:"firehose_aws_access_key_id"`
-	FirehoseAWSSecretAccessKey               string   `json:"firehose_aws_secret_access_key"`
-	FirehoseStreamName                       string   `json:"firehose_stream_name"`
+	FirehoseAWSAccessKeyID     string `json:"firehose_aws_access_key_id"`
+	FirehoseAWSSecretAccessKey string `json:"firehose_aws_secret_access_key"`
+	FirehoseStreamName         string `json:"firehose_stream_name"`
+	// DistributedQueryLogger lists where distributed query results are written: firehose, s3,
+	// filesystem or stdout
 	DistributedQueryLogger                   []string `json:"distributed_query_logger"`
 	DistributedQueryLoggerS3BucketName       string   `json:"distributed_query_logger_s3_bucket_name"`
 	DistributedQueryLoggerFirehoseStreamName string   `json:"distributed_query_logger_firehose_stream_name"`
-	DistributedQueryLoggerFilesytemPath      string   `json:"distributed_query_logger_filesytem_path"`
-	AutoApproveNodes                         string   `json:"auto_approve_nodes"`
+	DistributedQueryLoggerFilesystemPath     string   `json:"distributed_query_logger_filesystem_path"`
+	// DistributedQueryLoggerFilesytemPath is the misspelled name the filesystem path was first read
+	// from, see DistributedQueryLoggerPath
+	DistributedQueryLoggerFilesytemPath string `json:"distributed_query_logger_filesytem_path,omitempty"`
+	AutoApproveNodes                    string `json:"auto_approve_nodes"`
+	// SQLLintPolicy is off, warn or reject, see internal/pkg/sqllint.  It defaults to warn
+	SQLLintPolicy string `json:"sql_lint_policy,omitempty"`
+	// DistributedQueryRedeliverAfter is the number of seconds after a distributed query is sent that
+	// it is sent again if the node hasn't answered, up to DistributedQueryMaxDeliveries times.  Queries
+	// are given up on DistributedQueryTTL seconds after they were queued.  See DeliveryPolicy for
+	// the defaults
+	DistributedQueryRedeliverAfter int `json:"distributed_query_redeliver_after,omitempty"`
+	DistributedQueryMaxDeliveries  int `json:"distributed_query_max_deliveries,omitempty"`
+	DistributedQueryTTL            int `json:"distributed_query_ttl,omitempty"`
+}
+
+// DistributedQueryLoggerPath returns the directory the filesystem logger writes to, under either
+// spelling of the setting
+func (c ServerConfig) DistributedQueryLoggerPath() string {
+	if c.DistributedQueryLoggerFilesystemPath != "" {
+		return c.DistributedQueryLoggerFilesystemPath
+	}
+	return c.DistributedQueryLoggerFilesytemPath
 }
 
 func GetServerConfig(fn string) (*ServerConfig, error) {
@@ -353,11 +324,36 @@ func (u User) Validate(plaintext_pw string) error {
 	return bcrypt.CompareHashAndPassword(u.Password, []byte(plaintext_pw))
 }
 
+// DistributedQueryResult is a row a node wrote for a distributed query, or with the log type status,
+// the status the node reported for the query.  Name is the name the query was sent under, CalendarTime
+// is in RFC 3339 format
 type DistributedQueryResult struct {
 	Name           string            `json:"name"`
 	CalendarTime   string            `json:"calendarTime"`
-	Action         string            `json:"action"`
+	Action         string            `json:"action,omitempty"`
 	LogType        string            `json:"log_type"`
-	Columns        map[string]string `json:"columns"`
+	Columns        map[string]string `json:"columns,omitempty"`
 	HostIdentifier string            `json:"host_identifier"`
+	HostName       string            `json:"hostname,omitempty"`
+	Tags           []string          `json:"tags,omitempty"`
+	ConfigName     string            `json:"config_name,omitempty"`
+	CampaignID     string            `json:"campaign_id,omitempty"`
+	Query          string            `json:"query,omitempty"`
+	Status         *int              `json:"status,omitempty"`
+	Message        string            `json:"message,omitempty"`
+}
+
+// NewDistributedQueryResult returns a result of the named query with the log type, identifying the node
+// by its host identifier, hostname, tags and config.  The node key is the node's secret, so it is never
+// part of a result
+func NewDistributedQueryResult(oc OsqueryClient, name, logType string, now time.Time) DistributedQueryResult {
+	return DistributedQueryResult{
+		Name:           name,
+		CalendarTime:   now.UTC().Format(time.RFC3339),
+		LogType:        logType,
+		HostIdentifier: oc.HostIdentifier,
+		HostName:       oc.HostName,
+		Tags:           oc.Tags,
+		ConfigName:     oc.ConfigName,
+	}
 }
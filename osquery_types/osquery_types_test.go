@@ -1,23 +1,33 @@
 package osquery_types
 
 import (
+	"encoding/json"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
 )
 
 var (
 	q1 = PackQuery{
 		QueryName:   "testquery",
 		Query:       "select * from test;",
-		Interval:    "60",
+		Interval:    60,
 		Version:     "1.4.0",
 		Description: "test description",
 		Value:       "a test",
-		Snapshot:    "true",
+		Snapshot:    Bool(true),
 	}
 	p1 = Pack{
 		PackName: "test pack",
-		Queries:  []PackQuery{q1},
+		PackSettings: PackSettings{
+			Platform: "darwin",
+			Shard:    10,
+		},
+		Queries: []PackQuery{q1},
 	}
 )
 
@@ -33,20 +43,746 @@ func TestPackQuery_AsString(t *testing.T) {
 
 func TestPack_AsMap(t *testing.T) {
 	packMap := p1.AsMap()
-	expectedMap := map[string]map[string]map[string]string{
-		"queries": {
-			"testquery": {
+	expectedMap := map[string]interface{}{
+		"platform": "darwin",
+		"shard":    10,
+		"queries": map[string]interface{}{
+			"testquery": map[string]interface{}{
 				"query":       "select * from test;",
-				"interval":    "60",
+				"interval":    60,
 				"version":     "1.4.0",
 				"description": "test description",
 				"value":       "a test",
-				"snapshot":    "true",
+				"snapshot":    true,
 			},
 		},
 	}
 	eq := reflect.DeepEqual(packMap, expectedMap)
 	if !eq {
-		t.Errorf("maps not equal")
+		t.Errorf("maps not equal, got %+v", packMap)
+	}
+}
+
+func TestPackQuery_UnmarshalUpstream(t *testing.T) {
+	upstream := []byte(`{
+		"query": "select * from launchd;",
+		"interval": "3600",
+		"platform": "darwin",
+		"snapshot": "true",
+		"removed": false,
+		"shard": 25
+	}`)
+	pq := PackQuery{}
+	if err := json.Unmarshal(upstream, &pq); err != nil {
+		t.Fatal(err)
+	}
+	if pq.Interval != 3600 || pq.Shard != 25 || pq.Platform != "darwin" {
+		t.Errorf("unexpected query: %+v", pq)
+	}
+	if pq.Snapshot == nil || !bool(*pq.Snapshot) || pq.Removed == nil || bool(*pq.Removed) {
+		t.Errorf("bools not parsed: snapshot %v, removed %v", pq.Snapshot, pq.Removed)
+	}
+	if pq.Denylist != nil {
+		t.Error("denylist should be unset")
+	}
+
+	js, err := json.Marshal(pq.AsMap())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"interval":3600,"platform":"darwin","query":"select * from launchd;","removed":false,"shard":25,"snapshot":true}`
+	if string(js) != expected {
+		t.Errorf("Got: \n\t%s, expected: \n\t%s", js, expected)
+	}
+}
+
+func TestNodeConfigOverride_Apply(t *testing.T) {
+	oc := OsqueryConfig{
+		Options:    NewOsqueryOptions(),
+		Decorators: OsqueryDecorators{Always: []string{"select uuid from system_info;"}},
+	}
+	override := NodeConfigOverride{
+		Options: map[string]interface{}{
+			"verbose":               true,
+			"watchdog_memory_limit": 500,
+		},
+		Decorators: OsqueryDecorators{Always: []string{"select uuid from system_info;", "select version from osquery_info;"}},
+	}
+	if err := override.Apply(&oc); err != nil {
+		t.Fatal(err)
+	}
+	if limit, _ := oc.Options.Int("watchdog_memory_limit"); !oc.Options.Bool("verbose") || limit != 500 {
+		t.Errorf("options not overridden: %+v", oc.Options)
+	}
+	if refresh, _ := oc.Options.Int("config_refresh"); refresh != 300 {
+		t.Errorf("unrelated option changed, got config_refresh %d", refresh)
+	}
+	if len(oc.Decorators.Always) != 2 {
+		t.Errorf("expected 2 decorators, got %v", oc.Decorators.Always)
+	}
+
+	bad := NodeConfigOverride{Options: map[string]interface{}{"not_an_option": 1}}
+	if err := bad.Validate(); err == nil {
+		t.Error("expected unknown option to fail validation")
+	}
+	mistyped := NodeConfigOverride{Options: map[string]interface{}{"verbose": "yes"}}
+	if err := mistyped.Validate(); err == nil {
+		t.Error("expected mistyped option to fail validation")
+	}
+}
+
+func TestNodeConfigOverride_Expired(t *testing.T) {
+	now := time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		expires  string
+		expected bool
+	}{
+		{"", false},
+		{"2018-06-01T13:00:00Z", false},
+		{"2018-06-01T12:00:00Z", true},
+		{"2018-05-01T00:00:00Z", true},
+	}
+	for _, test := range tests {
+		o := NodeConfigOverride{Expires: test.expires}
+		if o.Expired(now) != test.expected {
+			t.Errorf("Expired() for %q: got %v, expected %v", test.expires, !test.expected, test.expected)
+		}
+	}
+}
+
+func TestPlatformMatches(t *testing.T) {
+	tests := []struct {
+		constraint string
+		platform   string
+		expected   bool
+	}{
+		{"", "darwin", true},
+		{"darwin", "", true},
+		{"darwin", "darwin", true},
+		{"darwin", "linux", false},
+		{"windows,darwin", "windows", true},
+		{"posix", "linux", true},
+		{"posix", "windows", false},
+		{"all", "windows", true},
+		{"ubuntu", "linux", true},
+		{"posix", "plan9", false},
+		{"plan9", "plan9", false},
+		{"any", "plan9", true},
+	}
+	for _, test := range tests {
+		if PlatformMatches(test.constraint, test.platform) != test.expected {
+			t.Errorf("PlatformMatches(%q, %q) expected %v", test.constraint, test.platform, test.expected)
+		}
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"3.3.0", "1.4.5", 1},
+		{"1.4.5", "1.4.5", 0},
+		{"1.4", "1.4.0", 0},
+		{"2.11.2", "2.9.0", 1},
+		{"3.2.6-7-g1234", "3.3.0", -1},
+	}
+	for _, test := range tests {
+		if got := CompareVersions(test.a, test.b); got != test.expected {
+			t.Errorf("CompareVersions(%q, %q) = %d, expected %d", test.a, test.b, got, test.expected)
+		}
+	}
+}
+
+func TestPack_ForTarget(t *testing.T) {
+	pack := Pack{
+		PackName: "mixed",
+		Queries: []PackQuery{
+			{QueryName: "everywhere", Query: "select * from time;"},
+			{QueryName: "mac_only", Query: "select * from launchd;", Platform: "darwin"},
+			{QueryName: "new_table", Query: "select * from new_table;", Version: "9.0.0"},
+		},
+	}
+	linux := OsqueryClient{HostDetails: map[string]map[string]string{
+		"os_version":   {"platform": "ubuntu"},
+		"osquery_info": {"version": "3.3.0"},
+	}}
+
+	result, ok, filtered := pack.ForTarget(linux.RenderTarget())
+	if !ok {
+		t.Fatal("pack should still apply")
+	}
+	if len(result.Queries) != 1 || result.Queries[0].QueryName != "everywhere" {
+		t.Errorf("unexpected queries: %+v", result.Queries)
+	}
+	if len(filtered) != 2 {
+		t.Errorf("expected 2 filtered queries, got %+v", filtered)
+	}
+
+	pack.Platform = "windows"
+	if _, ok, _ = pack.ForTarget(linux.RenderTarget()); ok {
+		t.Error("windows pack should not apply to linux")
+	}
+}
+
+func TestOsquerySchedule_Validate(t *testing.T) {
+	stored := `{
+		"time": {"query": "select * from time;", "interval": "60", "removed": "false"},
+		"uptime": {"query": "select * from uptime;", "interval": 3600, "snapshot": true, "platform": "posix", "version": "2.9.0", "shard": 10}
+	}`
+	schedule := OsquerySchedule{}
+	if err := json.Unmarshal([]byte(stored), &schedule); err != nil {
+		t.Fatal(err)
+	}
+	if err := schedule.Validate(); err != nil {
+		t.Errorf("expected schedule to be valid: %s", err)
+	}
+	if schedule["time"].Interval != 60 || schedule["time"].Removed == nil || *schedule["time"].Removed {
+		t.Errorf("unexpected time query: %+v", schedule["time"])
+	}
+
+	invalid := map[string]ScheduledQuery{
+		"no query":      {Interval: 60},
+		"zero interval": {Query: "select 1;"},
+		"long interval": {Query: "select 1;", Interval: MaxScheduleInterval + 1},
+		"bad platform":  {Query: "select 1;", Interval: 60, Platform: "beos"},
+		"bad version":   {Query: "select 1;", Interval: 60, Version: "latest"},
+		"bad shard":     {Query: "select 1;", Interval: 60, Shard: 101},
+	}
+	for name, sq := range invalid {
+		if err := (OsquerySchedule{name: sq}).Validate(); err == nil {
+			t.Errorf("%s: expected validation error", name)
+		}
+	}
+}
+
+func TestOsquerySchedule_UnmarshalLegacy(t *testing.T) {
+	// the schedule as it was stored when it was a struct holding an unused time query
+	type legacyTime struct {
+		Query    string `json:"query"`
+		Interval int    `json:"interval"`
+		Removed  string `json:"removed"`
+	}
+	stored, err := dynamodbattribute.MarshalMap(map[string]interface{}{
+		"config_name": "default",
+		"osquery_config": map[string]interface{}{
+			"options":  map[string]interface{}{"verbose": true},
+			"schedule": map[string]interface{}{"time": legacyTime{}},
+		},
+		"os_type": "linux",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	nc := OsqueryNamedConfig{}
+	if err = dynamodbattribute.UnmarshalMap(stored, &nc); err != nil {
+		t.Fatal(err)
+	}
+	if len(nc.OsqueryConfig.Schedule) != 0 {
+		t.Errorf("expected the legacy time query to be dropped, got %+v", nc.OsqueryConfig.Schedule)
+	}
+	if err = nc.OsqueryConfig.Validate(); err != nil {
+		t.Errorf("expected legacy config to validate: %s", err)
+	}
+
+	// saving the config again keeps real queries, including one named time
+	nc.OsqueryConfig.Schedule = OsquerySchedule{"time": {Query: "select * from time;", Interval: 60}}
+	if stored, err = dynamodbattribute.MarshalMap(nc); err != nil {
+		t.Fatal(err)
+	}
+	saved := OsqueryNamedConfig{}
+	if err = dynamodbattribute.UnmarshalMap(stored, &saved); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(saved.OsqueryConfig.Schedule, nc.OsqueryConfig.Schedule) {
+		t.Errorf("expected %+v, got %+v", nc.OsqueryConfig.Schedule, saved.OsqueryConfig.Schedule)
+	}
+}
+
+func TestOsqueryConfig_ValidateSections(t *testing.T) {
+	js := `{
+		"options": {},
+		"file_paths": {"homes": ["/home/%/.ssh/%%"], "etc": ["/etc/%%"]},
+		"file_accesses": ["homes"],
+		"exclude_paths": {"etc": ["/etc/mtab"]},
+		"yara": {"signatures": {"sig_group_1": ["/etc/osquery/yara/rules.sig"]}, "file_paths": {"homes": ["sig_group_1"]}},
+		"events": {"disable_subscribers": ["user_events"]},
+		"views": {"kernel_hashes": "select path, md5 from hash where path like '/boot/%';"},
+		"auto_table_construction": {"quarantine": {"query": "select * from events;", "path": "/db.sqlite", "columns": ["id"], "platform": "darwin"}},
+		"prometheus_targets": {"timeout": "5", "urls": ["http://localhost:9100/metrics"]},
+		"decorators": {"interval": {"3600": ["select total_seconds from uptime;"]}}
+	}`
+	oc := OsqueryConfig{}
+	if err := json.Unmarshal([]byte(js), &oc); err != nil {
+		t.Fatal(err)
+	}
+	if err := oc.Validate(); err != nil {
+		t.Fatalf("expected config to be valid: %s", err)
+	}
+
+	invalid := map[string]func(oc *OsqueryConfig){
+		"unknown file access":    func(oc *OsqueryConfig) { oc.FileAccesses = []string{"tmp"} },
+		"unknown exclude":        func(oc *OsqueryConfig) { oc.ExcludePaths = map[string][]string{"tmp": {"/tmp/x"}} },
+		"unknown yara group":     func(oc *OsqueryConfig) { oc.Yara.FilePaths["homes"] = []string{"missing"} },
+		"empty view":             func(oc *OsqueryConfig) { oc.Views["empty"] = "" },
+		"atc without columns":    func(oc *OsqueryConfig) { oc.AutoTableConstruction["t"] = ATCTable{Query: "select 1;", Path: "/x"} },
+		"bad prometheus url":     func(oc *OsqueryConfig) { oc.PrometheusTargets.Urls = []string{"localhost:9100"} },
+		"bad decorator interval": func(oc *OsqueryConfig) { oc.Decorators.Interval = map[string][]string{"90": {"select 1;"}} },
+	}
+	for name, mutate := range invalid {
+		broken := OsqueryConfig{}
+		if err := json.Unmarshal([]byte(js), &broken); err != nil {
+			t.Fatal(err)
+		}
+		mutate(&broken)
+		if err := broken.Validate(); err == nil {
+			t.Errorf("%s: expected validation error", name)
+		}
+	}
+
+	// the first invalid table is reported by name, in order
+	broken := OsqueryConfig{AutoTableConstruction: map[string]ATCTable{}}
+	for _, name := range []string{"d", "b", "c", "a"} {
+		broken.AutoTableConstruction[name] = ATCTable{Query: "select 1;", Path: "/x"}
+	}
+	for i := 0; i < 5; i++ {
+		if err := broken.Validate(); err == nil || !strings.Contains(err.Error(), `table "a"`) {
+			t.Fatalf("expected table a to be reported, got %v", err)
+		}
+	}
+}
+
+func TestOsqueryOptions_UnmarshalLegacy(t *testing.T) {
+	stored, err := dynamodbattribute.MarshalMap(map[string]interface{}{
+		"verbose":              true,
+		"disable_tables":       false,
+		"extenstions_autoload": true,
+		"aws_firehose_stream":  "",
+		"aws_sts_timeout":      "3600",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	options := OsqueryOptions{}
+	err = dynamodbattribute.Unmarshal(&dynamodb.AttributeValue{M: stored}, &options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = options.Validate(); err != nil {
+		t.Errorf("expected legacy options to be cleaned up: %s", err)
+	}
+	if timeout, ok := options.Int("aws_sts_timeout"); !ok || timeout != 3600 {
+		t.Errorf("expected aws_sts_timeout to be converted, got %v", options["aws_sts_timeout"])
+	}
+	if len(options) != 2 {
+		t.Errorf("unexpected options: %v", options)
+	}
+}
+
+func TestNewOsqueryOptions_Valid(t *testing.T) {
+	if err := NewOsqueryOptions().Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestOsqueryOptions_RestartRequired(t *testing.T) {
+	previous := OsqueryOptions{"config_refresh": float64(60), "events_max": float64(1000), "logger_plugin": "firehose", "verbose": true}
+	current := OsqueryOptions{"config_refresh": 300, "events_max": 1000, "logger_plugin": "tls", "host_identifier": "uuid", "verbose": true}
+	expected := []string{"host_identifier", "logger_plugin"}
+	if got := current.RestartRequired(previous); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+	if got := previous.RestartRequired(previous.Copy()); len(got) != 0 {
+		t.Errorf("expected no changes, got %v", got)
+	}
+}
+
+func TestOsqueryConfig_Redacted(t *testing.T) {
+	oc := OsqueryConfig{Options: OsqueryOptions{
+		"aws_access_key_id":     "AKIAEXAMPLE",
+		"aws_secret_access_key": "secret",
+		"aws_session_token":     "",
+		"aws_firehose_stream":   "osquery",
+	}}
+	redacted := oc.Redacted()
+	if redacted.Options["aws_access_key_id"] != RedactedValue || redacted.Options["aws_secret_access_key"] != RedactedValue {
+		t.Errorf("sensitive options not redacted: %v", redacted.Options)
+	}
+	if redacted.Options["aws_session_token"] != "" || redacted.Options["aws_firehose_stream"] != "osquery" {
+		t.Errorf("unexpected options changed: %v", redacted.Options)
+	}
+	if oc.Options["aws_secret_access_key"] != "secret" {
+		t.Error("redacting changed the original options")
+	}
+}
+
+func TestQueryPack_ResolveQuery(t *testing.T) {
+	qp := QueryPack{
+		PackName: "osx-attacks",
+		Queries:  []string{"osx-attacks/Conduit", "shared_query"},
+		Overrides: map[string]PackQueryOverride{
+			"shared_query": {Interval: 60, Snapshot: Bool(true), Platform: "darwin"},
+		},
+	}
+	if err := qp.ValidateOverrides(); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	conduit := qp.ResolveQuery(PackQuery{QueryName: "osx-attacks/Conduit", Query: "select 1;", Interval: 3600})
+	if conduit.QueryName != "Conduit" || conduit.Interval != 3600 {
+		t.Errorf("unexpected resolved query: %+v", conduit)
+	}
+
+	shared := qp.ResolveQuery(PackQuery{QueryName: "shared_query", Query: "select 2;", Interval: 3600, Platform: "posix"})
+	expected := PackQuery{QueryName: "shared_query", Query: "select 2;", Interval: 60, Snapshot: Bool(true), Platform: "darwin"}
+	if !reflect.DeepEqual(shared, expected) {
+		t.Errorf("Got: \n\t%+v, expected: \n\t%+v", shared, expected)
+	}
+
+	qp.Overrides["other"] = PackQueryOverride{Interval: 60}
+	if err := qp.ValidateOverrides(); err == nil {
+		t.Error("expected an override for a query not in the pack to be invalid")
+	}
+	delete(qp.Overrides, "other")
+	qp.Overrides["shared_query"] = PackQueryOverride{Platform: "beos"}
+	if err := qp.ValidateOverrides(); err == nil {
+		t.Error("expected an override with an unknown platform to be invalid")
+	}
+}
+
+func TestPackList_Marshal(t *testing.T) {
+	pl := PackList{
+		{PackName: "osquery-monitoring"},
+		{PackName: "incident-response", Start: "2018-03-01T00:00:00Z", End: "2018-03-08T00:00:00Z", Tag: "compromised"},
+	}
+	js, err := json.Marshal(pl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `["osquery-monitoring",{"pack_name":"incident-response","start":"2018-03-01T00:00:00Z","end":"2018-03-08T00:00:00Z","tag":"compromised"}]`
+	if string(js) != expected {
+		t.Errorf("got %s", js)
+	}
+	decoded := PackList{}
+	if err = json.Unmarshal(js, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, pl) {
+		t.Errorf("json round trip: got %+v", decoded)
+	}
+
+	// pack lists stored before entries could be limited are lists of names
+	stored, err := dynamodbattribute.MarshalMap(map[string]interface{}{"pack_list": []string{"osquery-monitoring"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	nc := OsqueryNamedConfig{}
+	if err = dynamodbattribute.UnmarshalMap(stored, &nc); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(nc.PackList, NewPackList("osquery-monitoring")) {
+		t.Errorf("legacy pack list: got %+v", nc.PackList)
+	}
+
+	nc.PackList = pl
+	stored, err = dynamodbattribute.MarshalMap(nc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored["pack_list"].L[0].S == nil || stored["pack_list"].L[1].M == nil {
+		t.Errorf("expected a plain entry to be stored as a string, got %s", stored["pack_list"])
+	}
+	nc = OsqueryNamedConfig{}
+	if err = dynamodbattribute.UnmarshalMap(stored, &nc); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(nc.PackList, pl) {
+		t.Errorf("dynamo round trip: got %+v", nc.PackList)
+	}
+}
+
+func TestPackListEntry_ForTarget(t *testing.T) {
+	entry := PackListEntry{PackName: "incident-response", Start: "2018-03-01T00:00:00Z", End: "2018-03-08T00:00:00Z", Tag: "compromised"}
+	tagged := RenderTarget{Tags: []string{"laptop", "compromised"}}
+	cases := []struct {
+		now      string
+		target   RenderTarget
+		included bool
+	}{
+		{"2018-02-28T23:59:59Z", tagged, false},
+		{"2018-03-01T00:00:00Z", tagged, true},
+		{"2018-03-07T23:59:59Z", tagged, true},
+		{"2018-03-08T00:00:00Z", tagged, false},
+		{"2018-03-04T00:00:00Z", RenderTarget{Tags: []string{"laptop"}}, false},
+		{"2018-03-04T00:00:00Z", RenderTarget{}, false},
+	}
+	for _, c := range cases {
+		now, _ := time.Parse(time.RFC3339, c.now)
+		if reason := entry.ForTarget(c.target, now); (reason == "") != c.included {
+			t.Errorf("%s %v: expected included %v, got reason %q", c.now, c.target.Tags, c.included, reason)
+		}
+	}
+
+	for _, invalid := range []PackListEntry{
+		{},
+		{PackName: "p", Start: "tomorrow"},
+		{PackName: "p", Start: "2018-03-08T00:00:00Z", End: "2018-03-01T00:00:00Z"},
+	} {
+		if err := invalid.Validate(); err == nil {
+			t.Errorf("%+v: expected validation error", invalid)
+		}
+	}
+}
+
+func TestNamedConfig_PackSchedule(t *testing.T) {
+	nc := OsqueryNamedConfig{
+		ConfigName: "default",
+		PackList: PackList{
+			{PackName: "osquery-monitoring"},
+			{PackName: "later", Start: "2018-05-01T00:00:00Z"},
+			{PackName: "old", End: "2018-01-01T00:00:00Z"},
+			{PackName: "sooner", Start: "2018-04-01T00:00:00Z"},
+			{PackName: "incident-response", End: "2018-04-01T00:00:00Z"},
+		},
+	}
+	now, _ := time.Parse(time.RFC3339, "2018-03-01T00:00:00Z")
+	ps := nc.PackSchedule(now)
+	names := func(packs []ScheduledPack) []string {
+		result := []string{}
+		for _, p := range packs {
+			result = append(result, p.PackName)
+		}
+		return result
+	}
+	if got := names(ps.Active); !reflect.DeepEqual(got, []string{"osquery-monitoring", "incident-response"}) {
+		t.Errorf("active: got %v", got)
+	}
+	if got := names(ps.Scheduled); !reflect.DeepEqual(got, []string{"sooner", "later"}) {
+		t.Errorf("scheduled: got %v", got)
+	}
+	if got := names(ps.Expired); !reflect.DeepEqual(got, []string{"old"}) {
+		t.Errorf("expired: got %v", got)
+	}
+}
+
+func TestCampaignTargets_Resolve(t *testing.T) {
+	darwin := map[string]map[string]string{"os_version": {"platform": "darwin"}}
+	ubuntu := map[string]map[string]string{"os_version": {"platform": "ubuntu"}}
+	clients := []OsqueryClient{
+		{NodeKey: "mac-prod", HostDetails: darwin, Tags: []string{"prod"}, ConfigurationGroup: "laptops"},
+		{NodeKey: "mac-dev", HostDetails: darwin, Tags: []string{"dev"}, ConfigurationGroup: "laptops"},
+		{NodeKey: "linux-prod", HostDetails: ubuntu, Tags: []string{"prod"}, ConfigurationGroup: "servers"},
+		{NodeKey: "pending", HostDetails: darwin, Tags: []string{"prod"}, PendingRegistrationApproval: true},
+	}
+	keys := func(ocs []OsqueryClient) []string {
+		result := []string{}
+		for _, oc := range ocs {
+			result = append(result, oc.NodeKey)
+		}
+		return result
+	}
+
+	for _, test := range []struct {
+		targets  CampaignTargets
+		expected []string
+		unknown  []string
+	}{
+		{CampaignTargets{Tags: []string{"prod"}}, []string{"mac-prod", "linux-prod"}, []string{}},
+		{CampaignTargets{Tags: []string{"prod"}, Platforms: []string{"linux"}}, []string{"linux-prod"}, []string{}},
+		{CampaignTargets{Groups: []string{"laptops"}, Platforms: []string{"posix"}}, []string{"mac-prod", "mac-dev"}, []string{}},
+		{CampaignTargets{Tags: []string{"dev"}, NodeKeys: []string{"linux-prod", "gone", "pending"}}, []string{"mac-dev", "linux-prod"}, []string{"gone"}},
+	} {
+		targeted, unknown := test.targets.Resolve(clients, nil)
+		if !reflect.DeepEqual(keys(targeted), test.expected) || !reflect.DeepEqual(unknown, test.unknown) {
+			t.Errorf("%+v: got %v and unknown %v", test.targets, keys(targeted), unknown)
+		}
+	}
+}
+
+func TestCampaignNode_State(t *testing.T) {
+	c := Campaign{CampaignID: "c1", Query: "SELECT 1", Targets: CampaignTargets{Tags: []string{"a"}},
+		CreatedAt: "2019-01-01T00:00:00Z", Timeout: 60}
+	if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	before := time.Date(2019, 1, 1, 0, 0, 30, 0, time.UTC)
+	after := time.Date(2019, 1, 1, 0, 1, 0, 0, time.UTC)
+
+	nodes := []CampaignNode{
+		{NodeKey: "b", HostIdentifier: "b", State: CampaignDelivered},
+		{NodeKey: "a", HostIdentifier: "a", State: CampaignAnswered, Rows: []map[string]string{{"x": "1"}}},
+	}
+	if nodes[0].StateAt(c, before) != CampaignDelivered || nodes[0].StateAt(c, after) != CampaignTimedOut {
+		t.Errorf("expected the delivered node to time out at the deadline")
+	}
+	if nodes[1].StateAt(c, after) != CampaignAnswered {
+		t.Errorf("expected an answered node to stay answered")
+	}
+
+	status := NewCampaignStatus(c, nodes, after)
+	if status.Counts[CampaignTimedOut] != 1 || status.Counts[CampaignAnswered] != 1 || status.Counts[CampaignQueued] != 0 {
+		t.Errorf("counts: got %v", status.Counts)
+	}
+	if status.Nodes[0].HostIdentifier != "a" || status.Nodes[0].Rows != nil {
+		t.Errorf("expected nodes sorted by host identifier without rows, got %+v", status.Nodes)
+	}
+
+	cn := CampaignNode{}
+	cn.SetRows([]map[string]string{{"x": "1"}, {"x": "2"}, {"x": "3"}}, 20)
+	if cn.RowCount != 3 || len(cn.Rows) != 2 || !cn.Truncated {
+		t.Errorf("expected 2 of 3 rows to fit, got %+v", cn)
+	}
+}
+
+func TestDistributedQuery_Deliver(t *testing.T) {
+	policy := DeliveryPolicy{RedeliverAfter: time.Minute, MaxDeliveries: 2, TTL: time.Hour}
+	start := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	dq := DistributedQuery{NodeKey: "a", Queries: []string{"select 1;"}, NextID: 2}
+	dq.AddPending(PendingQuery{Name: "c1", Query: "select 2;", CampaignID: "c1", QueuedAt: start.Format(time.RFC3339)})
+
+	sent, expired := dq.Deliver(start, policy)
+	if len(sent) != 2 || len(expired) != 0 || len(dq.Queries) != 0 {
+		t.Fatalf("expected both queries sent, got %+v %+v", sent, expired)
+	}
+	if sent[1].Name != "id3" || dq.NextID != 3 {
+		t.Errorf("expected the added query to be named after the last one, got %+v", sent[1])
+	}
+	if sent, _ := dq.Deliver(start.Add(30*time.Second), policy); len(sent) != 0 {
+		t.Errorf("expected nothing due before the redelivery timeout, got %+v", sent)
+	}
+
+	acked := dq.Acknowledge([]string{"c1", "unknown"})
+	if len(acked) != 1 || acked[0].Name != "c1" || len(dq.Pending) != 1 {
+		t.Fatalf("expected c1 acknowledged, got %+v, pending %+v", acked, dq.Pending)
+	}
+
+	sent, _ = dq.Deliver(start.Add(time.Minute), policy)
+	if len(sent) != 1 || sent[0].Deliveries != 2 {
+		t.Fatalf("expected the unanswered query redelivered, got %+v", sent)
+	}
+	sent, expired = dq.Deliver(start.Add(2*time.Minute), policy)
+	if len(sent) != 0 || len(expired) != 1 || expired[0].Outcome != DistributedRetriesExhausted || len(dq.Pending) != 0 {
+		t.Errorf("expected the query given up on after 2 deliveries, got %+v %+v", sent, expired)
+	}
+}
+
+func TestDistributedQuery_Expire(t *testing.T) {
+	policy := DeliveryPolicy{RedeliverAfter: time.Minute, MaxDeliveries: 3, TTL: time.Hour}
+	start := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	dq := DistributedQuery{Pending: []PendingQuery{
+		{Name: "id1", QueuedAt: start.Format(time.RFC3339)},
+		{Name: "c1", QueuedAt: start.Format(time.RFC3339), ExpiresAt: start.Add(10 * time.Minute).Format(time.RFC3339)},
+	}}
+	if expired := dq.Expire(start.Add(5*time.Minute), policy); len(expired) != 0 {
+		t.Errorf("expected nothing expired, got %+v", expired)
+	}
+	expired := dq.Expire(start.Add(10*time.Minute), policy)
+	if len(expired) != 1 || expired[0].Name != "c1" || expired[0].Outcome != DistributedExpired {
+		t.Errorf("expected c1 expired at its own deadline, got %+v", expired)
+	}
+	expired = dq.Expire(start.Add(time.Hour), policy)
+	if len(expired) != 1 || expired[0].Name != "id1" || len(dq.Pending) != 0 {
+		t.Errorf("expected id1 expired at the TTL, got %+v", expired)
+	}
+
+	ds := NewExpiredStatus(OsqueryClient{NodeKey: "a", HostIdentifier: "host"}, expired[0], start)
+	if !ds.Failed() || ds.Outcome != DistributedExpired || ds.Message == "" {
+		t.Errorf("got %+v", ds)
+	}
+}
+
+func TestDistributedSchedule_SetNextRun(t *testing.T) {
+	ds := DistributedSchedule{EndsAt: "2019-01-01T12:00:00Z", NextRunAt: "2019-01-01T08:00:00Z"}
+	ds.SetNextRun(time.Date(2019, 1, 1, 16, 0, 0, 0, time.UTC))
+	if ds.NextRunAt != "" || !ds.Ended(time.Date(2019, 1, 1, 9, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected the schedule to end, got %+v", ds)
+	}
+}
+
+func TestSavedQuery_Render(t *testing.T) {
+	sq := SavedQuery{
+		Name:  "owner_files",
+		Query: "SELECT * FROM file -- {{ignored}}\nWHERE path LIKE {{dir}} || '/%' AND uid = {{uid}} AND size > 0-{{min}} AND {{hidden}} /* {{also}} */ AND md5 = {{md5}};",
+		Params: []SavedQueryParam{
+			{Name: "dir", Type: ParamPath},
+			{Name: "uid", Type: ParamInteger},
+			{Name: "min", Type: ParamNumber, Default: "0"},
+			{Name: "hidden", Type: ParamBoolean, Default: "false"},
+			{Name: "md5", Type: ParamMD5},
+		},
+	}
+	if err := sq.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	rendered, err := sq.Render(map[string]string{
+		"dir": "/home/O'Brien",
+		"uid": " 501",
+		"min": "-1.5",
+		"md5": "D41D8CD98F00B204E9800998ECF8427E",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "SELECT * FROM file -- {{ignored}}\nWHERE path LIKE '/home/O''Brien' || '/%' AND uid = 501 AND size > 0-(-1.5) AND 0 /* {{also}} */ AND md5 = 'd41d8cd98f00b204e9800998ecf8427e';"
+	if rendered != expected {
+		t.Errorf("expected %s, got %s", expected, rendered)
+	}
+
+	invalid := []map[string]string{
+		{"dir": "/tmp", "uid": "501"},
+		{"dir": "/tmp", "uid": "501 OR 1=1", "md5": "d41d8cd98f00b204e9800998ecf8427e"},
+		{"dir": "/tmp", "uid": "501", "md5": "d41d8cd98f00b204e9800998ecf8427e' OR '1"},
+		{"dir": "/tmp\x00", "uid": "501", "md5": "d41d8cd98f00b204e9800998ecf8427e"},
+		{"dir": "/tmp", "uid": "501", "md5": "d41d8cd98f00b204e9800998ecf8427e", "user": "root"},
+	}
+	for _, values := range invalid {
+		if _, err := sq.Render(values); err == nil {
+			t.Errorf("expected %v to be rejected", values)
+		}
+	}
+}
+
+func TestSavedQuery_Validate(t *testing.T) {
+	valid := SavedQuery{
+		Name:      "users",
+		Query:     "SELECT * FROM users WHERE username = {{user}};",
+		Params:    []SavedQueryParam{{Name: "user", Type: ParamString}},
+		Platforms: []string{"darwin", "linux"},
+	}
+	if err := valid.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	invalid := []SavedQuery{valid, valid, valid, valid, valid, valid, valid}
+	invalid[0].Name = "users/all"
+	invalid[1].Query = "SELECT * FROM users WHERE username = '{{user}}';"
+	invalid[2].Query = "SELECT * FROM users WHERE uid = {{uid}};"
+	invalid[3].Params = []SavedQueryParam{{Name: "user", Type: "username"}}
+	invalid[4].Params = []SavedQueryParam{{Name: "user", Type: ParamString}, {Name: "user", Type: ParamPath}}
+	invalid[5].Params = []SavedQueryParam{{Name: "user", Type: ParamInteger, Default: "root"}}
+	invalid[6].Platforms = []string{"plan9"}
+	for _, sq := range invalid {
+		if err := sq.Validate(); err == nil {
+			t.Errorf("expected %+v to be invalid", sq)
+		}
+	}
+}
+
+func TestOsqueryClient_RenderTargetPlatform(t *testing.T) {
+	tests := []struct {
+		platform, like, expected string
+	}{
+		{"Ubuntu", "debian", "linux"},
+		{"darwin", "", "darwin"},
+		{"mydistro", "rhel fedora", "linux"},
+		{"plan9", "", "plan9"},
+		{"", "", ""},
+	}
+	for _, test := range tests {
+		oc := OsqueryClient{HostDetails: map[string]map[string]string{
+			"os_version": {"platform": test.platform, "platform_like": test.like},
+		}}
+		if got := oc.RenderTarget().Platform; got != test.expected {
+			t.Errorf("platform %q like %q: expected %q, got %q", test.platform, test.like, test.expected, got)
+		}
 	}
 }
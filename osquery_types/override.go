@@ -0,0 +1,95 @@
+package osquery_types
+
+import (
+	"fmt"
+	"time"
+)
+
+// NodeConfigOverride is a sparse set of changes applied to a single node's config after its
+// named config has been built.  Only the options that are set are changed, decorators are
+// appended to those of the named config and packs are added alongside the named config's packs
+type NodeConfigOverride struct {
+	Options    map[string]interface{} `json:"options,omitempty"`
+	Decorators OsqueryDecorators      `json:"decorators,omitempty"`
+	Packs      []string               `json:"packs,omitempty"`
+	// Expires is an optional RFC 3339 timestamp after which the override is no longer applied
+	Expires string `json:"expires,omitempty"`
+}
+
+// Validate checks that the expiry is parseable and that every option maps to a known osquery option
+// of the correct type
+func (o NodeConfigOverride) Validate() error {
+	if o.Expires != "" {
+		if _, err := time.Parse(time.RFC3339, o.Expires); err != nil {
+			return fmt.Errorf("invalid expires timestamp %q, expected RFC 3339: %s", o.Expires, err)
+		}
+	}
+	if err := o.Decorators.Validate(); err != nil {
+		return err
+	}
+	_, err := o.ApplyOptions(NewOsqueryOptions())
+	return err
+}
+
+// Expired returns true if the override has an expiry that is at or before now
+func (o NodeConfigOverride) Expired(now time.Time) bool {
+	if o.Expires == "" {
+		return false
+	}
+	expires, err := time.Parse(time.RFC3339, o.Expires)
+	if err != nil {
+		// an unparseable expiry can't be honoured, so treat it as expired rather than applying it forever
+		return true
+	}
+	return !now.Before(expires)
+}
+
+// ApplyOptions overlays the override options on top of opts and returns the result, leaving opts
+// unchanged
+func (o NodeConfigOverride) ApplyOptions(opts OsqueryOptions) (OsqueryOptions, error) {
+	if len(o.Options) == 0 {
+		return opts, nil
+	}
+	if err := OsqueryOptions(o.Options).Validate(); err != nil {
+		return opts, fmt.Errorf("invalid option override: %s", err)
+	}
+	result := opts.Copy()
+	for k, v := range o.Options {
+		result[k] = v
+	}
+	return result, nil
+}
+
+// Apply merges the override options and decorators into oc.  Packs are not resolved here since
+// they need to be looked up by name
+func (o NodeConfigOverride) Apply(oc *OsqueryConfig) error {
+	options, err := o.ApplyOptions(oc.Options)
+	if err != nil {
+		return err
+	}
+	oc.Options = options
+	oc.Decorators.Load = appendMissing(oc.Decorators.Load, o.Decorators.Load...)
+	oc.Decorators.Always = appendMissing(oc.Decorators.Always, o.Decorators.Always...)
+	for interval, queries := range o.Decorators.Interval {
+		if oc.Decorators.Interval == nil {
+			oc.Decorators.Interval = make(map[string][]string)
+		}
+		oc.Decorators.Interval[interval] = appendMissing(oc.Decorators.Interval[interval], queries...)
+	}
+	return nil
+}
+
+// appendMissing appends each value to list if it isn't already present
+func appendMissing(list []string, values ...string) []string {
+	existing := map[string]bool{}
+	for _, i := range list {
+		existing[i] = true
+	}
+	for _, v := range values {
+		if !existing[v] {
+			list = append(list, v)
+			existing[v] = true
+		}
+	}
+	return list
+}
@@ -0,0 +1,88 @@
+package osquery_types
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// QueryNamespaceSeparator separates the pack name from the query name in a namespaced query name
+const QueryNamespaceSeparator = "/"
+
+// NamespacedQueryName returns the name a query is stored under when it belongs to a pack, so that
+// queries with the same name in different packs don't overwrite each other
+func NamespacedQueryName(packName, queryName string) string {
+	return packName + QueryNamespaceSeparator + queryName
+}
+
+// NameInPack returns the name the query has inside the named pack, which is the query name without
+// the pack's namespace.  Queries from other namespaces, or with none, keep their full name
+func (pq PackQuery) NameInPack(packName string) string {
+	return strings.TrimPrefix(pq.QueryName, packName+QueryNamespaceSeparator)
+}
+
+// PackQueryOverride changes how a shared pack query runs in one pack, without changing it for the
+// other packs that use it.  Fields that are not set are taken from the query
+type PackQueryOverride struct {
+	Interval FlexInt   `json:"interval,omitempty"`
+	Snapshot *FlexBool `json:"snapshot,omitempty"`
+	Removed  *FlexBool `json:"removed,omitempty"`
+	Platform string    `json:"platform,omitempty"`
+}
+
+// Validate returns an error if the override has a field osquery would reject
+func (o PackQueryOverride) Validate() error {
+	if o.Interval < 0 || o.Interval > MaxScheduleInterval {
+		return fmt.Errorf("interval must be 0 (unset) or between 1 and %d seconds", MaxScheduleInterval)
+	}
+	return ValidatePlatform(o.Platform)
+}
+
+// Apply returns the query with the override's fields set
+func (o PackQueryOverride) Apply(pq PackQuery) PackQuery {
+	if o.Interval != 0 {
+		pq.Interval = o.Interval
+	}
+	if o.Snapshot != nil {
+		pq.Snapshot = o.Snapshot
+	}
+	if o.Removed != nil {
+		pq.Removed = o.Removed
+	}
+	if o.Platform != "" {
+		pq.Platform = o.Platform
+	}
+	return pq
+}
+
+// ValidateOverrides returns an error if an override is for a query that isn't in the pack, or is invalid
+func (qp QueryPack) ValidateOverrides() error {
+	inPack := map[string]bool{}
+	for _, queryName := range qp.Queries {
+		inPack[queryName] = true
+	}
+	names := make([]string, 0, len(qp.Overrides))
+	for queryName := range qp.Overrides {
+		names = append(names, queryName)
+	}
+	sort.Strings(names)
+	for _, queryName := range names {
+		if !inPack[queryName] {
+			return fmt.Errorf("override for query [%s] which is not in pack [%s]", queryName, qp.PackName)
+		}
+		if err := qp.Overrides[queryName].Validate(); err != nil {
+			return fmt.Errorf("override for query [%s]: %s", queryName, err)
+		}
+	}
+	return nil
+}
+
+// ResolveQuery returns the query as it runs in the pack, with the pack's override for it applied and
+// named without the pack's namespace
+func (qp QueryPack) ResolveQuery(pq PackQuery) PackQuery {
+	if o, ok := qp.Overrides[pq.QueryName]; ok {
+		pq = o.Apply(pq)
+	}
+	pq.QueryName = pq.NameInPack(qp.PackName)
+	return pq
+}
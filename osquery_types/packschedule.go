@@ -0,0 +1,249 @@
+package osquery_types
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+// PackListEntry is a pack in a named config's pack list.  A pack can be limited to a window of time,
+// so that a pack added for an incident is removed from the config without anyone having to remember
+// to, and to the nodes with a tag.  An entry with none of these set is stored and shown as just the
+// pack name, which is how every entry was stored before they could be limited
+type PackListEntry struct {
+	PackName string `json:"pack_name"`
+	// Start and End are optional RFC 3339 timestamps.  The pack is in the config from Start and is
+	// removed at End
+	Start string `json:"start,omitempty"`
+	End   string `json:"end,omitempty"`
+	// Tag limits the pack to the nodes with the tag
+	Tag string `json:"tag,omitempty"`
+}
+
+// packListEntry has the fields of PackListEntry without its marshalling methods
+type packListEntry PackListEntry
+
+// isPlain returns true if the entry is just a pack name
+func (e PackListEntry) isPlain() bool {
+	return e.Start == "" && e.End == "" && e.Tag == ""
+}
+
+// MarshalJSON writes an entry that is just a pack name as a string, and any other entry as an object
+func (e PackListEntry) MarshalJSON() ([]byte, error) {
+	if e.isPlain() {
+		return json.Marshal(e.PackName)
+	}
+	return json.Marshal(packListEntry(e))
+}
+
+// UnmarshalJSON accepts either a pack name or an object
+func (e *PackListEntry) UnmarshalJSON(b []byte) error {
+	if strings.HasPrefix(strings.TrimSpace(string(b)), `"`) {
+		*e = PackListEntry{}
+		return json.Unmarshal(b, &e.PackName)
+	}
+	return json.Unmarshal(b, (*packListEntry)(e))
+}
+
+// MarshalDynamoDBAttributeValue stores an entry that is just a pack name as a string, so that pack
+// lists without windows or tags are stored exactly as before
+func (e PackListEntry) MarshalDynamoDBAttributeValue(av *dynamodb.AttributeValue) error {
+	if e.isPlain() {
+		av.S = &e.PackName
+		return nil
+	}
+	m, err := dynamodbattribute.MarshalMap(packListEntry(e))
+	if err != nil {
+		return err
+	}
+	av.M = m
+	return nil
+}
+
+// UnmarshalDynamoDBAttributeValue accepts either a string or a map attribute
+func (e *PackListEntry) UnmarshalDynamoDBAttributeValue(av *dynamodb.AttributeValue) error {
+	*e = PackListEntry{}
+	switch {
+	case av == nil || av.NULL != nil:
+		return nil
+	case av.S != nil:
+		e.PackName = *av.S
+		return nil
+	case av.M != nil:
+		return dynamodbattribute.UnmarshalMap(av.M, (*packListEntry)(e))
+	}
+	return fmt.Errorf("cannot unmarshal %s into a pack list entry", av)
+}
+
+// Validate checks the entry names a pack and that its window, if it has one, is a valid time range
+func (e PackListEntry) Validate() error {
+	if e.PackName == "" {
+		return errors.New("pack list entry has no pack name")
+	}
+	start, err := parseWindowTime(e.Start)
+	if err != nil {
+		return fmt.Errorf("pack [%s] has an invalid start: %s", e.PackName, err)
+	}
+	end, err := parseWindowTime(e.End)
+	if err != nil {
+		return fmt.Errorf("pack [%s] has an invalid end: %s", e.PackName, err)
+	}
+	if e.Start != "" && e.End != "" && !end.After(start) {
+		return fmt.Errorf("pack [%s] ends before it starts", e.PackName)
+	}
+	return nil
+}
+
+func parseWindowTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return t, fmt.Errorf("expected an RFC 3339 timestamp, got %q", s)
+	}
+	return t, nil
+}
+
+// PackWindowState says where now is relative to a pack's window
+type PackWindowState string
+
+const (
+	// PackActive packs are in the config now
+	PackActive PackWindowState = "active"
+	// PackScheduled packs will be added to the config at their start
+	PackScheduled PackWindowState = "scheduled"
+	// PackExpired packs were removed from the config at their end
+	PackExpired PackWindowState = "expired"
+)
+
+// State returns whether the pack is active, scheduled or expired at now.  An entry with an invalid
+// window is treated as expired, rather than being sent forever
+func (e PackListEntry) State(now time.Time) PackWindowState {
+	start, err := parseWindowTime(e.Start)
+	if err != nil {
+		return PackExpired
+	}
+	end, err := parseWindowTime(e.End)
+	if err != nil {
+		return PackExpired
+	}
+	switch {
+	case e.End != "" && !now.Before(end):
+		return PackExpired
+	case e.Start != "" && now.Before(start):
+		return PackScheduled
+	}
+	return PackActive
+}
+
+// ForTarget returns an empty string if the pack is sent to the target at now, otherwise the reason
+// it isn't
+func (e PackListEntry) ForTarget(t RenderTarget, now time.Time) string {
+	switch e.State(now) {
+	case PackScheduled:
+		return fmt.Sprintf("scheduled to start at %s", e.Start)
+	case PackExpired:
+		return fmt.Sprintf("ended at %s", e.End)
+	}
+	if e.Tag != "" && !t.HasTag(e.Tag) {
+		return fmt.Sprintf("only sent to nodes tagged %q", e.Tag)
+	}
+	return ""
+}
+
+// PackList is the list of packs in a named config
+type PackList []PackListEntry
+
+// NewPackList returns a pack list of the named packs, with no windows or tags
+func NewPackList(packNames ...string) PackList {
+	pl := PackList{}
+	for _, packName := range packNames {
+		pl = append(pl, PackListEntry{PackName: packName})
+	}
+	return pl
+}
+
+// Names returns the name of every pack in the list
+func (pl PackList) Names() []string {
+	names := make([]string, 0, len(pl))
+	for _, e := range pl {
+		names = append(names, e.PackName)
+	}
+	return names
+}
+
+// Contains returns true if the pack is in the list, whatever its window
+func (pl PackList) Contains(packName string) bool {
+	for _, e := range pl {
+		if e.PackName == packName {
+			return true
+		}
+	}
+	return false
+}
+
+// Remove returns the list without any entries for the pack
+func (pl PackList) Remove(packName string) PackList {
+	result := PackList{}
+	for _, e := range pl {
+		if e.PackName != packName {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+// Validate checks every entry in the list
+func (pl PackList) Validate() error {
+	for _, e := range pl {
+		if err := e.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ScheduledPack is a pack list entry as shown in a PackSchedule
+type ScheduledPack packListEntry
+
+// PackSchedule lists the packs of a named config by whether they are in the config now.  Scheduled
+// packs are sorted by when they start, the others are in pack list order
+type PackSchedule struct {
+	ConfigName string          `json:"config_name"`
+	Active     []ScheduledPack `json:"active"`
+	Scheduled  []ScheduledPack `json:"scheduled"`
+	Expired    []ScheduledPack `json:"expired"`
+}
+
+// PackSchedule returns the config's packs by whether they are active, scheduled or expired at now
+func (nc OsqueryNamedConfig) PackSchedule(now time.Time) PackSchedule {
+	ps := PackSchedule{
+		ConfigName: nc.ConfigName,
+		Active:     []ScheduledPack{},
+		Scheduled:  []ScheduledPack{},
+		Expired:    []ScheduledPack{},
+	}
+	for _, e := range nc.PackList {
+		switch e.State(now) {
+		case PackActive:
+			ps.Active = append(ps.Active, ScheduledPack(e))
+		case PackScheduled:
+			ps.Scheduled = append(ps.Scheduled, ScheduledPack(e))
+		default:
+			ps.Expired = append(ps.Expired, ScheduledPack(e))
+		}
+	}
+	sort.SliceStable(ps.Scheduled, func(i, j int) bool {
+		a, _ := parseWindowTime(ps.Scheduled[i].Start)
+		b, _ := parseWindowTime(ps.Scheduled[j].Start)
+		return a.Before(b)
+	})
+	return ps
+}
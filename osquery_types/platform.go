@@ -0,0 +1,204 @@
+package osquery_types
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// RenderTarget describes the node a config is being built for.  An empty platform or version is
+// treated as unknown, and nothing is filtered on an unknown value.  Tags are always known, so packs
+// limited to a tag are left out for a target without it
+type RenderTarget struct {
+	Platform       string   `json:"platform,omitempty"`
+	OsqueryVersion string   `json:"osquery_version,omitempty"`
+	Tags           []string `json:"tags,omitempty"`
+}
+
+// RenderTarget returns the platform and osquery version reported by the node when it enrolled,
+// and the node's tags.  A linux distro that isn't recognised by name is recognised by the distros
+// osquery reports it is like
+func (oc OsqueryClient) RenderTarget() RenderTarget {
+	platform := NormalizePlatform(oc.HostDetails["os_version"]["platform"])
+	if !KnownPlatform(platform) {
+		for _, like := range strings.Fields(oc.HostDetails["os_version"]["platform_like"]) {
+			if linuxDistros[strings.ToLower(like)] {
+				platform = "linux"
+				break
+			}
+		}
+	}
+	return RenderTarget{
+		Platform:       platform,
+		OsqueryVersion: oc.HostDetails["osquery_info"]["version"],
+		Tags:           oc.Tags,
+	}
+}
+
+// HasTag returns true if the target has the tag
+func (t RenderTarget) HasTag(tag string) bool {
+	for _, tt := range t.Tags {
+		if tt == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// Key returns a string that is the same for targets that are sent the same config
+func (t RenderTarget) Key() string {
+	tags := append([]string{}, t.Tags...)
+	sort.Strings(tags)
+	return fmt.Sprintf("%s|%s|%s", t.Platform, t.OsqueryVersion, strings.Join(tags, ","))
+}
+
+// knownPlatforms are the platform names used in osquery packs
+var knownPlatforms = map[string]bool{"darwin": true, "linux": true, "windows": true, "freebsd": true}
+
+// linuxDistros are the values of os_version's platform, and platform_like, on linux
+var linuxDistros = map[string]bool{
+	"linux": true, "ubuntu": true, "debian": true, "centos": true, "rhel": true, "redhat": true, "fedora": true,
+	"amzn": true, "ol": true, "oracle": true, "rocky": true, "almalinux": true, "scientific": true, "arch": true,
+	"manjaro": true, "gentoo": true, "opensuse": true, "opensuse-leap": true, "suse": true, "sles": true,
+	"alpine": true, "linuxmint": true, "kali": true, "raspbian": true, "coreos": true,
+}
+
+// NormalizePlatform maps the platform reported in os_version (which is the distro name on linux)
+// to the platform names used in osquery packs.  Platforms that aren't recognised are returned as
+// they are, lower case
+func NormalizePlatform(platform string) string {
+	platform = strings.ToLower(strings.TrimSpace(platform))
+	if linuxDistros[platform] {
+		return "linux"
+	}
+	return platform
+}
+
+// KnownPlatform returns true if the normalized platform is one osquery packs can name
+func KnownPlatform(platform string) bool {
+	return knownPlatforms[platform]
+}
+
+// PlatformMatches returns true if a pack or query with the given platform constraint applies to
+// the platform.  Constraints are comma separated and may use the osquery groupings posix, all and any.
+// A platform that isn't recognised only matches all and any, as nothing else is known to run on it
+func PlatformMatches(constraint, platform string) bool {
+	if constraint == "" || platform == "" {
+		return true
+	}
+	known := KnownPlatform(platform)
+	for _, c := range strings.Split(constraint, ",") {
+		c = strings.ToLower(strings.TrimSpace(c))
+		switch c {
+		case "all", "any":
+			return true
+		case "posix":
+			if known && platform != "windows" {
+				return true
+			}
+		default:
+			if known && NormalizePlatform(c) == platform {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// CompareVersions compares two dotted version strings numerically, returning -1, 0 or 1.  Anything
+// after the numeric components (eg the "-7-g1234" of a development build) is ignored
+func CompareVersions(a, b string) int {
+	av := versionComponents(a)
+	bv := versionComponents(b)
+	for len(av) < len(bv) {
+		av = append(av, 0)
+	}
+	for len(bv) < len(av) {
+		bv = append(bv, 0)
+	}
+	for i := range av {
+		switch {
+		case av[i] < bv[i]:
+			return -1
+		case av[i] > bv[i]:
+			return 1
+		}
+	}
+	return 0
+}
+
+func versionComponents(version string) []int {
+	components := []int{}
+	for _, part := range strings.Split(strings.TrimSpace(version), ".") {
+		digits := part
+		for i, r := range part {
+			if r < '0' || r > '9' {
+				digits = part[:i]
+				break
+			}
+		}
+		n, err := strconv.Atoi(digits)
+		if err != nil {
+			break
+		}
+		components = append(components, n)
+		if digits != part {
+			break
+		}
+	}
+	return components
+}
+
+// VersionAtLeast returns true if version is the same as or newer than minimum.  An empty value on
+// either side means there is nothing to compare, so it is treated as satisfied
+func VersionAtLeast(version, minimum string) bool {
+	if version == "" || minimum == "" {
+		return true
+	}
+	return CompareVersions(version, minimum) >= 0
+}
+
+// FilteredEntry records a pack, or a query within a pack, left out of a rendered config and why
+type FilteredEntry struct {
+	Pack   string `json:"pack"`
+	Query  string `json:"query,omitempty"`
+	Reason string `json:"reason"`
+}
+
+// applies returns an empty string if something with the given platform and version constraints
+// applies to the target, otherwise the reason it doesn't
+func (t RenderTarget) applies(platform, version string) string {
+	if !PlatformMatches(platform, t.Platform) {
+		return fmt.Sprintf("platform %q does not include %s", platform, t.Platform)
+	}
+	if !VersionAtLeast(t.OsqueryVersion, version) {
+		return fmt.Sprintf("requires osquery %s, node has %s", version, t.OsqueryVersion)
+	}
+	return ""
+}
+
+// ForTarget returns the pack with any queries that don't apply to the target removed, whether
+// anything is left of the pack to send, and a record of what was removed
+func (p Pack) ForTarget(t RenderTarget) (Pack, bool, []FilteredEntry) {
+	filtered := []FilteredEntry{}
+	if reason := t.applies(p.Platform, p.Version); reason != "" {
+		filtered = append(filtered, FilteredEntry{Pack: p.PackName, Reason: reason})
+		return p, false, filtered
+	}
+
+	result := p
+	result.Queries = []PackQuery{}
+	for _, pq := range p.Queries {
+		if reason := t.applies(pq.Platform, pq.Version); reason != "" {
+			filtered = append(filtered, FilteredEntry{Pack: p.PackName, Query: pq.QueryName, Reason: reason})
+			continue
+		}
+		result.Queries = append(result.Queries, pq)
+	}
+	if len(p.Queries) > 0 && len(result.Queries) == 0 {
+		filtered = append(filtered, FilteredEntry{Pack: p.PackName, Reason: "no queries apply"})
+		return result, false, filtered
+	}
+	return result, true, filtered
+}
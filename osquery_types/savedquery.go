@@ -0,0 +1,268 @@
+package osquery_types
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// SavedQueryParamType is the type of a saved query parameter, which decides the values it accepts and
+// how they are written into the SQL
+type SavedQueryParamType string
+
+// Parameters of type string and path are written as quoted SQL strings.  The hash types only accept
+// hex digests of their length, and are written quoted and lower case.  integer, number and boolean
+// values are written as numbers, booleans as 1 or 0
+const (
+	ParamString  SavedQueryParamType = "string"
+	ParamPath    SavedQueryParamType = "path"
+	ParamInteger SavedQueryParamType = "integer"
+	ParamNumber  SavedQueryParamType = "number"
+	ParamBoolean SavedQueryParamType = "boolean"
+	ParamMD5     SavedQueryParamType = "md5"
+	ParamSHA1    SavedQueryParamType = "sha1"
+	ParamSHA256  SavedQueryParamType = "sha256"
+)
+
+var hashLengths = map[SavedQueryParamType]int{ParamMD5: 32, ParamSHA1: 40, ParamSHA256: 64}
+
+var hexRe = regexp.MustCompile(`^[0-9a-fA-F]+$`)
+
+// SavedQueryParam is a parameter of a saved query, written {{name}} in its SQL.  Default is used when
+// the query is launched without a value for the parameter, a parameter without a default must be given
+type SavedQueryParam struct {
+	Name        string              `json:"name"`
+	Type        SavedQueryParamType `json:"type"`
+	Description string              `json:"description,omitempty"`
+	Default     string              `json:"default,omitempty"`
+}
+
+// SavedQuery is a query in the saved query library, launched by name with values for its parameters.
+// Platforms lists the platforms the query runs on, and is empty if it runs on any
+type SavedQuery struct {
+	Name        string            `json:"name"`
+	Description string            `json:"description,omitempty"`
+	Author      string            `json:"author,omitempty"`
+	Tags        []string          `json:"tags,omitempty"`
+	Query       string            `json:"query"`
+	Params      []SavedQueryParam `json:"params,omitempty"`
+	Platforms   []string          `json:"platforms,omitempty"`
+	// CreatedAt and UpdatedAt are in RFC 3339 format
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+// savedQueryNameRe matches the names of saved queries, which are part of the URLs they're launched by
+var savedQueryNameRe = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+var paramNameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// placeholder is a {{name}} in a saved query's SQL, at [start, end)
+type placeholder struct {
+	name       string
+	start, end int
+}
+
+// placeholders returns the {{name}}s in the SQL.  Placeholders are replaced by whole SQL values, so
+// one inside a quoted string is an error: '%' || {{name}} || '%' matches part of a string instead.
+// Placeholders in comments are left alone, as a value can't be escaped inside a comment
+func placeholders(query string) ([]placeholder, error) {
+	found := []placeholder{}
+	var quote byte
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else if strings.HasPrefix(query[i:], "{{") {
+				return nil, fmt.Errorf("parameter at %d is inside a quoted string, use '...' || {{name}} || '...' instead", i)
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case strings.HasPrefix(query[i:], "--"):
+			if end := strings.IndexByte(query[i:], '\n'); end >= 0 {
+				i += end
+			} else {
+				i = len(query)
+			}
+		case strings.HasPrefix(query[i:], "/*"):
+			if end := strings.Index(query[i+2:], "*/"); end >= 0 {
+				i += end + 3
+			} else {
+				i = len(query)
+			}
+		case c == '{' && strings.HasPrefix(query[i:], "{{"):
+			end := strings.Index(query[i:], "}}")
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed {{ at %d", i)
+			}
+			name := strings.TrimSpace(query[i+2 : i+end])
+			if !paramNameRe.MatchString(name) {
+				return nil, fmt.Errorf("invalid parameter name %q", name)
+			}
+			found = append(found, placeholder{name: name, start: i, end: i + end + 2})
+			i += end + 1
+		}
+	}
+	return found, nil
+}
+
+// Validate checks the saved query has a name and SQL, that every parameter used is declared once with
+// a known type and that its platforms are valid
+func (sq SavedQuery) Validate() error {
+	if strings.TrimSpace(sq.Name) == "" {
+		return errors.New("saved query has no name")
+	}
+	if !savedQueryNameRe.MatchString(sq.Name) {
+		return fmt.Errorf("invalid saved query name %q, use letters, digits, '.', '_' and '-'", sq.Name)
+	}
+	if strings.TrimSpace(sq.Query) == "" {
+		return errors.New("saved query has no SQL")
+	}
+	declared := map[string]SavedQueryParam{}
+	for _, p := range sq.Params {
+		if !paramNameRe.MatchString(p.Name) {
+			return fmt.Errorf("invalid parameter name %q", p.Name)
+		}
+		if _, ok := declared[p.Name]; ok {
+			return fmt.Errorf("parameter %q is declared twice", p.Name)
+		}
+		if err := p.Type.validate(); err != nil {
+			return fmt.Errorf("parameter %q: %s", p.Name, err)
+		}
+		if p.Default != "" {
+			if _, err := p.Type.Literal(p.Default); err != nil {
+				return fmt.Errorf("parameter %q default: %s", p.Name, err)
+			}
+		}
+		declared[p.Name] = p
+	}
+	used, err := placeholders(sq.Query)
+	if err != nil {
+		return err
+	}
+	for _, ph := range used {
+		if _, ok := declared[ph.name]; !ok {
+			return fmt.Errorf("parameter %q is used but not declared", ph.name)
+		}
+	}
+	for _, platform := range sq.Platforms {
+		if err := ValidatePlatform(platform); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t SavedQueryParamType) validate() error {
+	switch t {
+	case ParamString, ParamPath, ParamInteger, ParamNumber, ParamBoolean, ParamMD5, ParamSHA1, ParamSHA256:
+		return nil
+	}
+	return fmt.Errorf("unknown type %q", t)
+}
+
+// quoteSQL returns s as a single quoted SQL string
+func quoteSQL(s string) string {
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
+}
+
+// negativeLiteral parenthesises negative numbers, so a number written after a minus sign can't start
+// a comment
+func negativeLiteral(n string) string {
+	if strings.HasPrefix(n, "-") {
+		return "(" + n + ")"
+	}
+	return n
+}
+
+// Literal returns the value as an SQL literal of the type, or an error if it isn't a valid value
+func (t SavedQueryParamType) Literal(value string) (string, error) {
+	if strings.ContainsRune(value, 0) {
+		return "", errors.New("value contains a NUL character")
+	}
+	switch t {
+	case ParamString, ParamPath:
+		return quoteSQL(value), nil
+	case ParamInteger:
+		n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("%q is not an integer", value)
+		}
+		return negativeLiteral(strconv.FormatInt(n, 10)), nil
+	case ParamNumber:
+		f, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+			return "", fmt.Errorf("%q is not a number", value)
+		}
+		return negativeLiteral(strconv.FormatFloat(f, 'g', -1, 64)), nil
+	case ParamBoolean:
+		b, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return "", fmt.Errorf("%q is not a boolean", value)
+		}
+		if b {
+			return "1", nil
+		}
+		return "0", nil
+	case ParamMD5, ParamSHA1, ParamSHA256:
+		value = strings.TrimSpace(value)
+		if len(value) != hashLengths[t] || !hexRe.MatchString(value) {
+			return "", fmt.Errorf("%q is not a %s hash", value, t)
+		}
+		return quoteSQL(strings.ToLower(value)), nil
+	}
+	return "", fmt.Errorf("unknown type %q", t)
+}
+
+// Render returns the SQL with each parameter replaced by its value, or its default if no value is
+// given, written as an SQL literal of the parameter's type.  Values for parameters the query doesn't
+// declare are an error, so a misspelt parameter isn't silently ignored
+func (sq SavedQuery) Render(values map[string]string) (string, error) {
+	declared := map[string]SavedQueryParam{}
+	for _, p := range sq.Params {
+		declared[p.Name] = p
+	}
+	for name := range values {
+		if _, ok := declared[name]; !ok {
+			return "", fmt.Errorf("saved query [%s] has no parameter %q", sq.Name, name)
+		}
+	}
+	literals := map[string]string{}
+	for _, p := range sq.Params {
+		value, ok := values[p.Name]
+		if !ok {
+			if p.Default == "" {
+				return "", fmt.Errorf("no value given for parameter %q", p.Name)
+			}
+			value = p.Default
+		}
+		literal, err := p.Type.Literal(value)
+		if err != nil {
+			return "", fmt.Errorf("parameter %q: %s", p.Name, err)
+		}
+		literals[p.Name] = literal
+	}
+
+	used, err := placeholders(sq.Query)
+	if err != nil {
+		return "", err
+	}
+	rendered := &strings.Builder{}
+	last := 0
+	for _, ph := range used {
+		literal, ok := literals[ph.name]
+		if !ok {
+			return "", fmt.Errorf("parameter %q is used but not declared", ph.name)
+		}
+		rendered.WriteString(sq.Query[last:ph.start])
+		rendered.WriteString(literal)
+		last = ph.end
+	}
+	rendered.WriteString(sq.Query[last:])
+	return rendered.String(), nil
+}
@@ -0,0 +1,123 @@
+package osquery_types
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+// MaxScheduleInterval is the longest interval osquery accepts for a scheduled query, one week
+const MaxScheduleInterval = 604800
+
+// ScheduledQuery is a query in the top level schedule of an osquery config.  Optional fields that
+// are not set are left out of the config so osquery applies its own defaults
+type ScheduledQuery struct {
+	Query    string    `json:"query"`
+	Interval FlexInt   `json:"interval"`
+	Snapshot *FlexBool `json:"snapshot,omitempty"`
+	Removed  *FlexBool `json:"removed,omitempty"`
+	Platform string    `json:"platform,omitempty"`
+	Version  string    `json:"version,omitempty"`
+	Shard    FlexInt   `json:"shard,omitempty"`
+	Denylist *FlexBool `json:"denylist,omitempty"`
+}
+
+// OsquerySchedule maps scheduled query names to their definitions
+type OsquerySchedule map[string]ScheduledQuery
+
+// UnmarshalDynamoDBAttributeValue reads a stored schedule, dropping the placeholder written by
+// earlier versions of SGT so that configs stored before schedules were a map still validate
+func (s *OsquerySchedule) UnmarshalDynamoDBAttributeValue(av *dynamodb.AttributeValue) error {
+	if av == nil || av.NULL != nil {
+		return nil
+	}
+	m := map[string]ScheduledQuery{}
+	if err := dynamodbattribute.Unmarshal(av, &m); err != nil {
+		return err
+	}
+	*s = OsquerySchedule(m)
+	s.upgradeLegacy()
+	return nil
+}
+
+// upgradeLegacy removes the time query stored when the schedule was a fixed struct.  It was never
+// filled in, so it was stored with no query and a zero interval, which osquery would be sent as is
+func (s OsquerySchedule) upgradeLegacy() {
+	if sq, ok := s["time"]; ok && strings.TrimSpace(sq.Query) == "" && sq.Interval == 0 {
+		delete(s, "time")
+	}
+}
+
+var validPlatforms = map[string]bool{
+	"all":     true,
+	"any":     true,
+	"posix":   true,
+	"darwin":  true,
+	"linux":   true,
+	"windows": true,
+	"freebsd": true,
+	"ubuntu":  true,
+	"centos":  true,
+}
+
+// ValidatePlatform returns an error if platform is not a platform constraint osquery understands
+func ValidatePlatform(platform string) error {
+	if platform == "" {
+		return nil
+	}
+	for _, p := range strings.Split(platform, ",") {
+		if !validPlatforms[strings.ToLower(strings.TrimSpace(p))] {
+			return fmt.Errorf("unknown platform %q", p)
+		}
+	}
+	return nil
+}
+
+// ValidateVersion returns an error if version is set but isn't a dotted version number
+func ValidateVersion(version string) error {
+	if version == "" {
+		return nil
+	}
+	if len(versionComponents(version)) == 0 {
+		return fmt.Errorf("invalid version %q", version)
+	}
+	return nil
+}
+
+// Validate checks the query has a statement and that its fields are in the ranges osquery accepts
+func (sq ScheduledQuery) Validate() error {
+	if strings.TrimSpace(sq.Query) == "" {
+		return fmt.Errorf("query must be set")
+	}
+	if sq.Interval < 1 || sq.Interval > MaxScheduleInterval {
+		return fmt.Errorf("interval must be between 1 and %d seconds, got %d", MaxScheduleInterval, sq.Interval)
+	}
+	if sq.Shard < 0 || sq.Shard > 100 {
+		return fmt.Errorf("shard must be between 0 and 100, got %d", sq.Shard)
+	}
+	if err := ValidatePlatform(sq.Platform); err != nil {
+		return err
+	}
+	return ValidateVersion(sq.Version)
+}
+
+// Validate checks every query in the schedule, reporting the first invalid one by name
+func (s OsquerySchedule) Validate() error {
+	names := make([]string, 0, len(s))
+	for name := range s {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if strings.TrimSpace(name) == "" {
+			return fmt.Errorf("scheduled query name must be set")
+		}
+		if err := s[name].Validate(); err != nil {
+			return fmt.Errorf("scheduled query %q: %s", name, err)
+		}
+	}
+	return nil
+}
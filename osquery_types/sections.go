@@ -0,0 +1,207 @@
+package osquery_types
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// OsqueryYara is the yara section of an osquery config.  Signatures groups rule files by name and
+// FilePaths maps file_paths categories to the signature groups their changes are scanned with
+type OsqueryYara struct {
+	Signatures    map[string][]string `json:"signatures,omitempty"`
+	FilePaths     map[string][]string `json:"file_paths,omitempty"`
+	SignatureUrls []string            `json:"signature_urls,omitempty"`
+}
+
+// OsqueryEvents is the events section of an osquery config
+type OsqueryEvents struct {
+	DisableSubscribers []string `json:"disable_subscribers,omitempty"`
+	EnableSubscribers  []string `json:"enable_subscribers,omitempty"`
+}
+
+// ATCTable is a table generated by osquery's auto table construction from a sqlite database on the node
+type ATCTable struct {
+	Query    string   `json:"query"`
+	Path     string   `json:"path"`
+	Columns  []string `json:"columns"`
+	Platform string   `json:"platform,omitempty"`
+}
+
+// PrometheusTargets is the prometheus_targets section of an osquery config
+type PrometheusTargets struct {
+	Timeout FlexInt  `json:"timeout,omitempty"`
+	Urls    []string `json:"urls"`
+}
+
+// sortedKeys returns the keys of m in order, so validation errors are reported consistently
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// validateCategories checks each category has a name and at least one non-empty entry
+func validateCategories(section string, m map[string][]string) error {
+	for _, category := range sortedKeys(m) {
+		if strings.TrimSpace(category) == "" {
+			return fmt.Errorf("%s: category name must be set", section)
+		}
+		if len(m[category]) == 0 {
+			return fmt.Errorf("%s: category %q has no entries", section, category)
+		}
+		for _, entry := range m[category] {
+			if strings.TrimSpace(entry) == "" {
+				return fmt.Errorf("%s: category %q has an empty entry", section, category)
+			}
+		}
+	}
+	return nil
+}
+
+// Validate checks every decorator interval is a whole number of minutes, which osquery requires
+func (d OsqueryDecorators) Validate() error {
+	for _, interval := range sortedKeys(d.Interval) {
+		seconds, err := strconv.Atoi(interval)
+		if err != nil || seconds <= 0 || seconds%60 != 0 {
+			return fmt.Errorf("decorators: interval %q must be a positive multiple of 60 seconds", interval)
+		}
+		for _, query := range d.Interval[interval] {
+			if strings.TrimSpace(query) == "" {
+				return fmt.Errorf("decorators: interval %q has an empty query", interval)
+			}
+		}
+	}
+	return nil
+}
+
+// Validate checks the signature groups are populated and that file_paths refers to known categories
+// and signature groups
+func (y OsqueryYara) Validate(filePaths map[string][]string) error {
+	if err := validateCategories("yara signatures", y.Signatures); err != nil {
+		return err
+	}
+	for _, category := range sortedKeys(y.FilePaths) {
+		if _, ok := filePaths[category]; !ok {
+			return fmt.Errorf("yara: file_paths category %q is not defined in file_paths", category)
+		}
+		for _, group := range y.FilePaths[category] {
+			if _, ok := y.Signatures[group]; !ok {
+				return fmt.Errorf("yara: file_paths category %q uses unknown signature group %q", category, group)
+			}
+		}
+	}
+	for _, u := range y.SignatureUrls {
+		if strings.TrimSpace(u) == "" {
+			return fmt.Errorf("yara: signature_urls has an empty entry")
+		}
+	}
+	return nil
+}
+
+// Validate checks the table has everything osquery needs to construct it
+func (t ATCTable) Validate() error {
+	if strings.TrimSpace(t.Query) == "" {
+		return fmt.Errorf("query must be set")
+	}
+	if strings.TrimSpace(t.Path) == "" {
+		return fmt.Errorf("path must be set")
+	}
+	if len(t.Columns) == 0 {
+		return fmt.Errorf("columns must be set")
+	}
+	return ValidatePlatform(t.Platform)
+}
+
+// Validate checks each target is an http or https url
+func (p PrometheusTargets) Validate() error {
+	if p.Timeout < 0 {
+		return fmt.Errorf("prometheus_targets: timeout must not be negative")
+	}
+	for _, target := range p.Urls {
+		u, err := url.Parse(target)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("prometheus_targets: invalid url %q", target)
+		}
+	}
+	return nil
+}
+
+// Validate checks the options against the osquery flag schema and each section of the config, including that sections which refer to file_paths
+// categories only use categories that are defined
+func (oc OsqueryConfig) Validate() error {
+	if err := oc.Options.Validate(); err != nil {
+		return err
+	}
+	if err := oc.Schedule.Validate(); err != nil {
+		return err
+	}
+	if err := oc.Decorators.Validate(); err != nil {
+		return err
+	}
+	if err := validateCategories("file_paths", oc.FilePaths); err != nil {
+		return err
+	}
+	for _, category := range oc.FileAccesses {
+		if _, ok := oc.FilePaths[category]; !ok {
+			return fmt.Errorf("file_accesses: category %q is not defined in file_paths", category)
+		}
+	}
+	if err := validateCategories("exclude_paths", oc.ExcludePaths); err != nil {
+		return err
+	}
+	for _, category := range sortedKeys(oc.ExcludePaths) {
+		if _, ok := oc.FilePaths[category]; !ok {
+			return fmt.Errorf("exclude_paths: category %q is not defined in file_paths", category)
+		}
+	}
+	if oc.Yara != nil {
+		if err := oc.Yara.Validate(oc.FilePaths); err != nil {
+			return err
+		}
+	}
+	for _, name := range sortedStringKeys(oc.Views) {
+		if strings.TrimSpace(name) == "" || strings.TrimSpace(oc.Views[name]) == "" {
+			return fmt.Errorf("views: view %q must have a name and a query", name)
+		}
+	}
+	tables := make([]string, 0, len(oc.AutoTableConstruction))
+	for name := range oc.AutoTableConstruction {
+		tables = append(tables, name)
+	}
+	sort.Strings(tables)
+	for _, name := range tables {
+		if strings.TrimSpace(name) == "" {
+			return fmt.Errorf("auto_table_construction: table name must be set")
+		}
+		if err := oc.AutoTableConstruction[name].Validate(); err != nil {
+			return fmt.Errorf("auto_table_construction: table %q: %s", name, err)
+		}
+	}
+	if oc.PrometheusTargets != nil {
+		if err := oc.PrometheusTargets.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func sortedStringKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Redacted returns a copy of the config with sensitive options redacted, for showing to users
+func (oc OsqueryConfig) Redacted() OsqueryConfig {
+	oc.Options = oc.Options.Redacted()
+	return oc
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/oktasecuritylabs/sgt/handlers/distributed"
 	"github.com/oktasecuritylabs/sgt/handlers/node"
 	"github.com/oktasecuritylabs/sgt/internal/pkg/filecarver"
+	"github.com/oktasecuritylabs/sgt/internal/pkg/sqllint"
 	"github.com/oktasecuritylabs/sgt/osquery_types"
 	"github.com/urfave/negroni"
 )
@@ -19,10 +20,15 @@ func Serve() error {
 	dynb := dyndb.NewDynamoDB()
 
 	router := mux.NewRouter()
+	campaignBroker := distributed.NewCampaignBroker()
 	serverConfig, err := osquery_types.GetServerConfig("config.json")
 	if err != nil {
 		return err
 	}
+	lintPolicy, err := sqllint.ParsePolicy(serverConfig.SQLLintPolicy)
+	if err != nil {
+		return err
+	}
 	//node endpoint
 	nodeAPI := router.PathPrefix("/node").Subrouter()
 	nodeAPI.Path("/configure").Handler(node.NodeConfigureRequest(dynb, serverConfig))
@@ -34,24 +40,50 @@ func Serve() error {
 	//apiRouter.HandleFunc("/configs", api.GetNamedConfigs).Methods(http.MethodGet, http.MethodPost)
 	apiRouter.Handle("/configs", api.GetNamedConfigsHandler(dynb)).Methods(http.MethodGet, http.MethodPost)
 	apiRouter.Handle("/configs/{config_name}", api.ConfigurationRequestHandler(dynb))
+	apiRouter.Handle("/configs/{config_name}/references", api.ReferencesHandler(dynb)).Methods(http.MethodGet)
+	apiRouter.Handle("/configs/{config_name}/render", api.RenderNamedConfigHandler(dynb, serverConfig)).Methods(http.MethodGet)
+	apiRouter.Handle("/configs/{config_name}/rollout", api.ConfigRolloutHandler(dynb, serverConfig)).Methods(http.MethodGet)
+	apiRouter.Handle("/configs/{config_name}/schedule", api.ConfigScheduleHandler(dynb)).Methods(http.MethodGet, http.MethodPost)
+	apiRouter.Handle("/configs/{config_name}/schedule/{query_name}", api.ConfigScheduledQueryHandler(dynb)).Methods(http.MethodGet, http.MethodPost, http.MethodDelete)
 	//apiRouter.HandleFunc("/configs/{config_name}", api.ConfigurationRequest).Methods(http.MethodPost)
 	//Nodes
 	//apiRouter.HandleFunc("/nodes", api.GetNodes).Methods(http.MethodGet)
 	apiRouter.Handle("/nodes", api.GetNodesHandler(dynb))
+	apiRouter.Handle("/nodes/resolve", api.ResolveNodesHandler(dynb)).Methods(http.MethodGet)
 	//apiRouter.HandleFunc("/nodes/{node_key}", api.ConfigureNode).Methods(http.MethodPost, http.MethodGet)
-	apiRouter.Handle("/nodes/{node_key}", api.ConfigureNodeHandler(dynb))
+	apiRouter.Handle("/nodes/{node_key}", api.ConfigureNodeHandler(dynb)).Methods(http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch)
 	apiRouter.Handle("/nodes/{node_key}", api.DeleteNodeHandler(dynb)).Methods(http.MethodDelete)
 	apiRouter.Handle("/nodes/{node_key}/approve", api.ApproveNode(dynb)).Methods(http.MethodPost)
+	apiRouter.Handle("/nodes/{node_key}/rendered-config", api.RenderedNodeConfigHandler(dynb, serverConfig)).Methods(http.MethodGet)
+	apiRouter.Handle("/nodes/{node_key}/override", api.ConfigureNodeOverrideHandler(dynb)).Methods(http.MethodGet, http.MethodPost, http.MethodDelete)
+	apiRouter.Handle("/nodes/{node_key}/distributed/statuses", api.DistributedStatusesHandler(dynb)).Methods(http.MethodGet)
 	//apiRouter.HandleFunc("/nodes/approve/_bulk", api.Placeholder).Methods("POST)
 	//Packs
 	apiRouter.Handle("/packs", api.GetQueryPacks(dynb)).Methods(http.MethodGet)
+	apiRouter.Handle("/packschedule", api.PackScheduleHandler(dynb)).Methods(http.MethodGet)
 	apiRouter.Handle("/packs/search/{search_string}", api.SearchQueryPacks(dynb)).Methods(http.MethodGet)
-	apiRouter.Handle("/packs/{pack_name}", api.ConfigurePack(dynb)).Methods(http.MethodPost)
+	apiRouter.Handle("/packs/import", api.ImportPackHandler(dynb, lintPolicy)).Methods(http.MethodPost)
+	apiRouter.Handle("/packs/{pack_name}/export", api.ExportPackHandler(dynb)).Methods(http.MethodGet)
+	apiRouter.Handle("/packs/{pack_name}", api.ConfigurePack(dynb, lintPolicy)).Methods(http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete)
+	apiRouter.Handle("/packs/{pack_name}/references", api.ReferencesHandler(dynb)).Methods(http.MethodGet)
+	apiRouter.Handle("/packs/{pack_name}/queries/{query_name}", api.ConfigurePackQuery(dynb, lintPolicy))
+	apiRouter.Handle("/packs/{pack_name}/queries/{query_name}/references", api.ReferencesHandler(dynb)).Methods(http.MethodGet)
 	//PackQueries
 	apiRouter.Handle("/packqueries", api.GetPackQueries(dynb)).Methods(http.MethodGet)
-	apiRouter.Handle("/packqueries/{query_name}", api.ConfigurePackQuery(dynb))
+	apiRouter.Handle("/packqueries/{query_name}", api.ConfigurePackQuery(dynb, lintPolicy))
 	apiRouter.Handle("/packqueries/search/{search_string}", api.SearchPackQueries(dynb))
-	apiRouter.Handle("/distributed/add", distributed.DistributedQueryAdd(dynb))
+	apiRouter.Handle("/packqueries/{query_name}/references", api.ReferencesHandler(dynb)).Methods(http.MethodGet)
+	apiRouter.Handle("/distributed/add", distributed.DistributedQueryAdd(dynb, lintPolicy))
+	apiRouter.Handle("/distributed/campaigns", distributed.CampaignsHandler(dynb, lintPolicy)).Methods(http.MethodGet, http.MethodPost)
+	apiRouter.Handle("/distributed/campaigns/{campaign_id}", distributed.CampaignHandler(dynb)).Methods(http.MethodGet)
+	apiRouter.Handle("/distributed/campaigns/{campaign_id}/results", distributed.CampaignResultsHandler(dynb)).Methods(http.MethodGet)
+	apiRouter.Handle("/distributed/campaigns/{campaign_id}/stream", distributed.CampaignStreamHandler(dynb, campaignBroker)).Methods(http.MethodGet)
+	apiRouter.Handle("/distributed/schedules", distributed.DistributedSchedulesHandler(dynb)).Methods(http.MethodGet, http.MethodPost)
+	apiRouter.Handle("/distributed/schedules/{schedule_id}", distributed.DistributedScheduleHandler(dynb)).Methods(http.MethodGet, http.MethodDelete)
+	apiRouter.Handle("/distributed/schedules/{schedule_id}/runs", distributed.DistributedScheduleRunsHandler(dynb)).Methods(http.MethodGet)
+	apiRouter.Handle("/distributed/queries", distributed.SavedQueriesHandler(dynb)).Methods(http.MethodGet, http.MethodPost)
+	apiRouter.Handle("/distributed/queries/{query_name}", distributed.SavedQueryHandler(dynb)).Methods(http.MethodGet, http.MethodDelete)
+	apiRouter.Handle("/distributed/queries/{query_name}/launch", distributed.LaunchSavedQueryHandler(dynb, lintPolicy)).Methods(http.MethodPost)
 	//Enforce uiAuth for all our api configuration endpoints
 	router.PathPrefix("/api/v1/configuration").Handler(negroni.New(
 		negroni.NewRecovery(),
@@ -61,9 +93,15 @@ func Serve() error {
 	//token
 	router.Handle("/api/v1/get-token", auth.GetTokenHandler(dynb))
 	//Distributed endpoint
+	resultWriter, err := distributed.NewResultWriter(serverConfig)
+	if err != nil {
+		return err
+	}
+	distributed.StartSweeper(dynb, serverConfig.DistributedDeliveryPolicy(), distributed.SweepInterval)
+	distributed.StartScheduler(dynb, lintPolicy, distributed.SchedulerInterval)
 	distributedRouter := mux.NewRouter().PathPrefix("/distributed").Subrouter()
-	distributedRouter.Handle("/read", distributed.DistributedQueryRead(dynb))
-	distributedRouter.Handle("/write", distributed.DistributedQueryWrite(dynb))
+	distributedRouter.Handle("/read", distributed.DistributedQueryRead(dynb, serverConfig))
+	distributedRouter.Handle("/write", distributed.DistributedQueryWrite(dynb, resultWriter, campaignBroker))
 	//auth for distributed read/write
 	router.PathPrefix("/distributed").Handler(negroni.New(
 		negroni.NewRecovery(),
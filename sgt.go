@@ -5,6 +5,7 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"os"
 	"sort"
@@ -14,6 +15,7 @@ import (
 	"github.com/oktasecuritylabs/sgt/handlers/auth"
 	"github.com/oktasecuritylabs/sgt/handlers/deploy"
 	"github.com/oktasecuritylabs/sgt/handlers/helpers"
+	"github.com/oktasecuritylabs/sgt/internal/pkg/shell"
 	"github.com/oktasecuritylabs/sgt/logger"
 	"github.com/oktasecuritylabs/sgt/server"
 )
@@ -25,6 +27,9 @@ const (
 	createUser   = "create-user"
 	runDeploy    = "deploy"
 	runDestroy   = "destroy"
+	importFleet  = "import-fleet"
+	exportFleet  = "export-fleet"
+	runShell     = "shell"
 )
 
 var commands = map[string]string{
@@ -34,6 +39,9 @@ var commands = map[string]string{
 	createUser:   "Create a new user",
 	runDeploy:    "Deploy new sgt environment",
 	runDestroy:   "Destroy existing infrastructure",
+	importFleet:  "Import Kolide Fleet (fleetctl) specs",
+	exportFleet:  "Export a config and its packs as Kolide Fleet (fleetctl) specs",
+	runShell:     "Run SQL interactively on a node, tag or selector as distributed queries",
 }
 
 func printHelp(err interface{}) {
@@ -299,6 +307,110 @@ func runSGT() error {
 			}
 		}
 
+	case importFleet:
+		importFleetCommand := flag.NewFlagSet(importFleet, flag.ExitOnError)
+		fileFlag := importFleetCommand.String("file", "", "fleetctl spec file to import")
+		configFlag := importFleetCommand.String("config", "default", "named config to import options and packs into")
+		replaceFlag := importFleetCommand.Bool("replace", false, "replace existing packs and queries")
+		dryRunFlag := importFleetCommand.Bool("dry-run", false, "report what would be imported without saving anything")
+		credFileFlag := importFleetCommand.String("credentials-file", "~/.aws/credentials",
+			"path to aws credentials file (default: ~/.aws/credentials)")
+		profileFlag := importFleetCommand.String("profile", "", "aws profile name")
+
+		importFleetCommand.Parse(os.Args[2:])
+
+		if strings.TrimSpace(*fileFlag) == "" {
+			importFleetCommand.Usage()
+			return errors.New("spec file required, please pass via -file flag")
+		}
+		if strings.TrimSpace(*profileFlag) == "" {
+			importFleetCommand.Usage()
+			return errors.New("aws profile name required, please pass via -profile flag")
+		}
+		data, err := ioutil.ReadFile(*fileFlag)
+		if err != nil {
+			return err
+		}
+		dyn := dyndb.DynDB{DB: auth.CrendentialedDbInstance(*credFileFlag, *profileFlag)}
+		report, err := deploy.ImportFleet(dyn, data, *configFlag, *replaceFlag, *dryRunFlag)
+		for _, entry := range report.Unmapped {
+			logger.Warn(entry)
+		}
+		if err != nil {
+			return err
+		}
+		if *dryRunFlag {
+			logger.Info("Dry run, nothing was imported")
+		}
+
+	case exportFleet:
+		exportFleetCommand := flag.NewFlagSet(exportFleet, flag.ExitOnError)
+		fileFlag := exportFleetCommand.String("file", "", "file to write the specs to (default: stdout)")
+		configFlag := exportFleetCommand.String("config", "default", "named config to export")
+		credFileFlag := exportFleetCommand.String("credentials-file", "~/.aws/credentials",
+			"path to aws credentials file (default: ~/.aws/credentials)")
+		profileFlag := exportFleetCommand.String("profile", "", "aws profile name")
+
+		exportFleetCommand.Parse(os.Args[2:])
+
+		if strings.TrimSpace(*profileFlag) == "" {
+			exportFleetCommand.Usage()
+			return errors.New("aws profile name required, please pass via -profile flag")
+		}
+		dyn := dyndb.DynDB{DB: auth.CrendentialedDbInstance(*credFileFlag, *profileFlag)}
+		out, report, err := deploy.ExportFleet(dyn, *configFlag)
+		for _, entry := range report.Unmapped {
+			logger.Warn(entry)
+		}
+		if err != nil {
+			return err
+		}
+		if *fileFlag == "" {
+			_, err = os.Stdout.Write(out)
+			return err
+		}
+		return ioutil.WriteFile(*fileFlag, out, 0644)
+
+	case runShell:
+		shellCommand := flag.NewFlagSet(runShell, flag.ExitOnError)
+		serverFlag := shellCommand.String("server", "", "sgt server url, eg https://sgt.example.com")
+		usernameFlag := shellCommand.String("username", "", "username to log in as")
+		nodeFlag := shellCommand.String("node", "", "host identifier, hostname or node key of the node to query")
+		tagFlag := shellCommand.String("tag", "", "query the nodes with this tag")
+		selectorFlag := shellCommand.String("selector", "", "query the nodes this node selector picks out")
+		formatFlag := shellCommand.String("format", string(shell.FormatTable), "print results as table, json or csv")
+		timeoutFlag := shellCommand.Int("timeout", shell.DefaultTimeout, "seconds nodes have to answer each query")
+		insecureFlag := shellCommand.Bool("insecure", false, "don't verify the server's certificate")
+
+		shellCommand.Parse(os.Args[2:])
+
+		if strings.TrimSpace(*serverFlag) == "" {
+			shellCommand.Usage()
+			return errors.New("server url required, please pass via -server flag")
+		}
+		if strings.TrimSpace(*usernameFlag) == "" {
+			shellCommand.Usage()
+			return errors.New("username required, please pass via -username flag")
+		}
+		targets, err := shell.Targets(*nodeFlag, *tagFlag, *selectorFlag)
+		if err != nil {
+			shellCommand.Usage()
+			return err
+		}
+		format, err := shell.ParseFormat(*formatFlag)
+		if err != nil {
+			return err
+		}
+		password, err := shell.ReadPassword(os.Stdin)
+		if err != nil {
+			return err
+		}
+		client := shell.NewClient(*serverFlag, *insecureFlag)
+		if err := client.Login(*usernameFlag, password); err != nil {
+			return err
+		}
+		return shell.New(client, targets, format, *timeoutFlag).Run(os.Stdin)
+
 	case runServer:
 		return server.Serve()
 	default:
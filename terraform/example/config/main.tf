@@ -12,4 +12,5 @@ module "config" {
   terraform_backend_bucket_name = "${var.terraform_backend_bucket_name}"
   environment = "${var.environment}"
   auto_approve_nodes = "${var.auto_approve_nodes}"
+  sql_lint_policy = "${var.sql_lint_policy}"
 }
\ No newline at end of file
@@ -10,6 +10,34 @@ output "dynamo_table_osquery_distributed_queries_arn" {
   value = "${module.datastore.dynamo_table_osquery_distributed_queries_arn}"
 }
 
+output "dynamo_table_osquery_distributed_statuses_arn" {
+  value = "${module.datastore.dynamo_table_osquery_distributed_statuses_arn}"
+}
+
+output "dynamo_table_osquery_campaigns_arn" {
+  value = "${module.datastore.dynamo_table_osquery_campaigns_arn}"
+}
+
+output "dynamo_table_osquery_campaign_nodes_arn" {
+  value = "${module.datastore.dynamo_table_osquery_campaign_nodes_arn}"
+}
+
+output "dynamo_table_osquery_distributed_schedules_arn" {
+  value = "${module.datastore.dynamo_table_osquery_distributed_schedules_arn}"
+}
+
+output "dynamo_table_osquery_distributed_schedule_runs_arn" {
+  value = "${module.datastore.dynamo_table_osquery_distributed_schedule_runs_arn}"
+}
+
+output "dynamo_table_osquery_leases_arn" {
+  value = "${module.datastore.dynamo_table_osquery_leases_arn}"
+}
+
+output "dynamo_table_osquery_saved_queries_arn" {
+  value = "${module.datastore.dynamo_table_osquery_saved_queries_arn}"
+}
+
 output "dynamo_table_osquery_packqueries_arn" {
   value = "${module.datastore.dynamo_table_osquery_packqueries_arn}"
 }
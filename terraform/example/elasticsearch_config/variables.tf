@@ -13,4 +13,7 @@ variable "ssl_private_key" {}
 variable "terraform_backend_bucket_name" {}
 
 variable "environment" {}
-variable "auto_approve_nodes" {}
\ No newline at end of file
+variable "auto_approve_nodes" {}
+variable "sql_lint_policy" {
+  default = "warn"
+}
@@ -80,6 +80,13 @@ data "aws_iam_policy_document" "server_dynamo_access_policy_doc" {
       "${data.terraform_remote_state.datastore.dynamo_table_osquery_clients_arn}",
       "${data.terraform_remote_state.datastore.dynamo_table_osquery_configurations_arn}",
       "${data.terraform_remote_state.datastore.dynamo_table_osquery_distributed_queries_arn}",
+      "${data.terraform_remote_state.datastore.dynamo_table_osquery_distributed_statuses_arn}",
+      "${data.terraform_remote_state.datastore.dynamo_table_osquery_campaigns_arn}",
+      "${data.terraform_remote_state.datastore.dynamo_table_osquery_campaign_nodes_arn}",
+      "${data.terraform_remote_state.datastore.dynamo_table_osquery_distributed_schedules_arn}",
+      "${data.terraform_remote_state.datastore.dynamo_table_osquery_distributed_schedule_runs_arn}",
+      "${data.terraform_remote_state.datastore.dynamo_table_osquery_leases_arn}",
+      "${data.terraform_remote_state.datastore.dynamo_table_osquery_saved_queries_arn}",
       "${data.terraform_remote_state.datastore.dynamo_table_osquery_packqueries_arn}",
       "${data.terraform_remote_state.datastore.dynamo_table_osquery_querypacks_arn}",
       "${data.terraform_remote_state.datastore.dynamo_table_osquery_users_arn}",
@@ -42,6 +42,121 @@ resource "aws_dynamodb_table" "osquery_distributed_queries" {
   }
 }
 
+resource "aws_dynamodb_table" "osquery_distributed_statuses" {
+  name = "osquery_distributed_statuses"
+  hash_key = "node_key"
+  range_key = "status_id"
+  read_capacity = "${var.distributed_table_read_capacity}"
+  write_capacity = "${var.distributed_table_write_capacity}"
+
+  attribute {
+    name = "node_key"
+    type = "S"
+  }
+
+  attribute {
+    name = "status_id"
+    type = "S"
+  }
+
+  ttl {
+    attribute_name = "time_to_live"
+    enabled = true
+  }
+}
+
+
+resource "aws_dynamodb_table" "osquery_campaigns" {
+  name = "osquery_campaigns"
+  hash_key = "campaign_id"
+  read_capacity = "${var.distributed_table_read_capacity}"
+  write_capacity = "${var.distributed_table_write_capacity}"
+
+  attribute {
+    name = "campaign_id"
+    type = "S"
+  }
+}
+
+
+resource "aws_dynamodb_table" "osquery_campaign_nodes" {
+  name = "osquery_campaign_nodes"
+  hash_key = "campaign_id"
+  range_key = "node_key"
+  read_capacity = "${var.distributed_table_read_capacity}"
+  write_capacity = "${var.distributed_table_write_capacity}"
+
+  attribute {
+    name = "campaign_id"
+    type = "S"
+  }
+
+  attribute {
+    name = "node_key"
+    type = "S"
+  }
+}
+
+
+
+resource "aws_dynamodb_table" "osquery_distributed_schedules" {
+  name = "osquery_distributed_schedules"
+  hash_key = "schedule_id"
+  read_capacity = "${var.distributed_table_read_capacity}"
+  write_capacity = "${var.distributed_table_write_capacity}"
+
+  attribute {
+    name = "schedule_id"
+    type = "S"
+  }
+}
+
+
+resource "aws_dynamodb_table" "osquery_distributed_schedule_runs" {
+  name = "osquery_distributed_schedule_runs"
+  hash_key = "schedule_id"
+  range_key = "run_at"
+  read_capacity = "${var.distributed_table_read_capacity}"
+  write_capacity = "${var.distributed_table_write_capacity}"
+
+  attribute {
+    name = "schedule_id"
+    type = "S"
+  }
+
+  attribute {
+    name = "run_at"
+    type = "S"
+  }
+}
+
+
+resource "aws_dynamodb_table" "osquery_leases" {
+  name = "osquery_leases"
+  hash_key = "lease_name"
+  read_capacity = "${var.distributed_table_read_capacity}"
+  write_capacity = "${var.distributed_table_write_capacity}"
+
+  attribute {
+    name = "lease_name"
+    type = "S"
+  }
+}
+
+
+resource "aws_dynamodb_table" "osquery_saved_queries" {
+  name = "osquery_saved_queries"
+  hash_key = "name"
+  read_capacity = "${var.distributed_table_read_capacity}"
+  write_capacity = "${var.distributed_table_write_capacity}"
+
+  attribute {
+    name = "name"
+    type = "S"
+  }
+}
+
+
 
 resource "aws_dynamodb_table" "osquery_packqueries" {
   name = "osquery_packqueries"
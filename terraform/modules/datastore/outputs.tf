@@ -10,6 +10,34 @@ output "dynamo_table_osquery_distributed_queries_arn" {
   value = "${aws_dynamodb_table.osquery_distributed_queries.arn}"
 }
 
+output "dynamo_table_osquery_distributed_statuses_arn" {
+  value = "${aws_dynamodb_table.osquery_distributed_statuses.arn}"
+}
+
+output "dynamo_table_osquery_campaigns_arn" {
+  value = "${aws_dynamodb_table.osquery_campaigns.arn}"
+}
+
+output "dynamo_table_osquery_campaign_nodes_arn" {
+  value = "${aws_dynamodb_table.osquery_campaign_nodes.arn}"
+}
+
+output "dynamo_table_osquery_distributed_schedules_arn" {
+  value = "${aws_dynamodb_table.osquery_distributed_schedules.arn}"
+}
+
+output "dynamo_table_osquery_distributed_schedule_runs_arn" {
+  value = "${aws_dynamodb_table.osquery_distributed_schedule_runs.arn}"
+}
+
+output "dynamo_table_osquery_leases_arn" {
+  value = "${aws_dynamodb_table.osquery_leases.arn}"
+}
+
+output "dynamo_table_osquery_saved_queries_arn" {
+  value = "${aws_dynamodb_table.osquery_saved_queries.arn}"
+}
+
 output "dynamo_table_osquery_packqueries_arn" {
   value = "${aws_dynamodb_table.osquery_packqueries.arn}"
 }
@@ -34,6 +34,7 @@ data "template_file" "sgt-config-file" {
     firehose_stream_name = "${data.terraform_remote_state.firehose.sgt-firehose-stream-name}",
     distributed_query_logger_firehose_stream_name = "${data.terraform_remote_state.firehose.sgt-distributed-firehose-stream-name}"
     auto_approve_nodes = "${var.auto_approve_nodes}"
+    sql_lint_policy = "${var.sql_lint_policy}"
   }
 }
 
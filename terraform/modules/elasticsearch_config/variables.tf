@@ -17,4 +17,9 @@ variable "priv_key" {
 variable "terraform_backend_bucket_name" {}
 
 variable "environment" {}
-variable "auto_approve_nodes" {}
\ No newline at end of file
+variable "auto_approve_nodes" {}
+
+variable "sql_lint_policy" {
+  description = "off, warn or reject, see the SQL linting section of docs/API.md"
+  default = "warn"
+}